@@ -0,0 +1,189 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/annotation_codec.go
+
+package client
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+)
+
+// TraceContextRejectedEvent is the event recorded on a span started without the object's stored trace context
+// because the AnnotationCodec rejected it.
+const TraceContextRejectedEvent = "trace context rejected"
+
+// ErrInvalidTraceSignature is returned by HMACAnnotationCodec.Decode when the stored trace context is not signed
+// with the codec's key.
+var ErrInvalidTraceSignature = errors.New("invalid trace context signature")
+
+// ErrEmptyHMACSecret is returned by NewHMACAnnotationCodec for an empty secret, which anyone could sign with.
+var ErrEmptyHMACSecret = errors.New("HMAC annotation codec secret is empty")
+
+// AnnotationCodec controls how trace context is stored in object annotations.
+type AnnotationCodec interface {
+	// Encode returns the annotations that store traceParent and traceState.
+	// Keys mapped to an empty value are removed from the object, which is how an empty traceParent clears the trace.
+	Encode(traceParent, traceState string) map[string]string
+
+	// Decode reads the trace context from annotations. It returns empty values and no error when no trace context
+	// is stored, and an error when the stored trace context must not be trusted.
+	Decode(annotations map[string]string) (traceParent, traceState string, err error)
+}
+
+// PlaintextAnnotationCodec stores the traceparent and tracestate values as they are.
+// Empty keys default to the keys the tracing client emits, or to the operatortrace default annotations when the codec
+// is used on its own.
+type PlaintextAnnotationCodec struct {
+	TraceParentKey string
+	TraceStateKey  string
+}
+
+var _ AnnotationCodec = PlaintextAnnotationCodec{}
+
+// Encode implements AnnotationCodec.
+func (c PlaintextAnnotationCodec) Encode(traceParent, traceState string) map[string]string {
+	if traceParent == "" {
+		traceState = ""
+	}
+	return map[string]string{
+		c.traceParentKey(): traceParent,
+		c.traceStateKey():  traceState,
+	}
+}
+
+// Decode implements AnnotationCodec.
+func (c PlaintextAnnotationCodec) Decode(annotations map[string]string) (string, string, error) {
+	traceParent := annotations[c.traceParentKey()]
+	if traceParent == "" {
+		return "", "", nil
+	}
+	return traceParent, annotations[c.traceStateKey()], nil
+}
+
+// keyedAnnotationCodec is implemented by the codecs that store trace context under annotation keys, so the keys
+// they leave empty can follow the annotation prefix and emitted keys of the options they are used with.
+type keyedAnnotationCodec interface {
+	withDefaultKeys(opts Options) AnnotationCodec
+}
+
+// annotationCodec returns the configured codec with its empty keys resolved against o.
+func (o Options) annotationCodec() AnnotationCodec {
+	if keyed, ok := o.AnnotationCodec.(keyedAnnotationCodec); ok {
+		return keyed.withDefaultKeys(o)
+	}
+	return o.AnnotationCodec
+}
+
+func (c PlaintextAnnotationCodec) withDefaultKeys(opts Options) AnnotationCodec {
+	return c.defaultKeys(opts)
+}
+
+func (c PlaintextAnnotationCodec) defaultKeys(opts Options) PlaintextAnnotationCodec {
+	if c.TraceParentKey == "" {
+		c.TraceParentKey = opts.EmittedTraceParentAnnotationKey()
+	}
+	if c.TraceStateKey == "" {
+		c.TraceStateKey = opts.EmittedTraceStateAnnotationKey()
+	}
+	return c
+}
+
+func (c PlaintextAnnotationCodec) traceParentKey() string {
+	if c.TraceParentKey == "" {
+		return constants.DefaultTraceParentAnnotation
+	}
+	return c.TraceParentKey
+}
+
+func (c PlaintextAnnotationCodec) traceStateKey() string {
+	if c.TraceStateKey == "" {
+		return constants.DefaultTraceStateAnnotation
+	}
+	return c.TraceStateKey
+}
+
+// HMACAnnotationCodec stores the trace context like PlaintextAnnotationCodec and adds an HMAC-SHA256 signature
+// over it, so readers of the annotations cannot forge the parent of the next reconcile.
+type HMACAnnotationCodec struct {
+	PlaintextAnnotationCodec
+
+	// SignatureKey is the annotation holding the signature. Defaults to traceparent-signature under the client's
+	// annotation prefix, or DefaultTraceSignatureAnnotation when the codec is used on its own.
+	SignatureKey string
+
+	secret []byte
+}
+
+var _ AnnotationCodec = (*HMACAnnotationCodec)(nil)
+
+// traceSignatureSuffix names the signature annotation under the annotation prefix.
+const traceSignatureSuffix = "traceparent-signature"
+
+// DefaultTraceSignatureAnnotation is the annotation HMACAnnotationCodec stores its signature in by default.
+const DefaultTraceSignatureAnnotation = constants.DefaultAnnotationPrefix + "/" + traceSignatureSuffix
+
+// NewHMACAnnotationCodec returns a codec that signs the trace context with secret. It returns ErrEmptyHMACSecret
+// when secret is empty.
+func NewHMACAnnotationCodec(secret []byte) (*HMACAnnotationCodec, error) {
+	if len(secret) == 0 {
+		return nil, ErrEmptyHMACSecret
+	}
+	return &HMACAnnotationCodec{secret: append([]byte(nil), secret...)}, nil
+}
+
+func (c *HMACAnnotationCodec) withDefaultKeys(opts Options) AnnotationCodec {
+	keyed := *c
+	keyed.PlaintextAnnotationCodec = c.PlaintextAnnotationCodec.defaultKeys(opts)
+	if keyed.SignatureKey == "" {
+		keyed.SignatureKey = opts.annotationPrefix() + "/" + traceSignatureSuffix
+	}
+	return &keyed
+}
+
+// Encode implements AnnotationCodec.
+func (c *HMACAnnotationCodec) Encode(traceParent, traceState string) map[string]string {
+	annotations := c.PlaintextAnnotationCodec.Encode(traceParent, traceState)
+	signature := ""
+	if traceParent != "" {
+		signature = c.sign(traceParent, annotations[c.traceStateKey()])
+	}
+	annotations[c.signatureKey()] = signature
+	return annotations
+}
+
+// Decode implements AnnotationCodec. It returns ErrInvalidTraceSignature when the signature is missing or does not
+// match the stored trace context.
+func (c *HMACAnnotationCodec) Decode(annotations map[string]string) (string, string, error) {
+	traceParent, traceState, _ := c.PlaintextAnnotationCodec.Decode(annotations)
+	if traceParent == "" {
+		return "", "", nil
+	}
+	signature, err := hex.DecodeString(annotations[c.signatureKey()])
+	if err != nil || !hmac.Equal(signature, c.mac(traceParent, traceState)) {
+		return "", "", ErrInvalidTraceSignature
+	}
+	return traceParent, traceState, nil
+}
+
+func (c *HMACAnnotationCodec) sign(traceParent, traceState string) string {
+	return hex.EncodeToString(c.mac(traceParent, traceState))
+}
+
+func (c *HMACAnnotationCodec) mac(traceParent, traceState string) []byte {
+	h := hmac.New(sha256.New, c.secret)
+	// The separator cannot appear in either header value, so distinct pairs never sign the same bytes.
+	h.Write([]byte(traceParent + "\n" + traceState))
+	return h.Sum(nil)
+}
+
+func (c *HMACAnnotationCodec) signatureKey() string {
+	if c.SignatureKey == "" {
+		return DefaultTraceSignatureAnnotation
+	}
+	return c.SignatureKey
+}
@@ -0,0 +1,233 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/annotation_codec_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	codecTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	codecTraceState  = "operatortrace_ts=1700000000"
+)
+
+func newTestHMACAnnotationCodec(t *testing.T, secret string) *HMACAnnotationCodec {
+	t.Helper()
+	codec, err := NewHMACAnnotationCodec([]byte(secret))
+	require.NoError(t, err)
+	return codec
+}
+
+func TestAnnotationCodecRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec AnnotationCodec
+	}{
+		{"plaintext", PlaintextAnnotationCodec{}},
+		{"hmac", newTestHMACAnnotationCodec(t, "secret")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := tt.codec.Encode(codecTraceParent, codecTraceState)
+			assert.Equal(t, codecTraceParent, annotations[constants.DefaultTraceParentAnnotation])
+
+			traceParent, traceState, err := tt.codec.Decode(annotations)
+			require.NoError(t, err)
+			assert.Equal(t, codecTraceParent, traceParent)
+			assert.Equal(t, codecTraceState, traceState)
+
+			traceParent, traceState, err = tt.codec.Decode(map[string]string{})
+			require.NoError(t, err)
+			assert.Empty(t, traceParent)
+			assert.Empty(t, traceState)
+
+			// Encoding an empty trace clears every annotation the codec owns
+			for key, value := range tt.codec.Encode("", "") {
+				assert.Empty(t, value, key)
+			}
+		})
+	}
+}
+
+func TestNewHMACAnnotationCodecRejectsEmptySecret(t *testing.T) {
+	codec, err := NewHMACAnnotationCodec(nil)
+	assert.ErrorIs(t, err, ErrEmptyHMACSecret)
+	assert.Nil(t, codec)
+}
+
+func TestAnnotationCodecFollowsAnnotationPrefix(t *testing.T) {
+	opts := newOptions(WithAnnotationPrefix("example.com"))
+	tests := []struct {
+		name         string
+		codec        AnnotationCodec
+		expectedKeys []string
+	}{
+		{"plaintext", PlaintextAnnotationCodec{}, []string{"example.com/traceparent", "example.com/tracestate"}},
+		{"hmac", newTestHMACAnnotationCodec(t, "secret"),
+			[]string{"example.com/traceparent", "example.com/tracestate", "example.com/traceparent-signature"}},
+		{"explicit keys", PlaintextAnnotationCodec{TraceParentKey: "a/tp", TraceStateKey: "a/ts"}, []string{"a/tp", "a/ts"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts.AnnotationCodec = tt.codec
+			annotations := map[string]string{}
+			persistTraceCarrier(annotations, opts, codecTraceParent, codecTraceState)
+			assert.ElementsMatch(t, tt.expectedKeys, keysOf(annotations))
+
+			stored, ok, err := decodeWithCodec(annotations, opts)
+			require.NoError(t, err)
+			require.True(t, ok)
+			assert.Equal(t, codecTraceParent, stored.TraceParent)
+		})
+	}
+}
+
+func keysOf(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func TestHMACAnnotationCodecRejectsTampering(t *testing.T) {
+	codec := newTestHMACAnnotationCodec(t, "secret")
+
+	tests := []struct {
+		name   string
+		tamper func(annotations map[string]string)
+	}{
+		{"forged traceparent", func(a map[string]string) {
+			a[constants.DefaultTraceParentAnnotation] = "00-11111111111111111111111111111111-2222222222222222-01"
+		}},
+		{"forged tracestate", func(a map[string]string) {
+			a[constants.DefaultTraceStateAnnotation] = "operatortrace_ts=1"
+		}},
+		{"missing signature", func(a map[string]string) {
+			delete(a, DefaultTraceSignatureAnnotation)
+		}},
+		{"malformed signature", func(a map[string]string) {
+			a[DefaultTraceSignatureAnnotation] = "not-hex"
+		}},
+		{"signed with another key", func(a map[string]string) {
+			for key, value := range newTestHMACAnnotationCodec(t, "other").Encode(codecTraceParent, codecTraceState) {
+				a[key] = value
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			annotations := codec.Encode(codecTraceParent, codecTraceState)
+			tt.tamper(annotations)
+
+			traceParent, traceState, err := codec.Decode(annotations)
+			assert.ErrorIs(t, err, ErrInvalidTraceSignature)
+			assert.Empty(t, traceParent)
+			assert.Empty(t, traceState)
+		})
+	}
+}
+
+func TestTracingClientWithHMACAnnotationCodec(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().WithStatusSubresource(&corev1.Pod{}).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil,
+		WithAnnotationCodec(newTestHMACAnnotationCodec(t, "secret")))
+
+	ctx := context.Background()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "signed-pod", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(ctx, pod))
+
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+	require.NotEmpty(t, stored.Annotations[DefaultTraceSignatureAnnotation])
+	signedTraceID := exporter.GetSpans()[0].SpanContext.TraceID()
+
+	startTrace := func() tracetest.SpanStub {
+		exporter.Reset()
+		_, span, err := tracingClient.StartTrace(ctx, &tracingtypes.RequestWithTraceID{
+			Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "signed-pod", Namespace: "default"}},
+		}, &corev1.Pod{})
+		require.NoError(t, err)
+		span.End()
+		spans := exporter.GetSpans()
+		return spans[len(spans)-1]
+	}
+
+	t.Run("signed trace is continued", func(t *testing.T) {
+		span := startTrace()
+		assert.Equal(t, signedTraceID, span.SpanContext.TraceID())
+		assert.Empty(t, span.Events)
+	})
+
+	t.Run("forged trace starts a new trace", func(t *testing.T) {
+		forged := "00-11111111111111111111111111111111-2222222222222222-01"
+		stored.Annotations[constants.DefaultTraceParentAnnotation] = forged
+		require.NoError(t, k8sClient.Update(ctx, stored))
+
+		span := startTrace()
+		assert.NotEqual(t, signedTraceID, span.SpanContext.TraceID())
+		assert.NotEqual(t, "11111111111111111111111111111111", span.SpanContext.TraceID().String())
+		assert.False(t, span.Parent.IsValid())
+		require.Len(t, span.Events, 1)
+		assert.Equal(t, TraceContextRejectedEvent, span.Events[0].Name)
+	})
+}
+
+func TestHMACAnnotationCodecOnlyStoresVerifiedParents(t *testing.T) {
+	const parentTraceID, parentSpanID = "11111111111111111111111111111111", "2222222222222222"
+	tests := []struct {
+		name     string
+		verified bool
+	}{
+		{"verified parent is continued", true},
+		{"unverified parent is only linked", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+			k8sClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil,
+				WithAnnotationCodec(newTestHMACAnnotationCodec(t, "secret")))
+
+			_, span, err := tracingClient.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "pod", Namespace: "default"}},
+				Parent:  tracingtypes.RequestParent{TraceID: parentTraceID, SpanID: parentSpanID, Verified: tt.verified},
+			}, &corev1.Pod{})
+			require.NoError(t, err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			stub := spans[len(spans)-1]
+			assert.Equal(t, tt.verified, stub.SpanContext.TraceID().String() == parentTraceID)
+			if !tt.verified {
+				require.Len(t, stub.Links, 1)
+				assert.Equal(t, parentTraceID, stub.Links[0].SpanContext.TraceID().String())
+			}
+		})
+	}
+}
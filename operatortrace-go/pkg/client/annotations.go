@@ -6,14 +6,16 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"maps"
 	"time"
 
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
+	"github.com/go-logr/logr"
 	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -22,25 +24,77 @@ type storedTraceContext struct {
 	TraceState   string
 	Timestamp    time.Time
 	Relationship TraceParentRelationship
+	Source       TraceContextSource
 }
 
 // addTraceAnnotations stores the current span context on the kubernetes object using traceparent/tracestate.
-func addTraceAnnotations(ctx context.Context, obj client.Object, opts Options) {
+// It sets a new annotations map on obj rather than changing the one obj holds, see copyAnnotations.
+func addTraceAnnotations(ctx context.Context, logger logr.Logger, obj client.Object, gvk schema.GroupVersionKind, opts Options) {
+	opts = opts.withCallOptions(ctx)
+	traceParent, traceState, ok := traceDataToPersist(ctx, opts)
+	if !ok || skipForeignTraceAnnotation(logger, obj, opts) || traceExpiringSoon(ctx, logger, obj, opts) {
+		return
+	}
+	traceState = withTraceSummaryBookkeeping(traceState, trace.SpanFromContext(ctx), gvk.Kind, opts, time.Now())
+
+	annotations := copyAnnotations(obj)
+	persistTraceCarrier(annotations, opts, traceParent, traceState)
+	obj.SetAnnotations(annotations)
+}
+
+// traceDataToPersist returns the traceparent and tracestate to store for the span in ctx, or false when nothing
+// should be stored. opts must already include the call options of ctx.
+func traceDataToPersist(ctx context.Context, opts Options) (traceParent, traceState string, ok bool) {
+	if opts.SkipTraceAnnotations || opts.exportUnhealthy() {
+		return "", "", false
+	}
 	span := trace.SpanFromContext(ctx)
 	spanContext := span.SpanContext()
 	if !spanContext.IsValid() {
-		return
+		return "", "", false
+	}
+	traceParent, traceState, err := tracecontext.SpanContextToTraceData(spanContext, opts.traceStateTimestampKey(), time.Now())
+	if err != nil {
+		// The timestamp could not be recorded, so keep the span's own trace state
+		traceState = spanContext.TraceState().String()
 	}
+	return traceParent, withChainDecision(traceState, span, opts), true
+}
 
-	annotations := ensureAnnotations(obj)
-	carrier := propagation.MapCarrier{}
-	propagator := otel.GetTextMapPropagator()
-	propagator.Inject(trace.ContextWithSpanContext(context.Background(), spanContext), carrier)
-	if traceState, err := tracecontext.BuildTraceStateString(spanContext, opts.traceStateTimestampKey(), time.Now()); err == nil && traceState != "" {
-		carrier["tracestate"] = traceState
+// skipForeignTraceAnnotation reports, and logs, that the traceparent annotation of obj is owned by a field manager
+// other than the one given to WithAnnotationFieldOwnerCheck, so writing it would conflict.
+func skipForeignTraceAnnotation(logger logr.Logger, obj client.Object, opts Options) bool {
+	owner := traceAnnotationFieldOwner(obj, opts)
+	if owner == "" {
+		return false
 	}
-	persistTraceCarrier(annotations, opts, carrier["traceparent"], carrier["tracestate"])
-	obj.SetAnnotations(annotations)
+	logger.Info("Skipping trace annotation write, the annotation is owned by another field manager",
+		"object", client.ObjectKeyFromObject(obj).String(), "annotation", opts.EmittedTraceParentAnnotationKey(), "fieldManager", owner)
+	return true
+}
+
+// traceAnnotationFieldOwner returns the field manager, other than the one given to WithAnnotationFieldOwnerCheck,
+// that owns the traceparent annotation of obj according to its managed fields, or "" if there is none.
+func traceAnnotationFieldOwner(obj client.Object, opts Options) string {
+	if opts.AnnotationFieldOwnerCheck == "" {
+		return ""
+	}
+	field := "f:" + opts.EmittedTraceParentAnnotationKey()
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager == opts.AnnotationFieldOwnerCheck || entry.FieldsV1 == nil || len(entry.FieldsV1.Raw) == 0 {
+			continue
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			continue
+		}
+		metadataFields, _ := fields["f:metadata"].(map[string]interface{})
+		annotationFields, _ := metadataFields["f:annotations"].(map[string]interface{})
+		if _, owned := annotationFields[field]; owned {
+			return entry.Manager
+		}
+	}
+	return ""
 }
 
 // overrideTraceContextFromRequest persists the trace context from the request struct onto the object annotations.
@@ -54,21 +108,38 @@ func overrideTraceContextFromRequest(request tracingtypes.RequestWithTraceID, ob
 		return
 	}
 
-	annotations := ensureAnnotations(obj)
-	persistTraceCarrier(annotations, opts, traceParent, "")
+	// Keep the stored tracestate of the same trace, which carries decisions such as the chain sampler's
+	traceState := ""
+	if stored, ok := extractTraceContextFromAnnotations(obj.GetAnnotations(), opts); ok && traceIDFromTraceParent(stored.TraceParent) == parent.TraceID {
+		traceState = stored.TraceState
+	}
+
+	annotations := copyAnnotations(obj)
+	persistTraceCarrier(annotations, opts, traceParent, traceState)
 	obj.SetAnnotations(annotations)
 }
 
-func ensureAnnotations(obj client.Object) map[string]string {
-	annotations := obj.GetAnnotations()
-	if annotations == nil {
-		annotations = map[string]string{}
-		obj.SetAnnotations(annotations)
-	}
+// copyAnnotations returns a copy of the annotations of obj to change and set back with SetAnnotations. The client
+// never changes the annotations map of an object in place: a caller still holding the map, e.g. while iterating it
+// in another goroutine, keeps seeing it unchanged instead of racing with the client.
+func copyAnnotations(obj client.Object) map[string]string {
+	annotations := make(map[string]string, len(obj.GetAnnotations())+2)
+	maps.Copy(annotations, obj.GetAnnotations())
 	return annotations
 }
 
 func extractTraceContextFromAnnotations(annotations map[string]string, opts Options) (storedTraceContext, bool) {
+	stored, ok, _ := decodeTraceContextFromAnnotations(annotations, opts)
+	return stored, ok
+}
+
+// decodeTraceContextFromAnnotations is extractTraceContextFromAnnotations that also returns why the configured
+// AnnotationCodec rejected the stored trace context.
+func decodeTraceContextFromAnnotations(annotations map[string]string, opts Options) (storedTraceContext, bool, error) {
+	if opts.AnnotationCodec != nil {
+		return decodeWithCodec(annotations, opts)
+	}
+
 	baseCfg := tracecontext.AnnotationExtractionConfig{
 		LegacyTraceIDKey:       opts.legacyTraceIDAnnotationKey(),
 		LegacySpanIDKey:        opts.legacySpanIDAnnotationKey(),
@@ -82,8 +153,8 @@ func extractTraceContextFromAnnotations(annotations map[string]string, opts Opti
 		relationship TraceParentRelationship
 	}
 
-	emittedParentKey := opts.emittedTraceParentAnnotationKey()
-	emittedStateKey := opts.emittedTraceStateAnnotationKey()
+	emittedParentKey := opts.EmittedTraceParentAnnotationKey()
+	emittedStateKey := opts.EmittedTraceStateAnnotationKey()
 	defaultParentKey := constants.DefaultTraceParentAnnotation
 	defaultStateKey := constants.DefaultTraceStateAnnotation
 
@@ -99,6 +170,16 @@ func extractTraceContextFromAnnotations(annotations map[string]string, opts Opti
 			relationship: TraceParentRelationshipParent,
 		},
 	}
+	// Objects written before a custom emitted key was configured still carry the prefixed key
+	prefixedParentKey := opts.prefixedTraceParentAnnotationKey()
+	prefixedStateKey := opts.prefixedTraceStateAnnotationKey()
+	if prefixedParentKey != emittedParentKey || prefixedStateKey != emittedStateKey {
+		candidates = append(candidates, candidate{
+			parentKey:    prefixedParentKey,
+			stateKey:     prefixedStateKey,
+			relationship: TraceParentRelationshipParent,
+		})
+	}
 	if defaultParentKey != emittedParentKey || defaultStateKey != emittedStateKey {
 		candidates = append(candidates, candidate{
 			parentKey:    defaultParentKey,
@@ -124,7 +205,8 @@ func extractTraceContextFromAnnotations(annotations map[string]string, opts Opti
 				TraceState:   result.TraceState,
 				Timestamp:    result.Timestamp,
 				Relationship: relationship,
-			}, true
+				Source:       annotationSource(result),
+			}, true, nil
 		}
 	}
 
@@ -134,23 +216,75 @@ func extractTraceContextFromAnnotations(annotations map[string]string, opts Opti
 			TraceState:   result.TraceState,
 			Timestamp:    result.Timestamp,
 			Relationship: TraceParentRelationshipParent,
-		}, true
+			Source:       annotationSource(result),
+		}, true, nil
 	}
 
-	return storedTraceContext{}, false
+	return storedTraceContext{}, false, nil
+}
+
+// annotationSource returns where in the annotations result was read from.
+func annotationSource(result tracecontext.AnnotationTraceContext) TraceContextSource {
+	if result.Legacy {
+		return TraceContextSourceLegacy
+	}
+	return TraceContextSourceAnnotation
+}
+
+// decodeWithCodec reads the trace context through opts.AnnotationCodec.
+// Codec annotations are only ever written by operatortrace, so the stored context is always the parent.
+func decodeWithCodec(annotations map[string]string, opts Options) (storedTraceContext, bool, error) {
+	traceParent, traceState, err := opts.annotationCodec().Decode(annotations)
+	if err != nil || traceParent == "" {
+		return storedTraceContext{}, false, err
+	}
+	// Run the decoded values through the regular extraction to normalize the traceparent and read the timestamp
+	result, ok := tracecontext.ExtractTraceContextFromAnnotations(
+		map[string]string{"traceparent": traceParent, "tracestate": traceState},
+		tracecontext.AnnotationExtractionConfig{
+			TraceParentKey:         "traceparent",
+			TraceStateKey:          "tracestate",
+			TraceStateTimestampKey: opts.traceStateTimestampKey(),
+		},
+	)
+	if !ok {
+		return storedTraceContext{}, false, nil
+	}
+	return storedTraceContext{
+		TraceParent:  result.TraceParent,
+		TraceState:   result.TraceState,
+		Timestamp:    result.Timestamp,
+		Relationship: TraceParentRelationshipParent,
+		Source:       TraceContextSourceAnnotation,
+	}, true, nil
 }
 
 func persistTraceCarrier(annotations map[string]string, opts Options, traceParent, traceState string) {
 	pruneLegacyTraceAnnotations(annotations, opts)
+	if traceParent != "" && opts.BinaryAnnotationEncoding {
+		if encoded, err := tracecontext.EncodeTraceParentBinary(traceParent); err == nil {
+			traceParent = encoded
+		}
+	}
+	if opts.AnnotationCodec != nil {
+		for key, value := range opts.annotationCodec().Encode(traceParent, traceState) {
+			if value != "" {
+				annotations[key] = value
+			} else {
+				delete(annotations, key)
+			}
+		}
+		return
+	}
 	if traceParent != "" {
-		annotations[opts.emittedTraceParentAnnotationKey()] = traceParent
+		annotations[opts.EmittedTraceParentAnnotationKey()] = traceParent
 	} else {
-		delete(annotations, opts.emittedTraceParentAnnotationKey())
+		delete(annotations, opts.EmittedTraceParentAnnotationKey())
 	}
 	if traceState != "" {
-		annotations[opts.emittedTraceStateAnnotationKey()] = traceState
+		annotations[opts.EmittedTraceStateAnnotationKey()] = traceState
 	} else {
-		delete(annotations, opts.emittedTraceStateAnnotationKey())
+		delete(annotations, opts.EmittedTraceStateAnnotationKey())
 	}
 }
 
@@ -2,14 +2,18 @@ package client
 
 import (
 	"context"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 func TestExtractTraceContextRelationshipSelection(t *testing.T) {
@@ -19,7 +23,7 @@ func TestExtractTraceContextRelationshipSelection(t *testing.T) {
 	require.NoError(t, err)
 
 	annotations := map[string]string{
-		opts.emittedTraceParentAnnotationKey(): traceParent,
+		opts.EmittedTraceParentAnnotationKey(): traceParent,
 	}
 
 	stored, ok := extractTraceContextFromAnnotations(annotations, opts)
@@ -71,3 +75,217 @@ func TestApplyStoredTraceContextUsesRelationship(t *testing.T) {
 	require.NotNil(t, linkPtr)
 	require.False(t, trace.SpanContextFromContext(ctxNoop).IsValid())
 }
+
+func TestPersistTraceCarrierBinaryEncoding(t *testing.T) {
+	traceParent, err := tracecontext.TraceParentFromIDs("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbb")
+	require.NoError(t, err)
+
+	for name, opts := range map[string]Options{
+		"w3c":    NewOptions(),
+		"binary": NewOptions(WithBinaryAnnotationEncoding()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			annotations := map[string]string{}
+			persistTraceCarrier(annotations, opts, traceParent, "")
+			if opts.BinaryAnnotationEncoding {
+				require.NotEqual(t, traceParent, annotations[opts.EmittedTraceParentAnnotationKey()])
+			}
+
+			stored, ok := extractTraceContextFromAnnotations(annotations, opts)
+			require.True(t, ok)
+			require.Equal(t, traceParent, stored.TraceParent)
+		})
+	}
+
+	// A binary encoded annotation is still readable by a client using the default encoding
+	annotations := map[string]string{}
+	persistTraceCarrier(annotations, NewOptions(WithBinaryAnnotationEncoding()), traceParent, "")
+	stored, ok := extractTraceContextFromAnnotations(annotations, NewOptions())
+	require.True(t, ok)
+	require.Equal(t, traceParent, stored.TraceParent)
+}
+
+func BenchmarkAnnotationEncodingSize(b *testing.B) {
+	traceParent, err := tracecontext.TraceParentFromIDs("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbb")
+	require.NoError(b, err)
+
+	for name, opts := range map[string]Options{
+		"w3c":    NewOptions(),
+		"binary": NewOptions(WithBinaryAnnotationEncoding()),
+	} {
+		b.Run(name, func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				annotations := map[string]string{}
+				persistTraceCarrier(annotations, opts, traceParent, "")
+				size = len(annotations[opts.EmittedTraceParentAnnotationKey()])
+			}
+			b.ReportMetric(float64(size), "bytes/annotation")
+		})
+	}
+}
+
+func TestExtractTraceContextWithCustomEmittedKeys(t *testing.T) {
+	traceParent, err := tracecontext.TraceParentFromIDs("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbb")
+	require.NoError(t, err)
+
+	opts := NewOptions(
+		WithTraceParentKey("example.com/parent"),
+		WithTraceStateKey("example.com/state"),
+	)
+	require.Equal(t, "example.com/parent", opts.EmittedTraceParentAnnotationKey())
+	require.Equal(t, "example.com/state", opts.EmittedTraceStateAnnotationKey())
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		found       bool
+	}{
+		{
+			name: "custom emitted key",
+			annotations: map[string]string{
+				"example.com/parent": traceParent,
+				"example.com/state":  "operatortrace_ts=2024-01-01T00:00:00Z",
+			},
+			found: true,
+		},
+		{
+			name:        "prefixed key written before the custom key was configured",
+			annotations: map[string]string{NewOptions().EmittedTraceParentAnnotationKey(): traceParent},
+			found:       true,
+		},
+		{
+			name:        "unrelated key",
+			annotations: map[string]string{"example.com/other": traceParent},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored, ok := extractTraceContextFromAnnotations(tt.annotations, opts)
+			require.Equal(t, tt.found, ok)
+			if tt.found {
+				require.Equal(t, traceParent, stored.TraceParent)
+				require.Equal(t, TraceParentRelationshipParent, stored.Relationship)
+			}
+		})
+	}
+
+	// Persisting uses the custom keys
+	annotations := map[string]string{}
+	persistTraceCarrier(annotations, opts, traceParent, "")
+	require.Equal(t, map[string]string{"example.com/parent": traceParent}, annotations)
+}
+
+func TestTraceKeyOptionsIgnoreEmptyValues(t *testing.T) {
+	opts := NewOptions(WithTraceParentKey(" "), WithTraceStateKey(""))
+	require.Empty(t, opts.TraceParentKey)
+	require.Empty(t, opts.TraceStateKey)
+	require.Equal(t, NewOptions().EmittedTraceParentAnnotationKey(), opts.EmittedTraceParentAnnotationKey())
+}
+
+func TestIncomingAnnotationKeys(t *testing.T) {
+	defaultKey := constants.DefaultTraceParentAnnotation
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected []string
+	}{
+		{
+			name:     "defaults",
+			expected: []string{defaultKey},
+		},
+		{
+			name:     "incoming key",
+			opts:     []Option{WithIncomingTraceParentAnnotation("example.com/incoming")},
+			expected: []string{"example.com/incoming", defaultKey},
+		},
+		{
+			name:     "custom prefix",
+			opts:     []Option{WithAnnotationPrefix("example.com")},
+			expected: []string{"example.com/traceparent", defaultKey},
+		},
+		{
+			name:     "custom emitted key",
+			opts:     []Option{WithTraceParentKey("example.com/parent")},
+			expected: []string{"example.com/parent", defaultKey},
+		},
+		{
+			name: "custom emitted key and prefix",
+			opts: []Option{
+				WithIncomingTraceParentAnnotation("example.com/incoming"),
+				WithAnnotationPrefix("example.com"),
+				WithTraceParentKey("example.com/parent"),
+			},
+			expected: []string{"example.com/incoming", "example.com/parent", "example.com/traceparent", defaultKey},
+		},
+		{
+			name:     "incoming key equal to the emitted key",
+			opts:     []Option{WithIncomingTraceParentAnnotation(defaultKey)},
+			expected: []string{defaultKey},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, NewOptions(tt.opts...).IncomingAnnotationKeys())
+		})
+	}
+}
+
+func TestValidateAnnotationKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		valid bool
+	}{
+		{"default key", constants.DefaultTraceParentAnnotation, true},
+		{"name without prefix", "traceparent", true},
+		{"name at the length limit", "example.com/" + strings.Repeat("a", 63), true},
+		{"name over the length limit", "example.com/" + strings.Repeat("a", 64), false},
+		{"prefix at the length limit", strings.Repeat("a.", 126) + "a/traceparent", true},
+		{"prefix over the length limit", strings.Repeat("a.", 127) + "a/traceparent", false},
+		{"space in prefix", "invalid annotation/traceparent", false},
+		{"empty name", "example.com/", false},
+		{"invalid character in name", "example.com/trace parent", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAnnotationKey(tt.key)
+			if tt.valid {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, ErrInvalidAnnotationKey)
+			require.ErrorContains(t, err, strconv.Quote(tt.key))
+		})
+	}
+}
+
+func TestAnnotationKeyOptionsIgnoreInvalidValues(t *testing.T) {
+	defaults := NewOptions()
+
+	opts := NewOptions(WithAnnotationPrefix("invalid annotation"), WithEmittedAnnotationSuffixes("trace parent", strings.Repeat("a", 64)))
+	require.Equal(t, defaults.EmittedTraceParentAnnotationKey(), opts.EmittedTraceParentAnnotationKey())
+	require.Equal(t, defaults.EmittedTraceStateAnnotationKey(), opts.EmittedTraceStateAnnotationKey())
+
+	// A suffix is validated against the prefix it is combined with
+	opts = NewOptions(WithAnnotationPrefix("example.com/"), WithEmittedAnnotationSuffixes("parent", ""))
+	require.Equal(t, "example.com/parent", opts.EmittedTraceParentAnnotationKey())
+	require.Equal(t, "example.com/tracestate", opts.EmittedTraceStateAnnotationKey())
+}
+
+func TestNewOptionsIgnoresInvalidAnnotationKey(t *testing.T) {
+	defaults := NewOptions()
+	opts := NewOptions(WithTraceParentKey("example.com/trace parent"), WithTraceStateKey("example.com/state"))
+	require.Equal(t, defaults.EmittedTraceParentAnnotationKey(), opts.EmittedTraceParentAnnotationKey())
+	require.Equal(t, "example.com/state", opts.EmittedTraceStateAnnotationKey())
+
+	_, err := NewOptionsStrict(WithTraceParentKey("example.com/trace parent"))
+	require.ErrorIs(t, err, ErrInvalidOption)
+	require.ErrorIs(t, err, ErrInvalidAnnotationKey)
+	require.EqualError(t, err, `invalid option: traceparent key: invalid annotation key "example.com/trace parent": `+
+		strings.Join(validation.IsQualifiedName("example.com/trace parent"), "; "))
+}
@@ -0,0 +1,326 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/async_persistence.go
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrAsyncTracePersistenceDisabled is returned by RunTracePersistence for clients created without
+// WithAsyncTracePersistence.
+var ErrAsyncTracePersistenceDisabled = errors.New("tracing client does not persist trace annotations asynchronously")
+
+// errTracePersistenceRunning is returned by RunTracePersistence when the client's worker is already running.
+var errTracePersistenceRunning = errors.New("trace persistence is already running for this client")
+
+// WithAsyncTracePersistence moves trace annotation writes off the reconcile's critical path. Writes go to the API
+// server without trace annotations, and an annotation-only merge patch for the object is queued once they succeed.
+// Patches for the same object are coalesced, so only the latest trace context is written.
+//
+// The queue is drained by RunTracePersistence, which must be running, e.g. as a manager runnable. While it holds
+// queueSize objects, further patches are applied inline after the write instead. The trade-off is trace stitching:
+// until its patch lands, a reconcile of the object does not see the new trace context.
+func WithAsyncTracePersistence(queueSize int) Option {
+	return func(o *Options) {
+		if queueSize <= 0 {
+			return
+		}
+		o.AsyncTracePersistenceQueueSize = queueSize
+	}
+}
+
+// RunTracePersistence runs the background worker of a client created with WithAsyncTracePersistence until ctx is
+// done, then applies the patches still queued before returning. It can be added to a manager with
+// manager.RunnableFunc.
+func RunTracePersistence(ctx context.Context, tc TracingClient) error {
+	impl, ok := tc.(*tracingClient)
+	if !ok || impl.persister == nil {
+		return ErrAsyncTracePersistenceDisabled
+	}
+	return impl.persister.run(ctx)
+}
+
+// persistenceKey identifies an object across kinds.
+type persistenceKey struct {
+	gvk schema.GroupVersionKind
+	types.NamespacedName
+}
+
+// annotationPatch is the annotation-only write queued for one object. A nil value removes the annotation.
+type annotationPatch struct {
+	obj         client.Object
+	annotations map[string]*string
+	opts        []client.PatchOption
+}
+
+// tracePersister applies queued annotation patches from a single goroutine.
+type tracePersister struct {
+	client  client.Client
+	logger  logr.Logger
+	queue   chan persistenceKey
+	running atomic.Bool
+
+	mu      sync.Mutex
+	pending map[persistenceKey]annotationPatch
+	// inFlight holds a channel for each patch being applied, closed once it is done.
+	inFlight map[persistenceKey]chan struct{}
+	// applied holds the last patch applied for an object until EndTrace consumes it or it expires.
+	applied map[persistenceKey]appliedPatch
+	// appliedTTL is how long applied patches are kept. Objects whose trace never ends would otherwise stay forever.
+	appliedTTL time.Duration
+	lastSweep  time.Time
+	now        func() time.Time
+}
+
+// appliedPatch is the annotation changes of the last patch applied for an object and when it was applied.
+type appliedPatch struct {
+	annotations map[string]*string
+	at          time.Time
+}
+
+// newTracePersister returns a persister with room for queueSize objects that keeps applied patches for
+// appliedTTL, the trace expiration: EndTrace of a trace older than that finds its context expired anyway.
+func newTracePersister(c client.Client, logger logr.Logger, queueSize int, appliedTTL time.Duration) *tracePersister {
+	if appliedTTL <= 0 {
+		appliedTTL = constants.DefaultTraceExpiration
+	}
+	return &tracePersister{
+		client:     c,
+		logger:     logger,
+		queue:      make(chan persistenceKey, queueSize),
+		pending:    make(map[persistenceKey]annotationPatch),
+		inFlight:   make(map[persistenceKey]chan struct{}),
+		applied:    make(map[persistenceKey]appliedPatch),
+		appliedTTL: appliedTTL,
+		now:        time.Now,
+	}
+}
+
+// enqueue queues patch for key, replacing a patch that is still waiting. It returns false when the queue is full.
+func (p *tracePersister) enqueue(key persistenceKey, patch annotationPatch) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, found := p.pending[key]; found {
+		p.pending[key] = patch
+		return true
+	}
+	select {
+	case p.queue <- key:
+		p.pending[key] = patch
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *tracePersister) run(ctx context.Context) error {
+	if !p.running.CompareAndSwap(false, true) {
+		return errTracePersistenceRunning
+	}
+	defer p.running.Store(false)
+
+	for {
+		select {
+		case key := <-p.queue:
+			p.persist(ctx, key)
+		case <-ctx.Done():
+			// Whatever is still queued was written without its trace, so finish the job before stopping
+			drainCtx := context.WithoutCancel(ctx)
+			for {
+				select {
+				case key := <-p.queue:
+					p.persist(drainCtx, key)
+				default:
+					return nil
+				}
+			}
+		}
+	}
+}
+
+// persist applies the pending patch for key, if it was not flushed already.
+func (p *tracePersister) persist(ctx context.Context, key persistenceKey) {
+	p.mu.Lock()
+	patch, found := p.pending[key]
+	if !found {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.pending, key)
+	done := make(chan struct{})
+	p.inFlight[key] = done
+	p.mu.Unlock()
+
+	err := p.apply(ctx, patch)
+
+	p.mu.Lock()
+	delete(p.inFlight, key)
+	if err == nil {
+		p.recordApplied(key, patch.annotations)
+	}
+	p.mu.Unlock()
+	close(done)
+}
+
+// applyInline applies patch for key on the caller's goroutine, for when the queue is full, and records it for
+// EndTrace like the worker does.
+func (p *tracePersister) applyInline(ctx context.Context, key persistenceKey, patch annotationPatch) {
+	if err := p.apply(ctx, patch); err != nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.recordApplied(key, patch.annotations)
+}
+
+// recordApplied stores annotations as the last patch applied for key and, at most once per TTL, drops the patches
+// that expired. The caller must hold p.mu.
+func (p *tracePersister) recordApplied(key persistenceKey, annotations map[string]*string) {
+	now := p.now()
+	p.applied[key] = appliedPatch{annotations: annotations, at: now}
+	if now.Sub(p.lastSweep) < p.appliedTTL {
+		return
+	}
+	p.lastSweep = now
+	for k, applied := range p.applied {
+		if now.Sub(applied.at) >= p.appliedTTL {
+			delete(p.applied, k)
+		}
+	}
+}
+
+// flush applies the patch still pending for key and waits for one being applied by the worker, so the object's
+// trace annotations are up to date when it returns. It returns the last patch applied for key, if any.
+func (p *tracePersister) flush(ctx context.Context, key persistenceKey) map[string]*string {
+	p.mu.Lock()
+	done, busy := p.inFlight[key]
+	p.mu.Unlock()
+	if busy {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	}
+	p.persist(ctx, key)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	applied, found := p.applied[key]
+	delete(p.applied, key)
+	if !found || p.now().Sub(applied.at) >= p.appliedTTL {
+		return nil
+	}
+	return applied.annotations
+}
+
+func (p *tracePersister) apply(ctx context.Context, patch annotationPatch) error {
+	data, err := json.Marshal(map[string]any{"metadata": map[string]any{"annotations": patch.annotations}})
+	if err != nil {
+		return err
+	}
+	if err := p.client.Patch(ctx, patch.obj, client.RawPatch(types.MergePatchType, data), patch.opts...); err != nil {
+		p.logger.Error(err, "Failed to persist trace annotations", "object", client.ObjectKeyFromObject(patch.obj).String())
+		return err
+	}
+	return nil
+}
+
+// traceAnnotationPatch returns the annotation changes that store traceParent and traceState on obj.
+func traceAnnotationPatch(obj client.Object, opts Options, traceParent, traceState string) map[string]*string {
+	before := obj.GetAnnotations()
+	after := make(map[string]string, len(before)+2)
+	for key, value := range before {
+		after[key] = value
+	}
+	persistTraceCarrier(after, opts, traceParent, traceState)
+
+	changes := map[string]*string{}
+	for key, value := range after {
+		if current, found := before[key]; !found || current != value {
+			value := value
+			changes[key] = &value
+		}
+	}
+	for key := range before {
+		if _, found := after[key]; !found {
+			changes[key] = nil
+		}
+	}
+	return changes
+}
+
+// applyAnnotationPatch applies changes to the annotations of obj in memory.
+func applyAnnotationPatch(obj client.Object, changes map[string]*string) {
+	if len(changes) == 0 {
+		return
+	}
+	annotations := copyAnnotations(obj)
+	for key, value := range changes {
+		if value == nil {
+			delete(annotations, key)
+			continue
+		}
+		annotations[key] = *value
+	}
+	obj.SetAnnotations(annotations)
+}
+
+// stageTraceAnnotations stores the trace context of ctx on obj before it is written. With async persistence the
+// object is left untouched, but for the legacy annotation migration, and the returned function, to be called once
+// the write succeeded, queues the patch.
+func (tc *tracingClient) stageTraceAnnotations(ctx context.Context, obj client.Object, gvk schema.GroupVersionKind) (afterWrite func()) {
+	tc.migrateLegacyTraceAnnotations(ctx, obj, gvk)
+	if tc.persister == nil {
+		addTraceAnnotations(ctx, tc.Logger, obj, gvk, tc.options)
+		return func() {}
+	}
+	opts := tc.options.withCallOptions(ctx)
+	traceParent, traceState, ok := traceDataToPersist(ctx, opts)
+	if !ok || skipForeignTraceAnnotation(tc.Logger, obj, opts) {
+		return func() {}
+	}
+	traceState = withTraceSummaryBookkeeping(traceState, trace.SpanFromContext(ctx), gvk.Kind, opts, time.Now())
+	return func() {
+		target, ok := obj.DeepCopyObject().(client.Object)
+		if !ok {
+			return
+		}
+		patch := annotationPatch{
+			obj:         target,
+			annotations: traceAnnotationPatch(target, opts, traceParent, traceState),
+			opts:        tc.patchOptions(ctx, nil),
+		}
+		if len(patch.annotations) == 0 {
+			return
+		}
+		key := persistenceKey{gvk: gvk, NamespacedName: client.ObjectKeyFromObject(obj)}
+		if !tc.persister.enqueue(key, patch) {
+			// The worker is behind, so pay for this write inline rather than losing the trace
+			tc.persister.applyInline(ctx, key, patch)
+		}
+	}
+}
+
+// flushTraceAnnotations applies the trace annotations still queued for obj and copies the last ones persisted
+// onto obj, so EndTrace compares and clears what is actually stored.
+func (tc *tracingClient) flushTraceAnnotations(ctx context.Context, obj client.Object, gvk schema.GroupVersionKind) {
+	if tc.persister == nil {
+		return
+	}
+	key := persistenceKey{gvk: gvk, NamespacedName: client.ObjectKeyFromObject(obj)}
+	applyAnnotationPatch(obj, tc.persister.flush(ctx, key))
+}
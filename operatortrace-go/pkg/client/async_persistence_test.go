@@ -0,0 +1,181 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/async_persistence_test.go
+
+package client
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+// asyncPersistenceClient returns a tracing client with async trace persistence and the trace annotation patches
+// it sent, in order.
+func asyncPersistenceClient(t *testing.T, queueSize int) (TracingClient, client.Client, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var patched []string
+	k8sClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			if err := c.Patch(ctx, obj, patch, opts...); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			patched = append(patched, obj.GetAnnotations()[constants.DefaultTraceParentAnnotation])
+			return nil
+		},
+	}).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil, WithAsyncTracePersistence(queueSize))
+	return tracingClient, k8sClient, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), patched...)
+	}
+}
+
+func storedTraceParent(t *testing.T, k8sClient client.Client, obj client.Object) string {
+	t.Helper()
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(obj), stored))
+	return stored.Annotations[constants.DefaultTraceParentAnnotation]
+}
+
+func TestAsyncTracePersistenceCoalescesPatches(t *testing.T) {
+	tracingClient, k8sClient, patched := asyncPersistenceClient(t, 10)
+	ctx := context.Background()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "async-pod", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(ctx, pod))
+	assert.Empty(t, pod.Annotations[constants.DefaultTraceParentAnnotation], "the write must not carry the trace")
+	assert.Empty(t, storedTraceParent(t, k8sClient, pod))
+
+	for _, image := range []string{"nginx:1", "nginx:2"} {
+		pod.Spec.Containers = []corev1.Container{{Name: "web", Image: image}}
+		require.NoError(t, tracingClient.Update(ctx, pod))
+	}
+	assert.Empty(t, patched(), "nothing is persisted until the worker runs")
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan error)
+	go func() { done <- RunTracePersistence(runCtx, tracingClient) }()
+	require.Eventually(t, func() bool { return storedTraceParent(t, k8sClient, pod) != "" }, time.Second, time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	// The three writes share one object, so only the last trace context is written
+	require.Len(t, patched(), 1)
+	assert.Equal(t, patched()[0], storedTraceParent(t, k8sClient, pod))
+}
+
+func TestAsyncTracePersistenceEndTraceFlushesFirst(t *testing.T) {
+	tracingClient, k8sClient, patched := asyncPersistenceClient(t, 10)
+	ctx := context.Background()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "async-pod", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(ctx, pod))
+	require.Empty(t, pod.Annotations[constants.DefaultTraceParentAnnotation])
+
+	// EndTrace sees the queued trace context although the worker never ran, then clears it
+	require.NoError(t, tracingClient.EndTrace(ctx, pod))
+	require.Len(t, patched(), 2)
+	assert.NotEmpty(t, patched()[0])
+	assert.Empty(t, patched()[1])
+	assert.Empty(t, storedTraceParent(t, k8sClient, pod))
+
+	// The flushed patch is not applied again when the worker starts
+	runCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	require.NoError(t, RunTracePersistence(runCtx, tracingClient))
+	assert.Len(t, patched(), 2)
+}
+
+func TestAsyncTracePersistenceShutdown(t *testing.T) {
+	tracingClient, k8sClient, patched := asyncPersistenceClient(t, 10)
+	ctx := context.Background()
+
+	pods := []*corev1.Pod{
+		{ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "second", Namespace: "default"}},
+	}
+	for _, pod := range pods {
+		require.NoError(t, tracingClient.Create(ctx, pod))
+	}
+
+	// A worker started after shutdown still drains what was queued
+	runCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	require.NoError(t, RunTracePersistence(runCtx, tracingClient))
+	assert.Len(t, patched(), 2)
+	for _, pod := range pods {
+		assert.NotEmpty(t, storedTraceParent(t, k8sClient, pod))
+	}
+}
+
+func TestAsyncTracePersistenceFullQueueAppliesInline(t *testing.T) {
+	tracingClient, k8sClient, patched := asyncPersistenceClient(t, 1)
+	ctx := context.Background()
+
+	queued := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "queued", Namespace: "default"}}
+	inline := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "inline", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(ctx, queued))
+	require.NoError(t, tracingClient.Create(ctx, inline))
+
+	assert.Len(t, patched(), 1)
+	assert.Empty(t, storedTraceParent(t, k8sClient, queued))
+	assert.NotEmpty(t, storedTraceParent(t, k8sClient, inline))
+
+	// EndTrace finds the inline patch like one applied by the worker and clears it
+	require.NoError(t, tracingClient.EndTrace(ctx, inline))
+	assert.Empty(t, storedTraceParent(t, k8sClient, inline))
+}
+
+func TestAsyncTracePersistenceAppliedPatchesExpire(t *testing.T) {
+	asyncClient, _, _ := asyncPersistenceClient(t, 1)
+	persister := asyncClient.(*tracingClient).persister
+	now := time.Now()
+	persister.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	// The queue holds one object, so the others are applied inline and never consumed by EndTrace
+	for _, name := range []string{"queued", "first", "second"} {
+		require.NoError(t, asyncClient.Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}))
+	}
+	require.Len(t, persister.applied, 2)
+
+	// An expired patch is not returned, and the next patch applied sweeps the expired ones
+	now = now.Add(constants.DefaultTraceExpiration)
+	first := persistenceKey{gvk: corev1.SchemeGroupVersion.WithKind("Pod"), NamespacedName: client.ObjectKey{Name: "first", Namespace: "default"}}
+	assert.Nil(t, persister.flush(ctx, first))
+	require.NoError(t, asyncClient.Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "third", Namespace: "default"}}))
+	assert.Len(t, persister.applied, 1)
+}
+
+func TestRunTracePersistence(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	syncClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil)
+	assert.ErrorIs(t, RunTracePersistence(context.Background(), syncClient), ErrAsyncTracePersistenceDisabled)
+
+	asyncClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil, WithAsyncTracePersistence(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- RunTracePersistence(ctx, asyncClient) }()
+	require.Eventually(t, func() bool {
+		return asyncClient.(*tracingClient).persister.running.Load()
+	}, time.Second, time.Millisecond)
+	assert.ErrorIs(t, RunTracePersistence(ctx, asyncClient), errTracePersistenceRunning)
+	cancel()
+	assert.NoError(t, <-done)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/chain_sampler.go
+
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	chainDecisionSampled   = "1"
+	chainDecisionUnsampled = "0"
+)
+
+// chainSampled reports whether the trace chain of sc is recorded. A decision recorded in the tracestate wins over
+// the hash of the trace ID, so the chain keeps the decision of its first hop even if the fraction differs between
+// controllers.
+func (o Options) chainSampled(sc trace.SpanContext) bool {
+	switch sc.TraceState().Get(constants.TraceStateChainSampledKey) {
+	case chainDecisionSampled:
+		return true
+	case chainDecisionUnsampled:
+		return false
+	}
+	return traceIDSampled(sc.TraceID(), o.TraceChainSampleFraction)
+}
+
+// traceIDSampled decides by the low 63 bits of traceID, like the OpenTelemetry TraceIDRatioBased sampler.
+func traceIDSampled(traceID trace.TraceID, fraction float64) bool {
+	if fraction >= 1 {
+		return true
+	}
+	bound := uint64(fraction * (1 << 63))
+	return binary.BigEndian.Uint64(traceID[8:16])>>1 < bound
+}
+
+// unsampledChainSpan returns a non-recording span to use instead of starting a span in ctx when the trace chain
+// sampler drops the chain: either the chain ctx continues, or the new trace a span started in ctx would root.
+// The span carries the decision in its tracestate, so annotations written under it propagate the decision.
+func unsampledChainSpan(ctx context.Context, opts Options) (context.Context, trace.Span, bool) {
+	if !opts.TraceChainSampling {
+		return ctx, nil, false
+	}
+
+	var spanContext trace.SpanContext
+	if parent := trace.SpanContextFromContext(ctx); parent.IsValid() {
+		if trace.SpanFromContext(ctx).IsRecording() || opts.chainSampled(parent) {
+			return ctx, nil, false
+		}
+		spanContext = parent.WithRemote(false)
+	} else {
+		var traceID trace.TraceID
+		var spanID trace.SpanID
+		_, _ = rand.Read(traceID[:])
+		_, _ = rand.Read(spanID[:])
+		if traceIDSampled(traceID, opts.TraceChainSampleFraction) {
+			return ctx, nil, false
+		}
+		spanContext = trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+	}
+
+	traceState, err := spanContext.TraceState().Insert(constants.TraceStateChainSampledKey, chainDecisionUnsampled)
+	if err == nil {
+		spanContext = spanContext.WithTraceState(traceState)
+	}
+	spanContext = spanContext.WithTraceFlags(spanContext.TraceFlags() &^ trace.FlagsSampled)
+	span := trace.SpanFromContext(trace.ContextWithSpanContext(context.Background(), spanContext))
+	return trace.ContextWithSpan(ctx, span), span, true
+}
+
+// withChainDecision records in traceState that the chain of a recording span is sampled, unless a decision is
+// already recorded, so later hops do not decide again by trace ID.
+func withChainDecision(traceState string, span trace.Span, opts Options) string {
+	if !opts.TraceChainSampling || !span.IsRecording() || span.SpanContext().TraceState().Get(constants.TraceStateChainSampledKey) != "" {
+		return traceState
+	}
+	updated, err := tracecontext.SetTraceStateKey(traceState, constants.TraceStateChainSampledKey, chainDecisionSampled)
+	if err != nil {
+		return traceState
+	}
+	return updated
+}
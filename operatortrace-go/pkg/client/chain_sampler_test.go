@@ -0,0 +1,109 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/chain_sampler_test.go
+
+package client
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTraceChainSamplerAcrossControllers(t *testing.T) {
+	tests := []struct {
+		name             string
+		firstFraction    float64
+		secondFraction   float64
+		expectedSampled  bool
+		expectedDecision string
+	}{
+		{"unsampled chain stays unsampled", 0, 1, false, chainDecisionUnsampled},
+		{"sampled chain stays sampled", 1, 0, true, chainDecisionSampled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			k8sClient := fake.NewClientBuilder().WithObjects(
+				&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default"}},
+			).Build()
+			first := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("first"), logr.Discard(), nil, WithTraceChainSampler(tt.firstFraction))
+			second := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("second"), logr.Discard(), nil, WithTraceChainSampler(tt.secondFraction))
+
+			// The first controller starts a new chain and writes the pod the second controller watches
+			ctx, span, err := first.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "config", Namespace: "default"}},
+			}, &corev1.ConfigMap{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedSampled, span.IsRecording())
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+			require.NoError(t, first.Create(ctx, pod))
+			span.End()
+
+			traceParent := pod.Annotations[constants.DefaultTraceParentAnnotation]
+			require.NotEmpty(t, traceParent, "the chain must propagate whether it is sampled or not")
+			assert.Contains(t, pod.Annotations[constants.DefaultTraceStateAnnotation], constants.TraceStateChainSampledKey+"="+tt.expectedDecision)
+			parts := strings.Split(traceParent, "-")
+			require.Len(t, parts, 4)
+
+			// The second controller follows the recorded decision, whatever its own fraction
+			ctx, span, err = second.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "pod", Namespace: "default"}},
+				Parent:  tracingtypes.RequestParent{TraceID: parts[1], SpanID: parts[2], Kind: "Pod", Name: "pod"},
+			}, &corev1.Pod{})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedSampled, span.IsRecording())
+			assert.Equal(t, parts[1], span.SpanContext().TraceID().String())
+			require.NoError(t, second.Update(ctx, pod))
+			span.End()
+
+			stored := &corev1.Pod{}
+			require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(pod), stored))
+			assert.Contains(t, stored.Annotations[constants.DefaultTraceParentAnnotation], parts[1])
+			assert.Contains(t, stored.Annotations[constants.DefaultTraceStateAnnotation], constants.TraceStateChainSampledKey+"="+tt.expectedDecision)
+
+			if tt.expectedSampled {
+				assert.NotEmpty(t, exporter.GetSpans())
+			} else {
+				assert.Empty(t, exporter.GetSpans())
+			}
+		})
+	}
+}
+
+func TestTraceIDSampled(t *testing.T) {
+	low := trace.TraceID{15: 1}
+	high := trace.TraceID{8: 0xff, 9: 0xff, 10: 0xff, 11: 0xff, 12: 0xff, 13: 0xff, 14: 0xff, 15: 0xff}
+
+	assert.True(t, traceIDSampled(low, 0.5))
+	assert.False(t, traceIDSampled(high, 0.5))
+	assert.True(t, traceIDSampled(high, 1))
+	assert.False(t, traceIDSampled(low, 0))
+}
+
+func TestWithTraceChainSampler(t *testing.T) {
+	for _, fraction := range []float64{-0.1, 1.5, math.NaN()} {
+		assert.False(t, NewOptions(WithTraceChainSampler(fraction)).TraceChainSampling, fraction)
+	}
+	opts := NewOptions(WithTraceChainSampler(0.25))
+	assert.True(t, opts.TraceChainSampling)
+	assert.Equal(t, 0.25, opts.TraceChainSampleFraction)
+}
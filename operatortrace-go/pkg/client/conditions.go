@@ -5,6 +5,7 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 
@@ -14,66 +15,85 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
-// GetConditionTime retrieves the time for a specific condition type from a Kubernetes object.
+// GetConditionTime retrieves the last transition time for a specific condition type from a Kubernetes object.
 func GetConditionTime(conditionType string, obj client.Object, scheme *runtime.Scheme) (metav1.Time, error) {
-	conditions, err := getConditionsAsMap(obj, scheme)
+	condition, err := getConditionAsMap(conditionType, obj, scheme)
 	if err != nil {
 		return metav1.Time{}, err
 	}
 
-	for _, condition := range conditions {
-		// Check if "Type" key exists
-		conType, exists := condition["Type"]
-		if !exists {
-			return metav1.Time{}, fmt.Errorf("condition does not contain a 'Type' field")
-		}
-
-		// Convert conType to string using reflection
-		conTypeStr, err := convertToString(conType)
-		if err != nil {
-			return metav1.Time{}, fmt.Errorf("failed to convert 'Type' field to string: %v", err)
-		}
-
-		if conTypeStr == conditionType {
-			time := condition["LastTransitionTime"].(metav1.Time)
-			return time, nil
-		}
+	transitionTime, ok := condition["LastTransitionTime"].(metav1.Time)
+	if !ok {
+		return metav1.Time{}, fmt.Errorf("condition of type %s does not contain a 'LastTransitionTime' field", conditionType)
 	}
-
-	return metav1.Time{}, fmt.Errorf("condition of type %s not found", conditionType)
+	return transitionTime, nil
 }
 
 // GetConditionMessage retrieves the message for a specific condition type from a Kubernetes object.
 func GetConditionMessage(conditionType string, obj client.Object, scheme *runtime.Scheme) (string, error) {
-	conditions, err := getConditionsAsMap(obj, scheme)
+	condition, err := getConditionAsMap(conditionType, obj, scheme)
 	if err != nil {
 		return "", err
 	}
 
-	for _, condition := range conditions {
-		// Check if "Type" key exists
-		conType, exists := condition["Type"]
-		if !exists {
-			return "", fmt.Errorf("condition does not contain a 'Type' field")
-		}
+	message, ok := condition["Message"].(string)
+	if !ok {
+		return "", fmt.Errorf("condition of type %s does not contain a 'Message' field", conditionType)
+	}
+	return message, nil
+}
 
-		// Convert conType to string using reflection
-		conTypeStr, err := convertToString(conType)
-		if err != nil {
-			return "", fmt.Errorf("failed to convert 'Type' field to string: %v", err)
-		}
+// GetConditionReason retrieves the reason for a specific condition type from a Kubernetes object.
+func GetConditionReason(conditionType string, obj client.Object, scheme *runtime.Scheme) (string, error) {
+	condition, err := getConditionAsMap(conditionType, obj, scheme)
+	if err != nil {
+		return "", err
+	}
 
-		if conTypeStr == conditionType {
-			message := condition["Message"].(string)
-			return message, nil
-		}
+	reason, err := convertToString(condition["Reason"])
+	if err != nil {
+		return "", fmt.Errorf("failed to convert 'Reason' field to string: %v", err)
+	}
+	return reason, nil
+}
+
+// SetConditionReason sets the reason for a specific condition type in a Kubernetes object.
+// If the condition does not exist yet, it is added with an Unknown status.
+func SetConditionReason(conditionType, reason string, obj client.Object, scheme *runtime.Scheme) error {
+	conditions, err := getConditionsAsMap(obj, scheme)
+	if err != nil {
+		return err
+	}
+
+	condition, err := findCondition(conditions, conditionType)
+	if err != nil {
+		return err
+	}
+	if condition != nil {
+		condition["Reason"] = reason
+	} else {
+		conditions = append(conditions, map[string]interface{}{
+			"Type":               conditionType,
+			"Status":             metav1.ConditionUnknown,
+			"LastTransitionTime": metav1.Now(),
+			"Reason":             reason,
+		})
 	}
 
-	return "", fmt.Errorf("condition of type %s not found", conditionType)
+	return setConditionsFromMap(obj, conditions, scheme)
 }
 
 // setConditionMessage sets the message for a specific condition type in a Kubernetes object.
+// A condition that already holds message is left untouched, so its LastTransitionTime keeps recording when the
+// message was first written; trace expiration for condition-stored trace context relies on that.
 func setConditionMessage(conditionType, message string, obj client.Object, scheme *runtime.Scheme) error {
+	existing, err := getConditionAsMap(conditionType, obj, scheme)
+	if errors.Is(err, ErrNoConditions) {
+		return err
+	}
+	if err == nil && existing["Message"] == message {
+		return nil
+	}
 	deleteConditionAsMap(conditionType, obj, scheme)
 
 	conditions, err := getConditionsAsMap(obj, scheme)
@@ -101,16 +121,9 @@ func deleteConditionAsMap(conditionType string, obj client.Object, scheme *runti
 
 	var outConditions []map[string]interface{}
 	for _, condition := range conditions {
-		// Check if "Type" key exists
-		conType, exists := condition["Type"]
-		if !exists {
-			return fmt.Errorf("condition does not contain a 'Type' field")
-		}
-
-		// Convert conType to string using reflection
-		conTypeStr, err := convertToString(conType)
+		conTypeStr, err := conditionTypeOf(condition)
 		if err != nil {
-			return fmt.Errorf("failed to convert 'Type' field to string: %v", err)
+			return err
 		}
 
 		if conTypeStr != conditionType {
@@ -122,11 +135,61 @@ func deleteConditionAsMap(conditionType string, obj client.Object, scheme *runti
 	return setConditionsFromMap(obj, outConditions, scheme)
 }
 
+// getConditionAsMap returns the condition matching conditionType as a map of field names to values.
+func getConditionAsMap(conditionType string, obj client.Object, scheme *runtime.Scheme) (map[string]interface{}, error) {
+	conditions, err := getConditionsAsMap(obj, scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	condition, err := findCondition(conditions, conditionType)
+	if err != nil {
+		return nil, err
+	}
+	if condition == nil {
+		return nil, fmt.Errorf("condition of type %s not found", conditionType)
+	}
+	return condition, nil
+}
+
+// findCondition returns the first of conditions matching conditionType, or nil when none does.
+func findCondition(conditions []map[string]interface{}, conditionType string) (map[string]interface{}, error) {
+	for _, condition := range conditions {
+		conTypeStr, err := conditionTypeOf(condition)
+		if err != nil {
+			return nil, err
+		}
+		if conTypeStr == conditionType {
+			return condition, nil
+		}
+	}
+	return nil, nil
+}
+
+// conditionTypeOf returns the 'Type' field of condition as a string.
+func conditionTypeOf(condition map[string]interface{}) (string, error) {
+	conType, exists := condition["Type"]
+	if !exists {
+		return "", fmt.Errorf("condition does not contain a 'Type' field")
+	}
+
+	conTypeStr, err := convertToString(conType)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert 'Type' field to string: %v", err)
+	}
+	return conTypeStr, nil
+}
+
+// getConditionsAsMap returns the status conditions of obj as maps of field names to values, or an error wrapping
+// ErrNoConditions when the kind of obj has none.
 func getConditionsAsMap(obj client.Object, scheme *runtime.Scheme) ([]map[string]interface{}, error) {
 	gvk, err := apiutil.GVKForObject(obj, scheme)
 	if err != nil {
 		return nil, fmt.Errorf("problem getting the GVK: %w", err)
 	}
+	if err := checkConditions(gvk, scheme); err != nil {
+		return nil, err
+	}
 
 	objTyped, err := scheme.New(gvk)
 	if err != nil {
@@ -140,18 +203,18 @@ func getConditionsAsMap(obj client.Object, scheme *runtime.Scheme) ([]map[string
 	val := reflect.ValueOf(objTyped)
 	statusField := val.Elem().FieldByName("Status")
 	if !statusField.IsValid() {
-		return nil, fmt.Errorf("status field not found in kind %s", gvk.Kind)
+		return nil, fmt.Errorf("%w: kind %s", ErrNoConditions, gvk.Kind)
 	}
 
 	conditionsField := statusField.FieldByName("Conditions")
 	if !conditionsField.IsValid() {
-		return nil, fmt.Errorf("conditions field not found in kind %s", gvk.Kind)
+		return nil, fmt.Errorf("%w: kind %s", ErrNoConditions, gvk.Kind)
 	}
 
 	conditionsValue := conditionsField.Interface()
 	val = reflect.ValueOf(conditionsValue)
 	if val.Kind() != reflect.Slice {
-		return nil, fmt.Errorf("conditions field is not a slice")
+		return nil, fmt.Errorf("%w: kind %s", ErrNoConditions, gvk.Kind)
 	}
 
 	var conditionsAsMap []map[string]interface{}
@@ -178,6 +241,9 @@ func setConditionsFromMap(obj client.Object, conditionsAsMap []map[string]interf
 	if err != nil {
 		return fmt.Errorf("problem getting the GVK: %w", err)
 	}
+	if err := checkConditions(gvk, scheme); err != nil {
+		return err
+	}
 
 	objTyped, err := scheme.New(gvk)
 	if err != nil {
@@ -191,12 +257,12 @@ func setConditionsFromMap(obj client.Object, conditionsAsMap []map[string]interf
 	val := reflect.ValueOf(objTyped)
 	statusField := val.Elem().FieldByName("Status")
 	if !statusField.IsValid() {
-		return fmt.Errorf("status field not found in kind %s", gvk.Kind)
+		return fmt.Errorf("%w: kind %s", ErrNoConditions, gvk.Kind)
 	}
 
 	conditionsField := statusField.FieldByName("Conditions")
 	if !conditionsField.IsValid() {
-		return fmt.Errorf("conditions field not found in kind %s", gvk.Kind)
+		return fmt.Errorf("%w: kind %s", ErrNoConditions, gvk.Kind)
 	}
 
 	elemType := conditionsField.Type().Elem()
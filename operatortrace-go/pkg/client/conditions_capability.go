@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/conditions_capability.go
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ErrNoConditions is returned by the condition helpers for objects whose kind has no status conditions, such as
+// ConfigMaps. Callers reading trace context treat it as absence rather than as a failure.
+var ErrNoConditions = errors.New("kind has no status conditions")
+
+// conditionsCapability records whether the Go type of a kind has a Status.Conditions slice.
+type conditionsCapability int
+
+const (
+	conditionsUnknown conditionsCapability = iota
+	conditionsPresent
+	conditionsAbsent
+)
+
+// conditionsCapabilityKey identifies a kind in a scheme: the same GVK can map to different Go types in different
+// schemes.
+type conditionsCapabilityKey struct {
+	scheme *runtime.Scheme
+	gvk    schema.GroupVersionKind
+}
+
+// conditionsCapabilities caches the conditionsCapability of kinds by conditionsCapabilityKey. Schemes do not change
+// once clients are created, so entries are never invalidated.
+var conditionsCapabilities sync.Map
+
+// checkConditions returns an error wrapping ErrNoConditions when objects of gvk have no status conditions in scheme,
+// before any object is converted or reflected upon. The answer is resolved from the Go type registered for gvk the
+// first time it is asked for and cached from then on; kinds scheme does not know stay unknown and return the
+// error of scheme.New.
+func checkConditions(gvk schema.GroupVersionKind, scheme *runtime.Scheme) error {
+	key := conditionsCapabilityKey{scheme: scheme, gvk: gvk}
+	capability := conditionsUnknown
+	if cached, ok := conditionsCapabilities.Load(key); ok {
+		capability = cached.(conditionsCapability)
+	} else {
+		obj, err := scheme.New(gvk)
+		if err != nil {
+			return fmt.Errorf("problem creating new object of kind %s: %w", gvk.Kind, err)
+		}
+		capability = conditionsPresent
+		if !hasConditionsField(reflect.TypeOf(obj)) {
+			capability = conditionsAbsent
+		}
+		conditionsCapabilities.Store(key, capability)
+	}
+	if capability == conditionsAbsent {
+		return fmt.Errorf("%w: kind %s", ErrNoConditions, gvk.Kind)
+	}
+	return nil
+}
+
+// hasConditionsField reports whether t, a pointer to a struct, has a Status struct with a Conditions slice.
+func hasConditionsField(t reflect.Type) bool {
+	if t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+		return false
+	}
+	status, ok := t.Elem().FieldByName("Status")
+	if !ok || status.Type.Kind() != reflect.Struct {
+		return false
+	}
+	conditions, ok := status.Type.FieldByName("Conditions")
+	return ok && conditions.Type.Kind() == reflect.Slice
+}
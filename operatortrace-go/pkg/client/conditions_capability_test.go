@@ -0,0 +1,173 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/conditions_capability_test.go
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var widgetGroupVersion = schema.GroupVersion{Group: "example.com", Version: "v1"}
+
+// widget is a custom resource with status conditions, as generated for a CRD.
+type widget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	Status            widgetStatus `json:"status,omitempty"`
+}
+
+type widgetStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// DeepCopyInto is what makes the scheme register the conversion of widget to itself, as for generated types.
+func (w *widget) DeepCopyInto(out *widget) {
+	*out = *w
+	w.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Status.Conditions = append([]metav1.Condition(nil), w.Status.Conditions...)
+}
+
+func (w *widget) DeepCopyObject() runtime.Object {
+	out := &widget{}
+	w.DeepCopyInto(out)
+	return out
+}
+
+// gadget is a custom resource without a status.
+type gadget struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+func (g *gadget) DeepCopyInto(out *gadget) {
+	*out = *g
+	g.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+}
+
+func (g *gadget) DeepCopyObject() runtime.Object {
+	out := &gadget{}
+	g.DeepCopyInto(out)
+	return out
+}
+
+func newCustomResourceScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	scheme.AddKnownTypes(widgetGroupVersion, &widget{}, &gadget{})
+	return scheme
+}
+
+func TestCheckConditions(t *testing.T) {
+	scheme := newCustomResourceScheme()
+
+	tests := []struct {
+		name         string
+		gvk          schema.GroupVersionKind
+		noConditions bool
+		wantErr      bool
+	}{
+		{name: "pod", gvk: corev1.SchemeGroupVersion.WithKind("Pod")},
+		{name: "configmap", gvk: corev1.SchemeGroupVersion.WithKind("ConfigMap"), noConditions: true, wantErr: true},
+		{name: "conditioned custom resource", gvk: widgetGroupVersion.WithKind("widget")},
+		{name: "custom resource without status", gvk: widgetGroupVersion.WithKind("gadget"), noConditions: true, wantErr: true},
+		{name: "unknown kind", gvk: widgetGroupVersion.WithKind("Unknown"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The second lookup is answered from the cache
+			for i := 0; i < 2; i++ {
+				err := checkConditions(tt.gvk, scheme)
+				assert.Equal(t, tt.wantErr, err != nil, err)
+				assert.Equal(t, tt.noConditions, errors.Is(err, ErrNoConditions), err)
+			}
+			_, cached := conditionsCapabilities.Load(conditionsCapabilityKey{scheme: scheme, gvk: tt.gvk})
+			assert.Equal(t, tt.gvk.Kind != "Unknown", cached)
+		})
+	}
+}
+
+func TestConditionsOfKindWithoutConditions(t *testing.T) {
+	scheme := newConditionTestScheme()
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"}, Data: map[string]string{"k": "v"}}
+	original := cm.DeepCopy()
+
+	_, err := GetConditionMessage("TraceID", cm, scheme)
+	assert.ErrorIs(t, err, ErrNoConditions)
+	_, err = GetConditionTime("TraceID", cm, scheme)
+	assert.ErrorIs(t, err, ErrNoConditions)
+	assert.ErrorIs(t, setConditionMessage("TraceID", testTraceIDHex, cm, scheme), ErrNoConditions)
+	assert.ErrorIs(t, SetConditionReason("TraceID", "reason", cm, scheme), ErrNoConditions)
+	assert.ErrorIs(t, deleteConditionAsMap("TraceID", cm, scheme), ErrNoConditions)
+	assert.Equal(t, original, cm)
+
+	_, ok := extractTraceContextFromConditions(cm, scheme)
+	assert.False(t, ok)
+}
+
+func TestConditionsOfCustomResource(t *testing.T) {
+	scheme := newCustomResourceScheme()
+	w := &widget{ObjectMeta: metav1.ObjectMeta{Name: "w", Namespace: "default"}}
+
+	require.NoError(t, setConditionMessage("TraceID", testTraceIDHex, w, scheme))
+	require.NoError(t, setConditionMessage("SpanID", testSpanIDHex, w, scheme))
+	require.Len(t, w.Status.Conditions, 2)
+
+	message, err := GetConditionMessage("TraceID", w, scheme)
+	require.NoError(t, err)
+	assert.Equal(t, testTraceIDHex, message)
+	stored, ok := extractTraceContextFromConditions(w, scheme)
+	require.True(t, ok)
+	assert.Contains(t, stored.TraceParent, testTraceIDHex)
+
+	require.NoError(t, deleteConditionAsMap("TraceID", w, scheme))
+	require.Len(t, w.Status.Conditions, 1)
+	assert.Equal(t, "SpanID", w.Status.Conditions[0].Type)
+}
+
+func TestEndTraceKindWithoutConditions(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"}}
+	annotateObjectWithTraceIDs(t, cm, NewOptions(), testTraceIDHex, testSpanIDHex)
+	k8sClient := fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(cm).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), clientgoscheme.Scheme)
+
+	stored := &corev1.ConfigMap{}
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(cm), stored))
+	// ConfigMaps have no status to patch, so only the annotations are cleared
+	require.NoError(t, tracingClient.EndTrace(context.Background(), stored))
+
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(cm), stored))
+	_, ok := extractTraceContextFromAnnotations(stored.GetAnnotations(), NewOptions())
+	assert.False(t, ok)
+}
+
+func BenchmarkGetConditionsAsMap(b *testing.B) {
+	scheme := newCustomResourceScheme()
+	for name, obj := range map[string]client.Object{
+		"configmap": &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"}},
+		"pod":       newConditionTestPod(corev1.PodCondition{Type: corev1.PodScheduled, Status: corev1.ConditionTrue}),
+		"widget": &widget{ObjectMeta: metav1.ObjectMeta{Name: "w", Namespace: "default"}, Status: widgetStatus{
+			Conditions: []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue}},
+		}},
+	} {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _ = getConditionsAsMap(obj, scheme)
+			}
+		})
+	}
+}
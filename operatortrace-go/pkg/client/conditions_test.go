@@ -0,0 +1,257 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/conditions_test.go
+
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newConditionTestPod(conditions ...corev1.PodCondition) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-pod",
+			Namespace: "default",
+		},
+		Status: corev1.PodStatus{
+			Conditions: conditions,
+		},
+	}
+}
+
+func newConditionTestScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	corev1.AddToScheme(scheme)
+	return scheme
+}
+
+func TestGetConditionTime(t *testing.T) {
+	transitionTime := metav1.NewTime(time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC))
+
+	tests := []struct {
+		name          string
+		conditionType string
+		pod           *corev1.Pod
+		expected      metav1.Time
+		wantErr       bool
+	}{
+		{
+			name:          "existing condition",
+			conditionType: "PodScheduled",
+			pod: newConditionTestPod(corev1.PodCondition{
+				Type:               corev1.PodScheduled,
+				Status:             corev1.ConditionTrue,
+				LastTransitionTime: transitionTime,
+			}),
+			expected: transitionTime,
+		},
+		{
+			name:          "missing condition",
+			conditionType: "TraceID",
+			pod: newConditionTestPod(corev1.PodCondition{
+				Type:   corev1.PodScheduled,
+				Status: corev1.ConditionTrue,
+			}),
+			wantErr: true,
+		},
+		{
+			name:          "no conditions",
+			conditionType: "PodScheduled",
+			pod:           newConditionTestPod(),
+			wantErr:       true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := GetConditionTime(tt.conditionType, tt.pod, newConditionTestScheme())
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.True(t, tt.expected.Equal(&result))
+			}
+		})
+	}
+}
+
+func TestGetConditionReason(t *testing.T) {
+	tests := []struct {
+		name          string
+		conditionType string
+		pod           *corev1.Pod
+		expected      string
+		wantErr       bool
+	}{
+		{
+			name:          "existing condition with reason",
+			conditionType: "PodScheduled",
+			pod: newConditionTestPod(corev1.PodCondition{
+				Type:   corev1.PodScheduled,
+				Status: corev1.ConditionTrue,
+				Reason: "Scheduled",
+			}),
+			expected: "Scheduled",
+		},
+		{
+			name:          "existing condition without reason",
+			conditionType: "PodScheduled",
+			pod: newConditionTestPod(corev1.PodCondition{
+				Type:   corev1.PodScheduled,
+				Status: corev1.ConditionTrue,
+			}),
+			expected: "",
+		},
+		{
+			name:          "missing condition",
+			conditionType: "TraceID",
+			pod: newConditionTestPod(corev1.PodCondition{
+				Type:   corev1.PodScheduled,
+				Status: corev1.ConditionTrue,
+				Reason: "Scheduled",
+			}),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := GetConditionReason(tt.conditionType, tt.pod, newConditionTestScheme())
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSetConditionReason(t *testing.T) {
+	tests := []struct {
+		name               string
+		conditionType      string
+		reason             string
+		pod                *corev1.Pod
+		expectedConditions int
+		expectedMessage    string
+	}{
+		{
+			name:          "updates existing condition",
+			conditionType: "PodScheduled",
+			reason:        "Rescheduled",
+			pod: newConditionTestPod(corev1.PodCondition{
+				Type:    corev1.PodScheduled,
+				Status:  corev1.ConditionTrue,
+				Reason:  "Scheduled",
+				Message: "Pod has been scheduled",
+			}),
+			expectedConditions: 1,
+			expectedMessage:    "Pod has been scheduled",
+		},
+		{
+			name:          "adds missing condition",
+			conditionType: "TraceID",
+			reason:        "TraceRecorded",
+			pod: newConditionTestPod(corev1.PodCondition{
+				Type:   corev1.PodScheduled,
+				Status: corev1.ConditionTrue,
+			}),
+			expectedConditions: 2,
+			expectedMessage:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := newConditionTestScheme()
+			err := SetConditionReason(tt.conditionType, tt.reason, tt.pod, scheme)
+			assert.NoError(t, err)
+			assert.Len(t, tt.pod.Status.Conditions, tt.expectedConditions)
+
+			reason, err := GetConditionReason(tt.conditionType, tt.pod, scheme)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.reason, reason)
+
+			// Setting the reason must not clobber the rest of the condition
+			message, err := GetConditionMessage(tt.conditionType, tt.pod, scheme)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedMessage, message)
+		})
+	}
+}
+
+func TestFindCondition(t *testing.T) {
+	scheduled := map[string]interface{}{"Type": corev1.PodScheduled, "Message": "scheduled"}
+	tests := []struct {
+		name          string
+		conditions    []map[string]interface{}
+		conditionType string
+		expected      map[string]interface{}
+		expectedErr   string
+	}{
+		{name: "found", conditions: []map[string]interface{}{scheduled}, conditionType: "PodScheduled", expected: scheduled},
+		{name: "not found", conditions: []map[string]interface{}{scheduled}, conditionType: "TraceID"},
+		{name: "missing type", conditions: []map[string]interface{}{{"Message": "m"}}, conditionType: "TraceID", expectedErr: "does not contain a 'Type' field"},
+		{name: "type not a string", conditions: []map[string]interface{}{{"Type": 1}}, conditionType: "TraceID", expectedErr: "failed to convert 'Type' field"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition, err := findCondition(tt.conditions, tt.conditionType)
+			if tt.expectedErr != "" {
+				assert.ErrorContains(t, err, tt.expectedErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, condition)
+		})
+	}
+}
+
+func TestSetConditionMessagePreservesTransitionTime(t *testing.T) {
+	const (
+		traceID    = "4bf92f3577b34da6a3ce929d0e0e4736"
+		newTraceID = "5bf92f3577b34da6a3ce929d0e0e4736"
+		spanID     = "00f067aa0ba902b7"
+	)
+	written := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	scheme := newConditionTestScheme()
+	opts := NewOptions(WithTraceExpiration(30 * time.Minute))
+
+	tests := []struct {
+		name            string
+		traceID         string
+		keepsTimestamp  bool
+		expectedExpired bool
+	}{
+		{"same trace keeps the timestamp", traceID, true, true},
+		{"new trace updates the timestamp", newTraceID, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := newConditionTestPod(
+				corev1.PodCondition{Type: "TraceID", Status: corev1.ConditionUnknown, LastTransitionTime: written, Message: traceID},
+				corev1.PodCondition{Type: "SpanID", Status: corev1.ConditionUnknown, LastTransitionTime: written, Message: spanID},
+			)
+
+			assert.NoError(t, setConditionMessage("TraceID", tt.traceID, pod, scheme))
+
+			transitionTime, err := GetConditionTime("TraceID", pod, scheme)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.keepsTimestamp, transitionTime.Equal(&written))
+
+			stored, ok := extractTraceContextFromConditions(pod, scheme)
+			assert.True(t, ok)
+			assert.Equal(t, tt.expectedExpired, traceContextExpired(stored.Timestamp, opts))
+		})
+	}
+}
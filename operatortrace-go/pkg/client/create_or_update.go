@@ -0,0 +1,235 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/create_or_update.go
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// CreateOrUpdate is the tracing equivalent of controllerutil.CreateOrUpdate.
+// The whole get/mutate/write flow is recorded under a single producer span, trace annotations are applied
+// exactly once right before the write, and the write is skipped when the mutation only touched trace data.
+// Clients that are not created by this package fall back to controllerutil.CreateOrUpdate.
+func CreateOrUpdate(ctx context.Context, tc TracingClient, obj client.Object, mutate controllerutil.MutateFn) (controllerutil.OperationResult, error) {
+	impl, ok := tc.(*tracingClient)
+	if !ok {
+		return controllerutil.CreateOrUpdate(ctx, tc, obj, mutate)
+	}
+	if impl.noop(ctx) {
+		return controllerutil.CreateOrUpdate(ctx, impl, obj, mutate)
+	}
+
+	ctx, span, gvk := impl.startApplySpan(ctx, "CreateOrUpdate", obj)
+	defer span.End()
+
+	key := client.ObjectKeyFromObject(obj)
+	if err := impl.Client.Get(ctx, key, obj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return controllerutil.OperationResultNone, recordSpanError(span, err)
+		}
+		return impl.createForApply(ctx, span, gvk, key, obj, mutate)
+	}
+
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := mutateObject(mutate, key, obj); err != nil {
+		return controllerutil.OperationResultNone, recordSpanError(span, err)
+	}
+
+	if !predicates.HasSignificantUpdate(existing, obj) {
+		impl.Logger.Info("Skipping update as object content has not changed", "object", impl.objectName(ctx, obj, gvk))
+		return controllerutil.OperationResultNone, nil
+	}
+
+	afterWrite := impl.stageTraceAnnotations(ctx, obj, gvk)
+	mirrorTraceContextToData(ctx, obj, gvk, impl.options)
+	stampPodTemplate(ctx, obj, gvk, existing, impl.options)
+	impl.Logger.Info("Updating object", "object", impl.objectName(ctx, obj, gvk))
+	if err := impl.Client.Update(ctx, obj); err != nil {
+		return controllerutil.OperationResultNone, recordSpanError(span, err)
+	}
+	afterWrite()
+	return controllerutil.OperationResultUpdated, nil
+}
+
+// CreateOrPatch is the tracing equivalent of controllerutil.CreateOrPatch.
+// It behaves like CreateOrUpdate but writes merge patches, patching the status subresource separately
+// when the mutation changed it.
+func CreateOrPatch(ctx context.Context, tc TracingClient, obj client.Object, mutate controllerutil.MutateFn) (controllerutil.OperationResult, error) {
+	impl, ok := tc.(*tracingClient)
+	if !ok {
+		return controllerutil.CreateOrPatch(ctx, tc, obj, mutate)
+	}
+	if impl.noop(ctx) {
+		return controllerutil.CreateOrPatch(ctx, impl, obj, mutate)
+	}
+
+	ctx, span, gvk := impl.startApplySpan(ctx, "CreateOrPatch", obj)
+	defer span.End()
+
+	key := client.ObjectKeyFromObject(obj)
+	if err := impl.Client.Get(ctx, key, obj); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return controllerutil.OperationResultNone, recordSpanError(span, err)
+		}
+		return impl.createForApply(ctx, span, gvk, key, obj, mutate)
+	}
+
+	existing := obj.DeepCopyObject().(client.Object)
+	if err := mutateObject(mutate, key, obj); err != nil {
+		return controllerutil.OperationResultNone, recordSpanError(span, err)
+	}
+
+	objectChanged, statusChanged, err := significantChanges(existing, obj)
+	if err != nil {
+		return controllerutil.OperationResultNone, recordSpanError(span, err)
+	}
+	if !objectChanged && !statusChanged {
+		impl.Logger.Info("Skipping patch as object content has not changed", "object", impl.objectName(ctx, obj, gvk))
+		return controllerutil.OperationResultNone, nil
+	}
+
+	// The main patch returns the server copy of obj, so keep the mutated status for the status patch
+	desired := obj.DeepCopyObject().(client.Object)
+
+	result := controllerutil.OperationResultUpdatedStatusOnly
+	if objectChanged {
+		afterWrite := impl.stageTraceAnnotations(ctx, obj, gvk)
+		mirrorTraceContextToData(ctx, obj, gvk, impl.options)
+		stampPodTemplate(ctx, obj, gvk, existing, impl.options)
+		impl.Logger.Info("Patching object", "object", impl.objectName(ctx, obj, gvk))
+		if err := impl.Client.Patch(ctx, obj, client.MergeFrom(existing), impl.patchOptions(ctx, nil)...); err != nil {
+			return controllerutil.OperationResultNone, recordSpanError(span, err)
+		}
+		afterWrite()
+		result = controllerutil.OperationResultUpdated
+	}
+
+	if statusChanged {
+		base := existing
+		if objectChanged {
+			base = obj.DeepCopyObject().(client.Object)
+			if err := copyStatus(obj, desired); err != nil {
+				return result, recordSpanError(span, err)
+			}
+		}
+		impl.Logger.Info("Patching object status", "object", impl.objectName(ctx, obj, gvk))
+		if err := impl.Client.Status().Patch(ctx, obj, client.MergeFrom(base)); err != nil {
+			return result, recordSpanError(span, err)
+		}
+		if objectChanged {
+			result = controllerutil.OperationResultUpdatedStatus
+		}
+	}
+
+	return result, nil
+}
+
+// startApplySpan starts the producer span shared by CreateOrUpdate and CreateOrPatch.
+func (tc *tracingClient) startApplySpan(ctx context.Context, operation string, obj client.Object) (context.Context, trace.Span, schema.GroupVersionKind) {
+	gvk := tc.writeGVK(obj)
+	spanOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindProducer)}
+	ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("%s %s %s", operation, gvk.GroupKind().Kind, tc.objectName(ctx, obj, gvk)), [10]tracingtypes.LinkedSpan{}, spanOpts...)
+	return ctx, span, gvk
+}
+
+// createForApply runs mutate on a missing object and creates it under the apply span.
+func (tc *tracingClient) createForApply(ctx context.Context, span trace.Span, gvk schema.GroupVersionKind, key client.ObjectKey, obj client.Object, mutate controllerutil.MutateFn) (controllerutil.OperationResult, error) {
+	if err := mutateObject(mutate, key, obj); err != nil {
+		return controllerutil.OperationResultNone, recordSpanError(span, err)
+	}
+	if err := tc.runCreateHooks(ctx, obj); err != nil {
+		return controllerutil.OperationResultNone, recordSpanError(span, err)
+	}
+	afterWrite := tc.stageTraceAnnotations(ctx, obj, gvk)
+	mirrorTraceContextToData(ctx, obj, gvk, tc.options)
+	stampPodTemplate(ctx, obj, gvk, nil, tc.options)
+	injectTraceEnvOnCreate(ctx, obj, gvk, tc.options)
+	tc.Logger.Info("Creating object", "object", tc.objectName(ctx, obj, gvk))
+	if err := tc.Client.Create(ctx, obj); err != nil {
+		return controllerutil.OperationResultNone, recordSpanError(span, err)
+	}
+	afterWrite()
+	return controllerutil.OperationResultCreated, nil
+}
+
+// mutateObject calls mutate and makes sure it did not change the object's identity.
+func mutateObject(mutate controllerutil.MutateFn, key client.ObjectKey, obj client.Object) error {
+	if mutate == nil {
+		return nil
+	}
+	if err := mutate(); err != nil {
+		return err
+	}
+	if newKey := client.ObjectKeyFromObject(obj); key != newKey {
+		return errors.New("MutateFn cannot mutate object name and/or object namespace")
+	}
+	return nil
+}
+
+// significantChanges reports whether mutated differs from existing outside of trace data,
+// separately for the object itself and for its status.
+func significantChanges(existing, mutated client.Object) (objectChanged, statusChanged bool, err error) {
+	existingObj, existingStatus, err := splitStatus(existing)
+	if err != nil {
+		return false, false, err
+	}
+	mutatedObj, mutatedStatus, err := splitStatus(mutated)
+	if err != nil {
+		return false, false, err
+	}
+	return predicates.HasSignificantUpdate(existingObj, mutatedObj), predicates.HasSignificantUpdate(existingStatus, mutatedStatus), nil
+}
+
+// splitStatus converts obj into two unstructured objects, one without the status field and one holding only it.
+func splitStatus(obj client.Object) (withoutStatus, statusOnly *unstructured.Unstructured, err error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	statusOnly = &unstructured.Unstructured{Object: map[string]interface{}{}}
+	if status, ok := content["status"]; ok {
+		statusOnly.Object["status"] = status
+		delete(content, "status")
+	}
+	return &unstructured.Unstructured{Object: content}, statusOnly, nil
+}
+
+// copyStatus overwrites the status of dst with the status of src.
+func copyStatus(dst, src client.Object) error {
+	srcContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(src)
+	if err != nil {
+		return err
+	}
+	dstContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(dst)
+	if err != nil {
+		return err
+	}
+	if status, ok := srcContent["status"]; ok {
+		dstContent["status"] = status
+	} else {
+		delete(dstContent, "status")
+	}
+	return runtime.DefaultUnstructuredConverter.FromUnstructured(dstContent, dst)
+}
+
+// recordSpanError records err on span and returns it unchanged.
+func recordSpanError(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
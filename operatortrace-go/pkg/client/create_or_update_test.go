@@ -0,0 +1,121 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/create_or_update_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+func TestCreateOrUpdateAndPatch(t *testing.T) {
+	type applyFunc func(context.Context, TracingClient, client.Object, controllerutil.MutateFn) (controllerutil.OperationResult, error)
+
+	tests := []struct {
+		name     string
+		existing *corev1.ConfigMap
+		mutate   func(cm *corev1.ConfigMap)
+		expected controllerutil.OperationResult
+	}{
+		{
+			name:     "created",
+			mutate:   func(cm *corev1.ConfigMap) { cm.Data = map[string]string{"key": "value"} },
+			expected: controllerutil.OperationResultCreated,
+		},
+		{
+			name:     "updated",
+			existing: &corev1.ConfigMap{Data: map[string]string{"key": "old"}},
+			mutate:   func(cm *corev1.ConfigMap) { cm.Data = map[string]string{"key": "value"} },
+			expected: controllerutil.OperationResultUpdated,
+		},
+		{
+			name:     "unchanged",
+			existing: &corev1.ConfigMap{Data: map[string]string{"key": "value"}},
+			mutate:   func(cm *corev1.ConfigMap) { cm.Data = map[string]string{"key": "value"} },
+			expected: controllerutil.OperationResultNone,
+		},
+		{
+			name:     "only trace data changed",
+			existing: &corev1.ConfigMap{Data: map[string]string{"key": "value"}},
+			mutate: func(cm *corev1.ConfigMap) {
+				annotateObjectWithTraceIDs(t, cm, NewOptions(), testTraceIDHex, testSpanIDHex)
+			},
+			expected: controllerutil.OperationResultNone,
+		},
+	}
+
+	for apply, fn := range map[string]applyFunc{"CreateOrUpdate": CreateOrUpdate, "CreateOrPatch": CreateOrPatch} {
+		for _, tt := range tests {
+			t.Run(apply+"/"+tt.name, func(t *testing.T) {
+				exporter := tracetest.NewInMemoryExporter()
+				tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+				builder := fake.NewClientBuilder()
+				if tt.existing != nil {
+					tt.existing.ObjectMeta = metav1.ObjectMeta{Name: "apply", Namespace: "default"}
+					builder = builder.WithObjects(tt.existing)
+				}
+				k8sClient := builder.Build()
+				tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil)
+
+				cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "apply", Namespace: "default"}}
+				result, err := fn(context.Background(), tracingClient, cm, func() error {
+					tt.mutate(cm)
+					return nil
+				})
+				require.NoError(t, err)
+				assert.Equal(t, tt.expected, result)
+
+				spans := exporter.GetSpans()
+				require.Len(t, spans, 1)
+				assert.Equal(t, apply+" ConfigMap apply", spans[0].Name)
+				assert.Equal(t, trace.SpanKindProducer, spans[0].SpanKind)
+
+				stored := &corev1.ConfigMap{}
+				require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(cm), stored))
+				assert.Equal(t, "value", stored.Data["key"])
+
+				traceID, _ := traceIDsFromObject(t, stored, NewOptions())
+				if tt.expected == controllerutil.OperationResultNone {
+					assert.NotEqual(t, spans[0].SpanContext.TraceID().String(), traceID)
+				} else {
+					assert.Equal(t, spans[0].SpanContext.TraceID().String(), traceID)
+				}
+			})
+		}
+	}
+}
+
+func TestCreateOrPatchStatus(t *testing.T) {
+	existing := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "apply", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithObjects(existing).WithStatusSubresource(existing).Build()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(tracetest.NewInMemoryExporter()))
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil)
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "apply", Namespace: "default"}}
+	result, err := CreateOrPatch(context.Background(), tracingClient, pod, func() error {
+		pod.Labels = map[string]string{"app": "apply"}
+		pod.Status.Message = "ready"
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, controllerutil.OperationResultUpdatedStatus, result)
+
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(pod), stored))
+	assert.Equal(t, "apply", stored.Labels["app"])
+	assert.Equal(t, "ready", stored.Status.Message)
+}
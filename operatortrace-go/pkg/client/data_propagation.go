@@ -0,0 +1,56 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/data_propagation.go
+
+package client
+
+import (
+	"context"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// mirrorTraceContextToData copies the current span context into the data keys configured for the object's kind.
+func mirrorTraceContextToData(ctx context.Context, obj client.Object, gvk schema.GroupVersionKind, opts Options) {
+	opts = opts.withCallOptions(ctx)
+	if len(opts.DataFieldPropagations) == 0 {
+		return
+	}
+	spanContext := trace.SpanFromContext(ctx).SpanContext()
+	if !spanContext.IsValid() {
+		return
+	}
+
+	for _, propagation := range opts.DataFieldPropagations {
+		if propagation.GVK != gvk {
+			continue
+		}
+		setDataValue(obj, propagation.DataKey, tracecontext.TraceParentFromSpanContext(spanContext))
+	}
+}
+
+// setDataValue writes value into the data field of ConfigMaps, Secrets or unstructured objects.
+func setDataValue(obj client.Object, key, value string) {
+	switch o := obj.(type) {
+	case *corev1.ConfigMap:
+		if o.Data == nil {
+			o.Data = map[string]string{}
+		}
+		o.Data[key] = value
+	case *corev1.Secret:
+		if o.Data == nil {
+			o.Data = map[string][]byte{}
+		}
+		o.Data[key] = []byte(value)
+	case *unstructured.Unstructured:
+		if o.Object == nil {
+			return
+		}
+		_ = unstructured.SetNestedField(o.Object, value, "data", key)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/expiration_thresholds.go
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TraceExpiringSoonEvent is recorded on a span whose trace context was not written to an object because the trace
+// had reached the expiration error threshold. It carries the trace age as StoredContextAgeAttributeKey.
+const TraceExpiringSoonEvent = "trace.expiring_soon"
+
+const (
+	defaultExpirationWarningThreshold = 0.8
+	defaultExpirationErrorThreshold   = 1.0
+)
+
+// WithExpirationThresholds sets, as fractions of the trace expiration, how old the trace continued by a write may
+// be. The age is counted from the timestamp stored with the trace the reconcile continued, so a slow reconcile does
+// not write a trace the next reconcile would discard as expired. From warning on the write is logged; from
+// errorThreshold on the trace annotations are not written and the span records TraceExpiringSoonEvent instead. The
+// defaults are 0.8 and 1. Thresholds that are not positive, or a warning threshold above the error threshold, are
+// ignored. Traces continued with ExpirationPolicyExtend are always written.
+func WithExpirationThresholds(warning, errorThreshold float64) Option {
+	return func(o *Options) {
+		if !(warning > 0 && errorThreshold > 0 && warning <= errorThreshold) {
+			o.reject("expiration thresholds %v and %v must be positive, the warning threshold at most the error threshold", warning, errorThreshold)
+			return
+		}
+		o.ExpirationWarningThreshold = warning
+		o.ExpirationErrorThreshold = errorThreshold
+	}
+}
+
+func (o Options) expirationWarningThreshold() float64 {
+	if o.ExpirationWarningThreshold <= 0 {
+		return defaultExpirationWarningThreshold
+	}
+	return o.ExpirationWarningThreshold
+}
+
+func (o Options) expirationErrorThreshold() float64 {
+	if o.ExpirationErrorThreshold <= 0 {
+		return defaultExpirationErrorThreshold
+	}
+	return o.ExpirationErrorThreshold
+}
+
+// traceExpiringSoon reports whether the trace of the span in ctx is too close to expiring to be written to obj,
+// recording TraceExpiringSoonEvent on the span when it is, and logs a write close to the warning threshold. The
+// age of the trace comes from the timestamp in the span's tracestate, inherited from the trace stored on the
+// object StartTrace read; new traces carry none and are never expiring. opts must include the call options of ctx.
+func traceExpiringSoon(ctx context.Context, logger logr.Logger, obj client.Object, opts Options) bool {
+	if opts.TraceExpirationPolicy == ExpirationPolicyExtend {
+		return false
+	}
+	span := trace.SpanFromContext(ctx)
+	storedAt, ok := tracecontext.ExtractTimestampFromTraceState(span.SpanContext().TraceState().String(), opts.traceStateTimestampKey())
+	if !ok {
+		return false
+	}
+	age := time.Since(storedAt)
+	expiration := opts.traceExpiration()
+	switch {
+	case float64(age) >= opts.expirationErrorThreshold()*float64(expiration):
+		span.AddEvent(TraceExpiringSoonEvent, trace.WithAttributes(StoredContextAgeAttributeKey.Int64(age.Milliseconds())))
+		return true
+	case float64(age) >= opts.expirationWarningThreshold()*float64(expiration):
+		logger.Info("Writing trace context close to expiring", "object", client.ObjectKeyFromObject(obj).String(),
+			"traceAge", age.String(), "traceExpiration", expiration.String())
+	}
+	return false
+}
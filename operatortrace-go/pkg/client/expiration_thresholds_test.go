@@ -0,0 +1,108 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/expiration_thresholds_test.go
+
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// contextContinuingTraceStoredAt returns a context whose span continues a trace stored age ago.
+func contextContinuingTraceStoredAt(t *testing.T, tracer trace.Tracer, age time.Duration) context.Context {
+	t.Helper()
+	traceState, err := tracecontext.SetTraceStateKey("", constants.TraceStateTimestampKey, time.Now().Add(-age).UTC().Format(time.RFC3339Nano))
+	require.NoError(t, err)
+	state, err := trace.ParseTraceState(traceState)
+	require.NoError(t, err)
+	traceID, _ := trace.TraceIDFromHex(testTraceIDHex)
+	spanID, _ := trace.SpanIDFromHex(testSpanIDHex)
+	stored := trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID, TraceFlags: trace.FlagsSampled, TraceState: state, Remote: true})
+	ctx, _ := tracer.Start(trace.ContextWithRemoteSpanContext(context.Background(), stored), "reconcile")
+	return ctx
+}
+
+func TestExpirationThresholds(t *testing.T) {
+	tests := []struct {
+		name    string
+		age     time.Duration
+		opts    []Option
+		written bool
+		warned  bool
+	}{
+		{name: "fresh trace", age: time.Minute, written: true},
+		{name: "past the warning threshold", age: 17 * time.Minute, written: true, warned: true},
+		{name: "past the error threshold", age: 21 * time.Minute},
+		{name: "extend policy", age: 21 * time.Minute, opts: []Option{WithTraceExpirationPolicy(ExpirationPolicyExtend)}, written: true},
+		{name: "custom thresholds warn", age: 11 * time.Minute, opts: []Option{WithExpirationThresholds(0.5, 0.75)}, written: true, warned: true},
+		{name: "custom thresholds skip", age: 16 * time.Minute, opts: []Option{WithExpirationThresholds(0.5, 0.75)}},
+		{name: "invalid thresholds ignored", age: 17 * time.Minute, opts: []Option{WithExpirationThresholds(0.9, 0.5)}, written: true, warned: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			var logs strings.Builder
+			logger := funcr.New(func(prefix, args string) { logs.WriteString(args + "\n") }, funcr.Options{})
+			k8sClient := fake.NewClientBuilder().Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logger, nil, tt.opts...)
+
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"}}
+			require.NoError(t, tracingClient.Create(contextContinuingTraceStoredAt(t, tp.Tracer("test"), tt.age), cm))
+
+			stored := &corev1.ConfigMap{}
+			require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(cm), stored))
+			_, written := stored.GetAnnotations()[constants.DefaultTraceParentAnnotation]
+			assert.Equal(t, tt.written, written)
+			assert.Equal(t, tt.warned, strings.Contains(logs.String(), "Writing trace context close to expiring"))
+
+			var events int
+			for _, s := range exporter.GetSpans() {
+				for _, event := range s.Events {
+					if event.Name == TraceExpiringSoonEvent {
+						events++
+					}
+				}
+			}
+			assert.Equal(t, !tt.written, events == 1)
+		})
+	}
+}
+
+func TestExpirationThresholdsNewTrace(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), funcr.New(func(string, string) {}, funcr.Options{}), nil,
+		WithExpirationThresholds(0.01, 0.01))
+
+	// A trace started by the reconcile carries no timestamp yet
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(context.Background(), cm))
+	assert.Contains(t, cm.GetAnnotations(), constants.DefaultTraceParentAnnotation)
+}
+
+func TestWithExpirationThresholdsStrict(t *testing.T) {
+	_, err := NewOptionsStrict(WithExpirationThresholds(0, 1))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+
+	opts, err := NewOptionsStrict(WithExpirationThresholds(0.5, 0.9))
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, opts.expirationWarningThreshold())
+	assert.Equal(t, 0.9, opts.expirationErrorThreshold())
+}
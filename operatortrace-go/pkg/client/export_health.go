@@ -0,0 +1,27 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/export_health.go
+
+package client
+
+// ExportHealth reports whether spans are being exported, e.g. an otelsetup.HealthExporter.
+type ExportHealth interface {
+	Healthy() bool
+}
+
+// WithExportFailureFallback stops persisting trace context annotations on written objects while health reports
+// the exporter unhealthy, so objects do not reference traces that were never exported. The writes themselves go
+// through unchanged, and annotations are persisted again once the exporter recovers.
+func WithExportFailureFallback(health ExportHealth) Option {
+	return func(o *Options) {
+		if health == nil {
+			return
+		}
+		o.ExportHealth = health
+	}
+}
+
+// exportUnhealthy reports whether WithExportFailureFallback is set and the exporter is unhealthy.
+func (o Options) exportUnhealthy() bool {
+	return o.ExportHealth != nil && !o.ExportHealth.Healthy()
+}
@@ -0,0 +1,67 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/export_health_test.go
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/otelsetup"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// toggledExporter fails its exports while fail is set.
+type toggledExporter struct {
+	*tracetest.InMemoryExporter
+	fail bool
+}
+
+func (e *toggledExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if e.fail {
+		return errors.New("backend unavailable")
+	}
+	return e.InMemoryExporter.ExportSpans(ctx, spans)
+}
+
+func TestWithExportFailureFallback(t *testing.T) {
+	inner := &toggledExporter{InMemoryExporter: tracetest.NewInMemoryExporter(), fail: true}
+	exporter := otelsetup.NewHealthExporter(inner, 1)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, WithExportFailureFallback(exporter))
+	traceParentKey := NewOptions().EmittedTraceParentAnnotationKey()
+
+	// The failed export of the first Create makes the exporter unhealthy
+	require.NoError(t, tracingClient.Create(context.Background(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "first", Namespace: "default"}}))
+	require.False(t, exporter.Healthy())
+
+	unhealthy := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "unhealthy", Namespace: "default"}, Data: map[string]string{"k": "v"}}
+	require.NoError(t, tracingClient.Create(context.Background(), unhealthy))
+	stored := &corev1.ConfigMap{}
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(unhealthy), stored))
+	assert.NotContains(t, stored.Annotations, traceParentKey)
+	assert.Equal(t, "v", stored.Data["k"], "the write itself goes through")
+
+	inner.fail = false
+	_, span := tp.Tracer("test").Start(context.Background(), "recover")
+	span.End()
+	require.True(t, exporter.Healthy())
+
+	healthy := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "healthy", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(context.Background(), healthy))
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(healthy), stored))
+	assert.Contains(t, stored.Annotations, traceParentKey)
+
+	assert.Nil(t, NewOptions(WithExportFailureFallback(nil)).ExportHealth)
+}
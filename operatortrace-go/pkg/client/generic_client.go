@@ -6,22 +6,32 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
 	"github.com/go-logr/logr"
 	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 type GenericClient interface {
 	StartTrace(ctx context.Context, obj client.Object) (context.Context, trace.Span, error)
+	StartTraceWithParent(ctx context.Context, obj client.Object, traceParent, traceState string) (context.Context, trace.Span, error)
+	StartTraceFromRequest(ctx context.Context, requestWithTraceID *tracingtypes.RequestWithTraceID, obj client.Object) (context.Context, trace.Span, error)
 	EndTrace(ctx context.Context, obj client.Object) error
+	EndTraceWithPatch(obj client.Object) (client.Patch, error)
+	EndTraceAnnotations(obj client.Object) map[string]string
 	StartSpan(ctx context.Context, operationName string) (context.Context, trace.Span)
+	StartSpanWithOptions(ctx context.Context, operationName string, opts ...trace.SpanStartOption) (context.Context, trace.Span)
+	StartSpanForObject(ctx context.Context, operationName string, obj client.Object, opts ...trace.SpanStartOption) (context.Context, trace.Span)
 	SetSpan(ctx context.Context, obj client.Object) (context.Context, trace.Span)
+	SetSpanFromAnnotations(ctx context.Context, obj client.Object) (context.Context, trace.Span, bool)
 }
 
 // genericClient wraps the trace.Tracer to provide helper methods for tracing kubernetes objects.
@@ -30,6 +40,9 @@ type genericClient struct {
 	logr.Logger
 	scheme  *runtime.Scheme
 	options Options
+
+	// gvks caches the GVK of each object type in scheme.
+	gvks *gvkCache
 }
 
 // NewTracingClient initializes and returns a new TracingClient
@@ -52,12 +65,19 @@ func NewGenericClientWithOptions(t trace.Tracer, l logr.Logger, scheme *runtime.
 	return newGenericClientWithOptions(t, l, tracingScheme, optFns...)
 }
 
+// NewGenericClientFromProvider creates a GenericClient whose spans use operatortrace's own versioned
+// instrumentation scope, obtained from tp, instead of a caller supplied tracer.
+func NewGenericClientFromProvider(tp trace.TracerProvider, l logr.Logger, scheme *runtime.Scheme, optFns ...Option) GenericClient {
+	return NewGenericClientWithOptions(tracerFromProvider(tp), l, scheme, optFns...)
+}
+
 func newGenericClientWithOptions(t trace.Tracer, l logr.Logger, scheme *runtime.Scheme, optFns ...Option) GenericClient {
 	return &genericClient{
 		Tracer:  t,
 		Logger:  l,
 		scheme:  scheme,
 		options: newOptions(optFns...),
+		gvks:    &gvkCache{},
 	}
 }
 
@@ -65,8 +85,8 @@ func newGenericClientWithOptions(t trace.Tracer, l logr.Logger, scheme *runtime.
 func (gc *genericClient) StartTrace(ctx context.Context, obj client.Object) (context.Context, trace.Span, error) {
 	linkedSpans := [10]tracingtypes.LinkedSpan{}
 
-	gvk, err := apiutil.GVKForObject(obj, gc.scheme)
-	objectName := obj.GetName()
+	gvk, err := gc.gvks.gvkForObject(obj, gc.scheme)
+	objectName := gc.options.withCallOptions(ctx).redactedName(obj, gvk)
 	objectKind := ""
 	if err == nil {
 		objectKind = gvk.GroupKind().Kind
@@ -81,26 +101,153 @@ func (gc *genericClient) StartTrace(ctx context.Context, obj client.Object) (con
 	return trace.ContextWithSpan(ctx, span), span, err
 }
 
+// StartTraceWithParent starts a new trace span from the given object, connected to the trace context of an
+// incoming call, e.g. the traceparent and tracestate headers of an HTTP request. The incoming trace becomes the
+// parent or a link of the span according to WithIncomingTraceRelationship, the same as incoming annotations. An
+// invalid traceParent is recorded on the span and returned, and the span is started from obj alone.
+func (gc *genericClient) StartTraceWithParent(ctx context.Context, obj client.Object, traceParent, traceState string) (context.Context, trace.Span, error) {
+	spanContext, parentErr := tracecontext.SpanContextFromTraceData(traceParent, traceState)
+	if parentErr != nil {
+		parentErr = fmt.Errorf("invalid traceparent %q: %w", traceParent, parentErr)
+	}
+
+	var spanOpts []trace.SpanStartOption
+	if parentErr == nil {
+		opts := gc.options.withCallOptions(ctx)
+		if opts.IncomingTraceRelationship == TraceParentRelationshipParent && !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, spanContext)
+		} else {
+			spanOpts = append(spanOpts, trace.WithLinks(trace.Link{SpanContext: spanContext}))
+		}
+	}
+
+	gvk, err := gc.gvks.gvkForObject(obj, gc.scheme)
+	objectName := gc.options.withCallOptions(ctx).redactedName(obj, gvk)
+	objectKind := ""
+	if err == nil {
+		objectKind = gvk.GroupKind().Kind
+	}
+
+	ctx, span := startSpanFromContext(ctx, gc.Logger, gc.Tracer, obj, gc.scheme, gc.options, fmt.Sprintf("StartTrace %s %s", objectKind, objectName), [10]tracingtypes.LinkedSpan{}, spanOpts...)
+	err = errors.Join(parentErr, err)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	gc.Logger.Info("Getting object", "object", objectName)
+	return trace.ContextWithSpan(ctx, span), span, err
+}
+
+// StartTraceFromRequest starts a new trace span from the given object, continuing the parent and links of
+// requestWithTraceID the way the TracingClient StartTrace does: an inherited parent is stored on obj and becomes
+// the parent of the span, any other parent is only linked.
+func (gc *genericClient) StartTraceFromRequest(ctx context.Context, requestWithTraceID *tracingtypes.RequestWithTraceID, obj client.Object) (context.Context, trace.Span, error) {
+	opts := gc.options.withCallOptions(ctx)
+	spanObj, linked, _ := applyRequestParent(*requestWithTraceID, obj, gc.scheme, opts)
+
+	gvk, err := gc.gvks.gvkForObject(obj, gc.scheme)
+	name := opts.redactedName(obj, gvk)
+	operationName := startTraceOperationName(*requestWithTraceID, gvk, err, name, opts)
+
+	ctx, span := startSpanFromContext(ctx, gc.Logger, gc.Tracer, spanObj, gc.scheme, gc.options, operationName, linked.LinkedSpans)
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	gc.Logger.Info("Getting object", "object", name)
+	return trace.ContextWithSpan(ctx, span), span, err
+}
+
 // EndTrace ends the trace span for the given object.
 func (gc *genericClient) EndTrace(ctx context.Context, obj client.Object) error {
-	annotations := obj.GetAnnotations()
-	if annotations == nil {
+	if obj.GetAnnotations() == nil {
 		return nil
 	}
 
+	annotations := copyAnnotations(obj)
 	persistTraceCarrier(annotations, gc.options, "", "")
 	obj.SetAnnotations(annotations)
 
 	return nil
 }
 
+// EndTraceWithPatch returns a merge patch that clears the trace annotations of obj, for callers that write
+// objects with their own client. obj is left unchanged.
+func (gc *genericClient) EndTraceWithPatch(obj client.Object) (client.Patch, error) {
+	original, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return nil, fmt.Errorf("cannot copy %T", obj)
+	}
+	ended, _ := obj.DeepCopyObject().(client.Object)
+	ended.SetAnnotations(gc.EndTraceAnnotations(obj))
+
+	data, err := client.MergeFrom(original).Data(ended)
+	if err != nil {
+		return nil, err
+	}
+	return client.RawPatch(types.MergePatchType, data), nil
+}
+
+// EndTraceAnnotations returns a copy of the annotations of obj without its trace annotations.
+// obj is left unchanged.
+func (gc *genericClient) EndTraceAnnotations(obj client.Object) map[string]string {
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		return nil
+	}
+	ended := make(map[string]string, len(annotations))
+	for key, value := range annotations {
+		ended[key] = value
+	}
+	persistTraceCarrier(ended, gc.options, "", "")
+	return ended
+}
+
 func (gc *genericClient) StartSpan(ctx context.Context, operationName string) (context.Context, trace.Span) {
-	return startSpanFromContext(ctx, gc.Logger, gc.Tracer, nil, gc.scheme, gc.options, operationName, [10]tracingtypes.LinkedSpan{})
+	return gc.StartSpanWithOptions(ctx, operationName)
+}
+
+// StartSpanWithOptions starts a span named operationName with opts, such as its kind and attributes, as a child of
+// the span in ctx.
+func (gc *genericClient) StartSpanWithOptions(ctx context.Context, operationName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return gc.StartSpanForObject(ctx, operationName, nil, opts...)
+}
+
+// StartSpanForObject is StartSpanWithOptions continuing the trace stored on obj, in its annotations or conditions,
+// when ctx carries no span.
+func (gc *genericClient) StartSpanForObject(ctx context.Context, operationName string, obj client.Object, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return startSpanFromContext(ctx, gc.Logger, gc.Tracer, obj, gc.scheme, gc.options, operationName, [10]tracingtypes.LinkedSpan{}, opts...)
 }
 
 func (gc *genericClient) SetSpan(ctx context.Context, obj client.Object) (context.Context, trace.Span) {
-	ctx, span := startSpanFromContextGeneric(ctx, gc.Logger, gc.Tracer, obj.GetName())
+	gvk, _ := gc.gvks.gvkForObject(obj, gc.scheme)
+	ctx, span := startSpanFromContextGeneric(ctx, gc.Logger, gc.Tracer, gc.options.withCallOptions(ctx).redactedName(obj, gvk))
 	ctxWithSpan := trace.ContextWithSpan(ctx, span)
-	addTraceAnnotations(ctxWithSpan, obj, gc.options)
+	addTraceAnnotations(ctxWithSpan, gc.Logger, obj, gvk, gc.options)
 	return ctxWithSpan, span
 }
+
+// SetSpanFromAnnotations starts a span continuing the trace stored in the annotations of obj, e.g. an object
+// received from another operator, without starting a new root span. The stored trace becomes the parent of the
+// span, or a link when it is stored under the incoming annotations and WithIncomingTraceRelationship asks for one.
+// When obj carries no usable trace context, because it has none, it is invalid or it expired, ctx is returned
+// unchanged with a no-op span and false.
+func (gc *genericClient) SetSpanFromAnnotations(ctx context.Context, obj client.Object) (context.Context, trace.Span, bool) {
+	opts := gc.options.withCallOptions(ctx)
+	stored, ok := extractTraceContextFromAnnotations(obj.GetAnnotations(), opts)
+	if !ok {
+		return ctx, noop.Span{}, false
+	}
+	if lookup := checkStoredTraceContext(stored, opts); lookup.rootReason != "" {
+		return ctx, noop.Span{}, false
+	}
+
+	var spanOpts []trace.SpanStartOption
+	ctx, incomingLink := applyStoredTraceContext(ctx, stored, opts, nil)
+	if incomingLink != nil {
+		spanOpts = append(spanOpts, trace.WithLinks(*incomingLink))
+	}
+	gvk, _ := gc.gvks.gvkForObject(obj, gc.scheme)
+	ctx, span := gc.Tracer.Start(ctx, opts.redactedName(obj, gvk), spanOpts...)
+	return ctx, span, true
+}
@@ -8,16 +8,25 @@ import (
 	"context"
 	"testing"
 
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
 	"github.com/go-logr/logr"
 	"github.com/go-logr/logr/testr"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 func initGenericTracer() trace.Tracer {
@@ -58,7 +67,7 @@ func TestGenericClientStartTraceAndEndTrace(t *testing.T) {
 	defer span.End()
 	assert.NoError(t, err)
 	ctx = trace.ContextWithSpan(ctx, span)
-	addTraceAnnotations(ctx, pod, gc.options)
+	addTraceAnnotations(ctx, gc.Logger, pod, corev1.SchemeGroupVersion.WithKind("Pod"), gc.options)
 	annotations := pod.GetAnnotations()
 	assert.NotEmpty(t, annotations[gc.options.EmittedTraceParentAnnotationKey()])
 
@@ -80,6 +89,28 @@ func TestGenericClientStartSpan(t *testing.T) {
 	assert.NotNil(t, span)
 }
 
+func TestGenericClientStartSpanForObject(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	genericClient := NewGenericClient(tp.Tracer("operatortrace"), logr.Discard())
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+	annotateObjectWithTraceIDs(t, pod, NewOptions(), testTraceIDHex, testSpanIDHex)
+	_, span := genericClient.StartSpanForObject(context.Background(), "render", pod, trace.WithSpanKind(trace.SpanKindProducer))
+	span.End()
+	_, span = genericClient.StartSpanWithOptions(context.Background(), "unrelated", trace.WithSpanKind(trace.SpanKindClient))
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, trace.SpanKindProducer, spans[0].SpanKind)
+	assert.Equal(t, testTraceIDHex, spans[0].SpanContext.TraceID().String())
+	assert.Equal(t, testSpanIDHex, spans[0].Parent.SpanID().String())
+	assert.Equal(t, trace.SpanKindClient, spans[1].SpanKind)
+	assert.NotEqual(t, testTraceIDHex, spans[1].SpanContext.TraceID().String())
+}
+
 func TestGenericClientSetSpan(t *testing.T) {
 	tracer := initGenericTracer()
 	logger := logr.Discard()
@@ -99,3 +130,223 @@ func TestGenericClientSetSpan(t *testing.T) {
 	annotations := pod.GetAnnotations()
 	assert.NotEmpty(t, annotations[gc.options.EmittedTraceParentAnnotationKey()])
 }
+
+func TestGenericClientSetSpanFromAnnotations(t *testing.T) {
+	traceParent := "00-" + testTraceIDHex + "-" + testSpanIDHex + "-01"
+
+	tests := []struct {
+		name        string
+		options     []Option
+		annotations map[string]string
+		continued   bool
+		linked      bool
+	}{
+		{
+			name:        "stored trace becomes the parent",
+			annotations: map[string]string{constants.DefaultTraceParentAnnotation: traceParent},
+			continued:   true,
+		},
+		{
+			name:        "incoming trace is linked",
+			options:     []Option{WithIncomingTraceParentAnnotation("example.com/traceparent"), WithIncomingTraceRelationship(TraceParentRelationshipLink)},
+			annotations: map[string]string{"example.com/traceparent": traceParent},
+			continued:   true,
+			linked:      true,
+		},
+		{
+			name: "no annotations",
+		},
+		{
+			name:        "invalid traceparent",
+			annotations: map[string]string{constants.DefaultTraceParentAnnotation: "invalid"},
+		},
+		{
+			name: "expired trace",
+			annotations: map[string]string{
+				constants.DefaultTraceParentAnnotation: traceParent,
+				constants.DefaultTraceStateAnnotation:  constants.TraceStateTimestampKey + "=2020-01-01T00:00:00Z",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			genericClient := NewGenericClientWithOptions(tp.Tracer("operatortrace"), logr.Discard(), nil, tt.options...)
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Annotations: tt.annotations}}
+
+			ctx, span, ok := genericClient.SetSpanFromAnnotations(context.Background(), pod)
+			span.End()
+			assert.Equal(t, tt.continued, ok)
+
+			spans := exporter.GetSpans()
+			if !tt.continued {
+				assert.Equal(t, context.Background(), ctx)
+				assert.False(t, span.SpanContext().IsValid())
+				assert.Empty(t, spans)
+				return
+			}
+			assert.Equal(t, span, trace.SpanFromContext(ctx))
+			require.Len(t, spans, 1)
+			assert.Equal(t, "pod", spans[0].Name)
+			if tt.linked {
+				assert.NotEqual(t, testTraceIDHex, spans[0].SpanContext.TraceID().String())
+				require.Len(t, spans[0].Links, 1)
+				assert.Equal(t, testSpanIDHex, spans[0].Links[0].SpanContext.SpanID().String())
+				return
+			}
+			assert.Equal(t, testTraceIDHex, spans[0].SpanContext.TraceID().String())
+			assert.Equal(t, testSpanIDHex, spans[0].Parent.SpanID().String())
+			// The object is only read
+			assert.Equal(t, tt.annotations, pod.GetAnnotations())
+		})
+	}
+}
+
+func TestNewGenericClientFromProvider(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	genericClient := NewGenericClientFromProvider(tp, logr.Discard(), nil)
+	_, span := genericClient.StartSpan(context.Background(), "test-operation")
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, constants.TracerName, spans[0].InstrumentationScope.Name)
+	assert.Equal(t, Version(), spans[0].InstrumentationScope.Version)
+}
+
+func TestGenericClientEndTraceWithPatch(t *testing.T) {
+	gc := NewGenericClient(initGenericTracer(), logr.Discard())
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "webhook-pod",
+			Namespace: "default",
+			Annotations: map[string]string{
+				constants.DefaultTraceParentAnnotation: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+				constants.DefaultTraceStateAnnotation:  "operatortrace_ts=1700000000",
+				constants.LegacyTraceIDAnnotation:      "4bf92f3577b34da6a3ce929d0e0e4736",
+				"app.kubernetes.io/owner":              "webhook",
+			},
+		},
+	}
+	k8sClient := fake.NewClientBuilder().WithObjects(pod.DeepCopy()).Build()
+
+	expected := map[string]string{"app.kubernetes.io/owner": "webhook"}
+	assert.Equal(t, expected, gc.EndTraceAnnotations(pod))
+
+	patch, err := gc.EndTraceWithPatch(pod)
+	require.NoError(t, err)
+	assert.Len(t, pod.Annotations, 4, "the object must not be modified")
+
+	require.NoError(t, k8sClient.Patch(context.Background(), pod.DeepCopy(), patch))
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), ctrlclient.ObjectKeyFromObject(pod), stored))
+	assert.Equal(t, expected, stored.Annotations)
+
+	untraced := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "plain-pod", Namespace: "default"}}
+	assert.Nil(t, gc.EndTraceAnnotations(untraced))
+	patch, err = gc.EndTraceWithPatch(untraced)
+	require.NoError(t, err)
+	data, err := patch.Data(untraced)
+	require.NoError(t, err)
+	assert.JSONEq(t, "{}", string(data))
+}
+
+func TestGenericClientStartTraceWithParent(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	incoming := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	incomingTraceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		traceParent  string
+		expectParent bool
+		expectLink   bool
+		expectErr    bool
+	}{
+		{"link by default", nil, incoming, false, true, false},
+		{"parent relationship", []Option{WithIncomingTraceRelationship(TraceParentRelationshipParent)}, incoming, true, false, false},
+		{"invalid traceparent", nil, "00-invalid", false, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			gc := NewGenericClientWithOptions(tp.Tracer("test"), logr.Discard(), nil, tt.opts...)
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "webhook-pod", Namespace: "default"}}
+
+			_, span, err := gc.StartTraceWithParent(context.Background(), pod, tt.traceParent, "")
+			span.End()
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tt.expectParent, spans[0].SpanContext.TraceID().String() == incomingTraceID)
+			assert.Equal(t, tt.expectParent, spans[0].Parent.IsValid())
+			linked := len(spans[0].Links) == 1 && spans[0].Links[0].SpanContext.TraceID().String() == incomingTraceID
+			assert.Equal(t, tt.expectLink, linked)
+		})
+	}
+}
+
+func TestGenericClientStartTraceFromRequest(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	parent := tracingtypes.RequestParent{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Kind:    "ConfigMap",
+		Name:    "settings",
+	}
+
+	tests := []struct {
+		name         string
+		opts         []Option
+		expectParent bool
+	}{
+		{"inherited parent", nil, true},
+		{"parent kind linked", []Option{WithRelationshipPerKind(map[schema.GroupKind]TraceParentRelationship{
+			{Kind: "ConfigMap"}: TraceParentRelationshipLink,
+		})}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			gc := NewGenericClientWithOptions(tp.Tracer("test"), logr.Discard(), nil, tt.opts...)
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "webhook-pod", Namespace: "default"}}
+			request := &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: ctrlclient.ObjectKeyFromObject(pod)},
+				Parent:  parent,
+			}
+
+			_, span, err := gc.StartTraceFromRequest(context.Background(), request, pod)
+			span.End()
+			require.NoError(t, err)
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			assert.Equal(t, "StartTrace Pod/webhook-pod Triggered By Changed Object ConfigMap/settings", spans[0].Name)
+			if tt.expectParent {
+				assert.Equal(t, parent.TraceID, spans[0].SpanContext.TraceID().String())
+				assert.Equal(t, parent.SpanID, spans[0].Parent.SpanID().String())
+				assert.Contains(t, pod.Annotations[constants.DefaultTraceParentAnnotation], parent.TraceID)
+				return
+			}
+			assert.NotEqual(t, parent.TraceID, spans[0].SpanContext.TraceID().String())
+			require.Len(t, spans[0].Links, 1)
+			assert.Equal(t, parent.SpanID, spans[0].Links[0].SpanContext.SpanID().String())
+			assert.Empty(t, pod.Annotations)
+		})
+	}
+}
@@ -0,0 +1,40 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/get_fresh.go
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// GetFresh is Get reading straight from the API server, e.g. to verify the resource version right after a write
+// the informer cache has not caught up with yet. It reads through the reader set with WithAPIReader, or else the
+// client's reader, which NewTracingClientForManager sets to the manager's API reader.
+func (tc *tracingClient) GetFresh(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if tc.noop(ctx) {
+		return tc.apiReader.Get(ctx, key, obj, opts...)
+	}
+
+	gvk, err := tc.gvks.gvkForObject(obj, tc.scheme)
+	if err != nil {
+		return fmt.Errorf("problem getting the scheme: %w", err)
+	}
+	callOpts := tc.options.withCallOptions(ctx)
+	name := callOpts.redactedKeyName(key, gvk)
+	ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, callOpts.keySpanName("GetFresh", key, gvk), [10]tracingtypes.LinkedSpan{}, trace.WithAttributes(ReaderAttributeKey.String(readerSourceAPI)))
+	defer span.End()
+
+	tc.Logger.Info("Getting fresh object", "object", name)
+
+	err = tc.apiReader.Get(ctx, key, obj, opts...)
+	if err != nil {
+		span.RecordError(err)
+	}
+	return err
+}
@@ -0,0 +1,88 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/get_fresh_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetFresh(t *testing.T) {
+	newClients := func() (apiServer client.Client, staleCache client.Client) {
+		cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"}, Data: map[string]string{"v": "1"}}
+		apiServer = fake.NewClientBuilder().WithObjects(cm.DeepCopy()).Build()
+		staleCache = fake.NewClientBuilder().WithObjects(cm.DeepCopy()).Build()
+
+		// The write reaches the API server before the cache catches up
+		require.NoError(t, apiServer.Update(context.Background(), &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default", ResourceVersion: "999"}, Data: map[string]string{"v": "2"}}))
+		return apiServer, staleCache
+	}
+	key := client.ObjectKey{Namespace: "default", Name: "cm"}
+
+	tests := []struct {
+		name         string
+		apiReaderOpt bool
+		noop         bool
+	}{
+		{name: "cached reader with WithAPIReader", apiReaderOpt: true},
+		{name: "API reader passed as the reader"},
+		{name: "noop", apiReaderOpt: true, noop: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiServer, staleCache := newClients()
+			var reader client.Reader = apiServer
+			var optFns []Option
+			if tt.apiReaderOpt {
+				reader = staleCache
+				optFns = append(optFns, WithAPIReader(apiServer))
+			}
+			if tt.noop {
+				optFns = append(optFns, WithNoop())
+			}
+			tracingClient := NewTracingClientWithOptions(staleCache, reader, initTracer(), logr.Discard(), nil, optFns...)
+			latest := &corev1.ConfigMap{}
+			require.NoError(t, apiServer.Get(context.Background(), key, latest))
+
+			fresh := &corev1.ConfigMap{}
+			require.NoError(t, tracingClient.GetFresh(context.Background(), key, fresh))
+			assert.Equal(t, latest.ResourceVersion, fresh.ResourceVersion)
+			assert.Equal(t, "2", fresh.Data["v"])
+
+			if tt.apiReaderOpt {
+				cached := &corev1.ConfigMap{}
+				require.NoError(t, tracingClient.Get(context.Background(), key, cached))
+				assert.NotEqual(t, latest.ResourceVersion, cached.ResourceVersion)
+			}
+		})
+	}
+}
+
+func TestGetFreshSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().Build()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard())
+
+	err := tracingClient.GetFresh(context.Background(), client.ObjectKey{Namespace: "default", Name: "missing"}, &corev1.ConfigMap{})
+	require.Error(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GetFresh ConfigMap missing", spans[0].Name)
+	assert.Contains(t, spans[0].Attributes, ReaderAttributeKey.String("api"))
+	assert.Len(t, spans[0].Events, 1)
+}
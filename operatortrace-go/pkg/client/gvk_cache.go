@@ -0,0 +1,58 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/gvk_cache.go
+
+package client
+
+import (
+	"reflect"
+	"sync"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// gvkCache remembers the result of apiutil.GVKForObject per Go type, so the scheme's type map is only walked once
+// per type. A cache is bound to the scheme of the client owning it; schemes do not change once clients are
+// created, so entries are never invalidated.
+type gvkCache struct {
+	kinds sync.Map
+}
+
+// gvkCacheEntry is the result of apiutil.GVKForObject for a Go type, the error included.
+type gvkCacheEntry struct {
+	gvk schema.GroupVersionKind
+	err error
+}
+
+// gvkForObject returns what apiutil.GVKForObject returns for obj and scheme. Objects whose GVK depends on the
+// object rather than its type, such as unstructured objects or types registered under several GVKs, are never
+// cached. A nil cache always calls apiutil.GVKForObject.
+func (c *gvkCache) gvkForObject(obj runtime.Object, scheme *runtime.Scheme) (schema.GroupVersionKind, error) {
+	if c == nil || obj == nil || !cacheableGVKType(obj) {
+		return apiutil.GVKForObject(obj, scheme)
+	}
+	t := reflect.TypeOf(obj)
+	if entry, ok := c.kinds.Load(t); ok {
+		e := entry.(gvkCacheEntry)
+		return e.gvk, e.err
+	}
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if gvks, _, kindsErr := scheme.ObjectKinds(obj); kindsErr == nil && len(gvks) > 1 {
+		// The GVK set on obj picks one of several, so the result only holds for this object
+		return gvk, err
+	}
+	c.kinds.Store(t, gvkCacheEntry{gvk: gvk, err: err})
+	return gvk, err
+}
+
+// cacheableGVKType reports whether the GVK of obj is decided by its Go type alone.
+func cacheableGVKType(obj runtime.Object) bool {
+	switch obj.(type) {
+	case runtime.Unstructured, *metav1.PartialObjectMetadata, *metav1.PartialObjectMetadataList:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/gvk_cache_test.go
+
+package client
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGVKCacheMatchesGVKForObject(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.NoError(t, corev1.AddToScheme(scheme))
+	multi := schema.GroupVersion{Group: "example.com", Version: "v1"}
+	scheme.AddKnownTypeWithName(multi.WithKind("First"), &corev1.ConfigMap{})
+	scheme.AddKnownTypeWithName(multi.WithKind("Second"), &corev1.ConfigMap{})
+
+	unstructuredSecret := &unstructured.Unstructured{}
+	unstructuredSecret.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Secret"))
+	partial := &metav1.PartialObjectMetadata{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"}}
+	second := &corev1.ConfigMap{TypeMeta: metav1.TypeMeta{APIVersion: "example.com/v1", Kind: "Second"}}
+
+	tests := []struct {
+		name      string
+		objs      []runtime.Object
+		expectHit bool
+	}{
+		{"registered type", []runtime.Object{&corev1.Pod{}, &corev1.Pod{}}, true},
+		{"unregistered type", []runtime.Object{&appsv1.Deployment{}, &appsv1.Deployment{}}, true},
+		{"unstructured objects", []runtime.Object{&unstructured.Unstructured{}, unstructuredSecret}, false},
+		{"partial metadata objects", []runtime.Object{&metav1.PartialObjectMetadata{}, partial}, false},
+		{"type registered under several kinds", []runtime.Object{&corev1.ConfigMap{}, second}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &gvkCache{}
+			for _, obj := range tt.objs {
+				expectedGVK, expectedErr := apiutil.GVKForObject(obj, scheme)
+				gvk, err := cache.gvkForObject(obj, scheme)
+				assert.Equal(t, expectedGVK, gvk)
+				if expectedErr != nil {
+					assert.EqualError(t, err, expectedErr.Error())
+				} else {
+					assert.NoError(t, err)
+				}
+			}
+			_, cached := cache.kinds.Load(reflect.TypeOf(tt.objs[0]))
+			assert.Equal(t, tt.expectHit, cached)
+		})
+	}
+}
+
+func TestGVKCacheSharedWithStatusClient(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	tc := NewTracingClient(k8sClient, k8sClient, nil, logr.Discard()).(*tracingClient)
+	status := tc.Status().(*tracingStatusClient)
+	assert.Same(t, tc.gvks, status.gvks)
+}
+
+func BenchmarkGVKForObject(b *testing.B) {
+	for name, lookup := range map[string]func(runtime.Object, *runtime.Scheme) (schema.GroupVersionKind, error){
+		"apiutil": apiutil.GVKForObject,
+		"cached":  (&gvkCache{}).gvkForObject,
+	} {
+		b.Run(name, func(b *testing.B) {
+			pod := &corev1.Pod{}
+			for i := 0; i < b.N; i++ {
+				if _, err := lookup(pod, clientgoscheme.Scheme); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
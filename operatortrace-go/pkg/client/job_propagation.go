@@ -0,0 +1,84 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/job_propagation.go
+
+package client
+
+import (
+	"context"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// InjectTraceContext returns annotations with the trace context of the span in ctx stored the way the tracing
+// client stores it on written objects, allocating the map when annotations is nil. It is meant for templates the
+// tracing client never writes itself, such as a CronJob's job template: the CronJob controller copies
+// jobTemplate.metadata.annotations onto every Job it creates, so the Jobs carry the trace of the reconcile that
+// created the CronJob.
+//
+//	cronJob.Spec.JobTemplate.Annotations = client.InjectTraceContext(ctx, cronJob.Spec.JobTemplate.Annotations)
+//	err := tracingClient.Create(ctx, cronJob)
+//
+// opts must match the options of the client that reads the annotations back.
+func InjectTraceContext(ctx context.Context, annotations map[string]string, opts ...Option) map[string]string {
+	o := newOptions(opts...)
+	traceParent, traceState, ok := traceDataToPersist(ctx, o)
+	if !ok {
+		return annotations
+	}
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	persistTraceCarrier(annotations, o, traceParent, traceState)
+	return annotations
+}
+
+// ExtractTraceFromJobAnnotations returns the trace and span IDs stored in the annotations of job, typically copied
+// there from its CronJob's job template by InjectTraceContext. When job carries no usable trace context and a reader
+// is configured with WithOwnerTraceFallback, the CronJob controlling job is read with ctx and its job template
+// annotations, then its own annotations, are used instead. Expired trace context is ignored like in StartTrace.
+func ExtractTraceFromJobAnnotations(ctx context.Context, job *batchv1.Job, opts ...Option) (traceID, spanID string, ok bool) {
+	o := newOptions(opts...)
+	if traceID, spanID, ok := traceIDsFromAnnotations(job.GetAnnotations(), o); ok {
+		return traceID, spanID, true
+	}
+
+	owner := metav1.GetControllerOf(job)
+	if o.OwnerTraceFallbackReader == nil || owner == nil || owner.Kind != "CronJob" {
+		return "", "", false
+	}
+	cronJob := &batchv1.CronJob{}
+	if err := o.OwnerTraceFallbackReader.Get(ctx, types.NamespacedName{Namespace: job.Namespace, Name: owner.Name}, cronJob); err != nil {
+		return "", "", false
+	}
+	if traceID, spanID, ok := traceIDsFromAnnotations(cronJob.Spec.JobTemplate.GetAnnotations(), o); ok {
+		return traceID, spanID, true
+	}
+	return traceIDsFromAnnotations(cronJob.GetAnnotations(), o)
+}
+
+// RequestParentFromJob returns the parent of a request reconciled because of job, e.g. for a Pod the Job created,
+// with the trace found by ExtractTraceFromJobAnnotations.
+func RequestParentFromJob(ctx context.Context, job *batchv1.Job, opts ...Option) (tracingtypes.RequestParent, bool) {
+	traceID, spanID, ok := ExtractTraceFromJobAnnotations(ctx, job, opts...)
+	if !ok {
+		return tracingtypes.RequestParent{}, false
+	}
+	return tracingtypes.RequestParent{TraceID: traceID, SpanID: spanID, Kind: "Job", Name: job.Name}, true
+}
+
+// traceIDsFromAnnotations returns the IDs of the usable trace context stored in annotations.
+func traceIDsFromAnnotations(annotations map[string]string, opts Options) (traceID, spanID string, ok bool) {
+	stored, ok := extractTraceContextFromAnnotations(annotations, opts)
+	if !ok {
+		return "", "", false
+	}
+	lookup := checkStoredTraceContext(stored, opts)
+	if lookup.rootReason != "" {
+		return "", "", false
+	}
+	return lookup.spanContext.TraceID().String(), lookup.spanContext.SpanID().String(), true
+}
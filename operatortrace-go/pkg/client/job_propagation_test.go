@@ -0,0 +1,148 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/job_propagation_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// jobFromCronJob creates the Job the CronJob controller would create for cronJob.
+func jobFromCronJob(t *testing.T, k8sClient client.Client, cronJob *batchv1.CronJob, name string) *batchv1.Job {
+	t.Helper()
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   cronJob.Namespace,
+			Labels:      cronJob.Spec.JobTemplate.Labels,
+			Annotations: cronJob.Spec.JobTemplate.Annotations,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(cronJob, batchv1.SchemeGroupVersion.WithKind("CronJob")),
+			},
+		},
+		Spec: cronJob.Spec.JobTemplate.Spec,
+	}
+	require.NoError(t, k8sClient.Create(context.Background(), job))
+	return job
+}
+
+func TestCronJobTraceReachesJobReconcile(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "schedule", Namespace: "default"}},
+	).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("test"), logr.Discard(), nil)
+
+	// The reconcile creating the CronJob injects its trace into the job template
+	ctx, span, err := tracingClient.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "schedule", Namespace: "default"}},
+	}, &corev1.ConfigMap{})
+	require.NoError(t, err)
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default"},
+		Spec:       batchv1.CronJobSpec{Schedule: "0 0 * * *"},
+	}
+	cronJob.Spec.JobTemplate.Annotations = InjectTraceContext(ctx, cronJob.Spec.JobTemplate.Annotations)
+	require.NoError(t, tracingClient.Create(ctx, cronJob))
+	span.End()
+	traceID := span.SpanContext().TraceID().String()
+
+	job := jobFromCronJob(t, k8sClient, cronJob, "nightly-28000000")
+	parent, ok := RequestParentFromJob(context.Background(), job)
+	require.True(t, ok)
+	assert.Equal(t, traceID, parent.TraceID)
+	assert.Equal(t, "Job", parent.Kind)
+	assert.Equal(t, job.Name, parent.Name)
+
+	// The reconcile triggered by the Job continues the trace
+	_, span, err = tracingClient.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: client.ObjectKeyFromObject(job)},
+		Parent:  parent,
+	}, &batchv1.Job{})
+	require.NoError(t, err)
+	span.End()
+	assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+}
+
+func TestExtractTraceFromJobAnnotations(t *testing.T) {
+	tracer := initTracer()
+	ctx, span := tracer.Start(context.Background(), "create")
+	span.End()
+	traced := InjectTraceContext(ctx, nil)
+	expectedTraceID := span.SpanContext().TraceID().String()
+	expectedSpanID := span.SpanContext().SpanID().String()
+
+	tests := []struct {
+		name             string
+		templateAnnotate map[string]string
+		cronJobAnnotate  map[string]string
+		jobAnnotate      map[string]string
+		fallback         bool
+		expectedOK       bool
+	}{
+		{name: "job annotations", jobAnnotate: traced, expectedOK: true},
+		{name: "job template annotations through the owner", templateAnnotate: traced, fallback: true, expectedOK: true},
+		{name: "cronjob annotations through the owner", cronJobAnnotate: traced, fallback: true, expectedOK: true},
+		{name: "owner not read without a fallback reader", templateAnnotate: traced},
+		{name: "no trace anywhere", fallback: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cronJob := &batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default", UID: "cronjob-uid", Annotations: tt.cronJobAnnotate},
+				Spec:       batchv1.CronJobSpec{Schedule: "0 0 * * *"},
+			}
+			cronJob.Spec.JobTemplate.Annotations = tt.templateAnnotate
+			k8sClient := fake.NewClientBuilder().WithObjects(cronJob).Build()
+			job := jobFromCronJob(t, k8sClient, cronJob, "nightly-28000000")
+			job.Annotations = tt.jobAnnotate
+
+			var opts []Option
+			if tt.fallback {
+				opts = append(opts, WithOwnerTraceFallback(k8sClient))
+			}
+			traceID, spanID, ok := ExtractTraceFromJobAnnotations(context.Background(), job, opts...)
+			assert.Equal(t, tt.expectedOK, ok)
+			if tt.expectedOK {
+				assert.Equal(t, expectedTraceID, traceID)
+				assert.Equal(t, expectedSpanID, spanID)
+			} else {
+				assert.Empty(t, traceID)
+				assert.Empty(t, spanID)
+			}
+		})
+	}
+}
+
+func TestInjectTraceContext(t *testing.T) {
+	assert.Nil(t, InjectTraceContext(context.Background(), nil), "no span, nothing to inject")
+
+	ctx, span := initTracer().Start(context.Background(), "create")
+	defer span.End()
+	annotations := InjectTraceContext(ctx, map[string]string{"team": "batch"})
+	assert.Equal(t, "batch", annotations["team"])
+	assert.Contains(t, annotations[constants.DefaultTraceParentAnnotation], span.SpanContext().TraceID().String())
+
+	custom := InjectTraceContext(ctx, nil, WithTraceParentKey("example.com/traceparent"))
+	assert.Contains(t, custom["example.com/traceparent"], span.SpanContext().TraceID().String())
+	assert.NotContains(t, custom, constants.DefaultTraceParentAnnotation)
+}
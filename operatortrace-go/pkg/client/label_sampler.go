@@ -0,0 +1,90 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/label_sampler.go
+
+package client
+
+import (
+	"context"
+	"crypto/rand"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/otelsetup"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// LabelSampledAttributeKey is set on the spans a LabelSamplingRule selected for tracing.
+const LabelSampledAttributeKey = otelsetup.LabelSampledAttributeKey
+
+// LabelSamplingRule decides whether the spans of objects carrying a label are traced. An empty LabelValue matches
+// any value of LabelKey.
+type LabelSamplingRule struct {
+	LabelKey   string
+	LabelValue string
+	Sample     bool
+}
+
+// matches reports whether labels satisfy the rule.
+func (r LabelSamplingRule) matches(labels map[string]string) bool {
+	value, ok := labels[r.LabelKey]
+	return ok && (r.LabelValue == "" || value == r.LabelValue)
+}
+
+// WithLabelSampler decides per object whether its spans are traced, from its labels. The rules are evaluated in
+// order and the first one matching the labels of the object decides; objects no rule matches are left to the SDK
+// sampler. Spans of dropped objects are not started, and the spans started under them belong to an unsampled
+// trace, so a parent based SDK sampler drops them as well. Selected spans carry LabelSampledAttributeKey, which the
+// sampler returned by otelsetup.NewLabelSampler always samples. Rules without a LabelKey are ignored.
+func WithLabelSampler(rules []LabelSamplingRule) Option {
+	return func(o *Options) {
+		valid := make([]LabelSamplingRule, 0, len(rules))
+		for _, rule := range rules {
+			if rule.LabelKey == "" {
+				continue
+			}
+			valid = append(valid, rule)
+		}
+		if len(valid) == 0 {
+			return
+		}
+		o.LabelSamplingRules = valid
+	}
+}
+
+// labelSamplingDecision returns the Sample value of the first rule matching the labels of obj, or false when no
+// rule matches.
+func (o Options) labelSamplingDecision(obj client.Object) (sample bool, matched bool) {
+	if obj == nil || len(o.LabelSamplingRules) == 0 {
+		return false, false
+	}
+	labels := obj.GetLabels()
+	for _, rule := range o.LabelSamplingRules {
+		if rule.matches(labels) {
+			return rule.Sample, true
+		}
+	}
+	return false, false
+}
+
+// labelUnsampledSpan returns a non-recording span to use instead of starting a span for obj when a label sampling
+// rule drops it. It continues the trace in ctx, or else the usable trace stored on obj, or else starts a new
+// trace, always with the sampled flag cleared.
+func labelUnsampledSpan(ctx context.Context, obj client.Object, scheme *runtime.Scheme, opts Options) (context.Context, trace.Span) {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		if lookup := lookupStoredTraceContext(obj, scheme, opts); lookup.rootReason == "" {
+			spanContext = lookup.spanContext
+		}
+	}
+	if !spanContext.IsValid() {
+		var traceID trace.TraceID
+		var spanID trace.SpanID
+		_, _ = rand.Read(traceID[:])
+		_, _ = rand.Read(spanID[:])
+		spanContext = trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID})
+	}
+	spanContext = spanContext.WithRemote(false).WithTraceFlags(spanContext.TraceFlags() &^ trace.FlagsSampled)
+	span := trace.SpanFromContext(trace.ContextWithSpanContext(context.Background(), spanContext))
+	return trace.ContextWithSpan(ctx, span), span
+}
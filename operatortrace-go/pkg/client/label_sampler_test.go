@@ -0,0 +1,89 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/label_sampler_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/otelsetup"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestLabelSampler(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	rules := []LabelSamplingRule{
+		{LabelKey: "critical", LabelValue: "true", Sample: true},
+		{LabelKey: "tier", LabelValue: "system", Sample: false},
+		{LabelKey: "debug", Sample: true},
+	}
+	storedTraceParent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name          string
+		base          sdktrace.Sampler
+		labels        map[string]string
+		stored        bool
+		expectSampled bool
+	}{
+		{"matching sample rule overrides the SDK sampler", sdktrace.NeverSample(), map[string]string{"critical": "true"}, false, true},
+		{"matching drop rule overrides the SDK sampler", sdktrace.AlwaysSample(), map[string]string{"tier": "system"}, false, false},
+		{"first matching rule wins", sdktrace.AlwaysSample(), map[string]string{"critical": "true", "tier": "system"}, false, true},
+		{"empty rule value matches any value", sdktrace.NeverSample(), map[string]string{"debug": "verbose"}, false, true},
+		{"other label value is not matched", sdktrace.NeverSample(), map[string]string{"critical": "false"}, false, false},
+		{"unlabeled object sampled by the SDK sampler", sdktrace.AlwaysSample(), nil, false, true},
+		{"unlabeled object dropped by the SDK sampler", sdktrace.NeverSample(), nil, false, false},
+		{"dropped object continuing a stored trace", sdktrace.AlwaysSample(), map[string]string{"tier": "system"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(
+				sdktrace.WithSyncer(exporter),
+				sdktrace.WithSampler(otelsetup.NewLabelSampler(sdktrace.ParentBased(tt.base))),
+			)
+			gc := NewGenericClientWithOptions(tp.Tracer("test"), logr.Discard(), nil, WithLabelSampler(rules))
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Labels: tt.labels}}
+			if tt.stored {
+				pod.Annotations = map[string]string{constants.DefaultTraceParentAnnotation: storedTraceParent}
+			}
+
+			ctx, span, err := gc.StartTrace(context.Background(), pod)
+			require.NoError(t, err)
+			// Unlabeled child spans follow the decision taken for the object
+			_, child := gc.StartSpan(ctx, "child")
+			child.End()
+			span.End()
+
+			assert.Equal(t, tt.expectSampled, span.SpanContext().IsSampled())
+			if tt.expectSampled {
+				assert.Len(t, exporter.GetSpans(), 2)
+			} else {
+				assert.Empty(t, exporter.GetSpans())
+			}
+			if tt.stored {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+			}
+		})
+	}
+}
+
+func TestWithLabelSamplerIgnoresInvalidRules(t *testing.T) {
+	opts := NewOptions(WithLabelSampler([]LabelSamplingRule{{LabelValue: "true", Sample: true}}))
+	assert.Empty(t, opts.LabelSamplingRules)
+
+	opts = NewOptions(WithLabelSampler([]LabelSamplingRule{{LabelKey: "critical", Sample: true}}), WithLabelSampler(nil))
+	assert.Equal(t, []LabelSamplingRule{{LabelKey: "critical", Sample: true}}, opts.LabelSamplingRules)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/legacy_migration.go
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// MigratedKindAttributeKey is the attribute of the legacy migration counter holding the kind of the migrated object.
+const MigratedKindAttributeKey = attribute.Key("operatortrace.legacy_migration.kind")
+
+// WithLegacyAnnotationMigration makes every write through the client remove the legacy trace ID, span ID and
+// trace time annotations from the written object in the same API call. An object carrying only the legacy
+// annotations gets their trace context rewritten as traceparent, with the legacy timestamp kept in the tracestate,
+// unless the write stores its own trace context anyway.
+func WithLegacyAnnotationMigration() Option {
+	return func(o *Options) {
+		o.LegacyAnnotationMigration = true
+	}
+}
+
+// WithLegacyMigrationCounter counts the objects whose legacy trace annotations were migrated, by kind.
+func WithLegacyMigrationCounter(c metric.Int64Counter) Option {
+	return func(o *Options) {
+		if c == nil {
+			return
+		}
+		o.LegacyMigrationCounter = c
+	}
+}
+
+// migrateLegacyTraceAnnotations migrates the legacy trace annotations of obj before it is written, when enabled.
+func (tc *tracingClient) migrateLegacyTraceAnnotations(ctx context.Context, obj client.Object, gvk schema.GroupVersionKind) {
+	opts := tc.options.withCallOptions(ctx)
+	if !opts.LegacyAnnotationMigration || !migrateLegacyTraceAnnotations(obj, opts) {
+		return
+	}
+	if opts.LegacyMigrationCounter != nil {
+		opts.LegacyMigrationCounter.Add(ctx, 1, metric.WithAttributes(MigratedKindAttributeKey.String(gvk.Kind)))
+	}
+}
+
+// migrateLegacyTraceAnnotations removes the legacy trace annotations from obj, first rewriting the trace context
+// they hold as traceparent and tracestate when obj carries no other. It reports whether obj carried any.
+func migrateLegacyTraceAnnotations(obj client.Object, opts Options) bool {
+	annotations := obj.GetAnnotations()
+	found := false
+	for _, key := range []string{opts.legacyTraceIDAnnotationKey(), opts.legacySpanIDAnnotationKey(), opts.legacyTraceTimeAnnotationKey()} {
+		if _, ok := annotations[key]; ok {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	migrated := copyAnnotations(obj)
+	if stored, ok := extractTraceContextFromAnnotations(annotations, opts); ok && stored.Source == TraceContextSourceLegacy {
+		traceState := ""
+		if !stored.Timestamp.IsZero() {
+			traceState, _ = tracecontext.SetTraceStateKey("", opts.traceStateTimestampKey(), stored.Timestamp.UTC().Format(time.RFC3339Nano))
+		}
+		persistTraceCarrier(migrated, opts, stored.TraceParent, traceState)
+	} else {
+		pruneLegacyTraceAnnotations(migrated, opts)
+	}
+	obj.SetAnnotations(migrated)
+	return true
+}
@@ -0,0 +1,93 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/legacy_migration_metric_test.go
+
+package client_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/testhelpers"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// TestLegacyAnnotationMigration lives outside the client package so it can count migrations with
+// testhelpers.RecordingCounter, as testhelpers imports the client.
+func TestLegacyAnnotationMigration(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	const (
+		legacyTraceID  = "4bf92f3577b34da6a3ce929d0e0e4736"
+		legacySpanID   = "00f067aa0ba902b7"
+		newTraceParent = "00-aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa-bbbbbbbbbbbbbbbb-01"
+	)
+	storedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	legacy := map[string]string{
+		constants.LegacyTraceIDAnnotation:     legacyTraceID,
+		constants.LegacySpanIDAnnotation:      legacySpanID,
+		constants.LegacyTraceIDTimeAnnotation: storedAt.Format(time.RFC3339),
+	}
+	traceState, err := tracecontext.SetTraceStateKey("", constants.TraceStateTimestampKey, storedAt.Format(time.RFC3339Nano))
+	require.NoError(t, err)
+	current := map[string]string{
+		constants.DefaultTraceParentAnnotation: newTraceParent,
+		constants.DefaultTraceStateAnnotation:  traceState,
+	}
+	merge := func(maps ...map[string]string) map[string]string {
+		merged := map[string]string{}
+		for _, m := range maps {
+			for k, v := range m {
+				merged[k] = v
+			}
+		}
+		return merged
+	}
+
+	tests := []struct {
+		name                string
+		annotations         map[string]string
+		expectedTraceParent string
+		expectedMigrations  int64
+	}{
+		{"legacy only", legacy, "00-" + legacyTraceID + "-" + legacySpanID + "-01", 1},
+		{"legacy and traceparent", merge(legacy, current), newTraceParent, 1},
+		{"traceparent only", current, newTraceParent, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default", Annotations: tt.annotations}}
+			k8sClient := fake.NewClientBuilder().WithObjects(cm.DeepCopy()).Build()
+			counter := &testhelpers.RecordingCounter{}
+			tracingClient := tracingclient.NewTracingClientWithOptions(k8sClient, k8sClient, sdktrace.NewTracerProvider().Tracer("operatortrace"), logr.Discard(), nil,
+				tracingclient.WithLegacyAnnotationMigration(), tracingclient.WithLegacyMigrationCounter(counter), tracingclient.WithSkipTraceAnnotations(true))
+
+			cm.Data = map[string]string{"k": "v"}
+			require.NoError(t, tracingClient.Update(context.Background(), cm))
+
+			stored := &corev1.ConfigMap{}
+			require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(cm), stored))
+			assert.Equal(t, "v", stored.Data["k"])
+			annotations := stored.GetAnnotations()
+			assert.Equal(t, tt.expectedTraceParent, annotations[constants.DefaultTraceParentAnnotation])
+			assert.Equal(t, traceState, annotations[constants.DefaultTraceStateAnnotation], "the timestamp must be preserved")
+			for key := range legacy {
+				assert.NotContains(t, annotations, key)
+			}
+			assert.Equal(t, tt.expectedMigrations, counter.Total())
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/legacy_migration_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestLegacyAnnotationMigrationDisabled(t *testing.T) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default", Annotations: map[string]string{
+		constants.LegacyTraceIDAnnotation: "4bf92f3577b34da6a3ce929d0e0e4736",
+		constants.LegacySpanIDAnnotation:  "00f067aa0ba902b7",
+	}}}
+	k8sClient := fake.NewClientBuilder().WithObjects(cm.DeepCopy()).Build()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, initTracer(), logr.Discard())
+
+	require.NoError(t, tracingClient.Update(context.Background(), cm))
+
+	stored := &corev1.ConfigMap{}
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(cm), stored))
+	assert.Contains(t, stored.GetAnnotations(), constants.LegacyTraceIDAnnotation)
+	assert.NotContains(t, stored.GetAnnotations(), constants.DefaultTraceParentAnnotation)
+}
+
+func TestEndTraceClearsLegacyAndTraceParentAnnotations(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default", Annotations: map[string]string{
+		constants.LegacyTraceIDAnnotation:      "4bf92f3577b34da6a3ce929d0e0e4736",
+		constants.LegacySpanIDAnnotation:       "00f067aa0ba902b7",
+		constants.DefaultTraceParentAnnotation: "00-aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa-bbbbbbbbbbbbbbbb-01",
+	}}}
+	k8sClient := fake.NewClientBuilder().WithObjects(pod.DeepCopy()).WithStatusSubresource(&corev1.Pod{}).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil, WithLegacyAnnotationMigration())
+
+	require.NoError(t, tracingClient.EndTrace(context.Background(), pod))
+
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(pod), stored))
+	for _, key := range []string{constants.LegacyTraceIDAnnotation, constants.LegacySpanIDAnnotation, constants.DefaultTraceParentAnnotation} {
+		assert.NotContains(t, stored.GetAnnotations(), key)
+	}
+}
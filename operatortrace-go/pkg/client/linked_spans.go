@@ -0,0 +1,44 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/linked_spans.go
+
+package client
+
+import (
+	"context"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestFromContext returns the request StartTrace is reconciling in ctx, and false when ctx carries none.
+func RequestFromContext(ctx context.Context) (types.RequestWithTraceID, bool) {
+	request, ok := ctx.Value(requestKey{}).(types.RequestWithTraceID)
+	return request, ok
+}
+
+// LinkedSpanContextsFromContext returns the span contexts of the spans linked to the request StartTrace is
+// reconciling in ctx, e.g. the other objects whose changes were merged into it. Invalid IDs are skipped.
+func LinkedSpanContextsFromContext(ctx context.Context) []trace.SpanContext {
+	links := linksFromContext(ctx)
+	if len(links) == 0 {
+		return nil
+	}
+	spanContexts := make([]trace.SpanContext, 0, len(links))
+	for _, link := range links {
+		spanContexts = append(spanContexts, link.SpanContext)
+	}
+	return spanContexts
+}
+
+// StartLinkedSpan starts a span with tracer linked to every span in LinkedSpanContextsFromContext(ctx), for
+// reconciler spans covering all the triggers of the request, such as rendering the manifests of changed inputs:
+//
+//	ctx, span := client.StartLinkedSpan(ctx, tracer, "RenderManifests")
+//	defer span.End()
+func StartLinkedSpan(ctx context.Context, tracer trace.Tracer, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if links := linksFromContext(ctx); len(links) > 0 {
+		opts = append([]trace.SpanStartOption{trace.WithLinks(links...)}, opts...)
+	}
+	return tracer.Start(ctx, name, opts...)
+}
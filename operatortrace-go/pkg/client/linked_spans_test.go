@@ -0,0 +1,171 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/linked_spans_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStartLinkedSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+	}).Build()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard())
+
+	triggers := []types.LinkedSpan{
+		{TraceID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", SpanID: "bbbbbbbbbbbbbbbb"},
+		{TraceID: "cccccccccccccccccccccccccccccccc", SpanID: "dddddddddddddddd"},
+		{TraceID: "not-hex", SpanID: "dddddddddddddddd"},
+	}
+	request := ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "app", Namespace: "default"})
+	for _, linked := range triggers {
+		request.AppendLinkedSpan(linked)
+	}
+
+	ctx, span, err := tracingClient.StartTrace(context.Background(), &request, &corev1.ConfigMap{})
+	require.NoError(t, err)
+	stored, ok := RequestFromContext(ctx)
+	require.True(t, ok)
+	assert.Equal(t, request.NamespacedName, stored.NamespacedName)
+
+	spanContexts := LinkedSpanContextsFromContext(ctx)
+	require.Len(t, spanContexts, 2)
+	for i, spanContext := range spanContexts {
+		assert.Equal(t, triggers[i].TraceID, spanContext.TraceID().String())
+		assert.Equal(t, triggers[i].SpanID, spanContext.SpanID().String())
+	}
+
+	_, rendered := StartLinkedSpan(ctx, tp.Tracer("reconciler"), "RenderManifests", trace.WithAttributes(ListItemCountAttributeKey.Int(2)))
+	rendered.End()
+	span.End()
+
+	var renderSpan tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		if s.Name == "RenderManifests" {
+			renderSpan = s
+		}
+	}
+	require.Equal(t, "RenderManifests", renderSpan.Name)
+	assert.Equal(t, span.SpanContext().SpanID(), renderSpan.Parent.SpanID())
+	assert.Contains(t, renderSpan.Attributes, ListItemCountAttributeKey.Int(2))
+	require.Len(t, renderSpan.Links, 2)
+	for i, link := range renderSpan.Links {
+		assert.Equal(t, spanContexts[i].TraceID(), link.SpanContext.TraceID())
+		assert.Equal(t, spanContexts[i].SpanID(), link.SpanContext.SpanID())
+	}
+}
+
+func TestStartLinkedSpanWithoutRequest(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	_, ok := RequestFromContext(context.Background())
+	assert.False(t, ok)
+	assert.Nil(t, LinkedSpanContextsFromContext(context.Background()))
+
+	_, span := StartLinkedSpan(context.Background(), tp.Tracer("reconciler"), "RenderManifests")
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Empty(t, spans[0].Links)
+}
+
+func TestLinkedSpanAttributes(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+	}).Build()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard())
+
+	// The parent replaced by a later event becomes a linked span describing its event
+	request := ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "app", Namespace: "default"})
+	request.Parent = types.RequestParent{TraceID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", SpanID: "bbbbbbbbbbbbbbbb", Name: "pod-1", Kind: "Pod", EventKind: "Update"}
+	request.Merge(types.RequestWithTraceID{
+		Request: request.Request,
+		Parent:  types.RequestParent{TraceID: "cccccccccccccccccccccccccccccccc", SpanID: "dddddddddddddddd", Name: "pod-2", Kind: "Pod", EventKind: "Create"},
+	})
+	request.AppendLinkedSpan(types.LinkedSpan{TraceID: "eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", SpanID: "ffffffffffffffff"})
+
+	_, span, err := tracingClient.StartTrace(context.Background(), &request, &corev1.ConfigMap{})
+	require.NoError(t, err)
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Len(t, spans[0].Links, 2)
+	assert.Equal(t, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", spans[0].Links[0].SpanContext.TraceID().String())
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		types.LinkObjectNameAttributeKey.String("pod-1"),
+		types.LinkObjectKindAttributeKey.String("Pod"),
+		types.LinkEventKindAttributeKey.String("Update"),
+	}, spans[0].Links[0].Attributes)
+	assert.Empty(t, spans[0].Links[1].Attributes)
+}
+
+func TestLinkedSpanAttributesRedacted(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+	}).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil,
+		WithRedactedKinds(corev1.SchemeGroupVersion.WithKind("Secret")))
+
+	request := ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "app", Namespace: "default"})
+	request.Parent = types.RequestParent{TraceID: "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", SpanID: "bbbbbbbbbbbbbbbb", Name: "credentials", Kind: "Secret", EventKind: "Update"}
+	request.Merge(types.RequestWithTraceID{
+		Request: request.Request,
+		Parent:  types.RequestParent{TraceID: "cccccccccccccccccccccccccccccccc", SpanID: "dddddddddddddddd", Name: "pod-1", Kind: "Pod", EventKind: "Update"},
+	})
+	request.Merge(types.RequestWithTraceID{
+		Request: request.Request,
+		Parent:  types.RequestParent{TraceID: "eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee", SpanID: "ffffffffffffffff", Name: "pod-2", Kind: "Pod", EventKind: "Create"},
+	})
+
+	ctx, span, err := tracingClient.StartTrace(context.Background(), &request, &corev1.ConfigMap{})
+	require.NoError(t, err)
+	_, linkedSpan := StartLinkedSpan(ctx, tp.Tracer("reconciler"), "RenderManifests")
+	linkedSpan.End()
+	span.End()
+
+	expected := [][]attribute.KeyValue{
+		{
+			types.LinkObjectNameAttributeKey.String(RedactValue("credentials")),
+			types.LinkObjectKindAttributeKey.String("Secret"),
+			types.LinkEventKindAttributeKey.String("Update"),
+		},
+		{
+			types.LinkObjectNameAttributeKey.String("pod-1"),
+			types.LinkObjectKindAttributeKey.String("Pod"),
+			types.LinkEventKindAttributeKey.String("Update"),
+		},
+	}
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	for _, s := range spans {
+		require.Len(t, s.Links, 2, s.Name)
+		for i := range expected {
+			assert.ElementsMatch(t, expected[i], s.Links[i].Attributes, s.Name)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/list_trace.go
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ListItemCountAttributeKey records the number of listed items on the StartTraceForList span.
+const ListItemCountAttributeKey = attribute.Key("operatortrace.list.item_count")
+
+// ListTracedItemCountAttributeKey records on the StartTraceForList span how many of the listed items carry a
+// usable stored trace context.
+const ListTracedItemCountAttributeKey = attribute.Key("operatortrace.list.traced_item_count")
+
+// StartTraceForList lists objects into list with the reader and starts a consumer span for reconciling them as a
+// batch, recording the number of items. Start a child span per item with ItemSpan.
+// IMPORTANT: Caller MUST call `defer span.End()` to end the trace from the calling function
+func (tc *tracingClient) StartTraceForList(ctx context.Context, list client.ObjectList, opts ...client.ListOption) (context.Context, trace.Span, error) {
+	if tc.noop(ctx) {
+		return ctx, trace.SpanFromContext(ctx), tc.Reader.List(ctx, list, opts...)
+	}
+
+	gvk, _ := tc.gvks.gvkForObject(list, tc.scheme)
+	spanOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindConsumer)}
+	if len(tc.readerAttributes) > 0 {
+		spanOpts = append(spanOpts, trace.WithAttributes(tc.readerAttributes...))
+	}
+	ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, nil, tc.scheme, tc.options, fmt.Sprintf("StartTraceForList %s", gvk.Kind), linkedSpansFromContext(ctx), spanOpts...)
+
+	if err := tc.Reader.List(ctx, list, opts...); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return ctx, span, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		span.RecordError(err)
+		return ctx, span, fmt.Errorf("problem extracting items from %s: %w", gvk.Kind, err)
+	}
+
+	callOpts := tc.options.withCallOptions(ctx)
+	traced := 0
+	for _, item := range items {
+		if obj, ok := item.(client.Object); ok && lookupStoredTraceContext(obj, tc.scheme, callOpts).rootReason == "" {
+			traced++
+		}
+	}
+	span.SetAttributes(ListItemCountAttributeKey.Int(len(items)), ListTracedItemCountAttributeKey.Int(traced))
+	return ctx, span, nil
+}
+
+// ItemSpan starts an internal child span of the span in ctx for obj, typically one of the items listed by
+// StartTraceForList, linked to the trace stored on obj when it carries a usable one.
+// IMPORTANT: Caller MUST call `defer span.End()` to end the span from the calling function
+func (tc *tracingClient) ItemSpan(ctx context.Context, obj client.Object) (context.Context, trace.Span) {
+	if tc.noop(ctx) {
+		return ctx, trace.SpanFromContext(ctx)
+	}
+
+	itemGVK := obj.GetObjectKind().GroupVersionKind()
+	if schemeGVK, err := tc.gvks.gvkForObject(obj, tc.scheme); err == nil {
+		itemGVK = schemeGVK
+	}
+	spanOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindInternal)}
+	if lookup := lookupStoredTraceContext(obj, tc.scheme, tc.options.withCallOptions(ctx)); lookup.rootReason == "" {
+		spanOpts = append(spanOpts, trace.WithLinks(trace.Link{SpanContext: lookup.spanContext}))
+	}
+	return startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, tc.options.withCallOptions(ctx).spanName("Item", itemGVK.Kind, obj, itemGVK), [10]tracingtypes.LinkedSpan{}, spanOpts...)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/list_trace_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStartTraceForList(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	const storedTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "annotated", Namespace: "default", Annotations: map[string]string{
+			constants.DefaultTraceParentAnnotation: "00-" + storedTraceID + "-00f067aa0ba902b7-01",
+		}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "unannotated", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other-namespace", Namespace: "other"}},
+	).Build()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard())
+
+	pods := &corev1.PodList{}
+	ctx, span, err := tracingClient.StartTraceForList(context.Background(), pods, client.InNamespace("default"))
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 2)
+	for i := range pods.Items {
+		_, itemSpan := tracingClient.ItemSpan(ctx, &pods.Items[i])
+		itemSpan.End()
+	}
+	span.End()
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, s := range exporter.GetSpans() {
+		spans[s.Name] = s
+	}
+	batch, ok := spans["StartTraceForList PodList"]
+	require.True(t, ok, "expected a batch span")
+	assert.Equal(t, trace.SpanKindConsumer, batch.SpanKind)
+	assert.Contains(t, batch.Attributes, ListItemCountAttributeKey.Int(2))
+	assert.Contains(t, batch.Attributes, ListTracedItemCountAttributeKey.Int(1))
+	assert.NotEqual(t, storedTraceID, batch.SpanContext.TraceID().String(), "items must not become the parent of the batch")
+
+	annotated := spans["Item Pod annotated"]
+	unannotated := spans["Item Pod unannotated"]
+	for _, item := range []tracetest.SpanStub{annotated, unannotated} {
+		assert.Equal(t, batch.SpanContext.SpanID(), item.Parent.SpanID())
+		assert.Equal(t, batch.SpanContext.TraceID(), item.SpanContext.TraceID())
+		assert.Equal(t, trace.SpanKindInternal, item.SpanKind)
+	}
+	require.Len(t, annotated.Links, 1)
+	assert.Equal(t, storedTraceID, annotated.Links[0].SpanContext.TraceID().String())
+	assert.Empty(t, unannotated.Links)
+}
+
+func TestStartTraceForListError(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().Build()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard())
+
+	_, span, err := tracingClient.StartTraceForList(context.Background(), &corev1.PodList{}, client.MatchingFields{"spec.nodeName": "node"})
+	require.Error(t, err)
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Len(t, spans[0].Events, 1)
+	assert.NotContains(t, spans[0].Attributes, ListItemCountAttributeKey.Int(0))
+}
+
+func TestStartTraceForListNoop(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, WithNoop())
+
+	pods := &corev1.PodList{}
+	ctx, span, err := tracingClient.StartTraceForList(context.Background(), pods)
+	require.NoError(t, err)
+	require.Len(t, pods.Items, 1)
+	_, itemSpan := tracingClient.ItemSpan(ctx, &pods.Items[0])
+	itemSpan.End()
+	span.End()
+
+	assert.False(t, span.SpanContext().IsValid())
+	assert.Empty(t, exporter.GetSpans())
+}
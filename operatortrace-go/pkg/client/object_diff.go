@@ -0,0 +1,28 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/object_diff.go
+
+package client
+
+import (
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnnotationChange is a key whose value differs between two annotation or label maps, see AnnotationDiff.
+type AnnotationChange = predicates.AnnotationChange
+
+// ObjectChangeSummary describes what changed between two versions of an object, see ObjectDiff.
+type ObjectChangeSummary = predicates.ObjectChangeSummary
+
+// AnnotationDiff returns the keys added, removed or changed between oldAnnotations and newAnnotations, sorted by
+// key, leaving out ignoreKeys. OldValue is "" for added keys and NewValue is "" for removed keys.
+func AnnotationDiff(oldAnnotations, newAnnotations map[string]string, ignoreKeys ...string) []AnnotationChange {
+	return predicates.AnnotationDiff(oldAnnotations, newAnnotations, ignoreKeys...)
+}
+
+// ObjectDiff summarizes what changed between oldObj and newObj, explaining why HasSignificantUpdate considers an
+// update significant. Trace annotations, ignoreAnnotations and trace conditions are ignored.
+func ObjectDiff(oldObj, newObj client.Object, ignoreAnnotations ...string) ObjectChangeSummary {
+	return predicates.ObjectDiff(oldObj, newObj, ignoreAnnotations...)
+}
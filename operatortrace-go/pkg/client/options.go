@@ -5,10 +5,20 @@
 package client
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"go.opentelemetry.io/otel/metric"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // TraceParentRelationship controls how an incoming traceparent should be attached to new spans.
@@ -19,20 +29,187 @@ const (
 	TraceParentRelationshipParent TraceParentRelationship = "parent"
 )
 
+// TraceExpirationPolicy controls what StartTrace does with stored trace context older than the trace expiration.
+type TraceExpirationPolicy string
+
+const (
+	// ExpirationPolicyDiscard starts a new trace with no reference to the expired one. It is the default.
+	ExpirationPolicyDiscard TraceExpirationPolicy = "discard"
+	// ExpirationPolicyLink starts a new trace that links to the expired span.
+	ExpirationPolicyLink TraceExpirationPolicy = "link"
+	// ExpirationPolicyExtend keeps continuing the expired trace. The next annotation write stamps it with the
+	// current time, so the trace stays alive for as long as the object keeps being written.
+	ExpirationPolicyExtend TraceExpirationPolicy = "extend"
+)
+
+// StartTraceReaderStrategy selects where StartTrace reads the reconciled object from.
+type StartTraceReaderStrategy string
+
+const (
+	// ReaderStrategyAPIOnly reads through the client's reader, usually the manager's API reader.
+	ReaderStrategyAPIOnly StartTraceReaderStrategy = "api"
+	// ReaderStrategyCacheOnly reads through the client used for writes, usually backed by the informer cache.
+	ReaderStrategyCacheOnly StartTraceReaderStrategy = "cache"
+	// ReaderStrategyCacheThenAPI reads through the client used for writes and falls back to the reader when the
+	// object is not found there, e.g. because the cache has not seen it yet.
+	ReaderStrategyCacheThenAPI StartTraceReaderStrategy = "cache_then_api"
+)
+
 // Options holds configuration for tracing clients and helpers.
 type Options struct {
 	AnnotationPrefix string
 	TraceExpiration  time.Duration
 
+	// TraceExpirationPolicy decides how expired stored trace context is used. Empty means ExpirationPolicyDiscard.
+	TraceExpirationPolicy TraceExpirationPolicy
+
+	// ExpirationWarningThreshold and ExpirationErrorThreshold are the fractions of TraceExpiration from which writing
+	// the continued trace is logged, or skipped. Zero uses the defaults. See WithExpirationThresholds.
+	ExpirationWarningThreshold float64
+	ExpirationErrorThreshold   float64
+
 	TraceStateTimestampKey string
 
 	EmittedTraceParentAnnotationSuffix string
 	EmittedTraceStateAnnotationSuffix  string
 
+	// TraceParentKey and TraceStateKey, when set, are the full annotation keys operatortrace emits and reads,
+	// taking precedence over AnnotationPrefix and the emitted suffixes.
+	TraceParentKey string
+	TraceStateKey  string
+
 	IncomingTraceParentAnnotation string
 	IncomingTraceStateAnnotation  string
 
 	IncomingTraceRelationship TraceParentRelationship
+
+	// RelationshipPerKind overrides, per source kind, whether a reconcile continues or only links the trace that
+	// triggered it. Kinds that are not listed keep the default behaviour.
+	RelationshipPerKind map[schema.GroupKind]TraceParentRelationship
+
+	// SkipTraceAnnotations disables persisting trace context annotations on written objects.
+	SkipTraceAnnotations bool
+
+	// LegacyAnnotationMigration removes the legacy trace annotations from written objects, rewriting their trace
+	// context as traceparent when the object carries no other.
+	LegacyAnnotationMigration bool
+
+	// LegacyMigrationCounter, when set, counts the objects whose legacy trace annotations were migrated.
+	LegacyMigrationCounter metric.Int64Counter
+
+	// ExportHealth, when set and unhealthy, also disables persisting trace context annotations.
+	ExportHealth ExportHealth
+
+	// AsyncTracePersistenceQueueSize, when positive, persists trace annotations from a background worker with a
+	// queue of this size instead of on the written object. See WithAsyncTracePersistence.
+	AsyncTracePersistenceQueueSize int
+
+	// FieldManager, when set, is used as the field owner for trace annotation writes.
+	FieldManager string
+
+	// StartTraceReaderStrategy selects where StartTrace reads the object from. Empty reads through the client's
+	// reader without recording the source on the span.
+	StartTraceReaderStrategy StartTraceReaderStrategy
+
+	// AnnotationFieldOwnerCheck, when set, is the field manager of this operator. Trace annotations are not written
+	// on objects whose traceparent annotation is owned by another field manager.
+	AnnotationFieldOwnerCheck string
+
+	// BinaryAnnotationEncoding stores traceparent annotations in the compact base64-URL binary format.
+	BinaryAnnotationEncoding bool
+
+	// ValidateReader checks at construction whether the reader is the cached client and warns if it is.
+	ValidateReader bool
+
+	// ResyncTrace marks StartTrace spans of reconciles triggered by an informer resync.
+	ResyncTrace bool
+
+	// MaxRetriesOn429 is how many times Create, Update, Patch and Delete are retried when the API server asks
+	// the client to delay, for example with a 429 and Retry-After. Zero disables retries.
+	MaxRetriesOn429 int
+
+	// StrictUpdateConflicts makes Update send updates of an object whose resource version is stale as they are, so
+	// the API server returns the conflict, instead of falling back to a merge patch.
+	StrictUpdateConflicts bool
+
+	// EndTraceRetries is how many times EndTrace re-reads the object and retries clearing its trace annotations
+	// after a resource version conflict. Zero disables retries and the optimistic lock they rely on.
+	EndTraceRetries int
+
+	// OwnerTraceFallbackReader, when set, is used by StartTrace to read the controller owner of an object that
+	// carries no trace context, so the reconcile continues the owner's trace instead of starting a new one.
+	OwnerTraceFallbackReader client.Reader
+	// OwnerChainDepth limits how many owners BuildOwnershipChainContext reads. See WithOwnerChainDepth.
+	OwnerChainDepth int
+
+	// APIReader, when set, is used by GetFresh instead of the client's reader. It must bypass the informer cache.
+	APIReader client.Reader
+
+	// ParentSelectionPolicy, when set, picks the parent of StartTrace spans between the trace stored on the object
+	// and an inherited request parent. Nil continues the request parent.
+	ParentSelectionPolicy ParentSelectionPolicy
+
+	// SpanNameRegistry, when set, names the spans of client operations it has a formatter for.
+	SpanNameRegistry *SpanNameRegistry
+
+	// RESTMapper, when set, is returned by the client's RESTMapper instead of the wrapped client's mapper.
+	RESTMapper meta.RESTMapper
+
+	// CreateHooks run, in order, on every object before the client creates it.
+	CreateHooks []CreateHook
+
+	// AnnotationCodec, when set, replaces how trace context is written to and read from annotations.
+	// The default nil codec uses the plaintext traceparent/tracestate annotations configured by the other options.
+	AnnotationCodec AnnotationCodec
+
+	// Noop disables all span creation, annotation writes and condition updates; calls go straight to the wrapped client.
+	Noop bool
+
+	// MaxSpansPerReconcile caps the client-operation spans created within a single StartTrace context. Zero disables the cap.
+	MaxSpansPerReconcile int
+
+	// SuppressSpans makes every span non-recording. Stored trace context is still continued and propagated.
+	SuppressSpans bool
+
+	// DataFieldPropagations lists kinds whose data should carry a copy of the traceparent.
+	DataFieldPropagations []DataFieldPropagation
+
+	// PodTemplatePropagation stores the trace context in the pod template annotations of workloads on Create and
+	// Update, so the Pods they create carry it. See WithPodTemplatePropagation.
+	PodTemplatePropagation bool
+	// PodTemplatePaths registers the pod template of kinds other than the built-in workloads.
+	PodTemplatePaths []PodTemplatePath
+	// TraceEnvPropagation sets the TRACEPARENT and TRACESTATE environment variables of the containers of Pods and
+	// Jobs on Create. See WithTraceEnvPropagation.
+	TraceEnvPropagation bool
+
+	// Redaction hides object names and namespaces from span names, span errors and log lines.
+	Redaction Redaction
+
+	// TraceChainSampling enables the trace chain sampler, which keeps TraceChainSampleFraction of the trace chains
+	// and makes every hop of a chain follow the decision taken when the chain started. See WithTraceChainSampler.
+	TraceChainSampling       bool
+	TraceChainSampleFraction float64
+
+	// TraceSummarySpans records the hops, start and kinds of the trace chain in the persisted tracestate and emits a
+	// summary span when EndTrace clears the chain. See WithTraceSummarySpans.
+	TraceSummarySpans bool
+
+	// LabelSamplingRules decide from the labels of objects whether their spans are traced. See WithLabelSampler.
+	LabelSamplingRules []LabelSamplingRule
+
+	// InheritTraceOn lists the RequestParent.ChangedFields entries (e.g. "spec", "data") whose change makes an update's trace the parent
+	// of the reconcile. Updates that only changed other fields are linked instead. Empty inherits on every change.
+	InheritTraceOn []string
+
+	// strict, set while NewOptionsStrict applies the options, collects the values options reject.
+	strict *optionErrors
+}
+
+// DataFieldPropagation mirrors the traceparent into DataKey of objects matching GVK.
+type DataFieldPropagation struct {
+	GVK     schema.GroupVersionKind
+	DataKey string
 }
 
 // Option mutates the Options struct during construction.
@@ -49,6 +226,12 @@ func defaultOptions() Options {
 	}
 }
 
+// appendOption appends elems to s, an Options slice, without writing into the array it shares with other copies.
+func appendOption[T any](s []T, elems ...T) []T {
+	return append(slices.Clip(s), elems...)
+}
+
+// newOptions applies optFns to the default options.
 func newOptions(optFns ...Option) Options {
 	opts := defaultOptions()
 	for _, fn := range optFns {
@@ -60,18 +243,56 @@ func newOptions(optFns ...Option) Options {
 	return opts
 }
 
-// NewOptions returns a fully-evaluated Options struct using the provided Option functions.
+// NewOptions returns a fully-evaluated Options struct using the provided Option functions. Invalid values, such as
+// annotation keys the API server would reject, are ignored. Use NewOptionsStrict to get an error for them instead.
 func NewOptions(optFns ...Option) Options {
 	return newOptions(optFns...)
 }
 
-// WithAnnotationPrefix overrides the default annotation prefix used for trace metadata.
+// WithCallOptions returns a context whose client calls apply optFns on top of the client's options.
+// Repeated calls accumulate, with later options winning.
+func WithCallOptions(ctx context.Context, optFns ...Option) context.Context {
+	if len(optFns) == 0 {
+		return ctx
+	}
+	existing, _ := ctx.Value(callOptionsKey{}).([]Option)
+	combined := make([]Option, 0, len(existing)+len(optFns))
+	combined = append(combined, existing...)
+	combined = append(combined, optFns...)
+	return context.WithValue(ctx, callOptionsKey{}, combined)
+}
+
+type callOptionsKey struct{}
+
+// withCallOptions returns a copy of the options with any per-call overrides from ctx applied.
+func (o Options) withCallOptions(ctx context.Context) Options {
+	if ctx == nil {
+		return o
+	}
+	optFns, _ := ctx.Value(callOptionsKey{}).([]Option)
+	for _, fn := range optFns {
+		if fn == nil {
+			continue
+		}
+		fn(&o)
+	}
+	return o
+}
+
+// WithAnnotationPrefix overrides the default annotation prefix used for trace metadata. A prefix that does not
+// form valid annotation keys is ignored.
 func WithAnnotationPrefix(prefix string) Option {
 	return func(o *Options) {
 		if prefix == "" {
 			return
 		}
-		o.AnnotationPrefix = sanitizePrefix(prefix)
+		prefixed := *o
+		prefixed.AnnotationPrefix = sanitizePrefix(prefix)
+		if err := prefixed.validatePrefixedAnnotationKeys(); err != nil {
+			o.reject("annotation prefix %q: %w", prefix, err)
+			return
+		}
+		o.AnnotationPrefix = prefixed.AnnotationPrefix
 	}
 }
 
@@ -79,12 +300,51 @@ func WithAnnotationPrefix(prefix string) Option {
 func WithTraceExpiration(d time.Duration) Option {
 	return func(o *Options) {
 		if d <= 0 {
+			o.reject("trace expiration %s is not positive", d)
 			return
 		}
 		o.TraceExpiration = d
 	}
 }
 
+// WithTraceExpirationPolicy configures what happens to stored trace context once it has expired.
+func WithTraceExpirationPolicy(p TraceExpirationPolicy) Option {
+	return func(o *Options) {
+		switch p {
+		case ExpirationPolicyDiscard, ExpirationPolicyLink, ExpirationPolicyExtend:
+			o.TraceExpirationPolicy = p
+		default:
+			o.reject("unknown trace expiration policy %q", p)
+		}
+	}
+}
+
+// WithStartTraceReaderStrategy selects whether StartTrace reads the reconciled object from the cache, the API server
+// or both, and records the source on the StartTrace span.
+func WithStartTraceReaderStrategy(strategy StartTraceReaderStrategy) Option {
+	return func(o *Options) {
+		switch strategy {
+		case ReaderStrategyAPIOnly, ReaderStrategyCacheOnly, ReaderStrategyCacheThenAPI:
+			o.StartTraceReaderStrategy = strategy
+		default:
+			o.reject("unknown StartTrace reader strategy %q", strategy)
+		}
+	}
+}
+
+// WithTraceChainSampler keeps new trace chains with probability fraction, recorded in the tracestate so every later
+// hop agrees. Values outside [0, 1] are ignored; the TracerProvider should sample based on the parent.
+func WithTraceChainSampler(fraction float64) Option {
+	return func(o *Options) {
+		if !(fraction >= 0 && fraction <= 1) {
+			o.reject("trace chain sample fraction %v is not within [0, 1]", fraction)
+			return
+		}
+		o.TraceChainSampling = true
+		o.TraceChainSampleFraction = fraction
+	}
+}
+
 // WithTraceStateTimestampKey customizes the key recorded inside tracestate for timestamp bookkeeping.
 func WithTraceStateTimestampKey(key string) Option {
 	return func(o *Options) {
@@ -119,40 +379,396 @@ func WithIncomingTraceStateAnnotation(key string) Option {
 func WithIncomingTraceRelationship(rel TraceParentRelationship) Option {
 	return func(o *Options) {
 		if rel != TraceParentRelationshipLink && rel != TraceParentRelationshipParent {
+			o.reject("unknown incoming trace relationship %q", rel)
 			return
 		}
 		o.IncomingTraceRelationship = rel
 	}
 }
 
-// WithEmittedAnnotationSuffixes customizes the suffixes operatortrace uses when emitting trace annotations.
+// WithOwnerTraceFallback makes StartTrace use the trace of the object's controller owner, read through reader,
+// when the object carries none.
+func WithOwnerTraceFallback(reader client.Reader) Option {
+	return func(o *Options) {
+		if reader == nil {
+			return
+		}
+		o.OwnerTraceFallbackReader = reader
+	}
+}
+
+// WithOwnerChainDepth limits how many owners up from the object BuildOwnershipChainContext reads, 5 by default.
+// Values below 1 are ignored.
+func WithOwnerChainDepth(n int) Option {
+	return func(o *Options) {
+		if n < 1 {
+			return
+		}
+		o.OwnerChainDepth = n
+	}
+}
+
+// WithAPIReader makes GetFresh read through reader, e.g. mgr.GetAPIReader(), for clients whose reader is backed by
+// the informer cache.
+func WithAPIReader(reader client.Reader) Option {
+	return func(o *Options) {
+		if reader == nil {
+			return
+		}
+		o.APIReader = reader
+	}
+}
+
+// WithRESTMapper makes the client return mapper from RESTMapper, e.g. to hand the manager's mapper to
+// EnqueueRequestForOwner through the TracingClient alone.
+func WithRESTMapper(mapper meta.RESTMapper) Option {
+	return func(o *Options) {
+		if mapper == nil {
+			return
+		}
+		o.RESTMapper = mapper
+	}
+}
+
+// WithRelationshipPerKind sets whether StartTrace parents or links the trace of each source kind.
+// Repeated calls merge, and link wins when entries of different groups match.
+func WithRelationshipPerKind(relationships map[schema.GroupKind]TraceParentRelationship) Option {
+	return func(o *Options) {
+		if len(relationships) == 0 {
+			return
+		}
+		// Copy so the caller's map, and maps shared with other Options copies, are never written.
+		merged := make(map[schema.GroupKind]TraceParentRelationship, len(o.RelationshipPerKind)+len(relationships))
+		for kind, rel := range o.RelationshipPerKind {
+			merged[kind] = rel
+		}
+		for kind, rel := range relationships {
+			if rel != TraceParentRelationshipLink && rel != TraceParentRelationshipParent {
+				o.reject("unknown trace relationship %q for %s", rel, kind)
+				continue
+			}
+			merged[kind] = rel
+		}
+		o.RelationshipPerKind = merged
+	}
+}
+
+// WithEmittedAnnotationSuffixes customizes the suffixes operatortrace uses when emitting trace annotations. A suffix
+// that does not form a valid annotation key is ignored.
 func WithEmittedAnnotationSuffixes(traceParentSuffix, traceStateSuffix string) Option {
 	return func(o *Options) {
 		if traceParentSuffix != "" {
-			o.EmittedTraceParentAnnotationSuffix = sanitizeSuffix(traceParentSuffix)
+			suffix := sanitizeSuffix(traceParentSuffix)
+			if err := validateAnnotationKey(buildAnnotationKey(o.annotationPrefix(), "", suffix)); err != nil {
+				o.reject("traceparent annotation suffix %q: %w", traceParentSuffix, err)
+			} else {
+				o.EmittedTraceParentAnnotationSuffix = suffix
+			}
 		}
 		if traceStateSuffix != "" {
-			o.EmittedTraceStateAnnotationSuffix = sanitizeSuffix(traceStateSuffix)
+			suffix := sanitizeSuffix(traceStateSuffix)
+			if err := validateAnnotationKey(buildAnnotationKey(o.annotationPrefix(), "", suffix)); err != nil {
+				o.reject("tracestate annotation suffix %q: %w", traceStateSuffix, err)
+			} else {
+				o.EmittedTraceStateAnnotationSuffix = suffix
+			}
+		}
+	}
+}
+
+// WithTraceParentKey sets the full annotation key used to emit and read traceparent values. A key that is not a
+// valid annotation name is ignored.
+func WithTraceParentKey(key string) Option {
+	return func(o *Options) {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return
+		}
+		if err := validateAnnotationKey(key); err != nil {
+			o.reject("traceparent key: %w", err)
+			return
+		}
+		o.TraceParentKey = key
+	}
+}
+
+// WithTraceStateKey sets the full annotation key used to emit and read tracestate values. A key that is not a
+// valid annotation name is ignored.
+func WithTraceStateKey(key string) Option {
+	return func(o *Options) {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return
+		}
+		if err := validateAnnotationKey(key); err != nil {
+			o.reject("tracestate key: %w", err)
+			return
 		}
+		o.TraceStateKey = key
+	}
+}
+
+// WithSkipTraceAnnotations controls whether trace context annotations are persisted on written objects.
+func WithSkipTraceAnnotations(skip bool) Option {
+	return func(o *Options) {
+		o.SkipTraceAnnotations = skip
+	}
+}
+
+// WithFieldManager sets the field owner of the patches the tracing client sends, unless the caller passes its own.
+func WithFieldManager(fieldManager string) Option {
+	return func(o *Options) {
+		if fieldManager == "" {
+			return
+		}
+		o.FieldManager = fieldManager
+	}
+}
+
+// WithAnnotationFieldOwnerCheck skips, and logs, the trace annotation write when a field manager other than
+// fieldManager owns the traceparent annotation.
+func WithAnnotationFieldOwnerCheck(fieldManager string) Option {
+	return func(o *Options) {
+		fieldManager = strings.TrimSpace(fieldManager)
+		if fieldManager == "" {
+			return
+		}
+		o.AnnotationFieldOwnerCheck = fieldManager
+	}
+}
+
+// WithBinaryAnnotationEncoding stores the traceparent annotation as a base64-URL encoded 25-byte binary
+// span context instead of the 55-character W3C string. Both formats are understood when reading annotations.
+func WithBinaryAnnotationEncoding() Option {
+	return func(o *Options) {
+		o.BinaryAnnotationEncoding = true
+	}
+}
+
+// WithReaderValidation warns, in the log and with an operatortrace.reader=cache attribute on StartTrace spans,
+// when the client is constructed with a reader that serves objects from the informer cache.
+func WithReaderValidation() Option {
+	return func(o *Options) {
+		o.ValidateReader = true
 	}
 }
 
-func (o Options) emittedTraceParentAnnotationKey() string {
+// WithResyncTrace sets the resync attribute on StartTrace spans of requests enqueued by a resync.
+func WithResyncTrace() Option {
+	return func(o *Options) {
+		o.ResyncTrace = true
+	}
+}
+
+// WithRetryOn429 retries Create, Update, Patch and Delete up to maxRetries times under the same producer span
+// when the API server responds with a Retry-After delay, waiting the requested time between attempts.
+func WithRetryOn429(maxRetries int) Option {
+	return func(o *Options) {
+		if maxRetries <= 0 {
+			return
+		}
+		o.MaxRetriesOn429 = maxRetries
+	}
+}
+
+// WithStrictUpdateConflicts disables Update's fallback to a merge patch when the object's resource version is no
+// longer the stored one, so the caller gets the conflict and can retry on the latest version.
+func WithStrictUpdateConflicts() Option {
+	return func(o *Options) {
+		o.StrictUpdateConflicts = true
+	}
+}
+
+// WithEndTraceRetry makes EndTrace clear the trace annotations with an optimistic lock and retry conflicts up to
+// maxRetries times, unless the stored trace has changed.
+func WithEndTraceRetry(maxRetries int) Option {
+	return func(o *Options) {
+		if maxRetries <= 0 {
+			return
+		}
+		o.EndTraceRetries = maxRetries
+	}
+}
+
+// CreateHook is called with the object about to be created and may modify it. An error aborts the create.
+type CreateHook func(ctx context.Context, obj client.Object) error
+
+// WithCreateHook runs hook on every object before the client creates it. Used as a call option it only applies
+// to creates made with that context, which is how per-reconcile behaviour such as owner references is injected.
+func WithCreateHook(hook CreateHook) Option {
+	return func(o *Options) {
+		if hook == nil {
+			return
+		}
+		o.CreateHooks = appendOption(o.CreateHooks, hook)
+	}
+}
+
+// WithAnnotationCodec stores trace context in annotations through codec, for example an HMACAnnotationCodec
+// that signs it so that tenants able to edit annotations cannot forge trace parents.
+func WithAnnotationCodec(codec AnnotationCodec) Option {
+	return func(o *Options) {
+		if codec == nil {
+			return
+		}
+		o.AnnotationCodec = codec
+	}
+}
+
+// WithNoop turns the tracing client into a pass-through to the wrapped client, removing all tracing overhead.
+// It is intended for profiling an operator's business logic without changing its code.
+func WithNoop() Option {
+	return func(o *Options) {
+		o.Noop = true
+	}
+}
+
+// WithMaxSpansPerReconcile caps the client-operation spans recorded per reconcile; later spans are non-recording.
+func WithMaxSpansPerReconcile(n int) Option {
+	return func(o *Options) {
+		if n <= 0 {
+			return
+		}
+		o.MaxSpansPerReconcile = n
+	}
+}
+
+// WithSuppressedSpans makes StartTrace and client operations non-recording while stored traces keep propagating.
+// It is meant as a call option, e.g. for the reconcile storm of startup.
+func WithSuppressedSpans() Option {
+	return func(o *Options) {
+		o.SuppressSpans = true
+	}
+}
+
+// WithDataFieldPropagation mirrors the traceparent into the named data key of created or updated objects of the given kind.
+// Use this for ConfigMaps/Secrets whose data is copied across clusters without their annotations.
+func WithDataFieldPropagation(gvk schema.GroupVersionKind, dataKey string) Option {
+	return func(o *Options) {
+		if gvk.Empty() || dataKey == "" {
+			return
+		}
+		o.DataFieldPropagations = appendOption(o.DataFieldPropagations, DataFieldPropagation{GVK: gvk, DataKey: dataKey})
+	}
+}
+
+// WithInheritTraceOn only parents the reconcile on update events that changed one of fields; other updates are
+// linked.
+func WithInheritTraceOn(fields ...string) Option {
+	return func(o *Options) {
+		for _, field := range fields {
+			if field = strings.TrimSpace(field); field != "" {
+				o.InheritTraceOn = appendOption(o.InheritTraceOn, field)
+			}
+		}
+	}
+}
+
+// inheritsTrace reports whether the request parent should become the parent of the reconcile span.
+func (o Options) inheritsTrace(parent tracingtypes.RequestParent) bool {
+	if parent.TraceID != "" && parent.SpanID != "" {
+		if rel, ok := o.relationshipForParentKind(parent.Kind); ok && rel == TraceParentRelationshipLink {
+			return false
+		}
+	}
+	if len(o.InheritTraceOn) == 0 || parent.ChangedFields == "" {
+		return true
+	}
+	switch parent.EventKind {
+	case "Create", "Delete", "Generic":
+		return true
+	}
+	for _, field := range o.InheritTraceOn {
+		if parent.HasChangedField(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// relationshipForKind returns the relationship configured for kind with WithRelationshipPerKind.
+func (o Options) relationshipForKind(kind schema.GroupKind) (TraceParentRelationship, bool) {
+	rel, ok := o.RelationshipPerKind[kind]
+	return rel, ok
+}
+
+// relationshipForParentKind returns the relationship configured for a request parent kind, matching any group.
+func (o Options) relationshipForParentKind(kind string) (TraceParentRelationship, bool) {
+	if kind == "" {
+		return "", false
+	}
+	var (
+		result TraceParentRelationship
+		found  bool
+	)
+	for groupKind, rel := range o.RelationshipPerKind {
+		if groupKind.Kind != kind {
+			continue
+		}
+		if !found || rel == TraceParentRelationshipLink {
+			result = rel
+		}
+		found = true
+	}
+	return result, found
+}
+
+// prefixedTraceParentAnnotationKey is the traceparent key derived from the prefix and suffix, ignoring TraceParentKey.
+func (o Options) prefixedTraceParentAnnotationKey() string {
 	return buildAnnotationKey(o.annotationPrefix(), constants.DefaultTraceParentAnnotation, o.EmittedTraceParentAnnotationSuffix)
 }
 
-func (o Options) emittedTraceStateAnnotationKey() string {
+// prefixedTraceStateAnnotationKey is the tracestate key derived from the prefix and suffix, ignoring TraceStateKey.
+func (o Options) prefixedTraceStateAnnotationKey() string {
 	return buildAnnotationKey(o.annotationPrefix(), constants.DefaultTraceStateAnnotation, o.EmittedTraceStateAnnotationSuffix)
 }
 
+// validatePrefixedAnnotationKeys validates the annotation keys derived from the prefix.
+func (o Options) validatePrefixedAnnotationKeys() error {
+	return errors.Join(
+		validateAnnotationKey(o.prefixedTraceParentAnnotationKey()),
+		validateAnnotationKey(o.prefixedTraceStateAnnotationKey()),
+		validateAnnotationKey(o.ownerTraceParentAnnotationKey()),
+	)
+}
+
+func (o Options) ownerTraceParentAnnotationKey() string {
+	return buildAnnotationKey(o.annotationPrefix(), constants.DefaultOwnerTraceParentAnnotation, constants.OwnerTraceParentAnnotationSuffix)
+}
+
 // EmittedTraceParentAnnotationKey returns the annotation key operatortrace will write when persisting traceparent values.
 func (o Options) EmittedTraceParentAnnotationKey() string {
-	return o.emittedTraceParentAnnotationKey()
+	if o.TraceParentKey != "" {
+		return o.TraceParentKey
+	}
+	return o.prefixedTraceParentAnnotationKey()
 }
 
 // EmittedTraceStateAnnotationKey returns the annotation key operatortrace will write when persisting tracestate values.
 func (o Options) EmittedTraceStateAnnotationKey() string {
-	return o.emittedTraceStateAnnotationKey()
+	if o.TraceStateKey != "" {
+		return o.TraceStateKey
+	}
+	return o.prefixedTraceStateAnnotationKey()
+}
+
+// IncomingAnnotationKeys returns the traceparent annotation keys checked for incoming trace context, in the order
+// they are checked: IncomingTraceParentAnnotation when set, the emitted key, the prefixed key objects written before
+// a custom emitted key carry, and the default key. Each key is listed once.
+func (o Options) IncomingAnnotationKeys() []string {
+	candidates := []string{
+		o.IncomingTraceParentAnnotation,
+		o.EmittedTraceParentAnnotationKey(),
+		o.prefixedTraceParentAnnotationKey(),
+		constants.DefaultTraceParentAnnotation,
+	}
+	keys := make([]string, 0, len(candidates))
+	for _, key := range candidates {
+		if key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
 }
 
 func (o Options) legacyTraceIDAnnotationKey() string {
@@ -200,6 +816,18 @@ func buildAnnotationKey(prefix, fallback, suffix string) string {
 	return prefix + "/" + suffix
 }
 
+// ErrInvalidAnnotationKey is returned, wrapped with the key, for annotation keys the API server would reject.
+var ErrInvalidAnnotationKey = errors.New("invalid annotation key")
+
+// validateAnnotationKey checks key against the Kubernetes annotation key rules: an optional DNS subdomain prefix of
+// at most 253 characters, a slash, and a name of at most 63 alphanumeric, '-', '_' or '.' characters.
+func validateAnnotationKey(key string) error {
+	if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+		return fmt.Errorf("%w %q: %s", ErrInvalidAnnotationKey, key, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
 func sanitizePrefix(prefix string) string {
 	return strings.TrimSuffix(prefix, "/")
 }
@@ -0,0 +1,113 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/options_validation.go
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrInvalidOption is returned, wrapped with the reason, by NewOptionsStrict for option values the lenient
+// constructors would silently ignore.
+var ErrInvalidOption = errors.New("invalid option")
+
+const (
+	// minStrictTraceExpiration and maxStrictTraceExpiration bound the trace expiration NewOptionsStrict accepts.
+	minStrictTraceExpiration = time.Second
+	maxStrictTraceExpiration = 7 * 24 * time.Hour
+)
+
+// optionErrors collects the option values rejected while NewOptionsStrict applies the options.
+type optionErrors struct {
+	errs []error
+}
+
+// reject records err when the options are built by NewOptionsStrict. The lenient constructors ignore it.
+func (o *Options) reject(format string, args ...any) {
+	if o.strict == nil {
+		return
+	}
+	o.strict.errs = append(o.strict.errs, fmt.Errorf("%w: "+format, append([]any{ErrInvalidOption}, args...)...))
+}
+
+// NewOptionsStrict is NewOptions returning an error, instead of ignoring the value, when:
+//   - the annotation prefix or an emitted suffix does not form valid annotation keys,
+//   - the trace expiration is not between a second and a week,
+//   - a trace relationship, expiration policy or reader strategy is not a known value,
+//   - the trace chain sample fraction is not within [0, 1],
+//   - an annotation key is not a valid annotation name,
+//   - a traceparent key, emitted or incoming, is also used as a tracestate key.
+//
+// Every problem found is reported, joined in the error.
+func NewOptionsStrict(optFns ...Option) (Options, error) {
+	opts := defaultOptions()
+	rejected := &optionErrors{}
+	opts.strict = rejected
+	for _, fn := range optFns {
+		if fn == nil {
+			continue
+		}
+		fn(&opts)
+	}
+	// Call options applied later must not record into the collector
+	opts.strict = nil
+
+	errs := append(rejected.errs, opts.validate()...)
+	if len(errs) > 0 {
+		return Options{}, errors.Join(errs...)
+	}
+	return opts, nil
+}
+
+// validate checks the options as a whole, once every option is applied.
+func (o Options) validate() []error {
+	var errs []error
+	if o.TraceExpiration < minStrictTraceExpiration || o.TraceExpiration > maxStrictTraceExpiration {
+		errs = append(errs, fmt.Errorf("%w: trace expiration %s is not between %s and %s", ErrInvalidOption, o.TraceExpiration, minStrictTraceExpiration, maxStrictTraceExpiration))
+	}
+
+	traceParentKeys := []string{o.EmittedTraceParentAnnotationKey()}
+	traceStateKeys := []string{o.EmittedTraceStateAnnotationKey()}
+	if o.IncomingTraceParentAnnotation != "" {
+		traceParentKeys = append(traceParentKeys, o.IncomingTraceParentAnnotation)
+	}
+	if o.IncomingTraceStateAnnotation != "" {
+		traceStateKeys = append(traceStateKeys, o.IncomingTraceStateAnnotation)
+	}
+	for _, key := range append(append([]string{}, traceParentKeys...), traceStateKeys...) {
+		if err := validateAnnotationKey(key); err != nil {
+			errs = append(errs, fmt.Errorf("%w: %w", ErrInvalidOption, err))
+		}
+	}
+	for _, parentKey := range traceParentKeys {
+		for _, stateKey := range traceStateKeys {
+			if parentKey == stateKey {
+				errs = append(errs, fmt.Errorf("%w: annotation key %q is used for both traceparent and tracestate", ErrInvalidOption, parentKey))
+			}
+		}
+	}
+	return errs
+}
+
+// NewTracingClientWithOptionsE is NewTracingClientWithOptions validating the options with NewOptionsStrict, and
+// returning its error instead of a client built from partly ignored options.
+func NewTracingClientWithOptionsE(c client.Client, r client.Reader, t trace.Tracer, l logr.Logger, scheme *runtime.Scheme, optFns ...Option) (TracingClient, error) {
+	opts, err := NewOptionsStrict(optFns...)
+	if err != nil {
+		return nil, err
+	}
+	tracingScheme := scheme
+	if tracingScheme == nil {
+		tracingScheme = clientgoscheme.Scheme
+	}
+	return newTracingClientWithOptions(c, r, t, l, tracingScheme, opts), nil
+}
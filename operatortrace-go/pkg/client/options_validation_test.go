@@ -0,0 +1,86 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/options_validation_test.go
+
+package client
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestNewOptionsStrict(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		contains string
+	}{
+		{name: "invalid annotation prefix", opts: []Option{WithAnnotationPrefix("Not_A.DNS/Prefix")}, contains: "annotation prefix"},
+		{name: "invalid traceparent suffix", opts: []Option{WithEmittedAnnotationSuffixes("trace parent", "")}, contains: "traceparent annotation suffix"},
+		{name: "invalid tracestate suffix", opts: []Option{WithEmittedAnnotationSuffixes("", "-state-")}, contains: "tracestate annotation suffix"},
+		{name: "negative expiration", opts: []Option{WithTraceExpiration(-time.Minute)}, contains: "not positive"},
+		{name: "expiration too short", opts: []Option{WithTraceExpiration(time.Millisecond)}, contains: "trace expiration 1ms"},
+		{name: "expiration too long", opts: []Option{WithTraceExpiration(30 * 24 * time.Hour)}, contains: "trace expiration 720h0m0s"},
+		{name: "unknown incoming relationship", opts: []Option{WithIncomingTraceRelationship("parentt")}, contains: `"parentt"`},
+		{name: "unknown relationship per kind", opts: []Option{WithRelationshipPerKind(map[schema.GroupKind]TraceParentRelationship{{Kind: "ConfigMap"}: "lnk"})}, contains: `"lnk" for ConfigMap`},
+		{name: "unknown expiration policy", opts: []Option{WithTraceExpirationPolicy("keep")}, contains: "expiration policy"},
+		{name: "unknown reader strategy", opts: []Option{WithStartTraceReaderStrategy("informer")}, contains: "reader strategy"},
+		{name: "chain sample fraction above one", opts: []Option{WithTraceChainSampler(1.5)}, contains: "sample fraction 1.5"},
+		{name: "chain sample fraction NaN", opts: []Option{WithTraceChainSampler(math.NaN())}, contains: "sample fraction NaN"},
+		{name: "invalid traceparent key", opts: []Option{WithTraceParentKey("example.com/trace/parent")}, contains: "example.com/trace/parent"},
+		{name: "invalid incoming key", opts: []Option{WithIncomingTraceStateAnnotation("bad key")}, contains: "bad key"},
+		{name: "emitted keys collide", opts: []Option{WithTraceParentKey("example.com/trace"), WithTraceStateKey("example.com/trace")}, contains: "both traceparent and tracestate"},
+		{name: "incoming traceparent is the emitted tracestate", opts: []Option{WithIncomingTraceParentAnnotation("operatortrace.azure.microsoft.com/tracestate")}, contains: "both traceparent and tracestate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NotPanics(t, func() { _, _ = NewOptionsStrict(tt.opts...) })
+			_, err := NewOptionsStrict(tt.opts...)
+			require.Error(t, err)
+			assert.ErrorIs(t, err, ErrInvalidOption)
+			assert.Contains(t, err.Error(), tt.contains)
+		})
+	}
+}
+
+func TestNewOptionsStrictValid(t *testing.T) {
+	optFns := []Option{
+		WithAnnotationPrefix("tracing.example.com"),
+		WithEmittedAnnotationSuffixes("parent", "state"),
+		WithTraceExpiration(time.Hour),
+		WithIncomingTraceRelationship(TraceParentRelationshipParent),
+		WithIncomingTraceParentAnnotation("example.com/traceparent"),
+	}
+	opts, err := NewOptionsStrict(optFns...)
+	require.NoError(t, err)
+	assert.Equal(t, NewOptions(optFns...), opts)
+
+	// Every problem is reported
+	_, err = NewOptionsStrict(WithIncomingTraceRelationship("parentt"), WithTraceExpiration(-time.Minute))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "parentt")
+	assert.Contains(t, err.Error(), "not positive")
+
+	// The lenient constructor keeps ignoring the values
+	assert.Equal(t, TraceParentRelationshipLink, NewOptions(WithIncomingTraceRelationship("parentt")).IncomingTraceRelationship)
+}
+
+func TestNewTracingClientWithOptionsE(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+
+	tc, err := NewTracingClientWithOptionsE(k8sClient, k8sClient, initTracer(), logr.Discard(), nil, WithIncomingTraceRelationship("parentt"))
+	assert.ErrorIs(t, err, ErrInvalidOption)
+	assert.Nil(t, tc)
+
+	tc, err = NewTracingClientWithOptionsE(k8sClient, k8sClient, initTracer(), logr.Discard(), nil, WithIncomingTraceRelationship(TraceParentRelationshipParent))
+	require.NoError(t, err)
+	assert.Equal(t, TraceParentRelationshipParent, tc.(*tracingClient).options.IncomingTraceRelationship)
+}
@@ -0,0 +1,167 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/owner_propagation.go
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// InjectOwnerTraceContext records the owner's last persisted traceparent on the child as a linked span.
+// Call it before creating the child so that spans started for the child, by any controller, always link
+// back to the owner's trace even when the child's own trace context comes from an unrelated cause.
+// It is a no-op when the owner carries no trace context.
+func InjectOwnerTraceContext(ctx context.Context, owner, child client.Object, opts ...Option) error {
+	if owner == nil || child == nil {
+		return fmt.Errorf("owner and child must not be nil")
+	}
+	options := newOptions(opts...).withCallOptions(ctx)
+
+	stored, ok := extractTraceContextFromAnnotations(owner.GetAnnotations(), options)
+	if !ok {
+		return nil
+	}
+	if _, err := tracecontext.SpanContextFromTraceData(stored.TraceParent, ""); err != nil {
+		return fmt.Errorf("invalid owner traceparent: %w", err)
+	}
+
+	annotations := copyAnnotations(child)
+	annotations[options.ownerTraceParentAnnotationKey()] = stored.TraceParent
+	child.SetAnnotations(annotations)
+	return nil
+}
+
+// ownerLinkFromObject returns a span link for the owner traceparent recorded by InjectOwnerTraceContext.
+func ownerLinkFromObject(obj client.Object, opts Options) (trace.Link, bool) {
+	if obj == nil {
+		return trace.Link{}, false
+	}
+	result, ok := tracecontext.ExtractTraceContextFromAnnotations(obj.GetAnnotations(), tracecontext.AnnotationExtractionConfig{
+		TraceParentKey: opts.ownerTraceParentAnnotationKey(),
+	})
+	if !ok {
+		return trace.Link{}, false
+	}
+	spanContext, err := tracecontext.SpanContextFromTraceData(result.TraceParent, "")
+	if err != nil {
+		return trace.Link{}, false
+	}
+	return trace.Link{SpanContext: spanContext}, true
+}
+
+// applyOwnerTraceFallback continues the trace of obj's controller owner when obj carries no usable trace context
+// and WithOwnerTraceFallback is configured. The owner's trace is added to request as a linked span, or becomes
+// the remote parent in the returned context when the incoming relationship is parent and ctx has no span yet.
+// Only the direct controller owner is read, and the lookup is cached in the returned context so later
+// StartTrace calls within the same reconcile do not read the owner again.
+func (tc *tracingClient) applyOwnerTraceFallback(ctx context.Context, obj client.Object, request *tracingtypes.RequestWithTraceID) context.Context {
+	opts := tc.options.withCallOptions(ctx)
+	if opts.OwnerTraceFallbackReader == nil || !tc.needsOwnerTraceFallback(obj, opts) {
+		return ctx
+	}
+	ref := metav1.GetControllerOf(obj)
+	if ref == nil {
+		return ctx
+	}
+
+	ctx, cache := ownerTraceCacheFromContext(ctx)
+	stored, ok := cache.lookup(ref.UID, func() (storedTraceContext, bool) {
+		return tc.readOwnerTraceContext(ctx, obj.GetNamespace(), *ref, opts)
+	})
+	if !ok || traceContextExpired(stored.Timestamp, opts) {
+		return ctx
+	}
+	spanContext, err := tracecontext.SpanContextFromTraceData(stored.TraceParent, stored.TraceState)
+	if err != nil {
+		return ctx
+	}
+
+	tc.Logger.Info("Using the controller owner's trace context", "ownerKind", ref.Kind, "relationship", string(opts.IncomingTraceRelationship))
+	if opts.IncomingTraceRelationship == TraceParentRelationshipParent && !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		return trace.ContextWithRemoteSpanContext(ctx, spanContext)
+	}
+	request.AppendLinkedSpan(tracingtypes.LinkedSpan{TraceID: spanContext.TraceID().String(), SpanID: spanContext.SpanID().String()})
+	return ctx
+}
+
+// needsOwnerTraceFallback reports whether obj has neither a usable trace context of its own nor an owner
+// traceparent recorded by InjectOwnerTraceContext.
+func (tc *tracingClient) needsOwnerTraceFallback(obj client.Object, opts Options) bool {
+	stored, ok, err := decodeTraceContextFromAnnotations(obj.GetAnnotations(), opts)
+	if err != nil {
+		// A rejected trace context means the object was tampered with, so do not substitute another trace
+		return false
+	}
+	if ok && !traceContextExpired(stored.Timestamp, opts) {
+		return false
+	}
+	if stored, ok := extractTraceContextFromConditions(obj, tc.scheme); ok && !traceContextExpired(stored.Timestamp, opts) {
+		return false
+	}
+	_, linked := ownerLinkFromObject(obj, opts)
+	return !linked
+}
+
+// readOwnerTraceContext reads the metadata of the owner referenced by ref and returns its stored trace context.
+func (tc *tracingClient) readOwnerTraceContext(ctx context.Context, namespace string, ref metav1.OwnerReference, opts Options) (storedTraceContext, bool) {
+	owner := &metav1.PartialObjectMetadata{}
+	owner.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+	if err := opts.OwnerTraceFallbackReader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, owner); err != nil {
+		tc.Logger.Info("Unable to read the controller owner for its trace context", "ownerKind", ref.Kind, "error", err.Error())
+		return storedTraceContext{}, false
+	}
+	// A recreated owner with the same name is a different object and its trace is unrelated
+	if owner.GetUID() != ref.UID {
+		return storedTraceContext{}, false
+	}
+	stored, ok, err := decodeTraceContextFromAnnotations(owner.GetAnnotations(), opts)
+	if err != nil {
+		return storedTraceContext{}, false
+	}
+	return stored, ok
+}
+
+type ownerTraceCacheKey struct{}
+
+// ownerTraceCache holds the owner trace lookups made during one reconcile, keyed by owner UID.
+type ownerTraceCache struct {
+	mu      sync.Mutex
+	entries map[types.UID]ownerTraceLookup
+}
+
+type ownerTraceLookup struct {
+	stored storedTraceContext
+	ok     bool
+}
+
+// ownerTraceCacheFromContext returns the cache stored in ctx, adding a new one when ctx has none.
+func ownerTraceCacheFromContext(ctx context.Context) (context.Context, *ownerTraceCache) {
+	if cache, ok := ctx.Value(ownerTraceCacheKey{}).(*ownerTraceCache); ok {
+		return ctx, cache
+	}
+	cache := &ownerTraceCache{entries: make(map[types.UID]ownerTraceLookup)}
+	return context.WithValue(ctx, ownerTraceCacheKey{}, cache), cache
+}
+
+// lookup returns the cached result for uid, calling read on the first lookup.
+func (c *ownerTraceCache) lookup(uid types.UID, read func() (storedTraceContext, bool)) (storedTraceContext, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if entry, found := c.entries[uid]; found {
+		return entry.stored, entry.ok
+	}
+	stored, ok := read()
+	c.entries[uid] = ownerTraceLookup{stored: stored, ok: ok}
+	return stored, ok
+}
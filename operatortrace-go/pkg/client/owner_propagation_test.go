@@ -0,0 +1,178 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/owner_propagation_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+)
+
+func TestInjectOwnerTraceContext(t *testing.T) {
+	opts := NewOptions()
+
+	owner := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "default"}}
+	annotateObjectWithTraceIDs(t, owner, opts, testTraceIDHex, testSpanIDHex)
+
+	child := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:        "child",
+		Namespace:   "default",
+		Annotations: map[string]string{"keep": "me"},
+	}}
+	require.NoError(t, InjectOwnerTraceContext(context.Background(), owner, child))
+
+	assert.Equal(t, "00-"+testTraceIDHex+"-"+testSpanIDHex+"-01", child.GetAnnotations()[opts.ownerTraceParentAnnotationKey()])
+	assert.Equal(t, "me", child.GetAnnotations()["keep"])
+	// The owner link never replaces the child's own trace context
+	_, ok := extractTraceContextFromAnnotations(child.GetAnnotations(), opts)
+	assert.False(t, ok)
+}
+
+func TestInjectOwnerTraceContextWithoutOwnerTrace(t *testing.T) {
+	owner := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "default"}}
+	child := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "child", Namespace: "default"}}
+
+	require.NoError(t, InjectOwnerTraceContext(context.Background(), owner, child))
+	assert.Empty(t, child.GetAnnotations())
+
+	assert.Error(t, InjectOwnerTraceContext(context.Background(), nil, child))
+	assert.Error(t, InjectOwnerTraceContext(context.Background(), owner, nil))
+}
+
+func TestInjectOwnerTraceContextCustomPrefix(t *testing.T) {
+	opts := NewOptions(WithAnnotationPrefix("example.com"))
+
+	owner := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "default"}}
+	annotateObjectWithTraceIDs(t, owner, opts, testTraceIDHex, testSpanIDHex)
+	child := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "child", Namespace: "default"}}
+
+	require.NoError(t, InjectOwnerTraceContext(context.Background(), owner, child, WithAnnotationPrefix("example.com")))
+	assert.Contains(t, child.GetAnnotations(), "example.com/owner-traceparent")
+}
+
+func TestOwnerTraceContextLinkedFromChildSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	owner := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "default"}}
+	annotateObjectWithTraceIDs(t, owner, NewOptions(), testTraceIDHex, testSpanIDHex)
+	child := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "child", Namespace: "default"}}
+	require.NoError(t, InjectOwnerTraceContext(context.Background(), owner, child))
+
+	k8sClient := fake.NewClientBuilder().WithObjects(child).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil)
+
+	// A controller with no trace of its own updates the child
+	child.Data = map[string]string{"key": "value"}
+	require.NoError(t, tracingClient.Update(context.Background(), child))
+
+	spans := exporter.GetSpans()
+	require.NotEmpty(t, spans)
+	var linked bool
+	for _, s := range spans {
+		for _, link := range s.Links {
+			if link.SpanContext.TraceID().String() == testTraceIDHex && link.SpanContext.SpanID().String() == testSpanIDHex {
+				linked = true
+			}
+		}
+	}
+	assert.True(t, linked, "expected a span linked to the owner's trace")
+	assert.Contains(t, child.GetAnnotations(), NewOptions().ownerTraceParentAnnotationKey())
+}
+
+func TestStartTraceOwnerTraceFallback(t *testing.T) {
+	owner := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "owner", Namespace: "default", UID: "owner-uid"}}
+	annotateObjectWithTraceIDs(t, owner, NewOptions(), testTraceIDHex, testSpanIDHex)
+	isController := true
+	controllerRef := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "owner", UID: "owner-uid", Controller: &isController}
+
+	tests := []struct {
+		name           string
+		childRefs      []metav1.OwnerReference
+		childHasTrace  bool
+		opts           func(reader client.Reader) []Option
+		expectedGets   int
+		expectedParent bool
+		expectedLink   bool
+	}{
+		{"links the owner trace by default", []metav1.OwnerReference{controllerRef}, false, func(r client.Reader) []Option {
+			return []Option{WithOwnerTraceFallback(r)}
+		}, 1, false, true},
+		{"parents on the owner trace", []metav1.OwnerReference{controllerRef}, false, func(r client.Reader) []Option {
+			return []Option{WithOwnerTraceFallback(r), WithIncomingTraceRelationship(TraceParentRelationshipParent)}
+		}, 1, true, false},
+		{"disabled without the option", []metav1.OwnerReference{controllerRef}, false, func(r client.Reader) []Option {
+			return nil
+		}, 0, false, false},
+		{"child trace takes precedence", []metav1.OwnerReference{controllerRef}, true, func(r client.Reader) []Option {
+			return []Option{WithOwnerTraceFallback(r)}
+		}, 0, false, false},
+		{"only the controller owner is followed", []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: "owner", UID: "owner-uid"}}, false, func(r client.Reader) []Option {
+			return []Option{WithOwnerTraceFallback(r)}
+		}, 0, false, false},
+		{"recreated owner is ignored", []metav1.OwnerReference{{APIVersion: "v1", Kind: "Pod", Name: "owner", UID: "old-uid", Controller: &isController}}, false, func(r client.Reader) []Option {
+			return []Option{WithOwnerTraceFallback(r)}
+		}, 1, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+			child := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "child", Namespace: "default", OwnerReferences: tt.childRefs}}
+			if tt.childHasTrace {
+				annotateObjectWithTraceIDs(t, child, NewOptions(), "fedcba0987654321fedcba0987654321", "fedcba0987654321")
+			}
+			k8sClient := fake.NewClientBuilder().WithObjects(child).Build()
+
+			ownerGets := 0
+			ownerReader := fake.NewClientBuilder().WithObjects(owner).WithInterceptorFuncs(interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					ownerGets++
+					return c.Get(ctx, key, obj, opts...)
+				},
+			}).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, tt.opts(ownerReader)...)
+
+			request := &tracingtypes.RequestWithTraceID{Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "child", Namespace: "default"}}}
+			ctx, span, err := tracingClient.StartTrace(context.Background(), request, &corev1.ConfigMap{})
+			require.NoError(t, err)
+			// A second StartTrace within the same reconcile reuses the owner lookup
+			_, nested, err := tracingClient.StartTrace(ctx, request, &corev1.ConfigMap{})
+			require.NoError(t, err)
+			nested.End()
+			span.End()
+			assert.Equal(t, tt.expectedGets, ownerGets)
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 2)
+			startTrace := spans[1]
+			assert.Equal(t, tt.expectedParent, startTrace.Parent.TraceID().String() == testTraceIDHex)
+			linked := false
+			for _, link := range startTrace.Links {
+				if link.SpanContext.TraceID().String() == testTraceIDHex && link.SpanContext.SpanID().String() == testSpanIDHex {
+					linked = true
+				}
+			}
+			assert.Equal(t, tt.expectedLink, linked)
+		})
+	}
+}
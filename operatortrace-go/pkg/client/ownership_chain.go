@@ -0,0 +1,116 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/ownership_chain.go
+
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultOwnerChainDepth is how many owners BuildOwnershipChainContext reads without WithOwnerChainDepth.
+const defaultOwnerChainDepth = 5
+
+// tracedOwner is an owner read by BuildOwnershipChainContext that carries a usable trace context.
+type tracedOwner struct {
+	owner       *metav1.PartialObjectMetadata
+	spanContext trace.SpanContext
+}
+
+// BuildOwnershipChainContext joins the traces of the owners of obj, e.g. the Deployment and ReplicaSet of a Pod,
+// so a reconcile deep in an ownership hierarchy is part of the trace of the object at its top. It reads the
+// metadata of the owners through c, following the controller owner reference, or the first owner reference when
+// there is no controller, up to WithOwnerChainDepth owners. The walk stops at an owner that no longer exists.
+//
+// For every owner carrying a usable trace context a span is started, with the global TracerProvider, linking to
+// that trace; the caller ends them. The returned ctx has the trace of the topmost traced owner as its remote
+// parent, so spans started from it continue that trace:
+//
+//	ctx, spans, err := client.BuildOwnershipChainContext(ctx, pod, k8sClient, client.WithOwnerChainDepth(3))
+//	defer func() {
+//		for _, span := range spans {
+//			span.End()
+//		}
+//	}()
+//
+// ctx is returned unchanged, without spans, when no owner carries a trace context, or with an error when an
+// owner could not be read.
+func BuildOwnershipChainContext(ctx context.Context, obj client.Object, c client.Client, opts ...Option) (context.Context, []trace.Span, error) {
+	if obj == nil || c == nil {
+		return ctx, nil, fmt.Errorf("object and client must not be nil")
+	}
+	options := newOptions(opts...).withCallOptions(ctx)
+	depth := options.OwnerChainDepth
+	if depth < 1 {
+		depth = defaultOwnerChainDepth
+	}
+
+	var traced []tracedOwner
+	seen := map[types.UID]struct{}{obj.GetUID(): {}}
+	current := obj
+	for i := 0; i < depth; i++ {
+		ref := ownerToFollow(current)
+		if ref == nil {
+			break
+		}
+		if _, cycle := seen[ref.UID]; cycle && ref.UID != "" {
+			break
+		}
+		owner := &metav1.PartialObjectMetadata{}
+		owner.SetGroupVersionKind(schema.FromAPIVersionAndKind(ref.APIVersion, ref.Kind))
+		if err := c.Get(ctx, types.NamespacedName{Namespace: current.GetNamespace(), Name: ref.Name}, owner); err != nil {
+			if apierrors.IsNotFound(err) {
+				break
+			}
+			return ctx, nil, fmt.Errorf("problem getting owner %s %s: %w", ref.Kind, ref.Name, err)
+		}
+		// A recreated owner with the same name is a different object and ends the chain
+		if ref.UID != "" && owner.GetUID() != ref.UID {
+			break
+		}
+		seen[ref.UID] = struct{}{}
+		current = owner
+
+		stored, ok, err := decodeTraceContextFromAnnotations(owner.GetAnnotations(), options)
+		if err != nil || !ok {
+			continue
+		}
+		if lookup := checkStoredTraceContext(stored, options); lookup.rootReason == "" {
+			traced = append(traced, tracedOwner{owner: owner, spanContext: lookup.spanContext})
+		}
+	}
+	if len(traced) == 0 {
+		return ctx, nil, nil
+	}
+
+	ctx = trace.ContextWithRemoteSpanContext(ctx, traced[len(traced)-1].spanContext)
+	tracer := tracerFromProvider(otel.GetTracerProvider())
+	spans := make([]trace.Span, 0, len(traced))
+	for _, o := range traced {
+		gvk := o.owner.GroupVersionKind()
+		_, span := tracer.Start(ctx, fmt.Sprintf("OwnerChain %s %s", gvk.Kind, options.redactedName(o.owner, gvk)),
+			trace.WithLinks(trace.Link{SpanContext: o.spanContext}))
+		spans = append(spans, span)
+	}
+	return ctx, spans, nil
+}
+
+// ownerToFollow returns the controller owner reference of obj, or its first owner reference without one.
+func ownerToFollow(obj client.Object) *metav1.OwnerReference {
+	if ref := metav1.GetControllerOf(obj); ref != nil {
+		return ref
+	}
+	if refs := obj.GetOwnerReferences(); len(refs) > 0 {
+		return &refs[0]
+	}
+	return nil
+}
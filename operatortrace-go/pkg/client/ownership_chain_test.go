@@ -0,0 +1,135 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/ownership_chain_test.go
+
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestBuildOwnershipChainContext(t *testing.T) {
+	const (
+		deploymentTraceID = "11111111111111111111111111111111"
+		replicaSetTraceID = "22222222222222222222222222222222"
+	)
+	isController := true
+	deployment := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default", UID: "deployment-uid"}}
+	annotateObjectWithTraceIDs(t, deployment, NewOptions(), deploymentTraceID, testSpanIDHex)
+	replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "app-1", Namespace: "default", UID: "replicaset-uid",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "Deployment", Name: "app", UID: "deployment-uid", Controller: &isController}},
+	}}
+	annotateObjectWithTraceIDs(t, replicaSet, NewOptions(), replicaSetTraceID, testSpanIDHex)
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "app-1-abc", Namespace: "default", UID: "pod-uid",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-1", UID: "replicaset-uid", Controller: &isController}},
+	}}
+
+	tests := []struct {
+		name          string
+		objects       []client.Object
+		opts          []Option
+		expectedSpans []string
+		expectedTrace string
+	}{
+		{
+			name:          "three-level chain",
+			objects:       []client.Object{deployment, replicaSet, pod},
+			expectedSpans: []string{"OwnerChain ReplicaSet app-1", "OwnerChain Deployment app"},
+			expectedTrace: deploymentTraceID,
+		},
+		{
+			name:          "limited depth",
+			objects:       []client.Object{deployment, replicaSet, pod},
+			opts:          []Option{WithOwnerChainDepth(1)},
+			expectedSpans: []string{"OwnerChain ReplicaSet app-1"},
+			expectedTrace: replicaSetTraceID,
+		},
+		{
+			name:          "missing owner ends the chain",
+			objects:       []client.Object{replicaSet, pod},
+			expectedSpans: []string{"OwnerChain ReplicaSet app-1"},
+			expectedTrace: replicaSetTraceID,
+		},
+		{
+			name:    "no owners",
+			objects: []client.Object{deployment, replicaSet, pod},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			setTestTracerProvider(t, sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+			k8sClient := fake.NewClientBuilder().WithObjects(tt.objects...).Build()
+			obj := pod.DeepCopy()
+			if tt.expectedSpans == nil {
+				obj.OwnerReferences = nil
+			}
+
+			ctx, spans, err := BuildOwnershipChainContext(context.Background(), obj, k8sClient, tt.opts...)
+			require.NoError(t, err)
+			for _, span := range spans {
+				span.End()
+			}
+
+			if tt.expectedTrace == "" {
+				assert.Empty(t, spans)
+				assert.False(t, trace.SpanContextFromContext(ctx).IsValid())
+				return
+			}
+			parent := trace.SpanContextFromContext(ctx)
+			assert.True(t, parent.IsRemote())
+			assert.Equal(t, tt.expectedTrace, parent.TraceID().String())
+
+			ended := exporter.GetSpans()
+			require.Len(t, ended, len(tt.expectedSpans))
+			for i, name := range tt.expectedSpans {
+				assert.Equal(t, name, ended[i].Name)
+				assert.Equal(t, tt.expectedTrace, ended[i].SpanContext.TraceID().String())
+				require.Len(t, ended[i].Links, 1)
+			}
+			assert.Equal(t, replicaSetTraceID, ended[0].Links[0].SpanContext.TraceID().String())
+		})
+	}
+}
+
+func TestBuildOwnershipChainContextGetError(t *testing.T) {
+	isController := true
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default",
+		OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "app-1", Controller: &isController}},
+	}}
+	getErr := errors.New("forbidden")
+	k8sClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+			return getErr
+		},
+	}).Build()
+
+	ctx := context.Background()
+	returned, spans, err := BuildOwnershipChainContext(ctx, pod, k8sClient)
+	assert.ErrorIs(t, err, getErr)
+	assert.Empty(t, spans)
+	assert.Equal(t, ctx, returned)
+}
+
+// setTestTracerProvider makes tp the global TracerProvider for the duration of the test.
+func setTestTracerProvider(t *testing.T, tp trace.TracerProvider) {
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+}
@@ -0,0 +1,74 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/parent_selection.go
+
+package client
+
+import (
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ParentSource names which trace context StartTrace continues when the reconciled object carries one and its
+// request inherits another from the object that triggered it.
+type ParentSource string
+
+const (
+	// ParentSourceAnnotation continues the trace stored on the reconciled object.
+	ParentSourceAnnotation ParentSource = "annotation"
+	// ParentSourceRequest continues the trace of the request parent.
+	ParentSourceRequest ParentSource = "request"
+)
+
+// ParentSelectionPolicy picks the parent of a StartTrace span between the usable trace stored on the reconciled
+// object and the inherited request parent. The one not picked is linked.
+type ParentSelectionPolicy interface {
+	SelectParent(annotation StoredTrace, request tracingtypes.RequestParent) ParentSource
+}
+
+type preferAnnotation struct{}
+
+func (preferAnnotation) SelectParent(StoredTrace, tracingtypes.RequestParent) ParentSource {
+	return ParentSourceAnnotation
+}
+
+type preferRequestParent struct{}
+
+func (preferRequestParent) SelectParent(StoredTrace, tracingtypes.RequestParent) ParentSource {
+	return ParentSourceRequest
+}
+
+var (
+	// PolicyPreferAnnotation keeps the trace already stored on the reconciled object and links the request parent.
+	PolicyPreferAnnotation ParentSelectionPolicy = preferAnnotation{}
+	// PolicyPreferRequestParent continues the trace of the most recent change that triggered the request, the
+	// default.
+	PolicyPreferRequestParent ParentSelectionPolicy = preferRequestParent{}
+)
+
+// WithParentSelectionPolicy sets how StartTrace picks the parent when the reconciled object carries a usable trace
+// and its request inherits the trace of the object that triggered it. Requests whose parent is only linked, see
+// WithInheritTraceOn and WithRelationshipPerKind, are not affected.
+func WithParentSelectionPolicy(policy ParentSelectionPolicy) Option {
+	return func(o *Options) {
+		if policy == nil {
+			return
+		}
+		o.ParentSelectionPolicy = policy
+	}
+}
+
+// prefersStoredParent reports whether the usable trace stored on obj, rather than the parent of request, is
+// the parent of the StartTrace span according to the parent selection policy.
+func (o Options) prefersStoredParent(request tracingtypes.RequestWithTraceID, obj client.Object, scheme *runtime.Scheme) bool {
+	parent := request.Parent
+	if o.ParentSelectionPolicy == nil || obj == nil || parent.TraceID == "" || parent.SpanID == "" {
+		return false
+	}
+	stored, ok := LookupStoredTrace(obj, scheme, o)
+	if !ok || stored.Expired || (stored.TraceID == parent.TraceID && stored.SpanID == parent.SpanID) {
+		return false
+	}
+	return o.ParentSelectionPolicy.SelectParent(stored, parent) == ParentSourceAnnotation
+}
@@ -0,0 +1,115 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/parent_selection_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestParentSelectionPolicy(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	const (
+		annotationTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		annotationSpanID  = "00f067aa0ba902b7"
+		requestTraceID    = "f620f5cad0af940c294f980c5366a6a1"
+		requestSpanID     = "45f359cdc1c8ab06"
+	)
+	traceState := func(storedAt time.Time) string {
+		state, err := tracecontext.SetTraceStateKey("", constants.TraceStateTimestampKey, storedAt.UTC().Format(time.RFC3339Nano))
+		require.NoError(t, err)
+		return state
+	}
+
+	tests := []struct {
+		name          string
+		policy        ParentSelectionPolicy
+		storedAt      time.Time
+		expectTraceID string
+		expectLinked  string
+	}{
+		{"default continues the request parent", nil, time.Now(), requestTraceID, ""},
+		{"prefer request parent", PolicyPreferRequestParent, time.Now(), requestTraceID, ""},
+		{"prefer annotation", PolicyPreferAnnotation, time.Now(), annotationTraceID, requestTraceID},
+		{"prefer annotation ignores an expired annotation", PolicyPreferAnnotation, time.Now().Add(-time.Hour), requestTraceID, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Annotations: map[string]string{
+				constants.DefaultTraceParentAnnotation: "00-" + annotationTraceID + "-" + annotationSpanID + "-01",
+				constants.DefaultTraceStateAnnotation:  traceState(tt.storedAt),
+			}}}
+			k8sClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, WithParentSelectionPolicy(tt.policy))
+
+			request := &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "pod"}},
+				Parent:  tracingtypes.RequestParent{TraceID: requestTraceID, SpanID: requestSpanID, Kind: "ConfigMap", Name: "config"},
+			}
+			_, span, err := tracingClient.StartTrace(context.Background(), request, &corev1.Pod{})
+			require.NoError(t, err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tt.expectTraceID, spans[0].SpanContext.TraceID().String())
+			var linked []string
+			for _, link := range spans[0].Links {
+				linked = append(linked, link.SpanContext.TraceID().String())
+			}
+			if tt.expectLinked == "" {
+				assert.NotContains(t, linked, annotationTraceID)
+			} else {
+				assert.Contains(t, linked, tt.expectLinked)
+			}
+		})
+	}
+}
+
+// requestFromKind prefers the request parent only for changes of one kind.
+type requestFromKind string
+
+func (k requestFromKind) SelectParent(_ StoredTrace, request tracingtypes.RequestParent) ParentSource {
+	if request.Kind == string(k) {
+		return ParentSourceRequest
+	}
+	return ParentSourceAnnotation
+}
+
+func TestCustomParentSelectionPolicy(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Annotations: map[string]string{
+		constants.DefaultTraceParentAnnotation: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}}}
+	opts := NewOptions(WithParentSelectionPolicy(requestFromKind("Secret")))
+	request := tracingtypes.RequestWithTraceID{Parent: tracingtypes.RequestParent{TraceID: "f620f5cad0af940c294f980c5366a6a1", SpanID: "45f359cdc1c8ab06", Kind: "Secret"}}
+
+	assert.False(t, opts.prefersStoredParent(request, pod, clientgoscheme.Scheme))
+	request.Parent.Kind = "ConfigMap"
+	assert.True(t, opts.prefersStoredParent(request, pod, clientgoscheme.Scheme))
+	assert.False(t, opts.prefersStoredParent(request, &corev1.Pod{}, clientgoscheme.Scheme), "objects without a stored trace continue the request parent")
+	assert.False(t, NewOptions(WithParentSelectionPolicy(nil)).prefersStoredParent(request, pod, clientgoscheme.Scheme))
+}
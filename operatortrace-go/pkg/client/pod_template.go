@@ -0,0 +1,146 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/pod_template.go
+
+package client
+
+import (
+	"context"
+	"maps"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PodTemplatePath is the path of the pod template in objects of a kind, e.g. ["spec", "template"] for a Deployment.
+type PodTemplatePath struct {
+	GroupKind schema.GroupKind
+	Fields    []string
+}
+
+// defaultPodTemplatePaths are the built-in workloads WithPodTemplatePropagation stores the trace context for.
+var defaultPodTemplatePaths = []PodTemplatePath{
+	{GroupKind: schema.GroupKind{Group: "apps", Kind: "Deployment"}, Fields: []string{"spec", "template"}},
+	{GroupKind: schema.GroupKind{Group: "apps", Kind: "StatefulSet"}, Fields: []string{"spec", "template"}},
+	{GroupKind: schema.GroupKind{Group: "apps", Kind: "DaemonSet"}, Fields: []string{"spec", "template"}},
+	{GroupKind: schema.GroupKind{Group: "batch", Kind: "Job"}, Fields: []string{"spec", "template"}},
+}
+
+// WithPodTemplatePropagation also stores the trace context in the pod template annotations of Deployments,
+// StatefulSets, DaemonSets, Jobs and the kinds registered with WithPodTemplatePath, so the trace flows to the Pods
+// they create.
+//
+// Changing a pod template rolls out new Pods, so the template only gets a new trace context on Create and on
+// Updates that change the template anyway; other Updates keep the stored one, and EndTrace leaves it in place.
+// Updates changing only the template trace annotations are not significant for HasSignificantUpdate.
+func WithPodTemplatePropagation() Option {
+	return func(o *Options) {
+		o.PodTemplatePropagation = true
+	}
+}
+
+// WithPodTemplatePath registers fields as the path of the pod template of objects of groupKind, typically a
+// custom resource embedding a PodTemplateSpec, for WithPodTemplatePropagation.
+func WithPodTemplatePath(groupKind schema.GroupKind, fields ...string) Option {
+	return func(o *Options) {
+		if groupKind.Kind == "" || len(fields) == 0 {
+			return
+		}
+		o.PodTemplatePaths = appendOption(o.PodTemplatePaths, PodTemplatePath{GroupKind: groupKind, Fields: append([]string(nil), fields...)})
+	}
+}
+
+// podTemplateFields returns the path of the pod template of groupKind, registered paths first.
+func (o Options) podTemplateFields(groupKind schema.GroupKind) ([]string, bool) {
+	for _, paths := range [][]PodTemplatePath{o.PodTemplatePaths, defaultPodTemplatePaths} {
+		for _, path := range paths {
+			if path.GroupKind == groupKind {
+				return path.Fields, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// stampPodTemplate stores the trace context of ctx in the pod template annotations of obj before it is written.
+// existing is the stored object on Update and nil on Create. When the template of obj does not otherwise differ
+// from the stored one, the stored template trace context is kept, so the write does not roll out new Pods.
+func stampPodTemplate(ctx context.Context, obj client.Object, gvk schema.GroupVersionKind, existing client.Object, opts Options) {
+	opts = opts.withCallOptions(ctx)
+	if !opts.PodTemplatePropagation {
+		return
+	}
+	fields, ok := opts.podTemplateFields(gvk.GroupKind())
+	if !ok {
+		return
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return
+	}
+	template, found, err := unstructured.NestedMap(content, fields...)
+	if err != nil || !found {
+		return
+	}
+
+	annotationFields := append(append([]string(nil), fields...), "metadata", "annotations")
+	var annotations map[string]string
+	if existing != nil && !podTemplateChanged(existing, template, fields, opts) {
+		existingContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(existing)
+		if err != nil {
+			return
+		}
+		annotations, _, _ = unstructured.NestedStringMap(existingContent, annotationFields...)
+	} else {
+		traceParent, traceState, ok := traceDataToPersist(ctx, opts)
+		if !ok {
+			return
+		}
+		annotations, _, _ = unstructured.NestedStringMap(content, annotationFields...)
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		persistTraceCarrier(annotations, opts, traceParent, traceState)
+	}
+
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(content, annotationFields...)
+	} else if err := unstructured.SetNestedStringMap(content, annotations, annotationFields...); err != nil {
+		return
+	}
+	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj)
+}
+
+// podTemplateChanged reports whether template, the pod template of the object being written, differs from the
+// pod template of existing other than by its trace annotations.
+func podTemplateChanged(existing client.Object, template map[string]interface{}, fields []string, opts Options) bool {
+	existingContent, err := runtime.DefaultUnstructuredConverter.ToUnstructured(existing)
+	if err != nil {
+		return true
+	}
+	existingTemplate, _, err := unstructured.NestedMap(existingContent, fields...)
+	if err != nil {
+		return true
+	}
+	return !equality.Semantic.DeepEqual(withoutTraceAnnotations(existingTemplate, opts), withoutTraceAnnotations(template, opts))
+}
+
+// withoutTraceAnnotations returns a copy of template without the trace annotations of its metadata.
+func withoutTraceAnnotations(template map[string]interface{}, opts Options) map[string]interface{} {
+	template = runtime.DeepCopyJSON(template)
+	annotations, found, err := unstructured.NestedStringMap(template, "metadata", "annotations")
+	if err != nil || !found {
+		return template
+	}
+	stripped := maps.Clone(annotations)
+	persistTraceCarrier(stripped, opts, "", "")
+	if len(stripped) == 0 {
+		unstructured.RemoveNestedField(template, "metadata", "annotations")
+		return template
+	}
+	_ = unstructured.SetNestedStringMap(template, stripped, "metadata", "annotations")
+	return template
+}
@@ -0,0 +1,143 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/pod_template_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPodTemplatePropagation(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(tracetest.NewInMemoryExporter()))
+	tracer := tp.Tracer("test")
+	k8sClient := fake.NewClientBuilder().Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tracer, logr.Discard(), nil, WithPodTemplatePropagation())
+
+	// reconcile runs fn under a new trace, against the stored Deployment, and returns the trace ID
+	reconcile := func(fn func(ctx context.Context, deployment *appsv1.Deployment)) trace.TraceID {
+		ctx, span := tracer.Start(context.Background(), "reconcile")
+		defer span.End()
+		deployment := &appsv1.Deployment{}
+		require.NoError(t, k8sClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "app"}, deployment))
+		fn(ctx, deployment)
+		return span.SpanContext().TraceID()
+	}
+	stored := func() *appsv1.Deployment {
+		deployment := &appsv1.Deployment{}
+		require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "app"}, deployment))
+		return deployment
+	}
+
+	ctx, span := tracer.Start(context.Background(), "create")
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{"example.com/other": "kept"}},
+				Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:v1"}}},
+			},
+		},
+	}
+	require.NoError(t, tracingClient.Create(ctx, deployment))
+	span.End()
+	created := stored()
+	createTraceParent := created.Spec.Template.Annotations[constants.DefaultTraceParentAnnotation]
+	assert.Contains(t, createTraceParent, span.SpanContext().TraceID().String())
+	assert.Equal(t, "kept", created.Spec.Template.Annotations["example.com/other"])
+
+	// Scaling does not change the template, so the template keeps its trace and no Pods are rolled out
+	traceID := reconcile(func(ctx context.Context, deployment *appsv1.Deployment) {
+		replicas := int32(3)
+		deployment.Spec.Replicas = &replicas
+		require.NoError(t, tracingClient.Update(ctx, deployment))
+	})
+	scaled := stored()
+	assert.Equal(t, createTraceParent, scaled.Spec.Template.Annotations[constants.DefaultTraceParentAnnotation])
+	assert.Contains(t, scaled.Annotations[constants.DefaultTraceParentAnnotation], traceID.String())
+
+	// Changing the image rolls out Pods anyway, so the template gets the new trace
+	traceID = reconcile(func(ctx context.Context, deployment *appsv1.Deployment) {
+		deployment.Spec.Template.Spec.Containers[0].Image = "app:v2"
+		require.NoError(t, tracingClient.Update(ctx, deployment))
+	})
+	rolled := stored()
+	rolledTraceParent := rolled.Spec.Template.Annotations[constants.DefaultTraceParentAnnotation]
+	assert.Contains(t, rolledTraceParent, traceID.String())
+	assert.Equal(t, "kept", rolled.Spec.Template.Annotations["example.com/other"])
+
+	// A new template trace alone is not a significant update
+	retraced := created.DeepCopy()
+	retraced.Spec.Template.Annotations[constants.DefaultTraceParentAnnotation] = rolledTraceParent
+	assert.False(t, predicates.HasSignificantUpdate(created, retraced))
+
+	// EndTrace clears the object trace but leaves the template alone
+	reconcile(func(ctx context.Context, deployment *appsv1.Deployment) {
+		require.NoError(t, tracingClient.EndTrace(ctx, deployment))
+	})
+	ended := stored()
+	assert.Empty(t, ended.Annotations[constants.DefaultTraceParentAnnotation])
+	assert.Equal(t, rolledTraceParent, ended.Spec.Template.Annotations[constants.DefaultTraceParentAnnotation])
+}
+
+func TestPodTemplatePropagationKinds(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(tracetest.NewInMemoryExporter()))
+	tracer := tp.Tracer("test")
+	widgetGVK := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	newWidget := func() *unstructured.Unstructured {
+		widget := &unstructured.Unstructured{}
+		widget.SetGroupVersionKind(widgetGVK)
+		widget.SetNamespace("default")
+		widget.SetName("widget")
+		require.NoError(t, unstructured.SetNestedField(widget.Object, "app:v1", "spec", "workload", "image"))
+		return widget
+	}
+
+	tests := []struct {
+		name          string
+		opts          []Option
+		expectStamped bool
+	}{
+		{"registered path", []Option{WithPodTemplatePropagation(), WithPodTemplatePath(widgetGVK.GroupKind(), "spec", "workload")}, true},
+		{"unregistered kind", []Option{WithPodTemplatePropagation()}, false},
+		{"propagation disabled", []Option{WithPodTemplatePath(widgetGVK.GroupKind(), "spec", "workload")}, false},
+		{"invalid path is ignored", []Option{WithPodTemplatePropagation(), WithPodTemplatePath(widgetGVK.GroupKind())}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := fake.NewClientBuilder().Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tracer, logr.Discard(), nil, tt.opts...)
+
+			ctx, span := tracer.Start(context.Background(), "create")
+			defer span.End()
+			widget := newWidget()
+			require.NoError(t, tracingClient.Create(ctx, widget))
+
+			stamped, _, err := unstructured.NestedString(widget.Object, "spec", "workload", "metadata", "annotations", constants.DefaultTraceParentAnnotation)
+			require.NoError(t, err)
+			if tt.expectStamped {
+				assert.Contains(t, stamped, span.SpanContext().TraceID().String())
+			} else {
+				assert.Empty(t, stamped)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/reader_validation.go
+
+package client
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// ReaderAttributeKey records on StartTrace spans that the object was read from the informer cache, or with
+// WithStartTraceReaderStrategy, which source served the object.
+const ReaderAttributeKey = attribute.Key("operatortrace.reader")
+
+const (
+	readerSourceCache = "cache"
+	readerSourceAPI   = "api"
+)
+
+// NewTracingClientForManager creates a TracingClient that writes through the manager's client and reads the object
+// in StartTrace and EndTrace through the manager's API reader, which bypasses the informer cache. Reading from the
+// cache can return a stale object whose trace annotations then lose the race against EndTrace.
+func NewTracingClientForManager(mgr manager.Manager, t trace.Tracer, l logr.Logger, optFns ...Option) TracingClient {
+	return NewTracingClientWithOptions(mgr.GetClient(), mgr.GetAPIReader(), t, l, mgr.GetScheme(), optFns...)
+}
+
+// NewTracingClientFromManager is NewTracingClientForManager that also takes the manager's REST mapper, so the
+// TracingClient is the only manager dependency a controller needs to hold, and logs with the manager's logger.
+// Options passed in opts take precedence.
+func NewTracingClientFromManager(mgr manager.Manager, t trace.Tracer, opts ...Option) TracingClient {
+	return NewTracingClientForManager(mgr, t, mgr.GetLogger(), append([]Option{WithRESTMapper(mgr.GetRESTMapper())}, opts...)...)
+}
+
+// readsFromCache reports whether r serves reads from an informer cache, either because it is the cache itself
+// or because it is the same cache-backed client used for writes.
+func readsFromCache(c client.Client, r client.Reader) bool {
+	if _, ok := r.(cache.Cache); ok {
+		return true
+	}
+	return sameInstance(c, r)
+}
+
+// sameInstance reports whether a and b hold the same value without panicking on uncomparable dynamic types.
+func sameInstance(a, b any) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		return false
+	}
+	if va.Kind() == reflect.Pointer {
+		return va.Pointer() == vb.Pointer()
+	}
+	return va.Type().Comparable() && a == b
+}
+
+// validateReader warns when the client reads from the informer cache and returns the span attributes recording it.
+func validateReader(c client.Client, r client.Reader, l logr.Logger) []attribute.KeyValue {
+	if !readsFromCache(c, r) {
+		return nil
+	}
+	l.Error(nil, "WARNING: the tracing client reader is the cached client, so StartTrace may read stale objects; pass an uncached reader such as mgr.GetAPIReader() or use NewTracingClientForManager")
+	return []attribute.KeyValue{ReaderAttributeKey.String(readerSourceCache)}
+}
+
+// getForStartTrace reads the object reconciled by StartTrace according to the reader strategy and returns the
+// source that served it, or "" when no strategy is configured. The strategies read the API server through the
+// APIReader option when it is set, like GetFresh.
+func (tc *tracingClient) getForStartTrace(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) (string, error) {
+	switch tc.options.withCallOptions(ctx).StartTraceReaderStrategy {
+	case ReaderStrategyAPIOnly:
+		return readerSourceAPI, tc.apiReader.Get(ctx, key, obj, opts...)
+	case ReaderStrategyCacheOnly:
+		return readerSourceCache, tc.Client.Get(ctx, key, obj, opts...)
+	case ReaderStrategyCacheThenAPI:
+		if err := tc.Client.Get(ctx, key, obj, opts...); !apierrors.IsNotFound(err) {
+			return readerSourceCache, err
+		}
+		return readerSourceAPI, tc.apiReader.Get(ctx, key, obj, opts...)
+	}
+	return "", tc.Reader.Get(ctx, key, obj, opts...)
+}
@@ -0,0 +1,224 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/reader_validation_test.go
+
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestReadsFromCache(t *testing.T) {
+	cachedClient := fake.NewClientBuilder().Build()
+	apiReader := fake.NewClientBuilder().Build()
+
+	tests := []struct {
+		name     string
+		reader   client.Reader
+		expected bool
+	}{
+		{"same client", cachedClient, true},
+		{"separate reader", apiReader, false},
+		{"informer cache", &informertest.FakeInformers{}, true},
+		{"nil reader", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, readsFromCache(cachedClient, tt.reader))
+		})
+	}
+}
+
+func TestReaderValidation(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+	cachedClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	apiReader := fake.NewClientBuilder().WithObjects(pod.DeepCopy()).Build()
+
+	tests := []struct {
+		name          string
+		reader        client.Reader
+		opts          []Option
+		expectWarning bool
+	}{
+		{"cached reader with validation", cachedClient, []Option{WithReaderValidation()}, true},
+		{"api reader with validation", apiReader, []Option{WithReaderValidation()}, false},
+		{"cached reader without validation", cachedClient, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			var logOutput strings.Builder
+			logger := funcr.New(func(prefix, args string) { logOutput.WriteString(args + "\n") }, funcr.Options{})
+
+			tracingClient := NewTracingClientWithOptions(cachedClient, tt.reader, tp.Tracer("operatortrace"), logger, nil, tt.opts...)
+			assert.Equal(t, tt.expectWarning, strings.Contains(logOutput.String(), "WARNING"))
+
+			_, span, err := tracingClient.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "test-pod", Namespace: "default"}},
+			}, &corev1.Pod{})
+			require.NoError(t, err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			if tt.expectWarning {
+				assert.Contains(t, spans[0].Attributes, ReaderAttributeKey.String("cache"))
+			} else {
+				for _, attr := range spans[0].Attributes {
+					assert.NotEqual(t, ReaderAttributeKey, attr.Key)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTracingClientForManager(t *testing.T) {
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "http://127.0.0.1:1"}, ctrl.Options{Metrics: metricsserver.Options{BindAddress: "0"}})
+	require.NoError(t, err)
+
+	tc := NewTracingClientForManager(mgr, initTracer(), logr.Discard(), WithReaderValidation())
+	impl, ok := tc.(*tracingClient)
+	require.True(t, ok)
+	assert.Equal(t, mgr.GetClient(), impl.Client)
+	assert.Equal(t, mgr.GetAPIReader(), impl.Reader)
+	assert.Equal(t, mgr.GetScheme(), impl.scheme)
+	assert.Empty(t, impl.readerAttributes)
+}
+
+func TestNewTracingClientFromManager(t *testing.T) {
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "http://127.0.0.1:1"}, ctrl.Options{Metrics: metricsserver.Options{BindAddress: "0"}})
+	require.NoError(t, err)
+
+	tc := NewTracingClientFromManager(mgr, initTracer())
+	impl, ok := tc.(*tracingClient)
+	require.True(t, ok)
+	assert.Equal(t, mgr.GetClient(), impl.Client)
+	assert.Equal(t, mgr.GetAPIReader(), impl.Reader)
+	assert.Equal(t, mgr.GetScheme(), impl.scheme)
+	assert.Same(t, mgr.GetRESTMapper(), tc.RESTMapper())
+
+	// An explicit mapper wins over the manager's
+	mapper := meta.NewDefaultRESTMapper(nil)
+	assert.Same(t, mapper, NewTracingClientFromManager(mgr, initTracer(), WithRESTMapper(mapper)).RESTMapper())
+}
+
+func TestWithRESTMapper(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	mapper := meta.NewDefaultRESTMapper(nil)
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected meta.RESTMapper
+	}{
+		{"wrapped client's mapper by default", nil, k8sClient.RESTMapper()},
+		{"nil mapper is ignored", []Option{WithRESTMapper(nil)}, k8sClient.RESTMapper()},
+		{"configured mapper", []Option{WithRESTMapper(mapper)}, mapper},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil, tt.opts...)
+			assert.Same(t, tt.expected, tc.RESTMapper())
+		})
+	}
+}
+
+func TestStartTraceReaderStrategy(t *testing.T) {
+	pod := func() *corev1.Pod {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default"}}
+	}
+
+	tests := []struct {
+		name           string
+		strategy       StartTraceReaderStrategy
+		inCache        bool
+		expectedSource string
+		expectedReads  []string
+		expectNotFound bool
+		// withAPIReader passes the API reader through WithAPIReader, with the cached client as the reader
+		withAPIReader bool
+	}{
+		{"cache then API on a cache hit", ReaderStrategyCacheThenAPI, true, "cache", []string{"cache"}, false, false},
+		{"cache then API on a cache miss", ReaderStrategyCacheThenAPI, false, "api", []string{"cache", "api"}, false, false},
+		{"cache only on a cache miss", ReaderStrategyCacheOnly, false, "cache", []string{"cache"}, true, false},
+		{"API only", ReaderStrategyAPIOnly, true, "api", []string{"api"}, false, false},
+		{"no strategy", "", true, "", []string{"api"}, false, false},
+		{"cache then API reads the APIReader on a cache miss", ReaderStrategyCacheThenAPI, false, "api", []string{"cache", "api"}, false, true},
+		{"API only reads the APIReader", ReaderStrategyAPIOnly, true, "api", []string{"api"}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reads []string
+			countingGet := func(source string) interceptor.Funcs {
+				return interceptor.Funcs{Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					reads = append(reads, source)
+					return c.Get(ctx, key, obj, opts...)
+				}}
+			}
+			cacheBuilder := fake.NewClientBuilder().WithInterceptorFuncs(countingGet("cache"))
+			if tt.inCache {
+				cacheBuilder = cacheBuilder.WithObjects(pod())
+			}
+			cachedClient := cacheBuilder.Build()
+			apiReader := fake.NewClientBuilder().WithObjects(pod()).WithInterceptorFuncs(countingGet("api")).Build()
+
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			var reader client.Reader = apiReader
+			optFns := []Option{WithStartTraceReaderStrategy(tt.strategy)}
+			if tt.withAPIReader {
+				reader = cachedClient
+				optFns = append(optFns, WithAPIReader(apiReader))
+			}
+			tracingClient := NewTracingClientWithOptions(cachedClient, reader, tp.Tracer("test"), logr.Discard(), nil, optFns...)
+
+			obj := &corev1.Pod{}
+			_, span, err := tracingClient.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "sample", Namespace: "default"}},
+			}, obj)
+			span.End()
+			if tt.expectNotFound {
+				assert.True(t, apierrors.IsNotFound(err))
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, "sample", obj.Name)
+			}
+			assert.Equal(t, tt.expectedReads, reads)
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			attrs := attribute.NewSet(spans[0].Attributes...)
+			source, found := attrs.Value(ReaderAttributeKey)
+			assert.Equal(t, tt.expectedSource != "", found)
+			assert.Equal(t, tt.expectedSource, source.AsString())
+		})
+	}
+}
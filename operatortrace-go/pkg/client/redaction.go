@@ -0,0 +1,165 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/redaction.go
+
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// redactedPrefix marks values replaced by RedactValue.
+const redactedPrefix = "redacted-"
+
+// RedactFunc returns the name and namespace to record for obj in span names, span errors and log lines.
+// Use RedactValue to keep redacted values correlatable.
+type RedactFunc func(obj client.Object) (name, namespace string)
+
+// Redaction controls which object names and namespaces are hidden from telemetry. The zero value redacts nothing.
+type Redaction struct {
+	// Kinds lists the kinds whose names and namespaces are replaced by RedactValue. Versions are ignored.
+	Kinds []schema.GroupKind
+
+	// Func, when set, decides the recorded name and namespace of every object and takes precedence over Kinds.
+	Func RedactFunc
+}
+
+// WithRedactedKinds hashes the names and namespaces of objects of the given kinds, e.g. Secrets,
+// wherever operatortrace records them.
+func WithRedactedKinds(gvks ...schema.GroupVersionKind) Option {
+	return func(o *Options) {
+		for _, gvk := range gvks {
+			if gvk.Empty() {
+				continue
+			}
+			o.Redaction.Kinds = appendOption(o.Redaction.Kinds, gvk.GroupKind())
+		}
+	}
+}
+
+// WithRedactFunc lets fn decide the name and namespace recorded for every object.
+func WithRedactFunc(fn RedactFunc) Option {
+	return func(o *Options) {
+		if fn == nil {
+			return
+		}
+		o.Redaction.Func = fn
+	}
+}
+
+// RedactValue returns a stable hash of value so redacted names can still be correlated across spans.
+// Empty values stay empty.
+func RedactValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return redactedPrefix + hex.EncodeToString(sum[:6])
+}
+
+// RedactedObjectKey returns key as recorded by tc, redacting it when tc is configured to redact obj's kind.
+// obj only needs to carry the object's type; its name and namespace are taken from key.
+func RedactedObjectKey(tc TracingClient, key types.NamespacedName, obj client.Object) string {
+	impl, ok := tc.(*tracingClient)
+	if !ok || !impl.options.redacts() {
+		return key.String()
+	}
+	gvk, _ := impl.gvks.gvkForObject(obj, impl.scheme)
+	name, namespace := impl.options.redact(objectForKey(key, gvk), gvk)
+	return types.NamespacedName{Name: name, Namespace: namespace}.String()
+}
+
+func (o Options) redacts() bool {
+	return o.Redaction.Func != nil || len(o.Redaction.Kinds) > 0
+}
+
+// redact returns the name and namespace of obj to record in telemetry.
+func (o Options) redact(obj client.Object, gvk schema.GroupVersionKind) (name, namespace string) {
+	if obj == nil {
+		return "", ""
+	}
+	name, namespace = obj.GetName(), obj.GetNamespace()
+	if o.Redaction.Func != nil {
+		return o.Redaction.Func(obj)
+	}
+	for _, kind := range o.Redaction.Kinds {
+		if kind == gvk.GroupKind() {
+			return RedactValue(name), RedactValue(namespace)
+		}
+	}
+	return name, namespace
+}
+
+// redactedName returns the name of obj to record in telemetry.
+func (o Options) redactedName(obj client.Object, gvk schema.GroupVersionKind) string {
+	name, _ := o.redact(obj, gvk)
+	return name
+}
+
+// redactedKeyName returns the name from key to record in telemetry for an object of kind gvk.
+func (o Options) redactedKeyName(key types.NamespacedName, gvk schema.GroupVersionKind) string {
+	if !o.redacts() {
+		return key.Name
+	}
+	return o.redactedName(objectForKey(key, gvk), gvk)
+}
+
+// redactedObjectKey returns the namespace/name of obj to record in telemetry.
+func (o Options) redactedObjectKey(obj client.Object, gvk schema.GroupVersionKind) string {
+	name, namespace := o.redact(obj, gvk)
+	return types.NamespacedName{Name: name, Namespace: namespace}.String()
+}
+
+// redactedParentName returns the name of the request parent to record in telemetry.
+// The parent only carries its Kind, so kinds are matched without their group.
+func (o Options) redactedParentName(parent tracingtypes.RequestParent, namespace string) string {
+	if !o.redacts() {
+		return parent.Name
+	}
+	obj := objectForKey(types.NamespacedName{Name: parent.Name, Namespace: namespace}, schema.GroupVersionKind{Kind: parent.Kind})
+	if o.Redaction.Func != nil {
+		name, _ := o.Redaction.Func(obj)
+		return name
+	}
+	for _, kind := range o.Redaction.Kinds {
+		if kind.Kind == parent.Kind {
+			return RedactValue(parent.Name)
+		}
+	}
+	return parent.Name
+}
+
+// redactedLinkAttributes returns the attributes of a span link to record in telemetry. The object name is redacted
+// by the rules of redactedParentName, since the link was recorded from a request parent.
+func (o Options) redactedLinkAttributes(attrs attribute.Set, namespace string) []attribute.KeyValue {
+	kvs := attrs.ToSlice()
+	name, ok := attrs.Value(tracingtypes.LinkObjectNameAttributeKey)
+	if !ok || !o.redacts() {
+		return kvs
+	}
+	kind, _ := attrs.Value(tracingtypes.LinkObjectKindAttributeKey)
+	redacted := o.redactedParentName(tracingtypes.RequestParent{Name: name.AsString(), Kind: kind.AsString()}, namespace)
+	for i := range kvs {
+		if kvs[i].Key == tracingtypes.LinkObjectNameAttributeKey {
+			kvs[i] = tracingtypes.LinkObjectNameAttributeKey.String(redacted)
+		}
+	}
+	return kvs
+}
+
+// objectForKey builds a metadata-only object for key so redaction can run before the object is read.
+func objectForKey(key types.NamespacedName, gvk schema.GroupVersionKind) client.Object {
+	obj := &metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+	}
+	obj.SetGroupVersionKind(gvk)
+	return obj
+}
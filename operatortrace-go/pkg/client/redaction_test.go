@@ -0,0 +1,129 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/redaction_test.go
+
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// exerciseSecret runs the common client calls against a Secret and returns the recorded telemetry as text.
+func exerciseSecret(t *testing.T, opts ...Option) (spans []string, logs string) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	var logOutput strings.Builder
+	logger := funcr.New(func(prefix, args string) { logOutput.WriteString(args + "\n") }, funcr.Options{})
+	k8sClient := fake.NewClientBuilder().Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logger, nil, opts...)
+
+	ctx := context.Background()
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "db-password", Namespace: "payments"},
+		Data:       map[string][]byte{"password": []byte("hunter2")},
+	}
+	require.NoError(t, tracingClient.Create(ctx, secret))
+
+	_, span, err := tracingClient.StartTrace(ctx, &tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "db-password", Namespace: "payments"}},
+		Parent:  tracingtypes.RequestParent{Kind: "Secret", Name: "db-password"},
+	}, &corev1.Secret{})
+	require.NoError(t, err)
+	span.End()
+
+	_, span, err = tracingClient.StartTrace(ctx, &tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "missing-password", Namespace: "payments"}},
+	}, &corev1.Secret{})
+	require.Error(t, err)
+	span.End()
+
+	require.NoError(t, tracingClient.Get(ctx, client.ObjectKeyFromObject(secret), secret))
+	secret.Data["password"] = []byte("correct horse")
+	require.NoError(t, tracingClient.Update(ctx, secret))
+	require.NoError(t, tracingClient.Delete(ctx, secret))
+
+	for _, s := range exporter.GetSpans() {
+		spans = append(spans, s.Name)
+		for _, event := range s.Events {
+			for _, attr := range event.Attributes {
+				spans = append(spans, attr.Value.Emit())
+			}
+		}
+	}
+	return spans, logOutput.String()
+}
+
+func TestRedactedKindsHideSecretNames(t *testing.T) {
+	spans, logs := exerciseSecret(t, WithRedactedKinds(corev1.SchemeGroupVersion.WithKind("Secret")))
+
+	assert.Contains(t, spans, "Create Secret "+RedactValue("db-password"))
+	assert.Contains(t, spans, "StartTrace Secret/"+RedactValue("db-password")+" Triggered By Changed Object Secret/"+RedactValue("db-password"))
+	assert.Contains(t, spans, "Get Secret "+RedactValue("db-password"))
+	assert.Contains(t, spans, "Update Secret "+RedactValue("db-password"))
+	assert.Contains(t, spans, "Delete Secret "+RedactValue("db-password"))
+	assert.Contains(t, spans, "StartTrace Unknown Object "+RedactValue("payments")+"/"+RedactValue("missing-password"))
+	for _, text := range append(spans, logs) {
+		assert.NotContains(t, text, "db-password")
+		assert.NotContains(t, text, "missing-password")
+	}
+	assert.Contains(t, logs, RedactValue("db-password"))
+}
+
+func TestRedactFunc(t *testing.T) {
+	spans, logs := exerciseSecret(t, WithRedactFunc(func(obj client.Object) (string, string) {
+		return "hidden", "hidden"
+	}))
+
+	assert.Contains(t, spans, "Create Secret hidden")
+	assert.Contains(t, spans, "Get Secret hidden")
+	assert.Contains(t, spans, "StartTrace Unknown Object hidden/hidden")
+	for _, text := range append(spans, logs) {
+		assert.NotContains(t, text, "db-password")
+	}
+}
+
+func TestNoRedactionByDefault(t *testing.T) {
+	spans, logs := exerciseSecret(t)
+
+	assert.Contains(t, spans, "Create Secret db-password")
+	assert.Contains(t, spans, "StartTrace Unknown Object payments/missing-password")
+	assert.Contains(t, logs, "db-password")
+	for _, text := range append(spans, logs) {
+		assert.NotContains(t, text, "redacted-")
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	assert.Equal(t, "", RedactValue(""))
+	assert.Equal(t, RedactValue("db-password"), RedactValue("db-password"))
+	assert.NotEqual(t, RedactValue("db-password"), RedactValue("db-username"))
+	assert.True(t, strings.HasPrefix(RedactValue("db-password"), "redacted-"))
+}
+
+func TestRedactedObjectKey(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	key := types.NamespacedName{Name: "db-password", Namespace: "payments"}
+
+	redacting := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil, WithRedactedKinds(corev1.SchemeGroupVersion.WithKind("Secret")))
+	assert.Equal(t, RedactValue("payments")+"/"+RedactValue("db-password"), RedactedObjectKey(redacting, key, &corev1.Secret{}))
+	assert.Equal(t, "payments/db-password", RedactedObjectKey(redacting, key, &corev1.ConfigMap{}))
+
+	plain := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil)
+	assert.Equal(t, "payments/db-password", RedactedObjectKey(plain, key, &corev1.Secret{}))
+}
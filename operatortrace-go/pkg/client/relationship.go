@@ -0,0 +1,45 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/relationship.go
+
+package client
+
+import (
+	"context"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyObjectKindRelationship attaches the trace stored on obj to the StartTrace span as configured for obj's kind
+// with WithRelationshipPerKind. A parent becomes the remote parent in the returned context; a link is added to
+// request and the returned object is nil, so the stored trace is not also used as the parent.
+// obj is returned unchanged when its kind is not configured or it carries no usable trace.
+func (tc *tracingClient) applyObjectKindRelationship(ctx context.Context, obj client.Object, request *tracingtypes.RequestWithTraceID) (context.Context, client.Object) {
+	opts := tc.options.withCallOptions(ctx)
+	if len(opts.RelationshipPerKind) == 0 {
+		return ctx, obj
+	}
+	gvk, err := tc.gvks.gvkForObject(obj, tc.scheme)
+	if err != nil {
+		return ctx, obj
+	}
+	rel, ok := opts.relationshipForKind(gvk.GroupKind())
+	if !ok {
+		return ctx, obj
+	}
+	lookup := lookupStoredTraceContext(obj, tc.scheme, opts)
+	if lookup.rootReason != "" {
+		return ctx, obj
+	}
+
+	if rel == TraceParentRelationshipParent {
+		if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			return ctx, obj
+		}
+		return trace.ContextWithRemoteSpanContext(ctx, lookup.spanContext), obj
+	}
+	request.AppendLinkedSpan(tracingtypes.LinkedSpan{TraceID: lookup.spanContext.TraceID().String(), SpanID: lookup.spanContext.SpanID().String()})
+	return ctx, nil
+}
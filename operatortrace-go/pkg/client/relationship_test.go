@@ -0,0 +1,130 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/relationship_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStartTraceRelationshipPerKind(t *testing.T) {
+	const (
+		configMapTraceID = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+		podTraceID       = "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+		parentSpanID     = "cccccccccccccccc"
+	)
+	relationships := WithRelationshipPerKind(map[schema.GroupKind]TraceParentRelationship{
+		{Kind: "ConfigMap"}: TraceParentRelationshipLink,
+		{Kind: "Pod"}:       TraceParentRelationshipParent,
+	})
+
+	tests := []struct {
+		name         string
+		parent       tracingtypes.RequestParent
+		annotateWith string
+		opts         []Option
+		expectParent string
+		expectLink   string
+	}{
+		{
+			name:       "request from a linked kind",
+			parent:     tracingtypes.RequestParent{TraceID: configMapTraceID, SpanID: parentSpanID, Kind: "ConfigMap", Name: "settings", EventKind: "Update"},
+			opts:       []Option{relationships},
+			expectLink: configMapTraceID,
+		},
+		{
+			name:         "request from a parented kind",
+			parent:       tracingtypes.RequestParent{TraceID: podTraceID, SpanID: parentSpanID, Kind: "Pod", Name: "pod", EventKind: "Update"},
+			opts:         []Option{relationships},
+			expectParent: podTraceID,
+		},
+		{
+			name:         "unlisted kind keeps the default",
+			parent:       tracingtypes.RequestParent{TraceID: configMapTraceID, SpanID: parentSpanID, Kind: "Secret", Name: "creds", EventKind: "Update"},
+			opts:         []Option{relationships},
+			expectParent: configMapTraceID,
+		},
+		{
+			name:         "stored trace of a linked object kind",
+			annotateWith: podTraceID,
+			opts:         []Option{WithRelationshipPerKind(map[schema.GroupKind]TraceParentRelationship{{Kind: "Pod"}: TraceParentRelationshipLink})},
+			expectLink:   podTraceID,
+		},
+		{
+			name:         "stored trace without per kind relationships",
+			annotateWith: podTraceID,
+			expectParent: podTraceID,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+			if tt.annotateWith != "" {
+				annotateObjectWithTraceIDs(t, pod, NewOptions(), tt.annotateWith, parentSpanID)
+			}
+			k8sClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, tt.opts...)
+
+			request := ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "pod", Namespace: "default"})
+			request.Parent = tt.parent
+			_, span, err := tracingClient.StartTrace(context.Background(), &request, &corev1.Pod{})
+			require.NoError(t, err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			if tt.expectParent != "" {
+				assert.Equal(t, tt.expectParent, spans[0].Parent.TraceID().String())
+				assert.Empty(t, spans[0].Links)
+				return
+			}
+			assert.False(t, spans[0].Parent.IsValid())
+			require.Len(t, spans[0].Links, 1)
+			assert.Equal(t, tt.expectLink, spans[0].Links[0].SpanContext.TraceID().String())
+		})
+	}
+}
+
+func TestWithRelationshipPerKind(t *testing.T) {
+	configMap := schema.GroupKind{Kind: "ConfigMap"}
+	appsConfigMap := schema.GroupKind{Group: "apps.example.com", Kind: "ConfigMap"}
+	requested := map[schema.GroupKind]TraceParentRelationship{configMap: TraceParentRelationshipParent}
+
+	opts := NewOptions(
+		WithRelationshipPerKind(requested),
+		WithRelationshipPerKind(map[schema.GroupKind]TraceParentRelationship{
+			appsConfigMap: TraceParentRelationshipLink,
+			{Kind: "Pod"}: "sometimes",
+		}),
+	)
+
+	assert.Equal(t, map[schema.GroupKind]TraceParentRelationship{
+		configMap:     TraceParentRelationshipParent,
+		appsConfigMap: TraceParentRelationshipLink,
+	}, opts.RelationshipPerKind)
+	assert.Len(t, requested, 1, "the caller's map is not modified")
+
+	// Request parents carry no group, and link wins when groups disagree
+	rel, ok := opts.relationshipForParentKind("ConfigMap")
+	assert.True(t, ok)
+	assert.Equal(t, TraceParentRelationshipLink, rel)
+	_, ok = opts.relationshipForParentKind("Pod")
+	assert.False(t, ok)
+}
@@ -0,0 +1,57 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/retry.go
+
+package client
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// ClientDelayRetryEvent is the event recorded on a write span before each retry of a request the API server asked to delay.
+const ClientDelayRetryEvent = "retrying after client delay"
+
+// RetryAfterAttributeKey records the delay, in seconds, the API server asked for when rejecting a write.
+const RetryAfterAttributeKey = attribute.Key("operatortrace.retry_after_seconds")
+
+// RetryAttemptAttributeKey records which retry of an operation a retry span or retry event covers, starting at 1.
+const RetryAttemptAttributeKey = attribute.Key("operatortrace.retry.attempt")
+
+// clientDelayUnit converts the Retry-After seconds into a wait; tests shorten it.
+var clientDelayUnit = time.Second
+
+// writeWithRetry calls write and, while the API server responds with a Retry-After delay, waits and retries it up to
+// the configured WithRetryOn429 count. Every attempt is recorded on span, and the last error is returned.
+func (tc *tracingClient) writeWithRetry(ctx context.Context, span trace.Span, write func() error) error {
+	maxRetries := tc.options.withCallOptions(ctx).MaxRetriesOn429
+	err := write()
+	for attempt := 1; err != nil; attempt++ {
+		seconds, ok := apierrors.SuggestsClientDelay(err)
+		if !ok {
+			return err
+		}
+		span.SetAttributes(RetryAfterAttributeKey.Int(seconds))
+		if attempt > maxRetries {
+			return err
+		}
+		span.AddEvent(ClientDelayRetryEvent, trace.WithAttributes(
+			RetryAttemptAttributeKey.Int(attempt),
+			RetryAfterAttributeKey.Int(seconds),
+			attribute.String("error", err.Error()),
+		))
+		timer := time.NewTimer(time.Duration(seconds) * clientDelayUnit)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		err = write()
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/retry_test.go
+
+package client
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestWriteRetryOn429(t *testing.T) {
+	defer func(unit time.Duration) { clientDelayUnit = unit }(clientDelayUnit)
+	clientDelayUnit = time.Millisecond
+
+	tooManyRequests := apierrors.NewTooManyRequests("slow down", 2)
+
+	tests := []struct {
+		name           string
+		opts           []Option
+		failures       int
+		failWith       error
+		expectedCalls  int
+		expectedEvents int
+		wantErr        bool
+	}{
+		{"retries until the write succeeds", []Option{WithRetryOn429(3)}, 2, tooManyRequests, 3, 2, false},
+		{"gives up after max retries", []Option{WithRetryOn429(2)}, 5, tooManyRequests, 3, 2, true},
+		{"no retries by default", nil, 1, tooManyRequests, 1, 0, true},
+		{"other errors are not retried", []Option{WithRetryOn429(3)}, 1, errors.New("boom"), 1, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			calls := 0
+			k8sClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+				Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+					calls++
+					if calls <= tt.failures {
+						return tt.failWith
+					}
+					return c.Create(ctx, obj, opts...)
+				},
+			}).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, tt.opts...)
+
+			err := tracingClient.Create(context.Background(), &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "busy-pod", Namespace: "default"}})
+			if tt.wantErr {
+				assert.ErrorIs(t, err, tt.failWith)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.expectedCalls, calls)
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			retries := 0
+			for _, event := range spans[0].Events {
+				if event.Name == ClientDelayRetryEvent {
+					retries++
+					assert.Contains(t, event.Attributes, RetryAttemptAttributeKey.Int(retries))
+				}
+			}
+			assert.Equal(t, tt.expectedEvents, retries)
+			if tt.failWith == tooManyRequests {
+				assert.Contains(t, spans[0].Attributes, RetryAfterAttributeKey.Int(2))
+			}
+		})
+	}
+}
+
+func TestWriteRetryOn429StopsWhenContextIsDone(t *testing.T) {
+	calls := 0
+	k8sClient := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Delete: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.DeleteOption) error {
+			calls++
+			return apierrors.NewTooManyRequests("slow down", 60)
+		},
+	}).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil, WithRetryOn429(3))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := tracingClient.Delete(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "busy-pod", Namespace: "default"}})
+	assert.True(t, apierrors.IsTooManyRequests(err))
+	assert.Equal(t, 1, calls)
+}
+
+func TestEndTraceRetriesConflicts(t *testing.T) {
+	conflict := apierrors.NewConflict(schema.GroupResource{Resource: "pods"}, "traced-pod", errors.New("the object has been modified"))
+
+	tests := []struct {
+		name            string
+		conflicts       int
+		otherTrace      bool
+		expectedRetries int
+		wantCleared     bool
+	}{
+		{"retries until the patch succeeds", 2, false, 2, true},
+		{"gives up after max retries", 5, false, 3, false},
+		{"stops when another trace was written", 1, true, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			conflicts := 0
+			k8sClient := fake.NewClientBuilder().WithStatusSubresource(&corev1.Pod{}).WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					if conflicts < tt.conflicts {
+						conflicts++
+						if tt.otherTrace {
+							// Another reconcile wrote its trace, which is what made the patch conflict
+							stored := &corev1.Pod{}
+							require.NoError(t, c.Get(ctx, client.ObjectKeyFromObject(obj), stored))
+							stored.Annotations[constants.DefaultTraceParentAnnotation] = "00-11111111111111111111111111111111-2222222222222222-01"
+							require.NoError(t, c.Update(ctx, stored))
+						}
+						return conflict
+					}
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			}).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, WithEndTraceRetry(3), WithTraceSummarySpans())
+
+			ctx := context.Background()
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "traced-pod", Namespace: "default"}}
+			require.NoError(t, tracingClient.Create(ctx, pod))
+			exporter.Reset()
+
+			// Like other annotation patch failures, a conflict left after the retries is only recorded on the span
+			require.NoError(t, tracingClient.EndTrace(ctx, pod))
+
+			stored := &corev1.Pod{}
+			require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+			assert.Equal(t, tt.wantCleared, stored.Annotations[constants.DefaultTraceParentAnnotation] == "")
+
+			var endTrace tracetest.SpanStub
+			var retries []tracetest.SpanStub
+			summaries := 0
+			for _, span := range exporter.GetSpans() {
+				if strings.HasPrefix(span.Name, "Retry EndTrace") {
+					retries = append(retries, span)
+				} else if strings.HasPrefix(span.Name, "EndTrace") {
+					endTrace = span
+				} else if strings.HasPrefix(span.Name, "TraceSummary") {
+					summaries++
+				}
+			}
+			require.True(t, endTrace.SpanContext.IsValid())
+			// Only a trace that was actually cleared is summarized
+			assert.Equal(t, tt.wantCleared, summaries == 1)
+			require.Len(t, retries, tt.expectedRetries)
+			for i, retry := range retries {
+				assert.Equal(t, endTrace.SpanContext.SpanID(), retry.Parent.SpanID())
+				assert.Equal(t, endTrace.InstrumentationScope, retry.InstrumentationScope, "retry spans use the client's tracer")
+				require.Len(t, retry.Links, 1)
+				assert.Equal(t, endTrace.SpanContext, retry.Links[0].SpanContext)
+				attrs := attribute.NewSet(retry.Attributes...)
+				attempt, _ := attrs.Value(RetryAttemptAttributeKey)
+				assert.Equal(t, int64(i+1), attempt.AsInt64())
+			}
+		})
+	}
+}
+
+func TestEndTraceWithoutRetryDoesNotRetryConflicts(t *testing.T) {
+	patches := 0
+	k8sClient := fake.NewClientBuilder().WithStatusSubresource(&corev1.Pod{}).WithInterceptorFuncs(interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+			patches++
+			return apierrors.NewConflict(schema.GroupResource{Resource: "pods"}, obj.GetName(), errors.New("conflict"))
+		},
+	}).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil)
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "traced-pod", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(context.Background(), pod))
+	_ = tracingClient.EndTrace(context.Background(), pod)
+	assert.Equal(t, 1, patches)
+}
@@ -0,0 +1,145 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/root_reason.go
+
+package client
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// RootReasonAttributeKey records on a StartTrace span that starts a new trace why the reconcile did not continue
+// an existing one. Its value is one of the RootReason constants.
+const RootReasonAttributeKey = attribute.Key("operatortrace.root_reason")
+
+// PreviousTraceIDAttributeKey records the trace ID a new root replaces, when the stored trace was expired or invalid.
+const PreviousTraceIDAttributeKey = attribute.Key("operatortrace.previous_trace_id")
+
+// StoredContextAgeAttributeKey records, in milliseconds, how old the trace context stored on the object was when
+// a span read it. It is only set when the stored context carries a timestamp.
+const StoredContextAgeAttributeKey = attribute.Key("operatortrace.stored_context_age_ms")
+
+// StoredTraceContextExpiredEvent is recorded on a span whose object carried a trace context that had expired.
+const StoredTraceContextExpiredEvent = "stored trace context expired"
+
+const (
+	// RootReasonNoStoredContext means neither the object nor the request carried a trace context.
+	RootReasonNoStoredContext = "no_stored_context"
+	// RootReasonExpired means the stored trace context is older than the trace expiration.
+	RootReasonExpired = "expired"
+	// RootReasonInvalid means the stored trace context could not be parsed or was rejected by the AnnotationCodec.
+	RootReasonInvalid = "invalid"
+	// RootReasonSuppressed means a trace context was available but configured to be linked rather than continued.
+	RootReasonSuppressed = "suppressed"
+)
+
+// storedTraceLookup is the trace context stored on an object, or why none can be continued.
+type storedTraceLookup struct {
+	stored      storedTraceContext
+	spanContext trace.SpanContext
+
+	// rootReason is empty when stored can be used, otherwise one of the RootReason constants.
+	rootReason      string
+	previousTraceID string
+
+	// rejectErr is set when the AnnotationCodec rejected the stored trace context.
+	rejectErr error
+}
+
+// lookupStoredTraceContext reads the trace context stored on obj, from its annotations first and then from its
+// TraceID/SpanID conditions.
+func lookupStoredTraceContext(obj client.Object, scheme *runtime.Scheme, opts Options) storedTraceLookup {
+	stored, ok, err := decodeTraceContextFromAnnotations(obj.GetAnnotations(), opts)
+	if err != nil {
+		// A rejected annotation means the object was tampered with, so do not fall back to its conditions either
+		return storedTraceLookup{rootReason: RootReasonInvalid, rejectErr: err}
+	}
+	lookup := storedTraceLookup{rootReason: RootReasonNoStoredContext}
+	if ok {
+		if lookup = checkStoredTraceContext(stored, opts); lookup.rootReason == "" {
+			return lookup
+		}
+	}
+	if stored, ok := extractTraceContextFromConditions(obj, scheme); ok {
+		// Report why the annotation could not be used over a condition that cannot be used either
+		if fromConditions := checkStoredTraceContext(stored, opts); fromConditions.rootReason == "" || lookup.rootReason == RootReasonNoStoredContext {
+			return fromConditions
+		}
+	}
+	return lookup
+}
+
+// checkStoredTraceContext parses stored and reports whether it is still usable.
+func checkStoredTraceContext(stored storedTraceContext, opts Options) storedTraceLookup {
+	spanContext, err := tracecontext.SpanContextFromTraceData(stored.TraceParent, stored.TraceState)
+	if err != nil {
+		return storedTraceLookup{stored: stored, rootReason: RootReasonInvalid, previousTraceID: traceIDFromTraceParent(stored.TraceParent)}
+	}
+	if traceContextExpired(stored.Timestamp, opts) && opts.TraceExpirationPolicy != ExpirationPolicyExtend {
+		return storedTraceLookup{stored: stored, spanContext: spanContext, rootReason: RootReasonExpired, previousTraceID: spanContext.TraceID().String()}
+	}
+	return storedTraceLookup{stored: stored, spanContext: spanContext}
+}
+
+// StoredTraceAge returns how old the trace context stored in the annotations of obj is, according to the timestamp
+// recorded with it. It returns false when obj carries no trace context or one without a timestamp.
+func StoredTraceAge(obj client.Object, opts Options) (time.Duration, bool) {
+	stored, ok := extractTraceContextFromAnnotations(obj.GetAnnotations(), opts)
+	if !ok {
+		return 0, false
+	}
+	return storedContextAge(stored)
+}
+
+// storedContextAge returns the age of stored, if it carries a timestamp.
+func storedContextAge(stored storedTraceContext) (time.Duration, bool) {
+	if stored.Timestamp.IsZero() {
+		return 0, false
+	}
+	return time.Since(stored.Timestamp), true
+}
+
+// rootReasonAttributes returns the attributes explaining why a StartTrace span for obj starts a new trace, or nil
+// when obj's stored trace becomes its parent. A nil obj means the request's trace was deliberately only linked.
+func rootReasonAttributes(obj client.Object, scheme *runtime.Scheme, opts Options) []attribute.KeyValue {
+	if obj == nil {
+		return []attribute.KeyValue{RootReasonAttributeKey.String(RootReasonSuppressed)}
+	}
+	lookup := lookupStoredTraceContext(obj, scheme, opts)
+	if lookup.rootReason == "" {
+		relationship := lookup.stored.Relationship
+		if relationship == "" {
+			relationship = opts.IncomingTraceRelationship
+		}
+		if relationship == TraceParentRelationshipParent {
+			return nil
+		}
+		return []attribute.KeyValue{RootReasonAttributeKey.String(RootReasonSuppressed)}
+	}
+	attrs := []attribute.KeyValue{RootReasonAttributeKey.String(lookup.rootReason)}
+	if lookup.previousTraceID != "" {
+		attrs = append(attrs, PreviousTraceIDAttributeKey.String(lookup.previousTraceID))
+	}
+	return attrs
+}
+
+// traceIDFromTraceParent returns the trace ID of a traceparent that failed to parse as a whole, or "" if the
+// trace ID itself is malformed.
+func traceIDFromTraceParent(traceParent string) string {
+	parts := strings.Split(traceParent, "-")
+	if len(parts) < 2 {
+		return ""
+	}
+	traceID, err := trace.TraceIDFromHex(parts[1])
+	if err != nil {
+		return ""
+	}
+	return traceID.String()
+}
@@ -0,0 +1,198 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/root_reason_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestStartTraceRootReason(t *testing.T) {
+	validTraceParent := "00-" + testTraceIDHex + "-" + testSpanIDHex + "-01"
+
+	tests := []struct {
+		name               string
+		annotations        map[string]string
+		parent             tracingtypes.RequestParent
+		opts               []Option
+		expectedReason     string
+		expectedPreviousID string
+	}{
+		{
+			name:           "no stored context",
+			expectedReason: RootReasonNoStoredContext,
+		},
+		{
+			name: "expired",
+			annotations: map[string]string{
+				constants.DefaultTraceParentAnnotation: validTraceParent,
+				constants.DefaultTraceStateAnnotation:  constants.TraceStateTimestampKey + "=2020-01-01T00:00:00Z",
+			},
+			expectedReason:     RootReasonExpired,
+			expectedPreviousID: testTraceIDHex,
+		},
+		{
+			name:               "malformed traceparent",
+			annotations:        map[string]string{constants.DefaultTraceParentAnnotation: "00-" + testTraceIDHex + "-not-a-span-01"},
+			expectedReason:     RootReasonInvalid,
+			expectedPreviousID: testTraceIDHex,
+		},
+		{
+			name:           "rejected by the annotation codec",
+			annotations:    map[string]string{constants.DefaultTraceParentAnnotation: validTraceParent},
+			opts:           []Option{WithAnnotationCodec(newTestHMACAnnotationCodec(t, "secret"))},
+			expectedReason: RootReasonInvalid,
+		},
+		{
+			name:           "incoming trace is only linked",
+			annotations:    map[string]string{"example.com/traceparent": validTraceParent},
+			opts:           []Option{WithIncomingTraceParentAnnotation("example.com/traceparent")},
+			expectedReason: RootReasonSuppressed,
+		},
+		{
+			name:           "request trace is not inherited",
+			parent:         tracingtypes.RequestParent{TraceID: testTraceIDHex, SpanID: testSpanIDHex, Kind: "Pod", Name: "sample", ChangedFields: "status"},
+			opts:           []Option{WithInheritTraceOn("spec")},
+			expectedReason: RootReasonSuppressed,
+		},
+		{
+			name:        "stored trace is continued",
+			annotations: map[string]string{constants.DefaultTraceParentAnnotation: validTraceParent},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default", Annotations: tt.annotations}}
+			k8sClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, tt.opts...)
+
+			_, span, err := tracingClient.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "sample", Namespace: "default"}},
+				Parent:  tt.parent,
+			}, &corev1.Pod{})
+			require.NoError(t, err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			attrs := attribute.NewSet(spans[0].Attributes...)
+			reason, found := attrs.Value(RootReasonAttributeKey)
+			if tt.expectedReason == "" {
+				assert.False(t, found)
+				assert.True(t, spans[0].Parent.IsValid())
+				return
+			}
+			assert.False(t, spans[0].Parent.IsValid())
+			assert.Equal(t, tt.expectedReason, reason.AsString())
+			previousID, found := attrs.Value(PreviousTraceIDAttributeKey)
+			assert.Equal(t, tt.expectedPreviousID != "", found)
+			assert.Equal(t, tt.expectedPreviousID, previousID.AsString())
+		})
+	}
+}
+
+func TestStoredContextAge(t *testing.T) {
+	validTraceParent := "00-" + testTraceIDHex + "-" + testSpanIDHex + "-01"
+	recent := time.Now().Add(-5 * time.Minute).UTC()
+	expired := time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		annotations   map[string]string
+		expectedAge   time.Duration
+		expectAge     bool
+		expectExpired bool
+	}{
+		{
+			name: "recent",
+			annotations: map[string]string{
+				constants.DefaultTraceParentAnnotation: validTraceParent,
+				constants.DefaultTraceStateAnnotation:  constants.TraceStateTimestampKey + "=" + recent.Format(time.RFC3339),
+			},
+			expectedAge: time.Since(recent),
+			expectAge:   true,
+		},
+		{
+			name: "expired",
+			annotations: map[string]string{
+				constants.DefaultTraceParentAnnotation: validTraceParent,
+				constants.DefaultTraceStateAnnotation:  constants.TraceStateTimestampKey + "=" + expired.Format(time.RFC3339),
+			},
+			expectedAge:   time.Since(expired),
+			expectAge:     true,
+			expectExpired: true,
+		},
+		{
+			name:        "without timestamp",
+			annotations: map[string]string{constants.DefaultTraceParentAnnotation: validTraceParent},
+		},
+		{
+			name: "no stored context",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default", Annotations: tt.annotations}}
+			age, ok := StoredTraceAge(pod, NewOptions())
+			require.Equal(t, tt.expectAge, ok)
+			assert.InDelta(t, tt.expectedAge.Seconds(), age.Seconds(), 60)
+
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			k8sClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil)
+			_, span, err := tracingClient.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "sample", Namespace: "default"}},
+			}, &corev1.Pod{})
+			require.NoError(t, err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			attrs := attribute.NewSet(spans[0].Attributes...)
+			ageMillis, found := attrs.Value(StoredContextAgeAttributeKey)
+			require.Equal(t, tt.expectAge, found)
+			if found {
+				assert.InDelta(t, tt.expectedAge.Milliseconds(), ageMillis.AsInt64(), float64(time.Minute.Milliseconds()))
+			}
+
+			var expiredEvents []sdktrace.Event
+			for _, event := range spans[0].Events {
+				if event.Name == StoredTraceContextExpiredEvent {
+					expiredEvents = append(expiredEvents, event)
+				}
+			}
+			if !tt.expectExpired {
+				assert.Empty(t, expiredEvents)
+				return
+			}
+			require.Len(t, expiredEvents, 1)
+			eventAttrs := attribute.NewSet(expiredEvents[0].Attributes...)
+			previousID, _ := eventAttrs.Value(PreviousTraceIDAttributeKey)
+			assert.Equal(t, testTraceIDHex, previousID.AsString())
+			_, found = eventAttrs.Value(StoredContextAgeAttributeKey)
+			assert.True(t, found)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/scheme_check.go
+
+package client
+
+import (
+	"errors"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// SchemeSubset verifies that every provided type is registered in the tracing client's scheme.
+// All missing registrations are reported together so they can be fixed in one pass.
+func (tc *tracingClient) SchemeSubset(types ...client.Object) error {
+	return schemeSubset(tc.scheme, types...)
+}
+
+// SetupTracingClient validates the manager's scheme before it starts so missing type registrations
+// surface as a descriptive startup error instead of failing on the first traced client call.
+func SetupTracingClient(mgr manager.Manager, types ...client.Object) error {
+	if mgr == nil {
+		return fmt.Errorf("manager must not be nil")
+	}
+	if mgr.GetScheme() == nil {
+		return fmt.Errorf("manager has no scheme configured")
+	}
+	if err := schemeSubset(mgr.GetScheme(), types...); err != nil {
+		return fmt.Errorf("tracing client setup failed: %w", err)
+	}
+	return nil
+}
+
+func schemeSubset(scheme *runtime.Scheme, types ...client.Object) error {
+	if scheme == nil {
+		return fmt.Errorf("scheme must not be nil")
+	}
+	var errs []error
+	for _, obj := range types {
+		if obj == nil {
+			continue
+		}
+		if _, err := apiutil.GVKForObject(obj, scheme); err != nil {
+			errs = append(errs, fmt.Errorf("type %T is not registered in the scheme: %w", obj, err))
+		}
+	}
+	return errors.Join(errs...)
+}
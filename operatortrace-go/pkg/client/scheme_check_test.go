@@ -0,0 +1,122 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/scheme_check_test.go
+
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// schemeManager is a manager stub that only serves a scheme.
+type schemeManager struct {
+	manager.Manager
+	scheme *runtime.Scheme
+}
+
+func (m schemeManager) GetScheme() *runtime.Scheme {
+	return m.scheme
+}
+
+func TestSchemeSubset(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+	k8sClient := fake.NewClientBuilder().WithScheme(scheme).Build()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, initTracer(), logr.Discard(), scheme)
+
+	tests := []struct {
+		name        string
+		types       []client.Object
+		errContains []string
+	}{
+		{name: "no types"},
+		{name: "registered types", types: []client.Object{&corev1.Pod{}, &corev1.ConfigMap{}}},
+		{
+			name:        "unregistered type",
+			types:       []client.Object{&corev1.Pod{}, &appsv1.Deployment{}},
+			errContains: []string{"*v1.Deployment"},
+		},
+		{
+			name:        "all missing registrations are reported",
+			types:       []client.Object{&appsv1.Deployment{}, &appsv1.StatefulSet{}},
+			errContains: []string{"*v1.Deployment", "*v1.StatefulSet"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tracingClient.SchemeSubset(tt.types...)
+			if len(tt.errContains) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+			for _, s := range tt.errContains {
+				assert.Contains(t, err.Error(), s)
+			}
+		})
+	}
+}
+
+func TestSetupTracingClient(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = corev1.AddToScheme(scheme)
+
+	assert.NoError(t, SetupTracingClient(schemeManager{scheme: scheme}, &corev1.Pod{}))
+
+	err := SetupTracingClient(schemeManager{scheme: scheme}, &corev1.Pod{}, &appsv1.Deployment{})
+	assert.ErrorContains(t, err, "tracing client setup failed")
+	assert.ErrorContains(t, err, "*v1.Deployment is not registered")
+
+	assert.Error(t, SetupTracingClient(schemeManager{}, &corev1.Pod{}))
+	assert.Error(t, SetupTracingClient(nil))
+}
+
+func TestWritesOfUnregisteredTypesStillSucceed(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	var logOutput strings.Builder
+	logger := funcr.New(func(prefix, args string) { logOutput.WriteString(args + "\n") }, funcr.Options{})
+	k8sClient := fake.NewClientBuilder().Build()
+	// The tracing client's scheme knows no types at all, while the wrapped client can write ConfigMaps
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logger, runtime.NewScheme())
+
+	ctx := context.Background()
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(ctx, cm))
+	cm.Data = map[string]string{"mode": "fast"}
+	require.NoError(t, tracingClient.Update(ctx, cm))
+	patched := cm.DeepCopy()
+	patched.Data["mode"] = "safe"
+	require.NoError(t, tracingClient.Patch(ctx, patched, client.MergeFrom(cm)))
+	require.NoError(t, tracingClient.Delete(ctx, patched))
+
+	var names []string
+	for _, span := range exporter.GetSpans() {
+		names = append(names, span.Name)
+	}
+	assert.Contains(t, names, "Create ConfigMap settings")
+	assert.Contains(t, names, "Update ConfigMap settings")
+	assert.Contains(t, names, "Patch ConfigMap settings")
+	assert.Contains(t, names, "Delete ConfigMap settings")
+	assert.Equal(t, 1, strings.Count(logOutput.String(), "not registered in the tracing client's scheme"))
+
+	// Failures of the wrapped client are still returned
+	assert.Error(t, tracingClient.Delete(ctx, patched))
+}
@@ -0,0 +1,60 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/span_budget.go
+
+package client
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanBudgetExceededEvent is the event recorded on the reconcile span once its span budget is exhausted.
+const SpanBudgetExceededEvent = "span budget exceeded"
+
+type spanBudgetKey struct{}
+
+// spanBudget counts the client-operation spans started under a single reconcile span.
+type spanBudget struct {
+	limit         int64
+	used          atomic.Int64
+	exceededOnce  sync.Once
+	reconcileSpan trace.Span
+}
+
+// withSpanBudget stores a fresh span budget for the reconcile span in ctx. A non-positive limit disables the budget.
+func withSpanBudget(ctx context.Context, reconcileSpan trace.Span, limit int) context.Context {
+	if limit <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, spanBudgetKey{}, &spanBudget{limit: int64(limit), reconcileSpan: reconcileSpan})
+}
+
+func spanBudgetFromContext(ctx context.Context) *spanBudget {
+	budget, _ := ctx.Value(spanBudgetKey{}).(*spanBudget)
+	return budget
+}
+
+// allow consumes one span from the budget and reports whether the span may be recorded.
+func (b *spanBudget) allow() bool {
+	if b.used.Add(1) <= b.limit {
+		return true
+	}
+	b.exceededOnce.Do(func() {
+		b.reconcileSpan.AddEvent(SpanBudgetExceededEvent, trace.WithAttributes(
+			attribute.Int64("operatortrace.span_budget.limit", b.limit),
+		))
+	})
+	return false
+}
+
+// nonRecordingSpanFromContext returns a span that shares the current span context but records nothing,
+// so code written against the span API keeps working while the budget is exhausted.
+func nonRecordingSpanFromContext(ctx context.Context) (context.Context, trace.Span) {
+	span := trace.SpanFromContext(trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx)))
+	return trace.ContextWithSpan(ctx, span), span
+}
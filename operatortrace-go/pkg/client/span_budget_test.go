@@ -0,0 +1,79 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/span_budget_test.go
+
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestMaxSpansPerReconcile(t *testing.T) {
+	tests := []struct {
+		name          string
+		opts          []Option
+		gets          int
+		expectedGets  int
+		expectedEvent bool
+	}{
+		{"budget caps spans", []Option{WithMaxSpansPerReconcile(5)}, 50, 5, true},
+		{"within budget", []Option{WithMaxSpansPerReconcile(5)}, 3, 3, false},
+		{"no budget", nil, 50, 50, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			defer func() { _ = tp.Shutdown(context.Background()) }()
+
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+			k8sClient := fake.NewClientBuilder().WithObjects(pod).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, tt.opts...)
+
+			req := tracingtypes.RequestWithTraceID{Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "pod", Namespace: "default"}}}
+			ctx, span, err := tracingClient.StartTrace(context.Background(), &req, &corev1.Pod{})
+			require.NoError(t, err)
+
+			for i := 0; i < tt.gets; i++ {
+				require.NoError(t, tracingClient.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{}))
+			}
+			span.End()
+
+			var getSpans, budgetEvents int
+			for _, s := range exporter.GetSpans() {
+				if strings.HasPrefix(s.Name, "Get Pod") {
+					getSpans++
+					assert.Equal(t, span.SpanContext().SpanID(), s.Parent.SpanID())
+				}
+				for _, event := range s.Events {
+					if event.Name == SpanBudgetExceededEvent {
+						budgetEvents++
+						assert.Equal(t, span.SpanContext().SpanID(), s.SpanContext.SpanID())
+					}
+				}
+			}
+			assert.Equal(t, tt.expectedGets, getSpans)
+			if tt.expectedEvent {
+				assert.Equal(t, 1, budgetEvents)
+			} else {
+				assert.Zero(t, budgetEvents)
+			}
+		})
+	}
+}
@@ -6,18 +6,22 @@ package client
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/types"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// sliceFromLinkedSpans converts a fixed array of LinkedSpan to OTEL links.
-func sliceFromLinkedSpans(linkedSpans [10]types.LinkedSpan) []trace.Link {
+// sliceFromLinkedSpans converts a fixed array of LinkedSpan to OTEL links, redacting their attributes as opts
+// redacts the objects of namespace.
+func sliceFromLinkedSpans(linkedSpans [10]types.LinkedSpan, opts Options, namespace string) []trace.Link {
 	links := make([]trace.Link, 0, len(linkedSpans))
 	for _, linkedSpan := range linkedSpans {
 		if linkedSpan.TraceID == "" || linkedSpan.SpanID == "" {
@@ -35,44 +39,210 @@ func sliceFromLinkedSpans(linkedSpans [10]types.LinkedSpan) []trace.Link {
 			TraceID: traceID,
 			SpanID:  spanID,
 			Remote:  true,
-		})})
+		}), Attributes: opts.redactedLinkAttributes(linkedSpan.LinkedSpanAttributes, namespace)})
 	}
 	return links
 }
 
 // startSpanFromContext starts a new span from the context and attaches trace information to the object.
 func startSpanFromContext(ctx context.Context, logger logr.Logger, tracer trace.Tracer, obj client.Object, scheme *runtime.Scheme, opts Options, operationName string, linkedSpansArray [10]types.LinkedSpan, spanOpts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	opts = opts.withCallOptions(ctx)
+
+	// An empty name is a span the span name registry suppressed
+	if operationName == "" {
+		return nonRecordingSpanFromContext(ctx)
+	}
+	if budget := spanBudgetFromContext(ctx); budget != nil && !budget.allow() {
+		return nonRecordingSpanFromContext(ctx)
+	}
+	if name := controllerNameFromContext(ctx); name != "" {
+		spanOpts = append(spanOpts, trace.WithAttributes(ControllerNameAttributeKey.String(name)))
+	}
+	if sample, ok := opts.labelSamplingDecision(obj); ok {
+		if !sample {
+			return labelUnsampledSpan(ctx, obj, scheme, opts)
+		}
+		spanOpts = append(spanOpts, trace.WithAttributes(LabelSampledAttributeKey.Bool(true)))
+	}
+
 	span := trace.SpanFromContext(ctx)
 	if span.SpanContext().IsValid() {
+		if opts.SuppressSpans {
+			return nonRecordingSpanFromContext(ctx)
+		}
+		if ctx, span, unsampled := unsampledChainSpan(ctx, opts); unsampled {
+			return ctx, span
+		}
+		if links := sliceFromLinkedSpans(linkedSpansArray, opts, namespaceOf(obj)); len(links) > 0 {
+			spanOpts = append(spanOpts, trace.WithLinks(links...))
+		}
 		return tracer.Start(ctx, operationName, spanOpts...)
 	}
 
+	if opts.SuppressSpans {
+		return suppressedSpan(ctx, obj, scheme, opts)
+	}
+
 	var (
 		incomingLink *trace.Link
-		applied      bool
+		rejectErr    error
+		expired      *storedTraceLookup
 	)
 
 	if obj != nil {
-		if storedCtx, ok := extractTraceContextFromAnnotations(obj.GetAnnotations(), opts); ok && !traceContextExpired(storedCtx.Timestamp, opts) {
-			ctx, incomingLink = applyStoredTraceContext(ctx, storedCtx, opts, incomingLink)
-			applied = true
+		lookup := lookupStoredTraceContext(obj, scheme, opts)
+		rejectErr = lookup.rejectErr
+		if age, ok := storedContextAge(lookup.stored); ok {
+			spanOpts = append(spanOpts, trace.WithAttributes(StoredContextAgeAttributeKey.Int64(age.Milliseconds())))
 		}
-		if !applied {
-			if storedCtx, ok := extractTraceContextFromConditions(obj, scheme); ok && !traceContextExpired(storedCtx.Timestamp, opts) {
-				ctx, incomingLink = applyStoredTraceContext(ctx, storedCtx, opts, incomingLink)
+		switch {
+		case lookup.rootReason == "":
+			ctx, incomingLink = applyStoredTraceContext(ctx, lookup.stored, opts, incomingLink)
+		case lookup.rootReason == RootReasonExpired:
+			expired = &lookup
+			if opts.TraceExpirationPolicy == ExpirationPolicyLink {
+				incomingLink = &trace.Link{SpanContext: lookup.spanContext}
 			}
 		}
 	}
 
-	links := sliceFromLinkedSpans(linkedSpansArray)
+	links := sliceFromLinkedSpans(linkedSpansArray, opts, namespaceOf(obj))
 	if incomingLink != nil {
 		links = append(links, *incomingLink)
 	}
+	if ownerLink, ok := ownerLinkFromObject(obj, opts); ok {
+		links = append(links, ownerLink)
+	}
 	if len(links) > 0 {
 		spanOpts = append(spanOpts, trace.WithLinks(links...))
 	}
 
-	return tracer.Start(ctx, operationName, spanOpts...)
+	if ctx, span, unsampled := unsampledChainSpan(ctx, opts); unsampled {
+		return ctx, span
+	}
+	ctx, span = tracer.Start(ctx, operationName, spanOpts...)
+	if rejectErr != nil {
+		logger.Info("Ignoring stored trace context rejected by the annotation codec", "error", rejectErr.Error())
+		span.AddEvent(TraceContextRejectedEvent, trace.WithAttributes(attribute.String("error", rejectErr.Error())))
+	}
+	if expired != nil {
+		age, _ := storedContextAge(expired.stored)
+		span.AddEvent(StoredTraceContextExpiredEvent, trace.WithAttributes(
+			StoredContextAgeAttributeKey.Int64(age.Milliseconds()),
+			PreviousTraceIDAttributeKey.String(expired.previousTraceID),
+		))
+	}
+	return ctx, span
+}
+
+// suppressedSpan returns a non-recording span that continues the trace stored on obj whatever the incoming trace
+// relationship, since a suppressed span must not start a trace of its own. Without a usable stored trace the span
+// belongs to no trace, so nothing is propagated.
+func suppressedSpan(ctx context.Context, obj client.Object, scheme *runtime.Scheme, opts Options) (context.Context, trace.Span) {
+	if obj != nil {
+		if lookup := lookupStoredTraceContext(obj, scheme, opts); lookup.rootReason == "" && lookup.spanContext.IsValid() {
+			ctx = trace.ContextWithRemoteSpanContext(ctx, lookup.spanContext)
+		}
+	}
+	return nonRecordingSpanFromContext(ctx)
+}
+
+// startRetrySpan starts the span of a retry of the operation whose span is in ctx. The retry span is a child of
+// that span and also links to it, so the retries of an operation can be followed as a chain.
+func startRetrySpan(ctx context.Context, tracer trace.Tracer, name string, attempt int, opts Options) (context.Context, trace.Span) {
+	opts = opts.withCallOptions(ctx)
+	if budget := spanBudgetFromContext(ctx); budget != nil && !budget.allow() {
+		return nonRecordingSpanFromContext(ctx)
+	}
+	if opts.SuppressSpans {
+		return nonRecordingSpanFromContext(ctx)
+	}
+	if ctx, span, unsampled := unsampledChainSpan(ctx, opts); unsampled {
+		return ctx, span
+	}
+	attrs := []attribute.KeyValue{RetryAttemptAttributeKey.Int(attempt)}
+	if controllerName := controllerNameFromContext(ctx); controllerName != "" {
+		attrs = append(attrs, ControllerNameAttributeKey.String(controllerName))
+	}
+	parent := trace.SpanFromContext(ctx)
+	return tracer.Start(ctx, name,
+		trace.WithLinks(trace.Link{SpanContext: parent.SpanContext()}),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+type requestKey struct{}
+
+// requestRedactionKey stores the options StartTrace redacted the request with.
+type requestRedactionKey struct{}
+
+// contextWithRequest stores the request being reconciled so later client calls can reuse its trace metadata.
+// opts are kept to redact the request the same way in spans started without the client, e.g. by StartLinkedSpan.
+func contextWithRequest(ctx context.Context, request types.RequestWithTraceID, opts Options) context.Context {
+	ctx = context.WithValue(ctx, requestRedactionKey{}, opts)
+	return context.WithValue(ctx, requestKey{}, request)
+}
+
+// linkedSpansFromContext returns the linked spans of the request stored by StartTrace, if any.
+func linkedSpansFromContext(ctx context.Context) [10]types.LinkedSpan {
+	request, _ := ctx.Value(requestKey{}).(types.RequestWithTraceID)
+	return request.LinkedSpans
+}
+
+// linksFromContext returns the links to the linked spans of the request stored by StartTrace, if any.
+func linksFromContext(ctx context.Context) []trace.Link {
+	request, _ := ctx.Value(requestKey{}).(types.RequestWithTraceID)
+	opts, _ := ctx.Value(requestRedactionKey{}).(Options)
+	return sliceFromLinkedSpans(request.LinkedSpans, opts, request.Namespace)
+}
+
+// namespaceOf returns the namespace of obj, or "" when obj is nil.
+func namespaceOf(obj client.Object) string {
+	if obj == nil {
+		return ""
+	}
+	return obj.GetNamespace()
+}
+
+// controllerNameFromContext returns the controller name of the request stored by StartTrace, if any.
+func controllerNameFromContext(ctx context.Context) string {
+	request, _ := ctx.Value(requestKey{}).(types.RequestWithTraceID)
+	return request.ControllerName
+}
+
+// applyRequestParent applies the parent of request to obj the way StartTrace does. An inherited parent is stored
+// on obj, so the span continues it, unless the parent selection policy keeps the trace obj carries and links the
+// parent instead. With an AnnotationCodec, a parent the codec did not verify is linked too, as storing it on obj would
+// sign a trace context anyone could have written. Otherwise, e.g. for an update that did not change an inherited
+// field, the parent is only linked, and the stale trace on obj must not become the parent either, so no object is
+// returned to start the span from.
+// It returns the request with the links to add and whether the parent was inherited.
+func applyRequestParent(request types.RequestWithTraceID, obj client.Object, scheme *runtime.Scheme, opts Options) (client.Object, types.RequestWithTraceID, bool) {
+	if opts.inheritsTrace(request.Parent) {
+		if opts.prefersStoredParent(request, obj, scheme) || (opts.AnnotationCodec != nil && !request.Parent.Verified) {
+			request.AppendLinkedSpan(types.LinkedSpan{TraceID: request.Parent.TraceID, SpanID: request.Parent.SpanID})
+			return obj, request, true
+		}
+		overrideTraceContextFromRequest(request, obj, opts)
+		return obj, request, true
+	}
+	request.AppendLinkedSpan(types.LinkedSpan{TraceID: request.Parent.TraceID, SpanID: request.Parent.SpanID})
+	return nil, request, false
+}
+
+// startTraceOperationName names the StartTrace span of the object named name, of kind gvk unless gvkErr is set,
+// after the object that triggered request when it is known.
+func startTraceOperationName(request types.RequestWithTraceID, gvk schema.GroupVersionKind, gvkErr error, name string, opts Options) string {
+	objectKind := ""
+	if gvkErr == nil {
+		objectKind = gvk.GroupKind().Kind
+	}
+	callerName := opts.redactedParentName(request.Parent, request.Namespace)
+	callerKind := request.Parent.Kind
+	if callerKind != "" && callerName != "" {
+		return fmt.Sprintf("StartTrace %s/%s Triggered By Changed Object %s/%s", objectKind, name, callerKind, callerName)
+	}
+	return fmt.Sprintf("StartTrace %s %s", objectKind, name)
 }
 
 func startSpanFromContextGeneric(ctx context.Context, logger logr.Logger, tracer trace.Tracer, operationName string) (context.Context, trace.Span) {
@@ -136,5 +306,6 @@ func extractTraceContextFromConditions(obj client.Object, scheme *runtime.Scheme
 		TraceParent:  traceParent,
 		Timestamp:    timestamp,
 		Relationship: TraceParentRelationshipParent,
+		Source:       TraceContextSourceCondition,
 	}, true
 }
@@ -0,0 +1,91 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/span_names.go
+
+package client
+
+import (
+	"fmt"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SpanNameFunc names the span of an operation on the object kind/namespace/name, already redacted as configured.
+// Returning "" suppresses the span: the operation runs under a non-recording span instead.
+type SpanNameFunc func(kind, namespace, name string) string
+
+// SpanNameRegistry holds the span name formatters of client operations, e.g. to name every "Get Secret" span
+// the same so backends aggregating by span name group them. It is safe for concurrent use, so formatters can be
+// registered while the client is in use.
+type SpanNameRegistry struct {
+	mu         sync.RWMutex
+	formatters map[string]SpanNameFunc
+}
+
+// NewSpanNameRegistry returns an empty SpanNameRegistry.
+func NewSpanNameRegistry() *SpanNameRegistry {
+	return &SpanNameRegistry{formatters: map[string]SpanNameFunc{}}
+}
+
+// Register makes fn name the spans matching pattern: an operation such as "Get", "Create" or "StatusUpdate",
+// or an operation and a kind such as "Get Secret", which takes precedence over the operation alone. Registering a
+// pattern again replaces its formatter. Empty patterns and nil formatters are ignored.
+func (r *SpanNameRegistry) Register(pattern string, fn SpanNameFunc) {
+	if pattern == "" || fn == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.formatters == nil {
+		r.formatters = map[string]SpanNameFunc{}
+	}
+	r.formatters[pattern] = fn
+}
+
+// lookup returns the formatter registered for operation on kind, or nil when there is none.
+func (r *SpanNameRegistry) lookup(operation, kind string) SpanNameFunc {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if fn, ok := r.formatters[operation+" "+kind]; ok {
+		return fn
+	}
+	return r.formatters[operation]
+}
+
+// WithSpanNameRegistry names the spans of client operations with the formatters of registry, falling back to the
+// default "<operation> <kind> <name>" for operations it has no formatter for.
+func WithSpanNameRegistry(registry *SpanNameRegistry) Option {
+	return func(o *Options) {
+		if registry == nil {
+			return
+		}
+		o.SpanNameRegistry = registry
+	}
+}
+
+// spanName returns the name of the span of operation on obj of kind, or "" when the registry suppresses it.
+func (o Options) spanName(operation, kind string, obj client.Object, gvk schema.GroupVersionKind) string {
+	name, namespace := o.redact(obj, gvk)
+	return o.formatSpanName(operation, kind, namespace, name)
+}
+
+// keySpanName is spanName for the object at key.
+func (o Options) keySpanName(operation string, key types.NamespacedName, gvk schema.GroupVersionKind) string {
+	if !o.redacts() {
+		return o.formatSpanName(operation, gvk.Kind, key.Namespace, key.Name)
+	}
+	return o.spanName(operation, gvk.Kind, objectForKey(key, gvk), gvk)
+}
+
+func (o Options) formatSpanName(operation, kind, namespace, name string) string {
+	if fn := o.SpanNameRegistry.lookup(operation, kind); fn != nil {
+		return fn(kind, namespace, name)
+	}
+	return fmt.Sprintf("%s %s %s", operation, kind, name)
+}
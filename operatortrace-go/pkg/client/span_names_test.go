@@ -0,0 +1,80 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/span_names_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestSpanNameRegistryLookup(t *testing.T) {
+	registry := NewSpanNameRegistry()
+	registry.Register("Get", func(kind, namespace, name string) string { return "Get " + kind })
+	registry.Register("Get Secret", func(kind, namespace, name string) string { return "Get Secret in " + namespace })
+	registry.Register("", func(kind, namespace, name string) string { return "ignored" })
+	registry.Register("Create", nil)
+	opts := NewOptions(WithSpanNameRegistry(registry))
+
+	tests := []struct {
+		name      string
+		operation string
+		kind      string
+		expected  string
+	}{
+		{"operation and kind", "Get", "Secret", "Get Secret in default"},
+		{"operation only", "Get", "ConfigMap", "Get ConfigMap"},
+		{"unregistered operation", "Update", "Secret", "Update Secret app"},
+		{"nil formatter ignored", "Create", "Secret", "Create Secret app"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, opts.formatSpanName(tt.operation, tt.kind, "default", "app"))
+		})
+	}
+
+	assert.Equal(t, "Get Secret app", NewOptions(WithSpanNameRegistry(nil)).formatSpanName("Get", "Secret", "default", "app"))
+}
+
+func TestSpanNameRegistryClientSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	registry := NewSpanNameRegistry()
+	registry.Register("Get Secret", func(kind, namespace, name string) string { return "Get Secret" })
+	registry.Register("Get ConfigMap", func(kind, namespace, name string) string { return "" })
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret-1", Namespace: "default"}},
+		&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "secret-2", Namespace: "default"}},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm", Namespace: "default"}},
+	).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, WithSpanNameRegistry(registry))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "reconcile")
+	for _, name := range []string{"secret-1", "secret-2"} {
+		require.NoError(t, tracingClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: name}, &corev1.Secret{}))
+	}
+	// Suppressed spans still perform the operation
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, tracingClient.Get(ctx, client.ObjectKey{Namespace: "default", Name: "cm"}, cm))
+	assert.Equal(t, "cm", cm.Name)
+	require.NoError(t, tracingClient.Create(ctx, &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}))
+	parent.End()
+
+	var names []string
+	for _, s := range exporter.GetSpans() {
+		names = append(names, s.Name)
+	}
+	assert.ElementsMatch(t, []string{"Get Secret", "Get Secret", "Create Pod pod", "reconcile"}, names)
+}
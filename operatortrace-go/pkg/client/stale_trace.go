@@ -0,0 +1,54 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/stale_trace.go
+
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrUnsupportedTracingClient is returned for TracingClient implementations not created by this package.
+var ErrUnsupportedTracingClient = errors.New("tracing client was not created by this package")
+
+// ClearStaleTraceContext clears the trace annotations of obj when the trace context stored on it is older than the
+// trace expiration of tc by more than margin, typically because the reconcile that wrote it never reached EndTrace.
+// Like EndTrace, it re-reads obj first and leaves it alone when the stored trace context has changed since obj was
+// read, and the patch is guarded by the resource version, so a trace written meanwhile is never cleared. It reports
+// whether the annotations were cleared; objects deleted or changed meanwhile are skipped without an error.
+func ClearStaleTraceContext(ctx context.Context, tc TracingClient, obj client.Object, margin time.Duration) (bool, error) {
+	impl, ok := tc.(*tracingClient)
+	if !ok {
+		return false, ErrUnsupportedTracingClient
+	}
+	opts := impl.options.withCallOptions(ctx)
+	age, ok := StoredTraceAge(obj, opts)
+	if !ok || age <= opts.traceExpiration()+margin {
+		return false, nil
+	}
+
+	current := obj.DeepCopyObject().(client.Object)
+	if err := impl.Reader.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	currentStored, _ := extractTraceContextFromAnnotations(current.GetAnnotations(), opts)
+	desiredStored, _ := extractTraceContextFromAnnotations(obj.GetAnnotations(), opts)
+	if currentStored.TraceParent != desiredStored.TraceParent {
+		return false, nil
+	}
+
+	patch := clearTraceAnnotationsPatch(current, opts, true)
+	if err := impl.Client.Patch(ctx, current, patch, impl.patchOptions(ctx, nil)...); err != nil {
+		if apierrors.IsConflict(err) {
+			return false, nil
+		}
+		return false, client.IgnoreNotFound(err)
+	}
+	obj.SetAnnotations(current.GetAnnotations())
+	return true, nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/stale_trace_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClearStaleTraceContext(t *testing.T) {
+	stale, err := tracecontext.SetTraceStateKey("", constants.TraceStateTimestampKey, time.Now().Add(-2*constants.DefaultTraceExpiration).UTC().Format(time.RFC3339Nano))
+	require.NoError(t, err)
+	staleAnnotations := func(spanID string) map[string]string {
+		traceParent, err := tracecontext.TraceParentFromIDs("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", spanID)
+		require.NoError(t, err)
+		return map[string]string{
+			constants.DefaultTraceParentAnnotation: traceParent,
+			constants.DefaultTraceStateAnnotation:  stale,
+		}
+	}
+
+	tests := []struct {
+		name          string
+		listed        map[string]string
+		stored        map[string]string
+		margin        time.Duration
+		expectCleared bool
+	}{
+		{"stale trace is cleared", staleAnnotations("bbbbbbbbbbbbbbbb"), staleAnnotations("bbbbbbbbbbbbbbbb"), 0, true},
+		{"trace within the margin is kept", staleAnnotations("bbbbbbbbbbbbbbbb"), staleAnnotations("bbbbbbbbbbbbbbbb"), 2 * constants.DefaultTraceExpiration, false},
+		{"trace written since the object was listed is kept", staleAnnotations("bbbbbbbbbbbbbbbb"), staleAnnotations("cccccccccccccccc"), 0, false},
+		{"object without trace is kept", nil, nil, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default", Annotations: tt.stored}}
+			k8sClient := fake.NewClientBuilder().WithObjects(stored).Build()
+			tc := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil)
+
+			listed := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "config", Namespace: "default", Annotations: tt.listed}}
+			cleared, err := ClearStaleTraceContext(context.Background(), tc, listed, tt.margin)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectCleared, cleared)
+
+			current := &corev1.ConfigMap{}
+			require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(stored), current))
+			if tt.expectCleared {
+				assert.Empty(t, current.Annotations[constants.DefaultTraceParentAnnotation])
+			} else {
+				assert.Equal(t, tt.stored[constants.DefaultTraceParentAnnotation], current.Annotations[constants.DefaultTraceParentAnnotation])
+			}
+		})
+	}
+
+	_, err = ClearStaleTraceContext(context.Background(), nil, &corev1.ConfigMap{}, 0)
+	assert.ErrorIs(t, err, ErrUnsupportedTracingClient)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/stored_trace.go
+
+package client
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TraceContextSource is where the trace context stored on an object was read from.
+type TraceContextSource string
+
+const (
+	// TraceContextSourceAnnotation is the traceparent and tracestate annotations.
+	TraceContextSourceAnnotation TraceContextSource = "annotation"
+	// TraceContextSourceCondition is the TraceID and SpanID status conditions.
+	TraceContextSourceCondition TraceContextSource = "condition"
+	// TraceContextSourceLegacy is the legacy trace ID and span ID annotations.
+	TraceContextSourceLegacy TraceContextSource = "legacy"
+)
+
+// StoredTrace is the trace context stored on an object.
+type StoredTrace struct {
+	TraceID string
+	SpanID  string
+	Source  TraceContextSource
+	// Timestamp is when the trace context was stored, zero when it was stored without one.
+	Timestamp time.Time
+	// Expired is set when the trace context is older than the trace expiration of the options.
+	Expired bool
+}
+
+// LookupStoredTrace returns the trace context stored on obj, read the way StartTrace reads it: from the
+// annotations first and then from the TraceID/SpanID conditions, which scheme is used to find. It returns false
+// when obj carries no trace context that parses, or the AnnotationCodec rejects it.
+func LookupStoredTrace(obj client.Object, scheme *runtime.Scheme, opts Options) (StoredTrace, bool) {
+	lookup := lookupStoredTraceContext(obj, scheme, opts)
+	if !lookup.spanContext.IsValid() {
+		return StoredTrace{}, false
+	}
+	return StoredTrace{
+		TraceID:   lookup.spanContext.TraceID().String(),
+		SpanID:    lookup.spanContext.SpanID().String(),
+		Source:    lookup.stored.Source,
+		Timestamp: lookup.stored.Timestamp,
+		Expired:   traceContextExpired(lookup.stored.Timestamp, opts),
+	}, true
+}
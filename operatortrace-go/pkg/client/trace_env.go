@@ -0,0 +1,114 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/trace_env.go
+
+package client
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// TraceParentEnvVar is the environment variable InjectTraceEnv stores the traceparent in, the name
+	// OpenTelemetry SDKs read the parent of a process from.
+	TraceParentEnvVar = "TRACEPARENT"
+	// TraceStateEnvVar is the environment variable InjectTraceEnv stores the tracestate in.
+	TraceStateEnvVar = "TRACESTATE"
+)
+
+// traceEnvPodSpecPaths are the paths of the pod spec in the kinds WithTraceEnvPropagation injects the trace
+// context into.
+var traceEnvPodSpecPaths = map[schema.GroupKind][]string{
+	{Group: "", Kind: "Pod"}:      {"spec"},
+	{Group: "batch", Kind: "Job"}: {"spec", "template", "spec"},
+}
+
+// InjectTraceEnv sets the TRACEPARENT and TRACESTATE environment variables of the containers and init containers
+// of podSpec to the trace context of the span in ctx, so a process instrumented with OpenTelemetry continues the
+// trace of the reconcile that created the Pod. Containers already defining either variable are left alone, which
+// also makes InjectTraceEnv safe to call again on the same pod spec.
+//
+//	client.InjectTraceEnv(ctx, &job.Spec.Template.Spec)
+//	err := tracingClient.Create(ctx, job)
+//
+// Nothing is injected when ctx carries no trace context to persist, e.g. in noop mode or when sampled out.
+func InjectTraceEnv(ctx context.Context, podSpec *corev1.PodSpec, opts ...Option) {
+	injectTraceEnv(ctx, podSpec, newOptions(opts...))
+}
+
+// injectTraceEnv is InjectTraceEnv with the options already built.
+func injectTraceEnv(ctx context.Context, podSpec *corev1.PodSpec, opts Options) {
+	if podSpec == nil {
+		return
+	}
+	traceParent, traceState, ok := traceDataToPersist(ctx, opts)
+	if !ok {
+		return
+	}
+	for _, containers := range [][]corev1.Container{podSpec.InitContainers, podSpec.Containers} {
+		for i := range containers {
+			injectContainerTraceEnv(&containers[i], traceParent, traceState)
+		}
+	}
+}
+
+// injectContainerTraceEnv appends the trace environment variables to container unless it defines one of them.
+func injectContainerTraceEnv(container *corev1.Container, traceParent, traceState string) {
+	for _, env := range container.Env {
+		if env.Name == TraceParentEnvVar || env.Name == TraceStateEnvVar {
+			return
+		}
+	}
+	container.Env = append(container.Env, corev1.EnvVar{Name: TraceParentEnvVar, Value: traceParent})
+	if traceState != "" {
+		container.Env = append(container.Env, corev1.EnvVar{Name: TraceStateEnvVar, Value: traceState})
+	}
+}
+
+// WithTraceEnvPropagation makes Create inject the trace context into the containers of Pods and of the pod
+// template of Jobs with InjectTraceEnv, so the processes they run continue the trace. Updates never change the
+// environment: it cannot be changed on a Pod, and changing it on a Job template has no effect on its Pods.
+func WithTraceEnvPropagation() Option {
+	return func(o *Options) {
+		o.TraceEnvPropagation = true
+	}
+}
+
+// injectTraceEnvOnCreate injects the trace context of ctx into the pod spec of obj before it is created.
+func injectTraceEnvOnCreate(ctx context.Context, obj client.Object, gvk schema.GroupVersionKind, opts Options) {
+	opts = opts.withCallOptions(ctx)
+	if !opts.TraceEnvPropagation {
+		return
+	}
+	fields, ok := traceEnvPodSpecPaths[gvk.GroupKind()]
+	if !ok {
+		return
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return
+	}
+	specContent, found, err := unstructured.NestedMap(content, fields...)
+	if err != nil || !found {
+		return
+	}
+	podSpec := &corev1.PodSpec{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specContent, podSpec); err != nil {
+		return
+	}
+	injectTraceEnv(ctx, podSpec, opts)
+	specContent, err = runtime.DefaultUnstructuredConverter.ToUnstructured(podSpec)
+	if err != nil {
+		return
+	}
+	if err := unstructured.SetNestedMap(content, specContent, fields...); err != nil {
+		return
+	}
+	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(content, obj)
+}
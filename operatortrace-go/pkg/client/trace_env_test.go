@@ -0,0 +1,162 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/trace_env_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// envValue returns the value of the environment variable name of container and whether it is defined once.
+func envValue(container corev1.Container, name string) (string, bool) {
+	var value string
+	count := 0
+	for _, env := range container.Env {
+		if env.Name == name {
+			value = env.Value
+			count++
+		}
+	}
+	return value, count == 1
+}
+
+func TestInjectTraceEnv(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(tracetest.NewInMemoryExporter()))
+	ctx, span := tp.Tracer("test").Start(context.Background(), "reconcile")
+	defer span.End()
+	traceParent, _, ok := traceDataToPersist(ctx, NewOptions())
+	require.True(t, ok)
+
+	podSpec := &corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "init"}},
+		Containers: []corev1.Container{
+			{Name: "app", Env: []corev1.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}}},
+			{Name: "own-trace", Env: []corev1.EnvVar{{Name: TraceParentEnvVar, Value: "own"}}},
+		},
+	}
+	InjectTraceEnv(ctx, podSpec)
+	// Injecting again leaves the variables alone
+	InjectTraceEnv(ctx, podSpec)
+
+	for _, container := range []corev1.Container{podSpec.InitContainers[0], podSpec.Containers[0]} {
+		value, ok := envValue(container, TraceParentEnvVar)
+		assert.True(t, ok, container.Name)
+		assert.Equal(t, traceParent, value, container.Name)
+	}
+	assert.Equal(t, corev1.EnvVar{Name: "LOG_LEVEL", Value: "debug"}, podSpec.Containers[0].Env[0])
+	assert.Equal(t, []corev1.EnvVar{{Name: TraceParentEnvVar, Value: "own"}}, podSpec.Containers[1].Env)
+
+	// Without trace context nothing is injected
+	empty := &corev1.PodSpec{Containers: []corev1.Container{{Name: "app"}}}
+	InjectTraceEnv(context.Background(), empty)
+	assert.Empty(t, empty.Containers[0].Env)
+	InjectTraceEnv(ctx, nil)
+}
+
+func TestTraceEnvPropagation(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(tracetest.NewInMemoryExporter()))
+	tracer := tp.Tracer("test")
+	newPodSpec := func() corev1.PodSpec {
+		return corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: "app:v1"}}}
+	}
+
+	tests := []struct {
+		name     string
+		options  []Option
+		obj      client.Object
+		podSpec  func(client.Object) corev1.PodSpec
+		injected bool
+	}{
+		{
+			name:     "job",
+			options:  []Option{WithTraceEnvPropagation()},
+			obj:      &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "default"}, Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: newPodSpec()}}},
+			podSpec:  func(obj client.Object) corev1.PodSpec { return obj.(*batchv1.Job).Spec.Template.Spec },
+			injected: true,
+		},
+		{
+			name:     "pod",
+			options:  []Option{WithTraceEnvPropagation()},
+			obj:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}, Spec: newPodSpec()},
+			podSpec:  func(obj client.Object) corev1.PodSpec { return obj.(*corev1.Pod).Spec },
+			injected: true,
+		},
+		{
+			name:    "disabled",
+			obj:     &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "default"}, Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: newPodSpec()}}},
+			podSpec: func(obj client.Object) corev1.PodSpec { return obj.(*batchv1.Job).Spec.Template.Spec },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k8sClient := fake.NewClientBuilder().Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tracer, logr.Discard(), nil, tt.options...)
+			stored := func() client.Object {
+				obj := tt.obj.DeepCopyObject().(client.Object)
+				require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(tt.obj), obj))
+				return obj
+			}
+
+			ctx, span := tracer.Start(context.Background(), "create")
+			require.NoError(t, tracingClient.Create(ctx, tt.obj.DeepCopyObject().(client.Object)))
+			span.End()
+			created := tt.podSpec(stored()).Containers[0]
+			traceParent, ok := envValue(created, TraceParentEnvVar)
+			if !tt.injected {
+				assert.Empty(t, created.Env)
+				return
+			}
+			require.True(t, ok)
+			assert.Contains(t, traceParent, span.SpanContext().TraceID().String())
+
+			// Updates under other traces keep the environment of the created object
+			for i := 0; i < 2; i++ {
+				ctx, span := tracer.Start(context.Background(), "reconcile")
+				obj := stored()
+				obj.SetLabels(map[string]string{"generation": string(rune('a' + i))})
+				require.NoError(t, tracingClient.Update(ctx, obj))
+				span.End()
+				assert.Equal(t, created.Env, tt.podSpec(stored()).Containers[0].Env)
+			}
+		})
+	}
+}
+
+func TestTraceEnvPropagationUnstructured(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(tracetest.NewInMemoryExporter()))
+	k8sClient := fake.NewClientBuilder().Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("test"), logr.Discard(), nil, WithTraceEnvPropagation())
+
+	job := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   map[string]interface{}{"name": "job", "namespace": "default"},
+		"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+			"containers": []interface{}{map[string]interface{}{"name": "app", "image": "app:v1"}},
+		}}},
+	}}
+	ctx, span := tp.Tracer("test").Start(context.Background(), "create")
+	require.NoError(t, tracingClient.Create(ctx, job))
+	span.End()
+
+	created := &batchv1.Job{}
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: "job"}, created))
+	traceParent, ok := envValue(created.Spec.Template.Spec.Containers[0], TraceParentEnvVar)
+	require.True(t, ok)
+	assert.Contains(t, traceParent, span.SpanContext().TraceID().String())
+}
@@ -0,0 +1,124 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/trace_summary.go
+
+package client
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceSummaryHopsAttributeKey records on a trace summary span how many hops persisted the trace chain.
+const TraceSummaryHopsAttributeKey = attribute.Key("operatortrace.summary.hops")
+
+// TraceSummaryDurationAttributeKey records on a trace summary span, in milliseconds, how long the trace chain ran,
+// from its first persisted hop to EndTrace.
+const TraceSummaryDurationAttributeKey = attribute.Key("operatortrace.summary.duration_ms")
+
+// TraceSummaryKindsAttributeKey records on a trace summary span the kinds the trace chain was persisted on, in the
+// order the chain reached them.
+const TraceSummaryKindsAttributeKey = attribute.Key("operatortrace.summary.kinds")
+
+// traceSummaryKindSeparator separates the kinds in the tracestate; kinds never contain it, and tracestate values
+// cannot contain the more natural ",".
+const traceSummaryKindSeparator = "."
+
+// WithTraceSummarySpans emits a "TraceSummary <kind>/<name>" span when EndTrace clears the trace context of an
+// object, summarizing the trace chain that ended: how many hops persisted it, how long it ran since its first hop
+// and the kinds it was persisted on. The summary is linked to the span that persisted the chain first.
+//
+// The summary is computed from bookkeeping every hop adds to the persisted tracestate, so every controller of the
+// chain must enable the option; hops of controllers without it are not counted.
+func WithTraceSummarySpans() Option {
+	return func(o *Options) {
+		o.TraceSummarySpans = true
+	}
+}
+
+// withTraceSummaryBookkeeping records in traceState, persisted by span on an object of kind, the hop of span in the
+// trace chain. The bookkeeping is derived from the tracestate span inherited from the previous hop, so every write
+// of the same span records the same hop count.
+func withTraceSummaryBookkeeping(traceState string, span trace.Span, kind string, opts Options, now time.Time) string {
+	if !opts.TraceSummarySpans {
+		return traceState
+	}
+	spanContext := span.SpanContext()
+	inherited := spanContext.TraceState()
+
+	hops, _ := strconv.Atoi(inherited.Get(constants.TraceStateHopsKey))
+	entries := [][2]string{{constants.TraceStateHopsKey, strconv.Itoa(hops + 1)}}
+	if inherited.Get(constants.TraceStateChainStartKey) == "" {
+		entries = append(entries, [2]string{constants.TraceStateChainStartKey, now.UTC().Format(time.RFC3339Nano)})
+	}
+	if inherited.Get(constants.TraceStateRootSpanKey) == "" {
+		entries = append(entries, [2]string{constants.TraceStateRootSpanKey, spanContext.SpanID().String()})
+	}
+	if kinds := traceSummaryKinds(inherited.Get(constants.TraceStateKindsKey)); kind != "" && !slices.Contains(kinds, kind) {
+		entries = append(entries, [2]string{constants.TraceStateKindsKey, strings.Join(append(kinds, kind), traceSummaryKindSeparator)})
+	}
+
+	for _, entry := range entries {
+		// A full tracestate or an overlong kind list only loses that part of the summary
+		if updated, err := tracecontext.SetTraceStateKey(traceState, entry[0], entry[1]); err == nil {
+			traceState = updated
+		}
+	}
+	return traceState
+}
+
+// traceSummaryKinds splits the kinds recorded in the tracestate.
+func traceSummaryKinds(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, traceSummaryKindSeparator)
+}
+
+// emitTraceSummarySpan starts and ends the summary span of the trace chain whose context stored was cleared from
+// an object named name, as a child of the span in ctx. Chains without bookkeeping, and spans that are not
+// recording, get no summary.
+func emitTraceSummarySpan(ctx context.Context, stored storedTraceContext, name string, opts Options) {
+	parent := trace.SpanFromContext(ctx)
+	if !opts.TraceSummarySpans || !parent.IsRecording() {
+		return
+	}
+	spanContext, err := tracecontext.SpanContextFromTraceData(stored.TraceParent, stored.TraceState)
+	if err != nil {
+		return
+	}
+	traceState := spanContext.TraceState()
+	hops, err := strconv.Atoi(traceState.Get(constants.TraceStateHopsKey))
+	if err != nil {
+		return
+	}
+
+	attrs := []attribute.KeyValue{
+		TraceSummaryHopsAttributeKey.Int(hops),
+		TraceSummaryKindsAttributeKey.StringSlice(traceSummaryKinds(traceState.Get(constants.TraceStateKindsKey))),
+	}
+	if start, err := time.Parse(time.RFC3339Nano, traceState.Get(constants.TraceStateChainStartKey)); err == nil {
+		attrs = append(attrs, TraceSummaryDurationAttributeKey.Int64(time.Since(start).Milliseconds()))
+	}
+	spanOpts := []trace.SpanStartOption{trace.WithAttributes(attrs...)}
+	if rootSpanID, err := trace.SpanIDFromHex(traceState.Get(constants.TraceStateRootSpanKey)); err == nil {
+		root := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    spanContext.TraceID(),
+			SpanID:     rootSpanID,
+			TraceFlags: spanContext.TraceFlags(),
+			Remote:     true,
+		})
+		spanOpts = append(spanOpts, trace.WithLinks(trace.Link{SpanContext: root}))
+	}
+
+	_, span := tracerFromProvider(parent.TracerProvider()).Start(ctx, "TraceSummary "+name, spanOpts...)
+	span.End()
+}
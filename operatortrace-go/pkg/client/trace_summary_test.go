@@ -0,0 +1,122 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/trace_summary_test.go
+
+package client
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestTraceSummarySpan(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		summary bool
+	}{
+		{name: "enabled", options: []Option{WithTraceSummarySpans()}, summary: true},
+		{name: "disabled"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			k8sClient := fake.NewClientBuilder().WithObjects(
+				&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "source", Namespace: "default"}},
+			).Build()
+			first := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("first"), logr.Discard(), nil, tt.options...)
+			second := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("second"), logr.Discard(), nil, tt.options...)
+
+			// The first controller starts the chain and creates the pod the second controller reconciles
+			ctx, span, err := first.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "source", Namespace: "default"}},
+			}, &corev1.Secret{})
+			require.NoError(t, err)
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+			require.NoError(t, first.Create(ctx, pod))
+			span.End()
+			rootSpanID := strings.Split(pod.Annotations[constants.DefaultTraceParentAnnotation], "-")[2]
+
+			// The second controller continues the chain from the pod and creates the config map ending it
+			ctx, span, err = second.StartTrace(context.Background(), &tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "pod", Namespace: "default"}},
+			}, &corev1.Pod{})
+			require.NoError(t, err)
+			traceID := span.SpanContext().TraceID()
+			cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "result", Namespace: "default"}}
+			require.NoError(t, second.Create(ctx, cm))
+			require.NoError(t, second.EndTrace(ctx, cm))
+			span.End()
+
+			stored := &corev1.ConfigMap{}
+			require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(cm), stored))
+			assert.Empty(t, stored.Annotations[constants.DefaultTraceParentAnnotation])
+
+			var summaries []sdktrace.ReadOnlySpan
+			for _, s := range exporter.GetSpans().Snapshots() {
+				if strings.HasPrefix(s.Name(), "TraceSummary") {
+					summaries = append(summaries, s)
+				}
+			}
+			if !tt.summary {
+				assert.Empty(t, summaries)
+				return
+			}
+			require.Len(t, summaries, 1)
+			summary := summaries[0]
+			assert.Equal(t, "TraceSummary ConfigMap/result", summary.Name())
+			assert.Equal(t, traceID, summary.SpanContext().TraceID())
+
+			attrs := attribute.NewSet(summary.Attributes()...)
+			hops, _ := attrs.Value(TraceSummaryHopsAttributeKey)
+			assert.Equal(t, int64(2), hops.AsInt64())
+			kinds, _ := attrs.Value(TraceSummaryKindsAttributeKey)
+			assert.Equal(t, []string{"Pod", "ConfigMap"}, kinds.AsStringSlice())
+			duration, ok := attrs.Value(TraceSummaryDurationAttributeKey)
+			require.True(t, ok)
+			assert.GreaterOrEqual(t, duration.AsInt64(), int64(0))
+
+			require.Len(t, summary.Links(), 1)
+			assert.Equal(t, traceID, summary.Links()[0].SpanContext.TraceID())
+			assert.Equal(t, rootSpanID, summary.Links()[0].SpanContext.SpanID().String())
+		})
+	}
+}
+
+func TestWithTraceSummaryBookkeeping(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(tracetest.NewInMemoryExporter()))
+	_, span := tp.Tracer("test").Start(context.Background(), "reconcile")
+	defer span.End()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	opts := NewOptions(WithTraceSummarySpans())
+
+	assert.Equal(t, "other=1", withTraceSummaryBookkeeping("other=1", span, "Pod", NewOptions(), now))
+
+	traceState := withTraceSummaryBookkeeping("other=1", span, "Pod", opts, now)
+	assert.Contains(t, traceState, constants.TraceStateHopsKey+"=1")
+	assert.Contains(t, traceState, constants.TraceStateChainStartKey+"=2026-01-02T03:04:05Z")
+	assert.Contains(t, traceState, constants.TraceStateRootSpanKey+"="+span.SpanContext().SpanID().String())
+	assert.Contains(t, traceState, constants.TraceStateKindsKey+"=Pod")
+	assert.Contains(t, traceState, "other=1")
+	// Writing again under the same span records the same hop
+	assert.Equal(t, traceState, withTraceSummaryBookkeeping(traceState, span, "Pod", opts, now))
+}
@@ -0,0 +1,51 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/tracer.go
+
+package client
+
+import (
+	"runtime/debug"
+	"sync"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	versionOnce sync.Once
+	version     string
+)
+
+// Version returns the operatortrace module version recorded in the binary's build info,
+// or "(devel)" when it cannot be determined.
+func Version() string {
+	versionOnce.Do(func() {
+		version = "(devel)"
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		if info.Main.Path == constants.ModulePath && info.Main.Version != "" {
+			version = info.Main.Version
+			return
+		}
+		for _, dep := range info.Deps {
+			if dep.Path != constants.ModulePath {
+				continue
+			}
+			if dep.Replace != nil && dep.Replace.Version != "" {
+				version = dep.Replace.Version
+			} else if dep.Version != "" {
+				version = dep.Version
+			}
+			return
+		}
+	})
+	return version
+}
+
+// tracerFromProvider returns operatortrace's own versioned tracer so its spans are distinguishable from user spans.
+func tracerFromProvider(tp trace.TracerProvider) trace.Tracer {
+	return tp.Tracer(constants.TracerName, trace.WithInstrumentationVersion(Version()))
+}
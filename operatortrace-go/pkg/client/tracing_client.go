@@ -6,19 +6,38 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/otelsetup"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
+// ResyncAttributeKey is set to true on StartTrace spans of reconciles triggered by a resync. See WithResyncTrace.
+const ResyncAttributeKey = attribute.Key("resync")
+
+// ClusterNameAttributeKey records the cluster of the reconciled object on StartTrace spans of requests that carry
+// a ClusterName.
+const ClusterNameAttributeKey = attribute.Key("operatortrace.cluster_name")
+
+// ControllerNameAttributeKey names the controller on the StartTrace span and the client operation spans of a
+// reconcile whose request carries a ControllerName. It is the key the otelsetup span processor uses as well.
+const ControllerNameAttributeKey = otelsetup.ControllerNameAttributeKey
+
 // TracingClient wraps the Kubernetes client to add tracing functionality
 type tracingClient struct {
 	scheme *runtime.Scheme
@@ -27,6 +46,21 @@ type tracingClient struct {
 	trace.Tracer
 	Logger  logr.Logger
 	options Options
+
+	// readerAttributes are added to spans of reads through Reader, set by WithReaderValidation.
+	readerAttributes []attribute.KeyValue
+
+	// unresolvedKinds tracks the object types missing from scheme that writes have already logged.
+	unresolvedKinds *unresolvedKinds
+
+	// gvks caches the GVK of each object type in scheme, shared with the status client.
+	gvks *gvkCache
+
+	// apiReader serves GetFresh, bypassing the informer cache: the APIReader option, or else Reader.
+	apiReader client.Reader
+
+	// persister writes trace annotations in the background, set by WithAsyncTracePersistence.
+	persister *tracePersister
 }
 
 var _ TracingClient = (*tracingClient)(nil)
@@ -39,47 +73,102 @@ func NewTracingClient(c client.Client, r client.Reader, t trace.Tracer, l logr.L
 		tracingScheme = scheme[0]
 	}
 
-	return newTracingClientWithOptions(c, r, t, l, tracingScheme)
+	return newTracingClientWithOptions(c, r, t, l, tracingScheme, newOptions())
 }
 
-// NewTracingClientWithOptions allows callers to customize operatortrace behavior via Option functions.
+// NewTracingClientWithOptions allows callers to customize operatortrace behavior via Option functions. Invalid
+// option values are ignored; see NewTracingClientWithOptionsE.
 func NewTracingClientWithOptions(c client.Client, r client.Reader, t trace.Tracer, l logr.Logger, scheme *runtime.Scheme, optFns ...Option) TracingClient {
 	tracingScheme := scheme
 	if tracingScheme == nil {
 		tracingScheme = clientgoscheme.Scheme
 	}
-	return newTracingClientWithOptions(c, r, t, l, tracingScheme, optFns...)
+	return newTracingClientWithOptions(c, r, t, l, tracingScheme, newOptions(optFns...))
+}
+
+// NewTracingClientFromProvider creates a TracingClient whose spans use operatortrace's own versioned
+// instrumentation scope, obtained from tp, instead of a caller supplied tracer.
+func NewTracingClientFromProvider(c client.Client, r client.Reader, tp trace.TracerProvider, l logr.Logger, scheme *runtime.Scheme, optFns ...Option) TracingClient {
+	return NewTracingClientWithOptions(c, r, tracerFromProvider(tp), l, scheme, optFns...)
 }
 
-func newTracingClientWithOptions(c client.Client, r client.Reader, t trace.Tracer, l logr.Logger, scheme *runtime.Scheme, optFns ...Option) TracingClient {
-	return &tracingClient{
+func newTracingClientWithOptions(c client.Client, r client.Reader, t trace.Tracer, l logr.Logger, scheme *runtime.Scheme, opts Options) TracingClient {
+	tc := &tracingClient{
 		scheme:  scheme,
 		Client:  c,
 		Reader:  r,
 		Tracer:  t,
 		Logger:  l,
-		options: newOptions(optFns...),
+		options: opts,
+
+		unresolvedKinds: &unresolvedKinds{},
+		gvks:            &gvkCache{},
+	}
+	tc.apiReader = r
+	if tc.options.APIReader != nil {
+		tc.apiReader = tc.options.APIReader
+	}
+	if tc.options.ValidateReader {
+		tc.readerAttributes = validateReader(c, r, l)
+	}
+	if tc.options.AsyncTracePersistenceQueueSize > 0 {
+		tc.persister = newTracePersister(c, l, tc.options.AsyncTracePersistenceQueueSize, tc.options.TraceExpiration)
+	}
+	return tc
+}
+
+// RESTMapper returns the mapper set with WithRESTMapper, falling back to the wrapped client's mapper.
+func (tc *tracingClient) RESTMapper() meta.RESTMapper {
+	if tc.options.RESTMapper != nil {
+		return tc.options.RESTMapper
+	}
+	return tc.Client.RESTMapper()
+}
+
+// GroupVersionKindFor returns the GVK of obj in the tracing client's scheme, which may differ from the scheme of
+// the wrapped client.
+func (tc *tracingClient) GroupVersionKindFor(obj runtime.Object) (schema.GroupVersionKind, error) {
+	return tc.gvks.gvkForObject(obj, tc.scheme)
+}
+
+// IsObjectNamespaced reports whether the type of obj, resolved in the tracing client's scheme, is namespace-scoped
+// according to RESTMapper.
+func (tc *tracingClient) IsObjectNamespaced(obj runtime.Object) (bool, error) {
+	gvk, err := tc.GroupVersionKindFor(obj)
+	if err != nil {
+		return false, err
 	}
+	return apiutil.IsGVKNamespaced(gvk, tc.RESTMapper())
 }
 
 // Create adds tracing and traceID annotation around the original client's Create method
 func (tc *tracingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
-	gvk, err := apiutil.GVKForObject(obj, tc.scheme)
-	if err != nil {
-		return fmt.Errorf("problem getting the scheme: %w", err)
+	// Create hooks change what is written, so they run even when tracing is disabled
+	if err := tc.runCreateHooks(ctx, obj); err != nil {
+		return err
+	}
+	if tc.noop(ctx) {
+		return tc.Client.Create(ctx, obj, opts...)
 	}
+	gvk := tc.writeGVK(obj)
 
 	kind := gvk.GroupKind().Kind
+	name := tc.objectName(ctx, obj, gvk)
 
 	createSpanOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindProducer)}
-	ctx, spanCreate := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("Create %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{}, createSpanOpts...)
+	ctx, spanCreate := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, tc.options.withCallOptions(ctx).spanName("Create", kind, obj, gvk), [10]tracingtypes.LinkedSpan{}, createSpanOpts...)
 	defer spanCreate.End()
 
-	addTraceAnnotations(ctx, obj, tc.options)
-	tc.Logger.Info("Creating object", "object", obj.GetName())
-	err = tc.Client.Create(ctx, obj, opts...)
+	afterWrite := tc.stageTraceAnnotations(ctx, obj, gvk)
+	mirrorTraceContextToData(ctx, obj, gvk, tc.options)
+	stampPodTemplate(ctx, obj, gvk, nil, tc.options)
+	injectTraceEnvOnCreate(ctx, obj, gvk, tc.options)
+	tc.Logger.Info("Creating object", "object", name)
+	err := tc.writeWithRetry(ctx, spanCreate, func() error { return tc.Client.Create(ctx, obj, opts...) })
 	if err != nil {
 		spanCreate.RecordError(err)
+	} else {
+		afterWrite()
 	}
 
 	return err
@@ -87,15 +176,16 @@ func (tc *tracingClient) Create(ctx context.Context, obj client.Object, opts ...
 
 // Update adds tracing and traceID annotation around the original client's Update method
 func (tc *tracingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
-	gvk, err := apiutil.GVKForObject(obj, tc.scheme)
-	if err != nil {
-		return fmt.Errorf("problem getting the scheme: %w", err)
+	if tc.noop(ctx) {
+		return tc.Client.Update(ctx, obj, opts...)
 	}
+	gvk := tc.writeGVK(obj)
 
 	kind := gvk.GroupKind().Kind
+	name := tc.objectName(ctx, obj, gvk)
 
 	// Prepare span (internal) for diff / significance check
-	ctx, spanPrepare := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("Prepare Update %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{})
+	ctx, spanPrepare := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("Prepare Update %s %s", kind, name), [10]tracingtypes.LinkedSpan{})
 	defer spanPrepare.End()
 
 	existingObj := obj.DeepCopyObject().(client.Object)
@@ -104,22 +194,25 @@ func (tc *tracingClient) Update(ctx context.Context, obj client.Object, opts ...
 	}
 
 	if !predicates.HasSignificantUpdate(existingObj, obj) {
-		tc.Logger.Info("Skipping update as object content has not changed", "object", obj.GetName())
+		tc.Logger.Info("Skipping update as object content has not changed", "object", name)
 		return nil
 	}
 
 	// Second span (producer) only for the actual mutation
 	updateSpanOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindProducer)}
-	ctx, spanUpdate := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("Update %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{}, updateSpanOpts...)
+	ctx, spanUpdate := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, tc.options.withCallOptions(ctx).spanName("Update", kind, obj, gvk), [10]tracingtypes.LinkedSpan{}, updateSpanOpts...)
 	defer spanUpdate.End()
 
-	addTraceAnnotations(ctx, obj, tc.options)
-	tc.Logger.Info("Updating object", "object", obj.GetName())
+	afterWrite := tc.stageTraceAnnotations(ctx, obj, gvk)
+	mirrorTraceContextToData(ctx, obj, gvk, tc.options)
+	stampPodTemplate(ctx, obj, gvk, existingObj, tc.options)
+	tc.Logger.Info("Updating object", "object", name)
 
 	// if resource version has changed, and there are no significant updates, we should do a patch instead of an update. This means probably just the traceID has changed / been removed.
-	if existingObj.GetResourceVersion() != obj.GetResourceVersion() {
-		tc.Logger.Info("Resource version has changed, using Patch instead of Update", "object", obj.GetName())
-		err = tc.Patch(ctx, obj, client.MergeFrom(existingObj))
+	// With strict update conflicts the update goes through as is, so the caller gets the conflict.
+	if existingObj.GetResourceVersion() != obj.GetResourceVersion() && !tc.options.withCallOptions(ctx).StrictUpdateConflicts {
+		tc.Logger.Info("Resource version has changed, using Patch instead of Update", "object", name)
+		err := tc.Patch(ctx, obj, updateFallbackPatch(existingObj, obj), updateToPatchOptions(opts)...)
 		if err != nil {
 			spanUpdate.RecordError(err)
 		}
@@ -127,42 +220,138 @@ func (tc *tracingClient) Update(ctx context.Context, obj client.Object, opts ...
 	}
 
 	// If the resource version has not changed, we can do a full update
-	err = tc.Client.Update(ctx, obj, opts...)
+	err := tc.writeWithRetry(ctx, spanUpdate, func() error { return tc.Client.Update(ctx, obj, opts...) })
 	if err != nil {
 		spanUpdate.RecordError(err)
+	} else if !isDryRunUpdate(opts) {
+		afterWrite()
 	}
 
 	return err
 }
 
+// updateFallbackPatch returns the merge patch Update sends instead of obj when the stored object existing has another
+// resource version. It is computed against existing and carries no resource version, so it applies to the live object
+// and obj wins over the changes written since the caller read it. WithStrictUpdateConflicts turns the fallback off.
+func updateFallbackPatch(existing, obj client.Object) client.Patch {
+	// The stale resource version must not end up in the patch, where it would make the patch conflict as well
+	base := existing.DeepCopyObject().(client.Object)
+	base.SetResourceVersion(obj.GetResourceVersion())
+	return client.MergeFrom(base)
+}
+
+// clearTraceAnnotationsPatch removes the trace annotations from obj and returns the patch that removes them from
+// the stored object, guarded by obj's resource version when optimisticLock is set.
+func clearTraceAnnotationsPatch(obj client.Object, opts Options, optimisticLock bool) client.Patch {
+	original := obj.DeepCopyObject().(client.Object)
+	annotations := copyAnnotations(obj)
+	persistTraceCarrier(annotations, opts, "", "")
+	obj.SetAnnotations(annotations)
+	if optimisticLock {
+		return client.MergeFromWithOptions(original, client.MergeFromWithOptimisticLock{})
+	}
+	return client.MergeFrom(original)
+}
+
+// retryEndTrace re-reads obj after a conflict and clears its trace annotations again, unless the stored trace is no
+// longer traceParent because another reconcile has written its own trace since. It reports whether it cleared them.
+func (tc *tracingClient) retryEndTrace(ctx context.Context, obj client.Object, traceParent string, attempt int, opts []client.PatchOption) (bool, error) {
+	gvk, _ := tc.gvks.gvkForObject(obj, tc.scheme)
+	ctx, span := startRetrySpan(ctx, tc.Tracer, fmt.Sprintf("Retry EndTrace %s %s", gvk.Kind, tc.objectName(ctx, obj, gvk)), attempt, tc.options)
+	defer span.End()
+
+	current := obj.DeepCopyObject().(client.Object)
+	if err := tc.Reader.Get(ctx, client.ObjectKeyFromObject(obj), current); err != nil {
+		span.RecordError(err)
+		return false, err
+	}
+	if stored, _ := extractTraceContextFromAnnotations(current.GetAnnotations(), tc.options); stored.TraceParent != traceParent {
+		span.RecordError(fmt.Errorf("trace context has changed, skipping patch: object %s", tc.objectName(ctx, obj, gvk)))
+		return false, nil
+	}
+
+	data, err := clearTraceAnnotationsPatch(current, tc.options, true).Data(current)
+	if err != nil {
+		span.RecordError(err)
+		return false, err
+	}
+	if err := tc.Client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, data), tc.patchOptions(ctx, opts)...); err != nil {
+		span.RecordError(err)
+		return false, err
+	}
+	return true, nil
+}
+
 func (tc *tracingClient) StartSpan(ctx context.Context, operationName string) (context.Context, trace.Span) {
-	return startSpanFromContext(ctx, tc.Logger, tc.Tracer, nil, tc.scheme, tc.options, operationName, [10]tracingtypes.LinkedSpan{})
+	return tc.StartSpanWithOptions(ctx, operationName)
 }
 
-// EmbedTraceIDInNamespacedName embeds the traceID and spanID in the key.Name
-func (tc *tracingClient) EmbedTraceIDInRequest(requestWithTraceID *tracingtypes.RequestWithTraceID, obj client.Object) error {
-	stored, ok := extractTraceContextFromAnnotations(obj.GetAnnotations(), tc.options)
-	if !ok || stored.TraceParent == "" {
+// StartSpanWithOptions starts a span named operationName with opts, such as its kind and attributes, as a child of
+// the span in ctx.
+func (tc *tracingClient) StartSpanWithOptions(ctx context.Context, operationName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return tc.StartSpanForObject(ctx, operationName, nil, opts...)
+}
+
+// StartSpanForObject is StartSpanWithOptions continuing the trace stored on obj, in its annotations or conditions,
+// when ctx carries no span.
+func (tc *tracingClient) StartSpanForObject(ctx context.Context, operationName string, obj client.Object, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if tc.noop(ctx) {
+		return nonRecordingSpanFromContext(ctx)
+	}
+	return startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, operationName, [10]tracingtypes.LinkedSpan{}, opts...)
+}
+
+// EmbedTraceIDInRequest embeds the trace context recorded on obj into the request.
+// The trace is read from the object's annotations, falling back to the TraceID/SpanID status conditions.
+// When the request already carries a different parent, the object's trace is appended to LinkedSpans
+// instead of overwriting the parent, mirroring how the tracing queue merges requests.
+// The optional eventKind is recorded on the request parent when the object becomes the parent.
+func (tc *tracingClient) EmbedTraceIDInRequest(requestWithTraceID *tracingtypes.RequestWithTraceID, obj client.Object, eventKind ...string) error {
+	if tc.options.Noop {
 		return nil
 	}
+	stored, ok, err := decodeTraceContextFromAnnotations(obj.GetAnnotations(), tc.options)
+	if err != nil {
+		// The annotation codec rejected the stored trace, so there is nothing trustworthy to embed
+		return nil
+	}
+	if !ok || stored.TraceParent == "" {
+		stored, ok = extractTraceContextFromConditions(obj, tc.scheme)
+		if !ok {
+			return nil
+		}
+	}
 	spanContext, err := tracecontext.SpanContextFromTraceData(stored.TraceParent, stored.TraceState)
 	if err != nil {
 		return nil
 	}
+	traceID := spanContext.TraceID().String()
+	spanID := spanContext.SpanID().String()
+
+	parent := requestWithTraceID.Parent
+	if parent.TraceID != "" && parent.SpanID != "" {
+		if parent.TraceID != traceID || parent.SpanID != spanID {
+			requestWithTraceID.AppendLinkedSpan(tracingtypes.LinkedSpan{TraceID: traceID, SpanID: spanID})
+		}
+		return nil
+	}
 
-	gvk, err := apiutil.GVKForObject(obj, tc.scheme)
+	gvk, err := tc.gvks.gvkForObject(obj, tc.scheme)
 	if err != nil {
 		return fmt.Errorf("problem getting the scheme: %w", err)
 	}
 	objectKind := gvk.GroupKind().Kind
 	objectName := obj.GetName()
 
-	requestWithTraceID.Parent.TraceID = spanContext.TraceID().String()
-	requestWithTraceID.Parent.SpanID = spanContext.SpanID().String()
+	requestWithTraceID.Parent.TraceID = traceID
+	requestWithTraceID.Parent.SpanID = spanID
 	requestWithTraceID.Parent.Kind = objectKind
 	requestWithTraceID.Parent.Name = objectName
+	if len(eventKind) > 0 && eventKind[0] != "" {
+		requestWithTraceID.Parent.EventKind = eventKind[0]
+	}
 
-	tc.Logger.Info("EmbedTraceIDInNamespacedName", "objectName", requestWithTraceID.Name)
+	tc.Logger.Info("EmbedTraceIDInNamespacedName", "objectName", tc.options.redactedKeyName(requestWithTraceID.NamespacedName, gvk))
 
 	return nil
 }
@@ -170,37 +359,66 @@ func (tc *tracingClient) EmbedTraceIDInRequest(requestWithTraceID *tracingtypes.
 // Get adds tracing around the original client's Get method
 // IMPORTANT: Caller MUST call `defer span.End()` to end the trace from the calling function
 func (tc *tracingClient) StartTrace(ctx context.Context, requestWithTraceID *tracingtypes.RequestWithTraceID, obj client.Object, opts ...client.GetOption) (context.Context, trace.Span, error) {
+	if tc.noop(ctx) {
+		_, err := tc.getForStartTrace(ctx, requestWithTraceID.NamespacedName, obj, opts...)
+		ctx, span := nonRecordingSpanFromContext(ctx)
+		return ctx, span, err
+	}
+
 	// All StartTrace call spans will be Consumer spans
 	spanOpts := []trace.SpanStartOption{
 		trace.WithSpanKind(trace.SpanKindConsumer),
 	}
+	if len(tc.readerAttributes) > 0 {
+		spanOpts = append(spanOpts, trace.WithAttributes(tc.readerAttributes...))
+	}
+	if requestWithTraceID.Parent.EventKind == tracingtypes.EventKindResync && tc.options.withCallOptions(ctx).ResyncTrace {
+		spanOpts = append(spanOpts, trace.WithAttributes(ResyncAttributeKey.Bool(true)))
+	}
+	if requestWithTraceID.ClusterName != "" {
+		spanOpts = append(spanOpts, trace.WithAttributes(ClusterNameAttributeKey.String(requestWithTraceID.ClusterName)))
+	}
+	if requestWithTraceID.ControllerName != "" {
+		spanOpts = append(spanOpts, trace.WithAttributes(ControllerNameAttributeKey.String(requestWithTraceID.ControllerName)))
+	}
 
 	// Create or retrieve the span from the context
-	getErr := tc.Reader.Get(ctx, requestWithTraceID.NamespacedName, obj, opts...)
+	source, getErr := tc.getForStartTrace(ctx, requestWithTraceID.NamespacedName, obj, opts...)
+	if source != "" {
+		spanOpts = append(spanOpts, trace.WithAttributes(ReaderAttributeKey.String(source)))
+	}
 	if getErr != nil {
-		ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("StartTrace Unknown Object %s", requestWithTraceID.NamespacedName), requestWithTraceID.LinkedSpans, spanOpts...)
+		unknownKey := requestWithTraceID.NamespacedName.String()
+		if callOpts := tc.options.withCallOptions(ctx); callOpts.redacts() {
+			gvk, _ := tc.gvks.gvkForObject(obj, tc.scheme)
+			unknownKey = callOpts.redactedObjectKey(objectForKey(requestWithTraceID.NamespacedName, gvk), gvk)
+		}
+		ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("StartTrace Unknown Object %s", unknownKey), requestWithTraceID.LinkedSpans, spanOpts...)
 		return trace.ContextWithSpan(ctx, span), span, getErr
 	}
-	overrideTraceContextFromRequest(*requestWithTraceID, obj, tc.options)
-
-	gvk, err := apiutil.GVKForObject(obj, tc.scheme)
-	objectKind := ""
-	if err == nil {
-		objectKind = gvk.GroupKind().Kind
+	spanObj, linked, inherited := applyRequestParent(*requestWithTraceID, obj, tc.scheme, tc.options.withCallOptions(ctx))
+	if inherited {
+		ctx = tc.applyOwnerTraceFallback(ctx, obj, &linked)
+		if requestWithTraceID.Parent.TraceID == "" || requestWithTraceID.Parent.SpanID == "" {
+			ctx, spanObj = tc.applyObjectKindRelationship(ctx, obj, &linked)
+		}
 	}
-	name := requestWithTraceID.Name
-	callerName := requestWithTraceID.Parent.Name
-	callerKind := requestWithTraceID.Parent.Kind
+	linkedSpans := linked.LinkedSpans
 
-	operationName := ""
+	gvk, err := tc.gvks.gvkForObject(obj, tc.scheme)
+	callOpts := tc.options.withCallOptions(ctx)
+	name := callOpts.redactedKeyName(requestWithTraceID.NamespacedName, gvk)
+	operationName := startTraceOperationName(*requestWithTraceID, gvk, err, name, callOpts)
 
-	if callerKind != "" && callerName != "" {
-		operationName = fmt.Sprintf("StartTrace %s/%s Triggered By Changed Object %s/%s", objectKind, name, callerKind, callerName)
-	} else {
-		operationName = fmt.Sprintf("StartTrace %s %s", objectKind, name)
+	if !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+		if attrs := rootReasonAttributes(spanObj, tc.scheme, callOpts); len(attrs) > 0 {
+			spanOpts = append(spanOpts, trace.WithAttributes(attrs...))
+		}
 	}
 
-	ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, operationName, requestWithTraceID.LinkedSpans, spanOpts...)
+	ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, spanObj, tc.scheme, tc.options, operationName, linkedSpans, spanOpts...)
+	ctx = withSpanBudget(ctx, span, tc.options.withCallOptions(ctx).MaxSpansPerReconcile)
+	ctx = contextWithRequest(ctx, *requestWithTraceID, callOpts)
 
 	if err != nil {
 		span.RecordError(err)
@@ -212,9 +430,15 @@ func (tc *tracingClient) StartTrace(ctx context.Context, requestWithTraceID *tra
 
 // Ends the trace by clearing the traceid from the object
 func (tc *tracingClient) EndTrace(ctx context.Context, obj client.Object, opts ...client.PatchOption) error {
-	ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("EndTrace %s %s", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName()), [10]tracingtypes.LinkedSpan{})
+	if tc.noop(ctx) {
+		return nil
+	}
+	gvk, _ := tc.gvks.gvkForObject(obj, tc.scheme)
+	name := tc.objectName(ctx, obj, gvk)
+	ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, tc.options.withCallOptions(ctx).spanName("EndTrace", gvk.Kind, obj, gvk), [10]tracingtypes.LinkedSpan{})
 	defer span.End()
 
+	tc.flushTraceAnnotations(ctx, obj, tc.writeGVK(obj))
 	annotations := obj.GetAnnotations()
 	if annotations == nil {
 		return nil
@@ -232,34 +456,41 @@ func (tc *tracingClient) EndTrace(ctx context.Context, obj client.Object, opts .
 	currentStored, _ := extractTraceContextFromAnnotations(currentObjFromServer.GetAnnotations(), tc.options)
 	desiredStored, _ := extractTraceContextFromAnnotations(obj.GetAnnotations(), tc.options)
 	if currentStored.TraceParent != desiredStored.TraceParent {
-		tc.Logger.Info("Trace context has changed, skipping patch", "object", obj.GetName())
-		span.RecordError(fmt.Errorf("trace context has changed, skipping patch: object %s", obj.GetName()))
+		tc.Logger.Info("Trace context has changed, skipping patch", "object", name)
+		span.RecordError(fmt.Errorf("trace context has changed, skipping patch: object %s", name))
 		return nil
 	}
 
 	// Remove the traceid and spanid annotations and create a patch
-	original := obj.DeepCopyObject().(client.Object)
-	patch := client.MergeFrom(original)
+	retries := tc.options.withCallOptions(ctx).EndTraceRetries
+	patch := clearTraceAnnotationsPatch(obj, tc.options, retries > 0)
 
-	persistTraceCarrier(annotations, tc.options, "", "")
-	obj.SetAnnotations(annotations)
-
-	tc.Logger.Info("Patching object", "object", obj.GetName())
+	tc.Logger.Info("Patching object", "object", name)
 	// Use the Patch function to apply the patch
 
-	err = tc.Client.Patch(ctx, obj, patch, opts...)
+	err = tc.Client.Patch(ctx, obj, patch, tc.patchOptions(ctx, opts)...)
+	cleared := err == nil
+	for attempt := 1; apierrors.IsConflict(err) && attempt <= retries; attempt++ {
+		cleared, err = tc.retryEndTrace(ctx, obj, desiredStored.TraceParent, attempt, opts)
+	}
 
 	if err != nil {
 		span.RecordError(err)
+	} else if cleared {
+		// The summary belongs to the trace EndTrace cleared, not to one another reconcile stored meanwhile
+		emitTraceSummarySpan(ctx, desiredStored, gvk.Kind+"/"+name, tc.options.withCallOptions(ctx))
 	}
 
-	original = obj.DeepCopyObject().(client.Object)
+	original := obj.DeepCopyObject().(client.Object)
 	// remove the traceid and spanid conditions from the object and create a status().patch
-	deleteConditionAsMap("TraceID", obj, tc.scheme)
+	if errors.Is(deleteConditionAsMap("TraceID", obj, tc.scheme), ErrNoConditions) {
+		// Kinds without status conditions never carry trace conditions, and often have no status to patch
+		return err
+	}
 	deleteConditionAsMap("SpanID", obj, tc.scheme)
 	patch = client.MergeFrom(original)
 
-	tc.Logger.Info("Patching object status", "object", obj.GetName())
+	tc.Logger.Info("Patching object status", "object", name)
 	err = tc.Client.Status().Patch(ctx, obj, patch)
 
 	if err != nil {
@@ -271,18 +502,22 @@ func (tc *tracingClient) EndTrace(ctx context.Context, obj client.Object, opts .
 
 // Get adds tracing around the original client's Get method
 func (tc *tracingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if tc.noop(ctx) {
+		return tc.Reader.Get(ctx, key, obj, opts...)
+	}
+
 	// Create or retrieve the span from the context
-	gvk, err := apiutil.GVKForObject(obj, tc.scheme)
+	gvk, err := tc.gvks.gvkForObject(obj, tc.scheme)
 	if err != nil {
 		return fmt.Errorf("problem getting the scheme: %w", err)
 	}
 
-	kind := gvk.GroupKind().Kind
-
-	ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("Get %s %s", kind, key.Name), [10]tracingtypes.LinkedSpan{})
+	callOpts := tc.options.withCallOptions(ctx)
+	name := callOpts.redactedKeyName(key, gvk)
+	ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, callOpts.keySpanName("Get", key, gvk), [10]tracingtypes.LinkedSpan{})
 	defer span.End()
 
-	tc.Logger.Info("Getting object", "object", key.Name)
+	tc.Logger.Info("Getting object", "object", name)
 
 	err = tc.Reader.Get(ctx, key, obj, opts...)
 
@@ -294,7 +529,10 @@ func (tc *tracingClient) Get(ctx context.Context, key client.ObjectKey, obj clie
 }
 
 func (tc *tracingClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
-	gvk, _ := apiutil.GVKForObject(list, tc.scheme)
+	if tc.noop(ctx) {
+		return tc.Client.List(ctx, list, opts...)
+	}
+	gvk, _ := tc.gvks.gvkForObject(list, tc.scheme)
 	kind := gvk.GroupKind().Kind
 	ctx, span := startSpanFromContextGeneric(ctx, tc.Logger, tc.Tracer, kind)
 	defer span.End()
@@ -307,16 +545,93 @@ func (tc *tracingClient) List(ctx context.Context, list client.ObjectList, opts
 	return err
 }
 
+// ForEach lists objects into list and calls fn for every item.
+// The whole iteration runs under a single span, with an internal child span per item so item-level
+// errors are recorded where they happened. fn receives the item span's context. Item errors do not
+// stop the iteration; they are joined and returned once all items have been processed.
+func (tc *tracingClient) ForEach(ctx context.Context, list client.ObjectList, fn func(ctx context.Context, obj client.Object) error, opts ...client.ListOption) error {
+	if tc.noop(ctx) {
+		if err := tc.Client.List(ctx, list, opts...); err != nil {
+			return err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, item := range items {
+			obj, ok := item.(client.Object)
+			if !ok {
+				errs = append(errs, fmt.Errorf("list item %T is not a client.Object", item))
+				continue
+			}
+			if err := fn(ctx, obj); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", client.ObjectKeyFromObject(obj), err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	gvk, _ := tc.gvks.gvkForObject(list, tc.scheme)
+	listKind := gvk.GroupKind().Kind
+
+	ctx, span := startSpanFromContext(ctx, tc.Logger, tc.Tracer, nil, tc.scheme, tc.options, fmt.Sprintf("ForEach %s", listKind), [10]tracingtypes.LinkedSpan{})
+	defer span.End()
+
+	if err := tc.List(ctx, list, opts...); err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		span.RecordError(err)
+		return fmt.Errorf("problem extracting items from %s: %w", listKind, err)
+	}
+
+	var errs []error
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok {
+			err := fmt.Errorf("list item %T is not a client.Object", item)
+			span.RecordError(err)
+			errs = append(errs, err)
+			continue
+		}
+		itemGVK := obj.GetObjectKind().GroupVersionKind()
+		if schemeGVK, err := tc.gvks.gvkForObject(obj, tc.scheme); err == nil {
+			itemGVK = schemeGVK
+		}
+		kind := itemGVK.Kind
+
+		itemCtx, itemSpan := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, tc.options.withCallOptions(ctx).spanName("ForEach", kind, obj, itemGVK), [10]tracingtypes.LinkedSpan{}, trace.WithSpanKind(trace.SpanKindInternal))
+		if err := fn(itemCtx, obj); err != nil {
+			itemSpan.RecordError(err)
+			itemSpan.SetStatus(codes.Error, err.Error())
+			errs = append(errs, fmt.Errorf("%s %s: %w", kind, tc.options.withCallOptions(ctx).redactedObjectKey(obj, itemGVK), err))
+		}
+		itemSpan.End()
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, fmt.Sprintf("%d of %d items failed", len(errs), len(items)))
+		return err
+	}
+	return nil
+}
+
 // Patch  adds tracing and traceID annotation around the original client's Patch method
 func (tc *tracingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
-	gvk, err := apiutil.GVKForObject(obj, tc.scheme)
-	if err != nil {
-		return fmt.Errorf("problem getting the scheme: %w", err)
+	if tc.noop(ctx) {
+		return tc.Client.Patch(ctx, obj, patch, opts...)
 	}
+	gvk := tc.writeGVK(obj)
 
 	kind := gvk.GroupKind().Kind
+	name := tc.objectName(ctx, obj, gvk)
 
-	ctx, spanPrepare := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("Prepare Patch %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{})
+	ctx, spanPrepare := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("Prepare Patch %s %s", kind, name), [10]tracingtypes.LinkedSpan{})
 	defer spanPrepare.End()
 
 	existingObj := obj.DeepCopyObject().(client.Object)
@@ -325,7 +640,7 @@ func (tc *tracingClient) Patch(ctx context.Context, obj client.Object, patch cli
 	}
 
 	if !predicates.HasSignificantUpdate(existingObj, obj) {
-		tc.Logger.Info("Skipping update as object content has not changed", "object", obj.GetName())
+		tc.Logger.Info("Skipping update as object content has not changed", "object", name)
 		return nil
 	}
 
@@ -335,14 +650,16 @@ func (tc *tracingClient) Patch(ctx context.Context, obj client.Object, patch cli
 		trace.WithSpanKind(trace.SpanKindProducer),
 	}
 
-	ctx, spanPatch := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("Patch %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{}, spanOpts...)
+	ctx, spanPatch := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, tc.options.withCallOptions(ctx).spanName("Patch", kind, obj, gvk), [10]tracingtypes.LinkedSpan{}, spanOpts...)
 	defer spanPatch.End()
 
-	addTraceAnnotations(ctx, obj, tc.options)
-	tc.Logger.Info("Patching object", "object", obj.GetName())
-	err = tc.Client.Patch(ctx, obj, patch, opts...)
+	afterWrite := tc.stageTraceAnnotations(ctx, obj, gvk)
+	tc.Logger.Info("Patching object", "object", name)
+	err := tc.writeWithRetry(ctx, spanPatch, func() error { return tc.Client.Patch(ctx, obj, patch, tc.patchOptions(ctx, opts)...) })
 	if err != nil {
 		spanPatch.RecordError(err)
+	} else if !isDryRunPatch(opts) {
+		afterWrite()
 	}
 
 	return err
@@ -350,19 +667,20 @@ func (tc *tracingClient) Patch(ctx context.Context, obj client.Object, patch cli
 
 // Delete adds tracing around the original client's Delete method
 func (tc *tracingClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
-	gvk, err := apiutil.GVKForObject(obj, tc.scheme)
-	if err != nil {
-		return fmt.Errorf("problem getting the scheme: %w", err)
+	if tc.noop(ctx) {
+		return tc.Client.Delete(ctx, obj, opts...)
 	}
+	gvk := tc.writeGVK(obj)
 
 	kind := gvk.GroupKind().Kind
+	name := tc.objectName(ctx, obj, gvk)
 
 	deleteSpanOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindProducer)}
-	ctx, spanDelete := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("Delete %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{}, deleteSpanOpts...)
+	ctx, spanDelete := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, tc.options.withCallOptions(ctx).spanName("Delete", kind, obj, gvk), [10]tracingtypes.LinkedSpan{}, deleteSpanOpts...)
 	defer spanDelete.End()
 
-	tc.Logger.Info("Deleting object", "object", obj.GetName())
-	err = tc.Client.Delete(ctx, obj, opts...)
+	tc.Logger.Info("Deleting object", "object", name)
+	err := tc.writeWithRetry(ctx, spanDelete, func() error { return tc.Client.Delete(ctx, obj, opts...) })
 	if err != nil {
 		spanDelete.RecordError(err)
 	}
@@ -370,22 +688,78 @@ func (tc *tracingClient) Delete(ctx context.Context, obj client.Object, opts ...
 }
 
 func (tc *tracingClient) DeleteAllOf(ctx context.Context, obj client.Object, opts ...client.DeleteAllOfOption) error {
-	gvk, err := apiutil.GVKForObject(obj, tc.scheme)
-	if err != nil {
-		return fmt.Errorf("problem getting the scheme: %w", err)
+	if tc.noop(ctx) {
+		return tc.Client.DeleteAllOf(ctx, obj, opts...)
 	}
+	gvk := tc.writeGVK(obj)
 
 	kind := gvk.GroupKind().Kind
+	name := tc.objectName(ctx, obj, gvk)
 
 	deleteAllOfSpanOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindProducer)}
-	ctx, spanDeleteAll := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, fmt.Sprintf("DeleteAllOf %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{}, deleteAllOfSpanOpts...)
+	ctx, spanDeleteAll := startSpanFromContext(ctx, tc.Logger, tc.Tracer, obj, tc.scheme, tc.options, tc.options.withCallOptions(ctx).spanName("DeleteAllOf", kind, obj, gvk), [10]tracingtypes.LinkedSpan{}, deleteAllOfSpanOpts...)
 	defer spanDeleteAll.End()
 
-	tc.Logger.Info("Deleting all of object", "object", obj.GetName())
-	err = tc.Client.DeleteAllOf(ctx, obj, opts...)
+	tc.Logger.Info("Deleting all of object", "object", name)
+	err := tc.Client.DeleteAllOf(ctx, obj, opts...)
 	if err != nil {
 		spanDeleteAll.RecordError(err)
 	}
 	return err
 
 }
+
+// runCreateHooks runs the create hooks configured for calls made with ctx on obj.
+func (tc *tracingClient) runCreateHooks(ctx context.Context, obj client.Object) error {
+	for _, hook := range tc.options.withCallOptions(ctx).CreateHooks {
+		if err := hook(ctx, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// objectName returns the name of obj to record in span names and log lines for calls made with ctx.
+func (tc *tracingClient) objectName(ctx context.Context, obj client.Object, gvk schema.GroupVersionKind) string {
+	return tc.options.withCallOptions(ctx).redactedName(obj, gvk)
+}
+
+// noop reports whether tracing is disabled for calls made with ctx.
+func (tc *tracingClient) noop(ctx context.Context) bool {
+	return tc.options.withCallOptions(ctx).Noop
+}
+
+// updateToPatchOptions translates the update options that also apply to a patch, such as the field manager and
+// dry run, into patch options.
+func updateToPatchOptions(opts []client.UpdateOption) []client.PatchOption {
+	if len(opts) == 0 {
+		return nil
+	}
+	updateOpts := (&client.UpdateOptions{}).ApplyOptions(opts).AsUpdateOptions()
+	return []client.PatchOption{&client.PatchOptions{
+		DryRun:          updateOpts.DryRun,
+		FieldManager:    updateOpts.FieldManager,
+		FieldValidation: updateOpts.FieldValidation,
+	}}
+}
+
+// isDryRunUpdate reports whether opts make the update a dry run, which must not persist trace annotations later.
+func isDryRunUpdate(opts []client.UpdateOption) bool {
+	return len((&client.UpdateOptions{}).ApplyOptions(opts).AsUpdateOptions().DryRun) > 0
+}
+
+// isDryRunPatch reports whether opts make the patch a dry run.
+func isDryRunPatch(opts []client.PatchOption) bool {
+	return len((&client.PatchOptions{}).ApplyOptions(opts).AsPatchOptions().DryRun) > 0
+}
+
+// patchOptions appends the configured field owner to the caller's patch options, unless the caller set its own.
+func (tc *tracingClient) patchOptions(ctx context.Context, opts []client.PatchOption) []client.PatchOption {
+	fieldManager := tc.options.withCallOptions(ctx).FieldManager
+	if fieldManager == "" || (&client.PatchOptions{}).ApplyOptions(opts).FieldManager != "" {
+		return opts
+	}
+	patchOpts := make([]client.PatchOption, 0, len(opts)+1)
+	patchOpts = append(patchOpts, opts...)
+	return append(patchOpts, client.FieldOwner(fieldManager))
+}
@@ -6,8 +6,11 @@ package client
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
 	"github.com/go-logr/logr"
@@ -15,16 +18,24 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
 	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
@@ -115,35 +126,117 @@ func TestEmbedTraceIDInRequest(t *testing.T) {
 		options: newOptions(),
 	}
 
-	// Mock object with traceID and spanID annotations
-	pod := &corev1.Pod{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      "test-pod",
-			Namespace: "default",
-		},
+	const (
+		otherTraceIDHex = "fedcba0987654321fedcba0987654321"
+		otherSpanIDHex  = "0987654321fedcba"
+	)
+
+	newRequest := func() tracingtypes.RequestWithTraceID {
+		return tracingtypes.RequestWithTraceID{
+			Request: ctrlreconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name:      "test-deployment",
+					Namespace: "default",
+				},
+			},
+		}
 	}
-	annotateObjectWithTraceIDs(t, pod, tracingClient.options, testTraceIDHex, testSpanIDHex)
 
-	// Set up a trace id request
-	request := tracingtypes.RequestWithTraceID{
-		Request: ctrlreconcile.Request{
-			NamespacedName: types.NamespacedName{
-				Name:      "test-deployment",
-				Namespace: "default",
+	tests := []struct {
+		name              string
+		pod               func() *corev1.Pod
+		request           func() tracingtypes.RequestWithTraceID
+		eventKind         []string
+		expectedParent    tracingtypes.RequestParent
+		expectedLinkCount int
+		expectedLinks     []tracingtypes.LinkedSpan
+	}{
+		{
+			name: "annotations",
+			pod: func() *corev1.Pod {
+				pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+				annotateObjectWithTraceIDs(t, pod, tracingClient.options, testTraceIDHex, testSpanIDHex)
+				return pod
 			},
+			request:        newRequest,
+			expectedParent: tracingtypes.RequestParent{TraceID: testTraceIDHex, SpanID: testSpanIDHex, Name: "test-pod", Kind: "Pod"},
+		},
+		{
+			name: "annotations with event kind",
+			pod: func() *corev1.Pod {
+				pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+				annotateObjectWithTraceIDs(t, pod, tracingClient.options, testTraceIDHex, testSpanIDHex)
+				return pod
+			},
+			request:        newRequest,
+			eventKind:      []string{"Update"},
+			expectedParent: tracingtypes.RequestParent{TraceID: testTraceIDHex, SpanID: testSpanIDHex, Name: "test-pod", Kind: "Pod", EventKind: "Update"},
+		},
+		{
+			name: "conditions only",
+			pod: func() *corev1.Pod {
+				pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+				require.NoError(t, setConditionMessage("TraceID", testTraceIDHex, pod, scheme))
+				require.NoError(t, setConditionMessage("SpanID", testSpanIDHex, pod, scheme))
+				return pod
+			},
+			request:        newRequest,
+			expectedParent: tracingtypes.RequestParent{TraceID: testTraceIDHex, SpanID: testSpanIDHex, Name: "test-pod", Kind: "Pod"},
+		},
+		{
+			name: "no trace",
+			pod: func() *corev1.Pod {
+				return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+			},
+			request: newRequest,
+		},
+		{
+			name: "parent already populated",
+			pod: func() *corev1.Pod {
+				pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+				annotateObjectWithTraceIDs(t, pod, tracingClient.options, testTraceIDHex, testSpanIDHex)
+				return pod
+			},
+			request: func() tracingtypes.RequestWithTraceID {
+				req := newRequest()
+				req.Parent = tracingtypes.RequestParent{TraceID: otherTraceIDHex, SpanID: otherSpanIDHex, Name: "other-pod", Kind: "Pod", EventKind: "Create"}
+				return req
+			},
+			eventKind:         []string{"Update"},
+			expectedParent:    tracingtypes.RequestParent{TraceID: otherTraceIDHex, SpanID: otherSpanIDHex, Name: "other-pod", Kind: "Pod", EventKind: "Create"},
+			expectedLinkCount: 1,
+			expectedLinks:     []tracingtypes.LinkedSpan{{TraceID: testTraceIDHex, SpanID: testSpanIDHex}},
+		},
+		{
+			name: "parent already populated with the same trace",
+			pod: func() *corev1.Pod {
+				pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+				annotateObjectWithTraceIDs(t, pod, tracingClient.options, testTraceIDHex, testSpanIDHex)
+				return pod
+			},
+			request: func() tracingtypes.RequestWithTraceID {
+				req := newRequest()
+				req.Parent = tracingtypes.RequestParent{TraceID: testTraceIDHex, SpanID: testSpanIDHex, Name: "test-pod", Kind: "Pod"}
+				return req
+			},
+			expectedParent: tracingtypes.RequestParent{TraceID: testTraceIDHex, SpanID: testSpanIDHex, Name: "test-pod", Kind: "Pod"},
 		},
 	}
 
-	// Call the function
-	err := tracingClient.EmbedTraceIDInRequest(&request, pod)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := tt.request()
 
-	// Assert no error
-	assert.NoError(t, err)
+			err := tracingClient.EmbedTraceIDInRequest(&request, tt.pod(), tt.eventKind...)
 
-	// Assert the object has been updated correctly
-	assert.Equal(t, testTraceIDHex, request.Parent.TraceID)
-	assert.Equal(t, testSpanIDHex, request.Parent.SpanID)
-	assert.Equal(t, "test-pod", request.Parent.Name)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedParent, request.Parent)
+			assert.Equal(t, tt.expectedLinkCount, request.LinkedSpanCount)
+			for i, link := range tt.expectedLinks {
+				assert.Equal(t, link, request.LinkedSpans[i])
+			}
+		})
+	}
 }
 
 func TestAutomaticAnnotationManagement(t *testing.T) {
@@ -443,6 +536,86 @@ func TestStartSpan(t *testing.T) {
 	assert.NotEmpty(t, spanID)
 }
 
+func TestStartSpanWithOptions(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().Build()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard())
+
+	_, span := tracingClient.StartSpanWithOptions(context.Background(), "render",
+		trace.WithSpanKind(trace.SpanKindProducer), trace.WithAttributes(attribute.String("template", "deployment")))
+	span.End()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}}
+	annotateObjectWithTraceIDs(t, pod, NewOptions(), testTraceIDHex, testSpanIDHex)
+	_, objectSpan := tracingClient.StartSpanForObject(context.Background(), "render pod", pod, trace.WithSpanKind(trace.SpanKindClient))
+	objectSpan.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, trace.SpanKindProducer, spans[0].SpanKind)
+	assert.Contains(t, spans[0].Attributes, attribute.String("template", "deployment"))
+	assert.False(t, spans[0].Parent.IsValid())
+
+	assert.Equal(t, trace.SpanKindClient, spans[1].SpanKind)
+	assert.Equal(t, testTraceIDHex, spans[1].SpanContext.TraceID().String())
+	assert.Equal(t, testSpanIDHex, spans[1].Parent.SpanID().String())
+}
+
+func TestStartSpanForObjectNoop(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, WithNoop())
+
+	_, span := tracingClient.StartSpanForObject(context.Background(), "render", &corev1.Pod{}, trace.WithSpanKind(trace.SpanKindProducer))
+	span.End()
+
+	assert.Empty(t, exporter.GetSpans())
+}
+
+func TestGroupVersionKindForAndIsObjectNamespaced(t *testing.T) {
+	scheme := runtime.NewScheme()
+	require.NoError(t, corev1.AddToScheme(scheme))
+	restmap := meta.NewDefaultRESTMapper(nil)
+	restmap.Add(corev1.SchemeGroupVersion.WithKind("Pod"), meta.RESTScopeNamespace)
+	restmap.Add(corev1.SchemeGroupVersion.WithKind("Node"), meta.RESTScopeRoot)
+	restmap.Add(corev1.SchemeGroupVersion.WithKind("Namespace"), meta.RESTScopeRoot)
+	k8sClient := fake.NewClientBuilder().WithRESTMapper(restmap).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), scheme)
+
+	tests := []struct {
+		name       string
+		obj        runtime.Object
+		kind       string
+		namespaced bool
+		err        bool
+	}{
+		{name: "namespace-scoped", obj: &corev1.Pod{}, kind: "Pod", namespaced: true},
+		{name: "cluster-scoped", obj: &corev1.Node{}, kind: "Node"},
+		{name: "cluster-scoped namespace", obj: &corev1.Namespace{}, kind: "Namespace"},
+		// The wrapped client knows Deployments, but the tracing client's scheme does not
+		{name: "not in the tracing client's scheme", obj: &appsv1.Deployment{}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gvk, err := tracingClient.GroupVersionKindFor(tt.obj)
+			namespaced, namespacedErr := tracingClient.IsObjectNamespaced(tt.obj)
+			if tt.err {
+				assert.Error(t, err)
+				assert.Error(t, namespacedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, namespacedErr)
+			assert.Equal(t, corev1.SchemeGroupVersion.WithKind(tt.kind), gvk)
+			assert.Equal(t, tt.namespaced, namespaced)
+		})
+	}
+}
+
 func TestPatchWithTracing(t *testing.T) {
 	// Create a fake Kubernetes client
 	k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Pod{
@@ -661,6 +834,22 @@ func TestEndTrace(t *testing.T) {
 	assert.Equal(t, 1, len(finalPod.Status.Conditions))
 }
 
+func TestEndTraceSpanNameUsesSchemeKind(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "typed-pod", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithObjects(pod).Build()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracingClient := NewTracingClient(k8sClient, k8sClient, tp.Tracer("test"), logr.Discard())
+
+	// Typed objects read from the API server carry no TypeMeta
+	pod.TypeMeta = metav1.TypeMeta{}
+	require.NoError(t, tracingClient.EndTrace(context.Background(), pod))
+
+	spans := exporter.GetSpans()
+	require.NotEmpty(t, spans)
+	assert.Equal(t, "EndTrace Pod typed-pod", spans[0].Name)
+}
+
 func TestEndTraceChangedAnnotation(t *testing.T) {
 	// Create a fake Kubernetes client
 	k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Pod{
@@ -1014,3 +1203,678 @@ func TestDeleteCondition(t *testing.T) {
 	expectedConditions := []map[string]interface{}(nil)
 	assert.Equal(t, expectedConditions, conditions)
 }
+
+func emittedTraceIDFromObject(t *testing.T, obj client.Object, opts Options) string {
+	t.Helper()
+	spanContext, err := tracecontext.SpanContextFromTraceData(obj.GetAnnotations()[opts.EmittedTraceParentAnnotationKey()], "")
+	require.NoError(t, err)
+	return spanContext.TraceID().String()
+}
+
+func TestCallOptionsOverrideIncomingRelationship(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	tracer := initTracer()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tracer, logr.Discard(), nil,
+		WithIncomingTraceParentAnnotation("external/traceparent"),
+		WithIncomingTraceRelationship(TraceParentRelationshipLink),
+	)
+	opts := tracingClientOptionsForTest(t, tracingClient)
+
+	incomingTraceParent, err := tracecontext.TraceParentFromIDs(testTraceIDHex, testSpanIDHex)
+	require.NoError(t, err)
+
+	newPod := func(name string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Namespace:   "default",
+				Annotations: map[string]string{"external/traceparent": incomingTraceParent},
+			},
+		}
+	}
+
+	// Client default is link: the created object starts a new trace
+	linkedPod := newPod("linked-pod")
+	require.NoError(t, tracingClient.Create(context.Background(), linkedPod))
+	linkedTraceID := emittedTraceIDFromObject(t, linkedPod, opts)
+	assert.NotEmpty(t, linkedTraceID)
+	assert.NotEqual(t, testTraceIDHex, linkedTraceID)
+
+	// Call option overrides the client default and inherits the incoming trace
+	ctx := WithCallOptions(context.Background(), WithIncomingTraceRelationship(TraceParentRelationshipParent))
+	parentedPod := newPod("parented-pod")
+	require.NoError(t, tracingClient.Create(ctx, parentedPod))
+	parentedTraceID := emittedTraceIDFromObject(t, parentedPod, opts)
+	assert.Equal(t, testTraceIDHex, parentedTraceID)
+
+	// The client-wide options are left untouched by the call override
+	assert.Equal(t, TraceParentRelationshipLink, tracingClientOptionsForTest(t, tracingClient).IncomingTraceRelationship)
+}
+
+func TestCallOptionsSkipTraceAnnotations(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	tracer := initTracer()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, tracer, logr.Discard())
+	opts := tracingClientOptionsForTest(t, tracingClient)
+
+	ctx, span := tracingClient.StartSpan(context.Background(), "test")
+	defer span.End()
+
+	skippedPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "skipped-pod", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(WithCallOptions(ctx, WithSkipTraceAnnotations(true)), skippedPod))
+	retrieved := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(skippedPod), retrieved))
+	assert.NotContains(t, retrieved.GetAnnotations(), opts.EmittedTraceParentAnnotationKey())
+
+	// Later call options win over earlier ones
+	annotatedPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "annotated-pod", Namespace: "default"}}
+	callCtx := WithCallOptions(WithCallOptions(ctx, WithSkipTraceAnnotations(true)), WithSkipTraceAnnotations(false))
+	require.NoError(t, tracingClient.Create(callCtx, annotatedPod))
+	retrieved = &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(annotatedPod), retrieved))
+	assert.Contains(t, retrieved.GetAnnotations(), opts.EmittedTraceParentAnnotationKey())
+}
+
+func TestPatchWithFieldManager(t *testing.T) {
+	// Simulate an API server that rejects trace annotation writes without an explicit field owner
+	var fieldManagers []string
+	conflictingPatch := func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+		patchOpts := &client.PatchOptions{}
+		patchOpts.ApplyOptions(opts)
+		fieldManagers = append(fieldManagers, patchOpts.FieldManager)
+		if patchOpts.FieldManager == "" {
+			return apierrors.NewConflict(schema.GroupResource{Resource: "pods"}, obj.GetName(), fmt.Errorf("conflict with field manager \"other-manager\""))
+		}
+		return c.Patch(ctx, obj, patch, opts...)
+	}
+	newClient := func() client.WithWatch {
+		return fake.NewClientBuilder().WithObjects(&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "test-pod",
+				Namespace: "default",
+			},
+		}).WithInterceptorFuncs(interceptor.Funcs{Patch: conflictingPatch}).Build()
+	}
+	tracer := initTracer()
+
+	patchPod := func(t *testing.T, tracingClient TracingClient, opts ...client.PatchOption) error {
+		t.Helper()
+		fieldManagers = nil
+		ctx, span := tracingClient.StartSpan(context.Background(), "test")
+		defer span.End()
+
+		pod := &corev1.Pod{}
+		require.NoError(t, tracingClient.Get(ctx, client.ObjectKey{Name: "test-pod", Namespace: "default"}, pod))
+		podPatch := client.MergeFrom(pod.DeepCopy())
+		pod.Labels = map[string]string{"updated": "true"}
+		return tracingClient.Patch(ctx, pod, podPatch, opts...)
+	}
+
+	t.Run("without field manager", func(t *testing.T) {
+		k8sClient := newClient()
+		tracingClient := NewTracingClient(k8sClient, k8sClient, tracer, logr.Discard())
+		err := patchPod(t, tracingClient)
+		assert.True(t, apierrors.IsConflict(err))
+	})
+
+	t.Run("with field manager", func(t *testing.T) {
+		k8sClient := newClient()
+		tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tracer, logr.Discard(), nil, WithFieldManager("trace-manager"))
+		opts := tracingClientOptionsForTest(t, tracingClient)
+		require.NoError(t, patchPod(t, tracingClient))
+
+		retrievedPod := &corev1.Pod{}
+		require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKey{Name: "test-pod", Namespace: "default"}, retrievedPod))
+		assert.NotEmpty(t, retrievedPod.GetAnnotations()[opts.EmittedTraceParentAnnotationKey()])
+		// Ownership is left to the API server, which rejects apply patches carrying managed fields
+		assert.Empty(t, retrievedPod.GetManagedFields())
+		assert.Equal(t, []string{"trace-manager"}, fieldManagers)
+	})
+
+	t.Run("caller field owner wins", func(t *testing.T) {
+		k8sClient := newClient()
+		tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tracer, logr.Discard(), nil, WithFieldManager("trace-manager"))
+		require.NoError(t, patchPod(t, tracingClient, client.FieldOwner("caller-manager")))
+		assert.Equal(t, []string{"caller-manager"}, fieldManagers)
+	})
+}
+
+func TestCreateWithDataFieldPropagation(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	tracer := initTracer()
+	configMapGVK := corev1.SchemeGroupVersion.WithKind("ConfigMap")
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tracer, logr.Discard(), nil,
+		WithDataFieldPropagation(configMapGVK, "operatortrace-traceparent"),
+	)
+
+	ctx, span := tracingClient.StartSpan(context.Background(), "hub reconcile")
+	defer span.End()
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "hub-config", Namespace: "default"},
+		Data:       map[string]string{"settings": "value"},
+	}
+	require.NoError(t, tracingClient.Create(ctx, configMap))
+
+	// Simulate a spoke cluster that only copies .data
+	hubCopy := &corev1.ConfigMap{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(configMap), hubCopy))
+	spokeCopy := &corev1.ConfigMap{Data: hubCopy.Data}
+
+	spanContext, err := tracecontext.ExtractFromMapData(spokeCopy.Data, "operatortrace-traceparent")
+	require.NoError(t, err)
+	assert.Equal(t, span.SpanContext().TraceID(), spanContext.TraceID())
+	assert.Equal(t, "value", spokeCopy.Data["settings"])
+
+	// Kinds that were not configured are left untouched
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "hub-secret", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(ctx, secret))
+	assert.Empty(t, secret.Data)
+}
+
+func TestForEachWithTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-1", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-2", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod-3", Namespace: "default"}},
+	).Build()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard())
+
+	var visited []string
+	err := tracingClient.ForEach(context.Background(), &corev1.PodList{}, func(ctx context.Context, obj client.Object) error {
+		visited = append(visited, obj.GetName())
+		assert.True(t, trace.SpanFromContext(ctx).SpanContext().IsValid())
+		if obj.GetName() == "pod-2" {
+			return fmt.Errorf("boom")
+		}
+		return nil
+	}, client.InNamespace("default"))
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "default/pod-2")
+	assert.Contains(t, err.Error(), "boom")
+	assert.ElementsMatch(t, []string{"pod-1", "pod-2", "pod-3"}, visited)
+
+	spans := exporter.GetSpans()
+	var listSpan tracetest.SpanStub
+	for _, s := range spans {
+		if s.Name == "ForEach PodList" {
+			listSpan = s
+		}
+	}
+	require.True(t, listSpan.SpanContext.IsValid(), "expected a list span")
+	assert.Equal(t, codes.Error, listSpan.Status.Code)
+
+	itemSpans := map[string]tracetest.SpanStub{}
+	for _, s := range spans {
+		if strings.HasPrefix(s.Name, "ForEach Pod ") {
+			itemSpans[s.Name] = s
+		}
+	}
+	require.Len(t, itemSpans, 3)
+	for name, s := range itemSpans {
+		assert.Equal(t, listSpan.SpanContext.SpanID(), s.Parent.SpanID(), name)
+		assert.Equal(t, trace.SpanKindInternal, s.SpanKind, name)
+	}
+	assert.Equal(t, codes.Error, itemSpans["ForEach Pod pod-2"].Status.Code)
+	assert.Len(t, itemSpans["ForEach Pod pod-2"].Events, 1)
+	assert.Equal(t, codes.Unset, itemSpans["ForEach Pod pod-1"].Status.Code)
+}
+
+func TestForEachEmptyList(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	tracingClient := NewTracingClient(k8sClient, k8sClient, initTracer(), logr.Discard())
+
+	called := false
+	err := tracingClient.ForEach(context.Background(), &corev1.PodList{}, func(ctx context.Context, obj client.Object) error {
+		called = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, called)
+}
+
+func TestNewTracingClientFromProvider(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithObjects(pod).WithStatusSubresource(pod).Build()
+	tracingClient := NewTracingClientFromProvider(k8sClient, k8sClient, tp, logr.Discard(), nil)
+
+	ctx, span := tracingClient.StartSpan(context.Background(), "reconcile")
+	retrieved := &corev1.Pod{}
+	require.NoError(t, tracingClient.Get(ctx, client.ObjectKeyFromObject(pod), retrieved))
+	retrieved.Status.Phase = corev1.PodRunning
+	require.NoError(t, tracingClient.Status().Update(ctx, retrieved))
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.NotEmpty(t, spans)
+	for _, s := range spans {
+		assert.Equal(t, constants.TracerName, s.InstrumentationScope.Name, s.Name)
+		assert.Equal(t, Version(), s.InstrumentationScope.Version, s.Name)
+	}
+	assert.NotEmpty(t, Version())
+}
+
+func TestNoopSkipsTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().WithStatusSubresource(&corev1.Pod{}).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, WithNoop())
+
+	ctx := context.Background()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "noop-pod", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(ctx, pod))
+	require.NoError(t, tracingClient.Update(ctx, pod))
+	require.NoError(t, tracingClient.Status().Update(ctx, pod))
+
+	ctx, span, err := tracingClient.StartTrace(ctx, &tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "noop-pod", Namespace: "default"}},
+	}, &corev1.Pod{})
+	require.NoError(t, err)
+	assert.False(t, span.SpanContext().IsValid())
+	require.NoError(t, tracingClient.EndTrace(ctx, pod))
+
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+	assert.Empty(t, stored.GetAnnotations())
+	assert.Empty(t, stored.Status.Conditions)
+	assert.Empty(t, exporter.GetSpans())
+}
+
+func TestNoopKeepsCallerSpanAndCallOptions(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().WithStatusSubresource(&corev1.Pod{}).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil)
+
+	ctx, parent := tp.Tracer("caller").Start(WithCallOptions(context.Background(), WithNoop()), "caller")
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "noop-pod", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(ctx, pod))
+	require.NoError(t, tracingClient.Status().Update(ctx, pod))
+
+	// Ending the spans of noop calls must not end the caller's span
+	_, span := tracingClient.StartSpanForObject(ctx, "render", pod)
+	assert.Equal(t, parent.SpanContext(), span.SpanContext())
+	span.End()
+	_, span, err := tracingClient.StartTrace(ctx, &tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "noop-pod", Namespace: "default"}},
+	}, &corev1.Pod{})
+	require.NoError(t, err)
+	span.End()
+	assert.True(t, parent.IsRecording())
+	assert.Empty(t, exporter.GetSpans())
+
+	parent.End()
+	stored := &corev1.Pod{}
+	require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+	assert.Empty(t, stored.Status.Conditions)
+	assert.Len(t, exporter.GetSpans(), 1)
+}
+
+func BenchmarkCreateNoop(b *testing.B) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(tracetest.NewNoopExporter()))
+
+	for name, optFns := range map[string][]Option{
+		"traced": nil,
+		"noop":   {WithNoop()},
+	} {
+		b.Run(name, func(b *testing.B) {
+			k8sClient := fake.NewClientBuilder().Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, optFns...)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("pod-%d", i), Namespace: "default"}}
+				if err := tracingClient.Create(context.Background(), pod); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestStartTraceInheritTraceOn(t *testing.T) {
+	const parentTraceID = "f620f5cad0af940c294f980c5366a6a1"
+	const parentSpanID = "45f359cdc1c8ab06"
+
+	tests := []struct {
+		name          string
+		opts          []Option
+		eventKind     string
+		changedFields string
+		expectInherit bool
+	}{
+		{"spec change inherits", []Option{WithInheritTraceOn("spec", "data")}, "Update", "spec", true},
+		{"spec and status change inherits", []Option{WithInheritTraceOn("spec")}, "Update", "spec,status", true},
+		{"status change links", []Option{WithInheritTraceOn("spec", "data")}, "Update", "status", false},
+		{"unknown change inherits", []Option{WithInheritTraceOn("spec")}, "Update", "", true},
+		{"create inherits", []Option{WithInheritTraceOn("spec")}, "Create", "status", true},
+		{"option unset inherits", nil, "Update", "status", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+			}).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, tt.opts...)
+
+			request := ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "pod", Namespace: "default"})
+			request.Parent = tracingtypes.RequestParent{
+				TraceID:       parentTraceID,
+				SpanID:        parentSpanID,
+				Name:          "other",
+				Kind:          "Pod",
+				EventKind:     tt.eventKind,
+				ChangedFields: tt.changedFields,
+			}
+
+			_, span, err := tracingClient.StartTrace(context.Background(), &request, &corev1.Pod{})
+			require.NoError(t, err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			if tt.expectInherit {
+				assert.Equal(t, parentTraceID, spans[0].SpanContext.TraceID().String())
+				assert.Equal(t, parentSpanID, spans[0].Parent.SpanID().String())
+				return
+			}
+			assert.NotEqual(t, parentTraceID, spans[0].SpanContext.TraceID().String())
+			assert.False(t, spans[0].Parent.IsValid())
+			require.Len(t, spans[0].Links, 1)
+			assert.Equal(t, parentTraceID, spans[0].Links[0].SpanContext.TraceID().String())
+			assert.Equal(t, parentSpanID, spans[0].Links[0].SpanContext.SpanID().String())
+		})
+	}
+}
+
+func TestStartTraceResyncAttribute(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		eventKind    string
+		expectResync bool
+	}{
+		{"resync is marked", []Option{WithResyncTrace()}, tracingtypes.EventKindResync, true},
+		{"change is not marked", []Option{WithResyncTrace()}, "Update", false},
+		{"option unset", nil, tracingtypes.EventKindResync, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+			}).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, tt.opts...)
+
+			request := ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "pod", Namespace: "default"})
+			request.Parent = tracingtypes.RequestParent{Name: "pod", Kind: "Pod", EventKind: tt.eventKind}
+
+			_, span, err := tracingClient.StartTrace(context.Background(), &request, &corev1.Pod{})
+			require.NoError(t, err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			if tt.expectResync {
+				assert.Contains(t, spans[0].Attributes, ResyncAttributeKey.Bool(true))
+			} else {
+				for _, attr := range spans[0].Attributes {
+					assert.NotEqual(t, ResyncAttributeKey, attr.Key)
+				}
+			}
+		})
+	}
+}
+
+func TestStartTraceClusterNameAttribute(t *testing.T) {
+	for _, clusterName := range []string{"", "east"} {
+		t.Run("cluster "+clusterName, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+			}).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil)
+
+			request := ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "pod", Namespace: "default"})
+			request.ClusterName = clusterName
+
+			_, span, err := tracingClient.StartTrace(context.Background(), &request, &corev1.Pod{})
+			require.NoError(t, err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			attrs := attribute.NewSet(spans[0].Attributes...)
+			value, found := attrs.Value(ClusterNameAttributeKey)
+			assert.Equal(t, clusterName != "", found)
+			assert.Equal(t, clusterName, value.AsString())
+		})
+	}
+}
+
+func TestStartTraceExpirationPolicy(t *testing.T) {
+	expiredAnnotations := map[string]string{
+		constants.DefaultTraceParentAnnotation: "00-" + testTraceIDHex + "-" + testSpanIDHex + "-01",
+		constants.DefaultTraceStateAnnotation:  constants.TraceStateTimestampKey + "=2020-01-01T00:00:00Z",
+	}
+
+	tests := []struct {
+		name           string
+		opts           []Option
+		expectParent   bool
+		expectLink     bool
+		expectedReason string
+	}{
+		{"discard by default", nil, false, false, RootReasonExpired},
+		{"discard", []Option{WithTraceExpirationPolicy(ExpirationPolicyDiscard)}, false, false, RootReasonExpired},
+		{"link", []Option{WithTraceExpirationPolicy(ExpirationPolicyLink)}, false, true, RootReasonExpired},
+		{"extend", []Option{WithTraceExpirationPolicy(ExpirationPolicyExtend)}, true, false, ""},
+		{"unknown policy is ignored", []Option{WithTraceExpirationPolicy("forever")}, false, false, RootReasonExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Annotations: expiredAnnotations},
+			}).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard(), nil, tt.opts...)
+
+			request := ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "pod", Namespace: "default"})
+			_, span, err := tracingClient.StartTrace(context.Background(), &request, &corev1.Pod{})
+			require.NoError(t, err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			assert.Equal(t, tt.expectParent, spans[0].Parent.IsValid())
+			assert.Equal(t, tt.expectParent, spans[0].SpanContext.TraceID().String() == testTraceIDHex)
+			if tt.expectLink {
+				require.Len(t, spans[0].Links, 1)
+				assert.Equal(t, testTraceIDHex, spans[0].Links[0].SpanContext.TraceID().String())
+				assert.Equal(t, testSpanIDHex, spans[0].Links[0].SpanContext.SpanID().String())
+			} else {
+				assert.Empty(t, spans[0].Links)
+			}
+			attrs := attribute.NewSet(spans[0].Attributes...)
+			reason, _ := attrs.Value(RootReasonAttributeKey)
+			assert.Equal(t, tt.expectedReason, reason.AsString())
+		})
+	}
+}
+
+func TestUpdateWithStaleResourceVersion(t *testing.T) {
+	tests := []struct {
+		name             string
+		clientOpts       []Option
+		updateOpts       []client.UpdateOption
+		expectedPatchOps *client.PatchOptions
+	}{
+		{
+			name:             "falls back to a merge patch of the live object",
+			expectedPatchOps: &client.PatchOptions{},
+		},
+		{
+			name:             "fallback keeps the update options",
+			updateOpts:       []client.UpdateOption{client.FieldOwner("my-controller"), client.DryRunAll},
+			expectedPatchOps: &client.PatchOptions{DryRun: []string{metav1.DryRunAll}, FieldManager: "my-controller"},
+		},
+		{
+			name:       "strict mode returns the conflict of the update",
+			clientOpts: []Option{WithStrictUpdateConflicts()},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var patchOpts *client.PatchOptions
+			k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"},
+			}).WithInterceptorFuncs(interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+					patchOpts = (&client.PatchOptions{}).ApplyOptions(opts)
+					return c.Patch(ctx, obj, patch, opts...)
+				},
+			}).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil, tt.clientOpts...)
+
+			ctx := context.Background()
+			pod := &corev1.Pod{}
+			require.NoError(t, k8sClient.Get(ctx, types.NamespacedName{Name: "pod", Namespace: "default"}, pod))
+			// Someone else updates the pod, so the copy held by the caller is stale
+			concurrent := pod.DeepCopy()
+			concurrent.Labels = map[string]string{"updated": "concurrently"}
+			require.NoError(t, k8sClient.Update(ctx, concurrent))
+
+			pod.Spec.Containers = []corev1.Container{{Name: "web", Image: "nginx"}}
+			err := tracingClient.Update(ctx, pod, tt.updateOpts...)
+			stored := &corev1.Pod{}
+			require.NoError(t, k8sClient.Get(ctx, client.ObjectKeyFromObject(pod), stored))
+			if tt.expectedPatchOps == nil {
+				// The concurrent change is kept and the caller gets the conflict to retry on the latest version
+				assert.True(t, apierrors.IsConflict(err), "expected a conflict, got %v", err)
+				assert.Equal(t, map[string]string{"updated": "concurrently"}, stored.Labels)
+				assert.Empty(t, stored.Spec.Containers)
+				assert.Nil(t, patchOpts)
+				return
+			}
+			require.NoError(t, err)
+			if len(tt.expectedPatchOps.DryRun) == 0 {
+				assert.Equal(t, pod.Spec.Containers, stored.Spec.Containers)
+			}
+			require.NotNil(t, patchOpts)
+			assert.Equal(t, tt.expectedPatchOps.DryRun, patchOpts.DryRun)
+			assert.Equal(t, tt.expectedPatchOps.FieldManager, patchOpts.FieldManager)
+		})
+	}
+}
+
+func TestUpdateWithAnnotationFieldOwnerCheck(t *testing.T) {
+	ownedBy := func(manager string) []metav1.ManagedFieldsEntry {
+		return []metav1.ManagedFieldsEntry{{
+			Manager:    manager,
+			Operation:  metav1.ManagedFieldsOperationApply,
+			APIVersion: "v1",
+			FieldsType: "FieldsV1",
+			FieldsV1:   &metav1.FieldsV1{Raw: []byte(`{"f:metadata":{"f:annotations":{"f:` + constants.DefaultTraceParentAnnotation + `":{}}}}`)},
+		}}
+	}
+	tests := []struct {
+		name          string
+		managedFields []metav1.ManagedFieldsEntry
+		expectWrite   bool
+	}{
+		{"owned by another operator", ownedBy("other-operator"), false},
+		{"owned by this operator", ownedBy("this-operator"), true},
+		{"not owned", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default", ManagedFields: tt.managedFields},
+			}).Build()
+			tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("test"), logr.Discard(), nil,
+				WithAnnotationFieldOwnerCheck("this-operator"),
+			)
+
+			ctx, span := tracingClient.StartSpan(context.Background(), "reconcile")
+			pod := &corev1.Pod{}
+			require.NoError(t, tracingClient.Get(ctx, client.ObjectKey{Name: "test-pod", Namespace: "default"}, pod))
+			pod.Labels = map[string]string{"updated": "true"}
+			require.NoError(t, tracingClient.Update(ctx, pod))
+			span.End()
+
+			stored := &corev1.Pod{}
+			require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(pod), stored))
+			assert.Equal(t, "true", stored.Labels["updated"])
+			if tt.expectWrite {
+				assert.Contains(t, stored.Annotations[constants.DefaultTraceParentAnnotation], span.SpanContext().TraceID().String())
+			} else {
+				assert.NotContains(t, stored.Annotations, constants.DefaultTraceParentAnnotation)
+			}
+			// The spans are recorded whether or not the annotation is written
+			assert.GreaterOrEqual(t, len(exporter.GetSpans()), 2)
+		})
+	}
+}
+
+func TestTraceAnnotationWritesCopyAnnotations(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:        "pod",
+		Namespace:   "default",
+		Annotations: map[string]string{"example.com/owner": "team"},
+	}}
+	k8sClient := fake.NewClientBuilder().WithObjects(pod.DeepCopy()).Build()
+	tracingClient := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil)
+	require.NoError(t, k8sClient.Get(context.Background(), client.ObjectKeyFromObject(pod), pod))
+
+	// A user goroutine keeps reading the map it got from the object while the client writes the object
+	held := pod.GetAnnotations()
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				for key, value := range held {
+					_ = key + value
+				}
+			}
+		}
+	}()
+
+	ctx, span := tracingClient.StartSpan(context.Background(), "write")
+	for i := 0; i < 10; i++ {
+		pod.Spec.Containers = []corev1.Container{{Name: "web", Image: fmt.Sprintf("nginx:%d", i)}}
+		require.NoError(t, tracingClient.Update(ctx, pod))
+	}
+	span.End()
+	require.NotEmpty(t, pod.GetAnnotations()[constants.DefaultTraceParentAnnotation])
+	require.NoError(t, tracingClient.EndTrace(context.Background(), pod))
+	close(stop)
+	<-done
+
+	assert.Equal(t, map[string]string{"example.com/owner": "team"}, held)
+	assert.Empty(t, pod.GetAnnotations()[constants.DefaultTraceParentAnnotation])
+}
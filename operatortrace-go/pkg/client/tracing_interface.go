@@ -9,6 +9,9 @@ import (
 
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
 	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -17,8 +20,23 @@ type TracingClient interface {
 	client.Client
 	trace.Tracer
 
+	// RESTMapper returns the mapper set with WithRESTMapper, or the wrapped client's mapper.
+	RESTMapper() meta.RESTMapper
+	// GroupVersionKindFor returns the GVK of obj in the tracing client's scheme.
+	GroupVersionKindFor(obj runtime.Object) (schema.GroupVersionKind, error)
+	// IsObjectNamespaced reports whether the type of obj is namespace-scoped, resolved with the tracing client's
+	// scheme and RESTMapper.
+	IsObjectNamespaced(obj runtime.Object) (bool, error)
+
 	StartTrace(ctx context.Context, requestWithTraceID *tracingtypes.RequestWithTraceID, obj client.Object, opts ...client.GetOption) (context.Context, trace.Span, error)
 	EndTrace(ctx context.Context, obj client.Object, opts ...client.PatchOption) error
 	StartSpan(ctx context.Context, operationName string) (context.Context, trace.Span)
-	EmbedTraceIDInRequest(requestWithTraceID *tracingtypes.RequestWithTraceID, obj client.Object) error
+	StartSpanWithOptions(ctx context.Context, operationName string, opts ...trace.SpanStartOption) (context.Context, trace.Span)
+	StartSpanForObject(ctx context.Context, operationName string, obj client.Object, opts ...trace.SpanStartOption) (context.Context, trace.Span)
+	EmbedTraceIDInRequest(requestWithTraceID *tracingtypes.RequestWithTraceID, obj client.Object, eventKind ...string) error
+	SchemeSubset(types ...client.Object) error
+	ForEach(ctx context.Context, list client.ObjectList, fn func(ctx context.Context, obj client.Object) error, opts ...client.ListOption) error
+	GetFresh(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error
+	StartTraceForList(ctx context.Context, list client.ObjectList, opts ...client.ListOption) (context.Context, trace.Span, error)
+	ItemSpan(ctx context.Context, obj client.Object) (context.Context, trace.Span)
 }
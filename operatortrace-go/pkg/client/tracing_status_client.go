@@ -14,7 +14,6 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
 )
 
 type tracingStatusClient struct {
@@ -24,10 +23,14 @@ type tracingStatusClient struct {
 	trace.Tracer
 	Logger  logr.Logger
 	options Options
+
+	unresolvedKinds *unresolvedKinds
+	gvks            *gvkCache
 }
 
 var _ client.StatusWriter = (*tracingStatusClient)(nil)
 
+// Status returns a status writer that traces writes unless the client, or the context of the write, is noop.
 func (tc *tracingClient) Status() client.StatusWriter {
 	return &tracingStatusClient{
 		scheme:       tc.scheme,
@@ -36,19 +39,24 @@ func (tc *tracingClient) Status() client.StatusWriter {
 		Tracer:       tc.Tracer,
 		Logger:       tc.Logger,
 		options:      tc.options,
+
+		unresolvedKinds: tc.unresolvedKinds,
+		gvks:            tc.gvks,
 	}
 }
 
 func (ts *tracingStatusClient) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
-	gvk, err := apiutil.GVKForObject(obj, ts.scheme)
-	if err != nil {
-		return fmt.Errorf("problem getting the scheme: %w", err)
+	if ts.options.withCallOptions(ctx).Noop {
+		return ts.StatusWriter.Update(ctx, obj, opts...)
 	}
 
+	gvk := writeGVK(obj, ts.scheme, ts.gvks, ts.Logger, ts.unresolvedKinds)
+
 	kind := gvk.GroupKind().Kind
+	name := ts.options.withCallOptions(ctx).redactedName(obj, gvk)
 
 	// Prepare span (internal) for diff check
-	ctx, spanPrepare := startSpanFromContext(ctx, ts.Logger, ts.Tracer, obj, ts.scheme, ts.options, fmt.Sprintf("Prepare StatusUpdate %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{})
+	ctx, spanPrepare := startSpanFromContext(ctx, ts.Logger, ts.Tracer, obj, ts.scheme, ts.options, fmt.Sprintf("Prepare StatusUpdate %s %s", kind, name), [10]tracingtypes.LinkedSpan{})
 	defer spanPrepare.End()
 
 	existingObj := obj.DeepCopyObject().(client.Object)
@@ -57,20 +65,20 @@ func (ts *tracingStatusClient) Update(ctx context.Context, obj client.Object, op
 	}
 
 	if !predicates.HasSignificantUpdate(existingObj, obj) {
-		ts.Logger.Info("Skipping update as object content has not changed", "object", obj.GetName())
+		ts.Logger.Info("Skipping update as object content has not changed", "object", name)
 		return nil
 	}
 
 	// Producer span for the actual status update
 	updateSpanOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindProducer)}
-	ctx, spanUpdate := startSpanFromContext(ctx, ts.Logger, ts.Tracer, obj, ts.scheme, ts.options, fmt.Sprintf("StatusUpdate %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{}, updateSpanOpts...)
+	ctx, spanUpdate := startSpanFromContext(ctx, ts.Logger, ts.Tracer, obj, ts.scheme, ts.options, ts.options.withCallOptions(ctx).spanName("StatusUpdate", kind, obj, gvk), linkedSpansFromContext(ctx), updateSpanOpts...)
 	defer spanUpdate.End()
 
 	setConditionMessage("TraceID", spanUpdate.SpanContext().TraceID().String(), obj, ts.scheme)
 	setConditionMessage("SpanID", spanUpdate.SpanContext().SpanID().String(), obj, ts.scheme)
 
-	ts.Logger.Info("updating status object", "object", obj.GetName())
-	err = ts.StatusWriter.Update(ctx, obj, opts...)
+	ts.Logger.Info("updating status object", "object", name)
+	err := ts.StatusWriter.Update(ctx, obj, opts...)
 	if err != nil {
 		spanUpdate.RecordError(err)
 	}
@@ -78,15 +86,17 @@ func (ts *tracingStatusClient) Update(ctx context.Context, obj client.Object, op
 }
 
 func (ts *tracingStatusClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
-	gvk, err := apiutil.GVKForObject(obj, ts.scheme)
-	if err != nil {
-		return fmt.Errorf("problem getting the scheme: %w", err)
+	if ts.options.withCallOptions(ctx).Noop {
+		return ts.StatusWriter.Patch(ctx, obj, patch, opts...)
 	}
 
+	gvk := writeGVK(obj, ts.scheme, ts.gvks, ts.Logger, ts.unresolvedKinds)
+
 	kind := gvk.GroupKind().Kind
+	name := ts.options.withCallOptions(ctx).redactedName(obj, gvk)
 
 	// Prepare span (internal) for diff check
-	ctx, spanPrepare := startSpanFromContext(ctx, ts.Logger, ts.Tracer, obj, ts.scheme, ts.options, fmt.Sprintf("Prepare StatusPatch %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{})
+	ctx, spanPrepare := startSpanFromContext(ctx, ts.Logger, ts.Tracer, obj, ts.scheme, ts.options, fmt.Sprintf("Prepare StatusPatch %s %s", kind, name), [10]tracingtypes.LinkedSpan{})
 	defer spanPrepare.End()
 
 	existingObj := obj.DeepCopyObject().(client.Object)
@@ -95,20 +105,20 @@ func (ts *tracingStatusClient) Patch(ctx context.Context, obj client.Object, pat
 	}
 
 	if !predicates.HasSignificantUpdate(existingObj, obj) {
-		ts.Logger.Info("Skipping update as object content has not changed", "object", obj.GetName())
+		ts.Logger.Info("Skipping update as object content has not changed", "object", name)
 		return nil
 	}
 
 	// Producer span for actual status patch
 	patchSpanOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindProducer)}
-	ctx, spanPatch := startSpanFromContext(ctx, ts.Logger, ts.Tracer, obj, ts.scheme, ts.options, fmt.Sprintf("StatusPatch %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{}, patchSpanOpts...)
+	ctx, spanPatch := startSpanFromContext(ctx, ts.Logger, ts.Tracer, obj, ts.scheme, ts.options, ts.options.withCallOptions(ctx).spanName("StatusPatch", kind, obj, gvk), linkedSpansFromContext(ctx), patchSpanOpts...)
 	defer spanPatch.End()
 
 	setConditionMessage("TraceID", spanPatch.SpanContext().TraceID().String(), obj, ts.scheme)
 	setConditionMessage("SpanID", spanPatch.SpanContext().SpanID().String(), obj, ts.scheme)
 
-	ts.Logger.Info("patching status object", "object", obj.GetName())
-	err = ts.StatusWriter.Patch(ctx, obj, patch, opts...)
+	ts.Logger.Info("patching status object", "object", name)
+	err := ts.StatusWriter.Patch(ctx, obj, patch, opts...)
 	if err != nil {
 		spanPatch.RecordError(err)
 	}
@@ -117,23 +127,25 @@ func (ts *tracingStatusClient) Patch(ctx context.Context, obj client.Object, pat
 }
 
 func (ts *tracingStatusClient) Create(ctx context.Context, obj client.Object, subResource client.Object, opts ...client.SubResourceCreateOption) error {
-	gvk, err := apiutil.GVKForObject(obj, ts.scheme)
-	if err != nil {
-		return fmt.Errorf("problem getting the scheme: %w", err)
+	if ts.options.withCallOptions(ctx).Noop {
+		return ts.StatusWriter.Create(ctx, obj, subResource, opts...)
 	}
 
+	gvk := writeGVK(obj, ts.scheme, ts.gvks, ts.Logger, ts.unresolvedKinds)
+
 	kind := gvk.GroupKind().Kind
+	name := ts.options.withCallOptions(ctx).redactedName(obj, gvk)
 
 	// Single producer span (no diff check required for create)
 	createSpanOpts := []trace.SpanStartOption{trace.WithSpanKind(trace.SpanKindProducer)}
-	ctx, spanCreate := startSpanFromContext(ctx, ts.Logger, ts.Tracer, obj, ts.scheme, ts.options, fmt.Sprintf("StatusCreate %s %s", kind, obj.GetName()), [10]tracingtypes.LinkedSpan{}, createSpanOpts...)
+	ctx, spanCreate := startSpanFromContext(ctx, ts.Logger, ts.Tracer, obj, ts.scheme, ts.options, ts.options.withCallOptions(ctx).spanName("StatusCreate", kind, obj, gvk), linkedSpansFromContext(ctx), createSpanOpts...)
 	defer spanCreate.End()
 
 	setConditionMessage("TraceID", spanCreate.SpanContext().TraceID().String(), obj, ts.scheme)
 	setConditionMessage("SpanID", spanCreate.SpanContext().SpanID().String(), obj, ts.scheme)
 
-	ts.Logger.Info("creating status object", "object", obj.GetName())
-	err = ts.StatusWriter.Create(ctx, obj, subResource, opts...)
+	ts.Logger.Info("creating status object", "object", name)
+	err := ts.StatusWriter.Create(ctx, obj, subResource, opts...)
 	if err != nil {
 		spanCreate.RecordError(err)
 	}
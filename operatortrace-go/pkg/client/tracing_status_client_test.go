@@ -0,0 +1,82 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/tracing_status_client_test.go
+
+package client
+
+import (
+	"context"
+	"testing"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Compile guards: the status client and the main client share startSpanFromContext, so a signature change
+// must be made in both places.
+var (
+	_ func(context.Context, logr.Logger, trace.Tracer, client.Object, *runtime.Scheme, Options, string, [10]tracingtypes.LinkedSpan, ...trace.SpanStartOption) (context.Context, trace.Span) = startSpanFromContext
+	_ client.StatusWriter                                                                                                                                                                    = (*tracingStatusClient)(nil)
+)
+
+func TestStatusClientInheritsOptions(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().Build()
+	tc := NewTracingClientWithOptions(k8sClient, k8sClient, initTracer(), logr.Discard(), nil,
+		WithAnnotationPrefix("example.com"),
+		WithIncomingTraceRelationship(TraceParentRelationshipParent),
+	)
+
+	statusClient, ok := tc.Status().(*tracingStatusClient)
+	require.True(t, ok)
+	assert.Equal(t, tracingClientOptionsForTest(t, tc), statusClient.options)
+}
+
+func TestStatusUpdateLinksRequestSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer func() { _ = tp.Shutdown(context.Background()) }()
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+	k8sClient := fake.NewClientBuilder().WithObjects(pod).WithStatusSubresource(pod).Build()
+	tc := NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace"), logr.Discard())
+
+	linked := tracingtypes.LinkedSpan{TraceID: testTraceIDHex, SpanID: testSpanIDHex}
+	req := tracingtypes.RequestWithTraceID{
+		Request:         ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "test-pod", Namespace: "default"}},
+		LinkedSpans:     [10]tracingtypes.LinkedSpan{linked},
+		LinkedSpanCount: 1,
+	}
+
+	reconciled := &corev1.Pod{}
+	ctx, span, err := tc.StartTrace(context.Background(), &req, reconciled)
+	require.NoError(t, err)
+
+	reconciled.Status.Phase = corev1.PodRunning
+	require.NoError(t, tc.Status().Update(ctx, reconciled))
+	span.End()
+
+	var statusSpan *tracetest.SpanStub
+	spans := exporter.GetSpans()
+	for i := range spans {
+		if spans[i].Name == "StatusUpdate Pod test-pod" {
+			statusSpan = &spans[i]
+		}
+	}
+	require.NotNil(t, statusSpan)
+	assert.Equal(t, span.SpanContext().TraceID(), statusSpan.SpanContext.TraceID())
+	require.Len(t, statusSpan.Links, 1)
+	assert.Equal(t, testTraceIDHex, statusSpan.Links[0].SpanContext.TraceID().String())
+	assert.Equal(t, testSpanIDHex, statusSpan.Links[0].SpanContext.SpanID().String())
+}
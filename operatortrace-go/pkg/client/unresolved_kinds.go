@@ -0,0 +1,48 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/client/unresolved_kinds.go
+
+package client
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// unresolvedKinds remembers the Go types the scheme could not resolve a GVK for, so each is only logged once.
+type unresolvedKinds struct {
+	seen sync.Map
+}
+
+// writeGVK returns the GVK of obj used to name the spans of a write.
+// A scheme that does not know obj's type must not break the write, so the Go type name stands in for the kind
+// and the miss is logged once per type. The write itself then succeeds or fails on the wrapped client alone.
+func writeGVK(obj client.Object, scheme *runtime.Scheme, gvks *gvkCache, logger logr.Logger, unresolved *unresolvedKinds) schema.GroupVersionKind {
+	gvk, err := gvks.gvkForObject(obj, scheme)
+	if err == nil {
+		return gvk
+	}
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil {
+		return schema.GroupVersionKind{}
+	}
+	if unresolved != nil {
+		if _, logged := unresolved.seen.LoadOrStore(t, struct{}{}); logged {
+			return schema.GroupVersionKind{Kind: t.Name()}
+		}
+	}
+	logger.Info("Object type is not registered in the tracing client's scheme, naming its spans after the Go type", "type", t.String(), "error", err.Error())
+	return schema.GroupVersionKind{Kind: t.Name()}
+}
+
+func (tc *tracingClient) writeGVK(obj client.Object) schema.GroupVersionKind {
+	return writeGVK(obj, tc.scheme, tc.gvks, tc.Logger, tc.unresolvedKinds)
+}
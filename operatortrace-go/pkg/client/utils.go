@@ -5,10 +5,14 @@
 package client
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"reflect"
 
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/types"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -25,6 +29,64 @@ func ClientObjectToRequestWithTraceID(key *ctrlclient.ObjectKey) tracingtypes.Re
 	}
 }
 
+// RequestWithTraceIDFromHTTPRequest builds a request for key whose parent is the trace carried by the
+// W3C trace context headers of r, e.g. a webhook delivery that should trigger a reconcile.
+// The parent is recorded with Kind "HTTP", the request path as Name and the method as EventKind.
+// When r carries no valid trace context only the key is set.
+func RequestWithTraceIDFromHTTPRequest(r *http.Request, key types.NamespacedName) tracingtypes.RequestWithTraceID {
+	request := tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: key},
+	}
+	if r == nil {
+		return request
+	}
+	traceParent, traceState, ok := tracecontext.ExtractFromHTTPHeaders(r.Header)
+	if !ok {
+		return request
+	}
+	spanContext, err := tracecontext.SpanContextFromTraceData(traceParent, traceState)
+	if err != nil {
+		return request
+	}
+	request.Parent = tracingtypes.RequestParent{
+		TraceID:   spanContext.TraceID().String(),
+		SpanID:    spanContext.SpanID().String(),
+		Kind:      "HTTP",
+		EventKind: r.Method,
+	}
+	if r.URL != nil {
+		request.Parent.Name = r.URL.Path
+	}
+	return request
+}
+
+// GetTraceIDFromContext returns the trace ID of the span in ctx as a hex string, or "" if ctx has no valid span context.
+// See GetTraceContextStrings.
+func GetTraceIDFromContext(ctx context.Context) string {
+	traceID, _ := GetTraceContextStrings(ctx)
+	return traceID
+}
+
+// GetSpanIDFromContext returns the span ID of the span in ctx as a hex string, or "" if ctx has no valid span context.
+// See GetTraceContextStrings.
+func GetSpanIDFromContext(ctx context.Context) string {
+	_, spanID := GetTraceContextStrings(ctx)
+	return spanID
+}
+
+// GetTraceContextStrings returns the trace and span IDs of the active span in ctx as hex strings, falling back to a
+// remote span context stored with trace.ContextWithRemoteSpanContext. Both are empty when neither is valid.
+func GetTraceContextStrings(ctx context.Context) (traceID, spanID string) {
+	spanContext := trace.SpanFromContext(ctx).SpanContext()
+	if !spanContext.IsValid() {
+		spanContext = trace.SpanContextFromContext(ctx)
+	}
+	if !spanContext.IsValid() {
+		return "", ""
+	}
+	return spanContext.TraceID().String(), spanContext.SpanID().String()
+}
+
 func convertToString(value interface{}) (string, error) {
 	v := reflect.ValueOf(value)
 	switch v.Kind() {
@@ -5,9 +5,16 @@
 package client
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
 	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -35,3 +42,98 @@ func TestConvertToString(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestWithTraceIDFromHTTPRequest(t *testing.T) {
+	key := types.NamespacedName{Name: "webhook-target", Namespace: "default"}
+	traceParent := "00-" + testTraceIDHex + "-" + testSpanIDHex + "-01"
+
+	tests := []struct {
+		name           string
+		header         http.Header
+		expectedParent tracingtypes.RequestParent
+	}{
+		{
+			name:   "traceparent header",
+			header: http.Header{"Traceparent": {traceParent}},
+			expectedParent: tracingtypes.RequestParent{
+				TraceID:   testTraceIDHex,
+				SpanID:    testSpanIDHex,
+				Name:      "/events",
+				Kind:      "HTTP",
+				EventKind: http.MethodPost,
+			},
+		},
+		{
+			name:   "traceparent and tracestate headers",
+			header: http.Header{"Traceparent": {traceParent}, "Tracestate": {"vendor=value"}},
+			expectedParent: tracingtypes.RequestParent{
+				TraceID:   testTraceIDHex,
+				SpanID:    testSpanIDHex,
+				Name:      "/events",
+				Kind:      "HTTP",
+				EventKind: http.MethodPost,
+			},
+		},
+		{
+			name:   "invalid traceparent header",
+			header: http.Header{"Traceparent": {"not-a-traceparent"}},
+		},
+		{
+			name: "no trace headers",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/events", nil)
+			for name, values := range tt.header {
+				r.Header[name] = values
+			}
+
+			request := RequestWithTraceIDFromHTTPRequest(r, key)
+			assert.Equal(t, key, request.NamespacedName)
+			assert.Equal(t, tt.expectedParent, request.Parent)
+			assert.Zero(t, request.LinkedSpanCount)
+		})
+	}
+
+	t.Run("nil request", func(t *testing.T) {
+		request := RequestWithTraceIDFromHTTPRequest(nil, key)
+		assert.Equal(t, tracingtypes.RequestWithTraceID{Request: request.Request}, request)
+		assert.Equal(t, key, request.NamespacedName)
+	})
+}
+
+func TestGetTraceContextStrings(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	activeCtx, span := tp.Tracer("operatortrace").Start(context.Background(), "active")
+	defer span.End()
+
+	remote := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		expectedTraceID string
+		expectedSpanID  string
+	}{
+		{"no span context", context.Background(), "", ""},
+		{"active span", activeCtx, span.SpanContext().TraceID().String(), span.SpanContext().SpanID().String()},
+		{"remote span context", trace.ContextWithRemoteSpanContext(context.Background(), remote), "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceID, spanID := GetTraceContextStrings(tt.ctx)
+			assert.Equal(t, tt.expectedTraceID, traceID)
+			assert.Equal(t, tt.expectedSpanID, spanID)
+			assert.Equal(t, tt.expectedTraceID, GetTraceIDFromContext(tt.ctx))
+			assert.Equal(t, tt.expectedSpanID, GetSpanIDFromContext(tt.ctx))
+		})
+	}
+}
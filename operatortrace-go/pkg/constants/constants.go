@@ -14,10 +14,24 @@ const (
 	EmittedTraceParentAnnotationSuffix = "traceparent"
 	// EmittedTraceStateAnnotationSuffix controls the suffix used for tracestate annotations emitted by operatortrace.
 	EmittedTraceStateAnnotationSuffix = "tracestate"
+	// OwnerTraceParentAnnotationSuffix controls the suffix used for the owner's traceparent linked from child objects.
+	OwnerTraceParentAnnotationSuffix = "owner-traceparent"
 
 	DefaultTraceParentAnnotation = DefaultAnnotationPrefix + "/" + EmittedTraceParentAnnotationSuffix
 	DefaultTraceStateAnnotation  = DefaultAnnotationPrefix + "/" + EmittedTraceStateAnnotationSuffix
 	TraceStateTimestampKey       = "operatortrace_ts"
+	// TraceStateChainSampledKey records in tracestate whether the trace chain sampler kept the chain ("1") or not ("0").
+	TraceStateChainSampledKey = "operatortrace_sampled"
+	// TraceStateHopsKey records in tracestate how many controllers persisted the trace chain so far.
+	TraceStateHopsKey = "operatortrace_hops"
+	// TraceStateChainStartKey records in tracestate when the first hop of the trace chain persisted it (RFC 3339).
+	TraceStateChainStartKey = "operatortrace_start"
+	// TraceStateRootSpanKey records in tracestate the span ID of the span that persisted the trace chain first.
+	TraceStateRootSpanKey = "operatortrace_root"
+	// TraceStateKindsKey records in tracestate the kinds the trace chain was persisted on, separated by ".".
+	TraceStateKindsKey = "operatortrace_kinds"
+
+	DefaultOwnerTraceParentAnnotation = DefaultAnnotationPrefix + "/" + OwnerTraceParentAnnotationSuffix
 
 	// Legacy annotation keys are retained for backwards compatibility and migration logic.
 	LegacyTraceIDAnnotation     = DefaultAnnotationPrefix + "/trace-id"
@@ -26,6 +40,13 @@ const (
 
 	ResourceVersionKey = "resourceVersion"
 
+	// InstrumentationScopeName is the tracer name operatortrace spans are expected to be created with.
+	InstrumentationScopeName = "operatortrace"
+	// TracerName is the instrumentation scope of the tracer operatortrace obtains from a TracerProvider.
+	TracerName = "github.com/Azure/operatortrace"
+	// ModulePath is the Go module path used to look up the library version from build info.
+	ModulePath = "github.com/Azure/operatortrace/operatortrace-go"
+
 	// TraceExpirationTime is kept for backward compatibility (minutes).
 	TraceExpirationTime = 20
 )
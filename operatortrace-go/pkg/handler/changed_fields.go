@@ -0,0 +1,21 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/handler/changed_fields.go
+
+package handler
+
+import (
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+)
+
+// changedFields returns the fields that differ between oldObj and newObj in the RequestParent.ChangedFields
+// format. It returns "" when either object is missing or delivered as a tombstone.
+func changedFields(oldObj, newObj any) string {
+	oldClientObj, oldTombstone := objectFromEvent(oldObj)
+	newClientObj, newTombstone := objectFromEvent(newObj)
+	if oldClientObj == nil || newClientObj == nil || oldTombstone || newTombstone {
+		return ""
+	}
+	return tracingtypes.JoinChangedFields(predicates.ChangedFields(oldClientObj, newClientObj)...)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/handler/changed_fields_test.go
+
+package handler
+
+import (
+	"context"
+	"testing"
+
+	tracingqueue "github.com/Azure/operatortrace/operatortrace-go/pkg/tracingqueue"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestChangedFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(pod *corev1.Pod)
+		expected string
+	}{
+		{"no change", func(pod *corev1.Pod) {}, ""},
+		{"spec", func(pod *corev1.Pod) { pod.Spec.NodeName = "node1" }, "spec"},
+		{"status", func(pod *corev1.Pod) { pod.Status.Phase = corev1.PodRunning }, "status"},
+		{"spec and status", func(pod *corev1.Pod) {
+			pod.Spec.NodeName = "node1"
+			pod.Status.Phase = corev1.PodRunning
+		}, "spec,status"},
+		{"labels", func(pod *corev1.Pod) { pod.Labels = map[string]string{"app": "test"} }, "labels"},
+		{"annotations", func(pod *corev1.Pod) { pod.Annotations["team"] = "tracing" }, "annotations"},
+		{"finalizers", func(pod *corev1.Pod) { pod.Finalizers = []string{"example.com/cleanup"} }, "finalizers"},
+		{"trace annotations only", func(pod *corev1.Pod) {
+			pod.Annotations = traceAnnotations("cccccccccccccccccccccccccccccccc", "dddddddddddddddd")
+		}, ""},
+		{"resource version only", func(pod *corev1.Pod) { pod.ResourceVersion = "2" }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldPod := newTracedPod("pod1")
+			newPod := oldPod.DeepCopy()
+			tt.mutate(newPod)
+			assert.Equal(t, tt.expected, changedFields(oldPod, newPod))
+		})
+	}
+
+	t.Run("missing object", func(t *testing.T) {
+		assert.Equal(t, "", changedFields(nil, newTracedPod("pod1")))
+	})
+	t.Run("tombstone", func(t *testing.T) {
+		assert.Equal(t, "", changedFields(cache.DeletedFinalStateUnknown{Obj: newTracedPod("pod1")}, newTracedPod("pod1")))
+	})
+}
+
+func TestEnqueueRequestForObjectUpdateRecordsChangedFields(t *testing.T) {
+	h := &EnqueueRequestForObject{Scheme: scheme.Scheme}
+	queue := tracingqueue.NewTracingQueue()
+
+	oldPod := newTracedPod("pod1")
+	newPod := oldPod.DeepCopy()
+	newPod.Status.Phase = corev1.PodRunning
+	h.Update(context.TODO(), event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod}, queue)
+
+	req, _ := queue.Get()
+	assert.Equal(t, "Update", req.Parent.EventKind)
+	assert.Equal(t, "status", req.Parent.ChangedFields)
+	assert.Equal(t, baseTraceID, req.Parent.TraceID)
+}
+
+func TestEnqueueOwnerUpdateRecordsChangedFields(t *testing.T) {
+	restmap := meta.NewDefaultRESTMapper([]schema.GroupVersion{appsv1.SchemeGroupVersion})
+	restmap.Add(appsv1.SchemeGroupVersion.WithKind("ReplicaSet"), meta.RESTScopeNamespace)
+	h := EnqueueRequestForOwner(scheme.Scheme, restmap, &appsv1.ReplicaSet{})
+
+	tests := []struct {
+		name     string
+		mutate   func(pod *corev1.Pod)
+		expected string
+	}{
+		{"spec only", func(pod *corev1.Pod) { pod.Spec.NodeName = "node1" }, "spec"},
+		{"status only", func(pod *corev1.Pod) { pod.Status.Phase = corev1.PodRunning }, "status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldPod := newTracedPod("pod1")
+			oldPod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "owner", UID: "abcdef1"}}
+			newPod := oldPod.DeepCopy()
+			tt.mutate(newPod)
+
+			queue := tracingqueue.NewTracingQueue()
+			h.Update(context.TODO(), event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod}, queue)
+
+			req, _ := queue.Get()
+			assert.Equal(t, "owner", req.Name)
+			assert.Equal(t, tt.expected, req.Parent.ChangedFields)
+		})
+	}
+}
@@ -33,8 +33,10 @@ import (
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
@@ -63,21 +65,26 @@ type TypedEnqueueRequestForObject[object client.Object] struct {
 	// AnnotationConfig overrides which annotation keys are read for trace context.
 	// If nil, defaults to the operatortrace default keys.
 	AnnotationConfig *tracecontext.AnnotationExtractionConfig
+
+	// AnnotationCodec, when set, reads the trace context instead of AnnotationConfig and marks the parent of the
+	// requests it accepts as verified. Set it to the codec of the tracing client.
+	AnnotationCodec tracingclient.AnnotationCodec
+
+	// ClusterName is recorded on every request so controllers watching several clusters keep the same object
+	// in different clusters apart. Empty means the controller's own cluster.
+	ClusterName string
 }
 
 // Create implements EventHandler.
 func (e *TypedEnqueueRequestForObject[T]) Create(ctx context.Context, evt event.TypedCreateEvent[T], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
-	if isNil(evt.Object) {
-		return
-	}
-	q.Add(e.objectToRequestWithTraceID(evt.Object, "Create"))
+	e.enqueueObject(evt.Object, "Create", "", false, q)
 }
 
 // Update implements EventHandler.
 func (e *TypedEnqueueRequestForObject[T]) Update(ctx context.Context, evt event.TypedUpdateEvent[T], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
 	switch {
 	case !isNil(evt.ObjectNew):
-		q.Add(e.objectToRequestWithTraceID(evt.ObjectNew, "Update"))
+		e.enqueueObject(evt.ObjectNew, "Update", changedFields(evt.ObjectOld, evt.ObjectNew), false, q)
 	case !isNil(evt.ObjectOld):
 		// Do not enqueue the old object, as it is not the source of the event.
 	default:
@@ -87,18 +94,94 @@ func (e *TypedEnqueueRequestForObject[T]) Update(ctx context.Context, evt event.
 
 // Delete implements EventHandler.
 func (e *TypedEnqueueRequestForObject[T]) Delete(ctx context.Context, evt event.TypedDeleteEvent[T], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
-	if isNil(evt.Object) {
-		return
-	}
-	q.Add(e.objectToRequestWithTraceID(evt.Object, "Delete"))
+	e.enqueueObject(evt.Object, "Delete", "", evt.DeleteStateUnknown, q)
 }
 
 // Generic implements EventHandler.
 func (e *TypedEnqueueRequestForObject[T]) Generic(ctx context.Context, evt event.TypedGenericEvent[T], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
-	if isNil(evt.Object) {
+	e.enqueueObject(evt.Object, "Generic", "", false, q)
+}
+
+// enqueueObject adds a request for obj, unwrapping tombstones. Objects whose final state is unknown
+// are enqueued as deletions without a parent trace, since their annotations may be stale.
+// changed is recorded as the parent's ChangedFields for update events.
+func (e *TypedEnqueueRequestForObject[T]) enqueueObject(obj any, eventKind, changed string, deleteStateUnknown bool, q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
+	o, tombstone := objectFromEvent(obj)
+	if o == nil {
 		return
 	}
-	q.Add(e.objectToRequestWithTraceID(evt.Object, "Generic"))
+	if tombstone || deleteStateUnknown {
+		request := deletedObjectToRequestWithTraceID(o, e.Scheme)
+		request.ClusterName = e.ClusterName
+		q.Add(request)
+		return
+	}
+	request := e.objectToRequestWithTraceID(o, eventKind)
+	request.Parent.ChangedFields = changed
+	q.Add(request)
+}
+
+// objectFromEvent returns the object carried by an event, unwrapping cache.DeletedFinalStateUnknown
+// tombstones. The second return value reports whether the object was delivered as a tombstone.
+func objectFromEvent(obj any) (client.Object, bool) {
+	switch tombstone := obj.(type) {
+	case cache.DeletedFinalStateUnknown:
+		return objectFromTombstone(tombstone), true
+	case *cache.DeletedFinalStateUnknown:
+		if tombstone == nil {
+			return nil, false
+		}
+		return objectFromTombstone(*tombstone), true
+	}
+	if isNil(obj) {
+		return nil, false
+	}
+	o, ok := obj.(client.Object)
+	if !ok {
+		return nil, false
+	}
+	return o, false
+}
+
+// objectFromTombstone extracts the last known object from a tombstone, falling back to the tombstone key.
+func objectFromTombstone(tombstone cache.DeletedFinalStateUnknown) client.Object {
+	if o, ok := tombstone.Obj.(client.Object); ok && !isNil(o) {
+		return o
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(tombstone.Key)
+	if err != nil || name == "" {
+		return nil
+	}
+	return &metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+	}
+}
+
+// deletedObjectToRequestWithTraceID builds a Delete request for obj without reading its trace context.
+func deletedObjectToRequestWithTraceID(obj client.Object, scheme *runtime.Scheme) tracingtypes.RequestWithTraceID {
+	senderKind := ""
+	if scheme != nil {
+		if gvk, err := apiutil.GVKForObject(obj, scheme); err == nil {
+			senderKind = gvk.GroupKind().Kind
+		}
+	}
+
+	return tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{
+			NamespacedName: types.NamespacedName{
+				Name:      obj.GetName(),
+				Namespace: obj.GetNamespace(),
+			},
+		},
+		Parent: tracingtypes.RequestParent{
+			Name:      obj.GetName(),
+			Kind:      senderKind,
+			EventKind: "Delete",
+		},
+	}
 }
 
 func isNil(arg any) bool {
@@ -114,7 +197,7 @@ func isNil(arg any) bool {
 }
 
 func (e *TypedEnqueueRequestForObject[T]) objectToRequestWithTraceID(obj client.Object, eventKind string) tracingtypes.RequestWithTraceID {
-	traceID, spanID := traceAndSpanIDsFromAnnotations(obj.GetAnnotations(), e.annotationConfig())
+	traceID, spanID, verified := traceAndSpanIDsFromCodec(obj.GetAnnotations(), e.annotationConfig(), e.AnnotationCodec)
 	if (traceID == "" || spanID == "") && e.Scheme != nil {
 		if condTraceID, condSpanID := traceAndSpanIDsFromStatus(obj, e.Scheme); condTraceID != "" && condSpanID != "" {
 			traceID, spanID = condTraceID, condSpanID
@@ -138,12 +221,14 @@ func (e *TypedEnqueueRequestForObject[T]) objectToRequestWithTraceID(obj client.
 				Namespace: obj.GetNamespace(),
 			},
 		},
+		ClusterName: e.ClusterName,
 		Parent: tracingtypes.RequestParent{
 			TraceID:   traceID,
 			SpanID:    spanID,
 			Name:      senderName,
 			Kind:      senderKind,
 			EventKind: eventKind,
+			Verified:  verified,
 		},
 	}
 }
@@ -164,6 +249,24 @@ func defaultAnnotationExtractionConfig() tracecontext.AnnotationExtractionConfig
 	}
 }
 
+// traceAndSpanIDsFromCodec reads the trace and span IDs from annotations, through codec when it is set.
+// It reports whether codec verified them.
+func traceAndSpanIDsFromCodec(annotations map[string]string, cfg tracecontext.AnnotationExtractionConfig, codec tracingclient.AnnotationCodec) (string, string, bool) {
+	if codec == nil {
+		traceID, spanID := traceAndSpanIDsFromAnnotations(annotations, cfg)
+		return traceID, spanID, false
+	}
+	traceParent, traceState, err := codec.Decode(annotations)
+	if err != nil || traceParent == "" {
+		return "", "", false
+	}
+	traceID, spanID := traceAndSpanIDsFromAnnotations(
+		map[string]string{"traceparent": traceParent, "tracestate": traceState},
+		tracecontext.AnnotationExtractionConfig{TraceParentKey: "traceparent", TraceStateKey: "tracestate"},
+	)
+	return traceID, spanID, traceID != ""
+}
+
 func traceAndSpanIDsFromAnnotations(annotations map[string]string, cfg tracecontext.AnnotationExtractionConfig) (string, string) {
 	tc, found := tracecontext.ExtractTraceContextFromAnnotations(annotations, cfg)
 	if !found {
@@ -0,0 +1,89 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/handler/enqueue_builder.go
+
+package handler
+
+import (
+	"slices"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// TypedEnqueueRequestForObjectBuilder builds a TypedEnqueueRequestForObject that reads trace context from custom
+// annotation keys. Keys that are not configured keep the operatortrace defaults.
+type TypedEnqueueRequestForObjectBuilder[T client.Object] struct {
+	scheme      *runtime.Scheme
+	cfg         tracecontext.AnnotationExtractionConfig
+	codec       tracingclient.AnnotationCodec
+	clusterName string
+}
+
+// NewTypedEnqueueRequestForObject starts building a TypedEnqueueRequestForObject with the default annotation keys.
+func NewTypedEnqueueRequestForObject[T client.Object]() *TypedEnqueueRequestForObjectBuilder[T] {
+	return &TypedEnqueueRequestForObjectBuilder[T]{cfg: defaultAnnotationExtractionConfig()}
+}
+
+// WithScheme sets the scheme used to determine the GVK of enqueued objects.
+func (b *TypedEnqueueRequestForObjectBuilder[T]) WithScheme(scheme *runtime.Scheme) *TypedEnqueueRequestForObjectBuilder[T] {
+	b.scheme = scheme
+	return b
+}
+
+// WithPrimaryTraceParentAnnotation reads the traceparent from key instead of the default annotation.
+func (b *TypedEnqueueRequestForObjectBuilder[T]) WithPrimaryTraceParentAnnotation(key string) *TypedEnqueueRequestForObjectBuilder[T] {
+	if key != "" {
+		b.cfg.TraceParentKey = key
+	}
+	return b
+}
+
+// WithFallbackTraceParentAnnotations reads the traceparent from keys, in order, when the primary annotation is not set.
+func (b *TypedEnqueueRequestForObjectBuilder[T]) WithFallbackTraceParentAnnotations(keys ...string) *TypedEnqueueRequestForObjectBuilder[T] {
+	for _, key := range keys {
+		if key != "" {
+			b.cfg.FallbackTraceParentKeys = append(b.cfg.FallbackTraceParentKeys, key)
+		}
+	}
+	return b
+}
+
+// WithLegacyTraceAnnotations reads legacy trace and span IDs from the given keys when no traceparent is found.
+func (b *TypedEnqueueRequestForObjectBuilder[T]) WithLegacyTraceAnnotations(traceIDKey, spanIDKey string) *TypedEnqueueRequestForObjectBuilder[T] {
+	if traceIDKey != "" {
+		b.cfg.LegacyTraceIDKey = traceIDKey
+	}
+	if spanIDKey != "" {
+		b.cfg.LegacySpanIDKey = spanIDKey
+	}
+	return b
+}
+
+// WithAnnotationCodec reads the trace context through codec, which should be the codec of the tracing client.
+func (b *TypedEnqueueRequestForObjectBuilder[T]) WithAnnotationCodec(codec tracingclient.AnnotationCodec) *TypedEnqueueRequestForObjectBuilder[T] {
+	if codec != nil {
+		b.codec = codec
+	}
+	return b
+}
+
+// WithClusterName records clusterName on every request, for controllers watching several clusters.
+func (b *TypedEnqueueRequestForObjectBuilder[T]) WithClusterName(clusterName string) *TypedEnqueueRequestForObjectBuilder[T] {
+	b.clusterName = clusterName
+	return b
+}
+
+// Build returns the configured handler. The builder can keep being used without affecting it.
+func (b *TypedEnqueueRequestForObjectBuilder[T]) Build() *TypedEnqueueRequestForObject[T] {
+	cfg := b.cfg
+	cfg.FallbackTraceParentKeys = slices.Clone(cfg.FallbackTraceParentKeys)
+	return &TypedEnqueueRequestForObject[T]{
+		Scheme:           b.scheme,
+		AnnotationConfig: &cfg,
+		AnnotationCodec:  b.codec,
+		ClusterName:      b.clusterName,
+	}
+}
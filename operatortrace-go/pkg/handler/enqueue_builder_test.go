@@ -0,0 +1,91 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/handler/enqueue_builder_test.go
+
+package handler
+
+import (
+	"context"
+	"testing"
+
+	tracingqueue "github.com/Azure/operatortrace/operatortrace-go/pkg/tracingqueue"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestTypedEnqueueRequestForObjectBuilder(t *testing.T) {
+	const (
+		customTraceID   = "cccccccccccccccccccccccccccccccc"
+		customSpanID    = "dddddddddddddddd"
+		fallbackTraceID = "eeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+		fallbackSpanID  = "ffffffffffffffff"
+	)
+
+	h := NewTypedEnqueueRequestForObject[client.Object]().
+		WithScheme(scheme.Scheme).
+		WithPrimaryTraceParentAnnotation("example.com/traceparent").
+		WithFallbackTraceParentAnnotations("example.com/old-traceparent").
+		WithLegacyTraceAnnotations("example.com/trace-id", "example.com/span-id").
+		Build()
+
+	tests := []struct {
+		name            string
+		annotations     map[string]string
+		expectedTraceID string
+		expectedSpanID  string
+	}{
+		{
+			name: "custom key takes precedence over the default key",
+			annotations: map[string]string{
+				"example.com/traceparent":     mustBuildTraceParent(customTraceID, customSpanID),
+				"example.com/old-traceparent": mustBuildTraceParent(fallbackTraceID, fallbackSpanID),
+			},
+			expectedTraceID: customTraceID,
+			expectedSpanID:  customSpanID,
+		},
+		{
+			name: "fallback key is used when the primary key is missing",
+			annotations: map[string]string{
+				"example.com/old-traceparent": mustBuildTraceParent(fallbackTraceID, fallbackSpanID),
+			},
+			expectedTraceID: fallbackTraceID,
+			expectedSpanID:  fallbackSpanID,
+		},
+		{
+			name: "custom legacy keys",
+			annotations: map[string]string{
+				"example.com/trace-id": customTraceID,
+				"example.com/span-id":  customSpanID,
+			},
+			expectedTraceID: customTraceID,
+			expectedSpanID:  customSpanID,
+		},
+		{
+			name:        "default key is no longer read",
+			annotations: traceAnnotations(baseTraceID, baseSpanID),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod1", Namespace: "default", Annotations: tt.annotations}}
+			queue := tracingqueue.NewTracingQueue()
+			h.Create(context.TODO(), event.CreateEvent{Object: pod}, queue)
+
+			req, _ := queue.Get()
+			assert.Equal(t, tt.expectedTraceID, req.Parent.TraceID)
+			assert.Equal(t, tt.expectedSpanID, req.Parent.SpanID)
+			assert.Equal(t, "Pod", req.Parent.Kind)
+		})
+	}
+}
+
+func TestTypedEnqueueRequestForObjectBuilderDefaults(t *testing.T) {
+	h := NewTypedEnqueueRequestForObject[client.Object]().Build()
+	assert.Equal(t, defaultAnnotationExtractionConfig(), *h.AnnotationConfig)
+	assert.Nil(t, h.Scheme)
+}
@@ -125,6 +125,12 @@ func (e *enqueueRequestsFromMapFunc[object, request]) Generic(
 }
 
 func (e *enqueueRequestsFromMapFunc[object, request]) mapAndEnqueue(ctx context.Context, q workqueue.TypedRateLimitingInterface[request], o object, reqs map[request]empty) {
+	// Hand the mapper the last known object rather than the tombstone wrapping it.
+	if inner, tombstone := objectFromEvent(o); tombstone && inner != nil {
+		if typed, ok := inner.(object); ok {
+			o = typed
+		}
+	}
 	for _, req := range e.toRequests(ctx, o) {
 		_, ok := reqs[req]
 		if !ok {
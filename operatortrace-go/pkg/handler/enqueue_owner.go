@@ -29,8 +29,11 @@ import (
 	"context"
 	"fmt"
 
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/metric"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -74,17 +77,25 @@ func EnqueueRequestForOwner(scheme *runtime.Scheme, mapper meta.RESTMapper, owne
 //
 // TypedEnqueueRequestForOwner is experimental and subject to future change.
 func TypedEnqueueRequestForOwner[object client.Object](scheme *runtime.Scheme, mapper meta.RESTMapper, ownerType client.Object, opts ...OwnerOption) handler.TypedEventHandler[object, tracingtypes.RequestWithTraceID] {
+	e := newEnqueueRequestForOwner[object](scheme, mapper, ownerType)
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// newEnqueueRequestForOwner returns the handler of ownerType without options, panicking when the scheme does not
+// know ownerType.
+func newEnqueueRequestForOwner[object client.Object](scheme *runtime.Scheme, mapper meta.RESTMapper, ownerType client.Object) *enqueueRequestForOwner[object] {
 	e := &enqueueRequestForOwner[object]{
 		ownerType: ownerType,
 		mapper:    mapper,
 		scheme:    scheme,
+		logger:    logr.Discard(),
 	}
 	if err := e.parseOwnerTypeGroupKind(scheme); err != nil {
 		panic(err)
 	}
-	for _, opt := range opts {
-		opt(e)
-	}
 	return e
 }
 
@@ -102,9 +113,61 @@ func WithAnnotationExtractionConfig(cfg tracecontext.AnnotationExtractionConfig)
 	}
 }
 
+// WithAnnotationCodec reads the trace context through codec, which should be the codec of the tracing client, and
+// marks the parent of the owner requests it accepts as verified.
+func WithAnnotationCodec(codec tracingclient.AnnotationCodec) OwnerOption {
+	return func(e enqueueRequestForOwnerInterface) {
+		if codec == nil {
+			return
+		}
+		e.setAnnotationCodec(codec)
+	}
+}
+
+// WithClusterName records clusterName on every owner request, for controllers watching several clusters.
+func WithClusterName(clusterName string) OwnerOption {
+	return func(e enqueueRequestForOwnerInterface) {
+		e.setClusterName(clusterName)
+	}
+}
+
+// WithLogger logs, at V(1), whether each owner request of an event is a new request or was merged into another
+// request for the same owner, adding its parent as a linked span.
+func WithLogger(l logr.Logger) OwnerOption {
+	return func(e enqueueRequestForOwnerInterface) {
+		if l.GetSink() == nil {
+			return
+		}
+		e.setLogger(l)
+	}
+}
+
+// WithMeter counts the owner requests merged into another request for the same owner on the
+// SpanDeduplicatedMetricName counter of m.
+func WithMeter(m metric.Meter) OwnerOption {
+	return func(e enqueueRequestForOwnerInterface) {
+		if m == nil {
+			return
+		}
+		counter, err := m.Int64Counter(SpanDeduplicatedMetricName,
+			metric.WithDescription("Number of owner requests merged into another request for the same owner"))
+		if err != nil {
+			return
+		}
+		e.setDeduplicatedCounter(counter)
+	}
+}
+
+// SpanDeduplicatedMetricName is the counter of owner requests merged into another request for the same owner.
+const SpanDeduplicatedMetricName = "operatortrace.handler.span_deduplicated"
+
 type enqueueRequestForOwnerInterface interface {
 	setIsController(bool)
 	setAnnotationConfig(tracecontext.AnnotationExtractionConfig)
+	setAnnotationCodec(tracingclient.AnnotationCodec)
+	setClusterName(string)
+	setLogger(logr.Logger)
+	setDeduplicatedCounter(metric.Int64Counter)
 }
 
 type enqueueRequestForOwner[object client.Object] struct {
@@ -125,6 +188,16 @@ type enqueueRequestForOwner[object client.Object] struct {
 
 	// annotationConfig allows callers to override which annotations to read for trace context.
 	annotationCfg *tracecontext.AnnotationExtractionConfig
+
+	// annotationCodec, when set, reads the trace context instead of annotationCfg.
+	annotationCodec tracingclient.AnnotationCodec
+
+	// clusterName is recorded on every request. Empty means the controller's own cluster.
+	clusterName string
+
+	// logger reports whether owner requests are new or merged; deduplicated counts the merged ones.
+	logger       logr.Logger
+	deduplicated metric.Int64Counter
 }
 
 func (e *enqueueRequestForOwner[object]) setIsController(isController bool) {
@@ -135,6 +208,22 @@ func (e *enqueueRequestForOwner[object]) setAnnotationConfig(cfg tracecontext.An
 	e.annotationCfg = &cfg
 }
 
+func (e *enqueueRequestForOwner[object]) setAnnotationCodec(codec tracingclient.AnnotationCodec) {
+	e.annotationCodec = codec
+}
+
+func (e *enqueueRequestForOwner[object]) setClusterName(clusterName string) {
+	e.clusterName = clusterName
+}
+
+func (e *enqueueRequestForOwner[object]) setLogger(l logr.Logger) {
+	e.logger = l
+}
+
+func (e *enqueueRequestForOwner[object]) setDeduplicatedCounter(c metric.Int64Counter) {
+	e.deduplicated = c
+}
+
 func (e *enqueueRequestForOwner[object]) annotationConfig() tracecontext.AnnotationExtractionConfig {
 	if e.annotationCfg != nil {
 		return *e.annotationCfg
@@ -144,38 +233,90 @@ func (e *enqueueRequestForOwner[object]) annotationConfig() tracecontext.Annotat
 
 // Create implements EventHandler.
 func (e *enqueueRequestForOwner[object]) Create(ctx context.Context, evt event.TypedCreateEvent[object], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
-	reqs := map[tracingtypes.RequestWithTraceID]empty{}
-	e.getOwnerReconcileRequest(evt.Object, reqs, "new")
-	for req := range reqs {
-		q.Add(req)
+	reqs := ownerRequests{}
+	e.getOwnerReconcileRequestForEvent(evt.Object, reqs, "new", false)
+	for _, req := range reqs {
+		q.Add(*req)
 	}
 }
 
 // Update implements EventHandler.
 func (e *enqueueRequestForOwner[object]) Update(ctx context.Context, evt event.TypedUpdateEvent[object], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
-	reqs := map[tracingtypes.RequestWithTraceID]empty{}
-	e.getOwnerReconcileRequest(evt.ObjectOld, reqs, "old")
-	e.getOwnerReconcileRequest(evt.ObjectNew, reqs, "new")
-	for req := range reqs {
-		q.Add(req)
+	reqs := ownerRequests{}
+	e.getOwnerReconcileRequestForEvent(evt.ObjectOld, reqs, "old", false)
+	e.getOwnerReconcileRequestForEvent(evt.ObjectNew, reqs, "new", false)
+	changed := changedFields(evt.ObjectOld, evt.ObjectNew)
+	for _, req := range reqs {
+		req.Parent.ChangedFields = changed
+		q.Add(*req)
 	}
 }
 
 // Delete implements EventHandler.
 func (e *enqueueRequestForOwner[object]) Delete(ctx context.Context, evt event.TypedDeleteEvent[object], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
-	reqs := map[tracingtypes.RequestWithTraceID]empty{}
-	e.getOwnerReconcileRequest(evt.Object, reqs, "new")
-	for req := range reqs {
-		q.Add(req)
+	reqs := ownerRequests{}
+	e.getOwnerReconcileRequestForEvent(evt.Object, reqs, "new", evt.DeleteStateUnknown)
+	for _, req := range reqs {
+		q.Add(*req)
 	}
 }
 
 // Generic implements EventHandler.
 func (e *enqueueRequestForOwner[object]) Generic(ctx context.Context, evt event.TypedGenericEvent[object], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
-	reqs := map[tracingtypes.RequestWithTraceID]empty{}
-	e.getOwnerReconcileRequest(evt.Object, reqs, "new")
-	for req := range reqs {
-		q.Add(req)
+	reqs := ownerRequests{}
+	e.getOwnerReconcileRequestForEvent(evt.Object, reqs, "new", false)
+	for _, req := range reqs {
+		q.Add(*req)
+	}
+}
+
+// ownerRequests collects the owner requests of one event by their Key. Requests with different traces are
+// different map keys, so an owner referenced by both objects of an update, or by several owner references, is
+// merged into a single request the way the tracing queue merges duplicates.
+type ownerRequests map[types.NamespacedName]*tracingtypes.RequestWithTraceID
+
+// add adds req, merging it into the request already collected for its key. It reports whether req was merged.
+func (r ownerRequests) add(req tracingtypes.RequestWithTraceID) bool {
+	if existing, ok := r[req.Key()]; ok {
+		existing.Merge(req)
+		return true
+	}
+	r[req.Key()] = &req
+	return false
+}
+
+// addRequest adds req to result and reports whether it is a new request or was merged into one already collected
+// for the same owner.
+func (e *enqueueRequestForOwner[object]) addRequest(result ownerRequests, req tracingtypes.RequestWithTraceID) {
+	if !result.add(req) {
+		e.logger.V(1).Info("Enqueueing owner request", "owner", req.NamespacedName.String(), "parentKind", req.Parent.Kind, "parentName", req.Parent.Name, "traceID", req.Parent.TraceID)
+		return
+	}
+	merged := result[req.Key()]
+	e.logger.V(1).Info("Merged owner request", "owner", req.NamespacedName.String(), "parentKind", req.Parent.Kind, "parentName", req.Parent.Name, "traceID", req.Parent.TraceID, "linkedSpans", merged.LinkedSpanCount)
+	if e.deduplicated != nil {
+		e.deduplicated.Add(context.Background(), 1)
+	}
+}
+
+// getOwnerReconcileRequestForEvent unwraps tombstones before building owner requests. Owners of objects
+// whose final state is unknown are enqueued as deletions without a parent trace.
+func (e *enqueueRequestForOwner[object]) getOwnerReconcileRequestForEvent(obj any, result ownerRequests, eventKind string, deleteStateUnknown bool) {
+	o, tombstone := objectFromEvent(obj)
+	if o == nil {
+		return
+	}
+	if !tombstone && !deleteStateUnknown {
+		e.getOwnerReconcileRequest(o, result, eventKind)
+		return
+	}
+
+	deleted := ownerRequests{}
+	e.getOwnerReconcileRequest(o, deleted, "Delete")
+	for _, req := range deleted {
+		req.Parent.TraceID = ""
+		req.Parent.SpanID = ""
+		result.add(*req)
 	}
 }
 
@@ -199,7 +340,7 @@ func (e *enqueueRequestForOwner[object]) parseOwnerTypeGroupKind(scheme *runtime
 
 // getOwnerReconcileRequest looks at object and builds a map of reconcile.Request to reconcile
 // owners of object that match e.OwnerType.
-func (e *enqueueRequestForOwner[object]) getOwnerReconcileRequest(obj metav1.Object, result map[tracingtypes.RequestWithTraceID]empty, eventKind string) {
+func (e *enqueueRequestForOwner[object]) getOwnerReconcileRequest(obj metav1.Object, result ownerRequests, eventKind string) {
 	// Iterate through the OwnerReferences looking for a match on Group and Kind against what was requested
 	// by the user
 	for _, ref := range e.getOwnersReferences(obj) {
@@ -233,6 +374,7 @@ func (e *enqueueRequestForOwner[object]) getOwnerReconcileRequest(obj metav1.Obj
 						Name: ref.Name,
 					},
 				},
+				ClusterName: e.clusterName,
 			}
 
 			// if owner is not namespaced then we should not set the namespace
@@ -245,20 +387,21 @@ func (e *enqueueRequestForOwner[object]) getOwnerReconcileRequest(obj metav1.Obj
 				request.NamespacedName.Namespace = obj.GetNamespace()
 			}
 
-			traceID, spanID := traceAndSpanIDsFromAnnotations(obj.GetAnnotations(), e.annotationConfig())
+			traceID, spanID, verified := traceAndSpanIDsFromCodec(obj.GetAnnotations(), e.annotationConfig(), e.annotationCodec)
 			senderName := obj.GetName()
 			senderKind := kind
 
 			if traceID != "" && spanID != "" {
 				request.Parent.TraceID = traceID
 				request.Parent.SpanID = spanID
+				request.Parent.Verified = verified
 			}
 
 			request.Parent.EventKind = eventKind
 			request.Parent.Name = senderName
 			request.Parent.Kind = senderKind
 
-			result[request] = empty{}
+			e.addRequest(result, request)
 		}
 	}
 }
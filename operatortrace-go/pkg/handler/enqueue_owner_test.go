@@ -6,21 +6,28 @@ package handler
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	tracingconstants "github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/testhelpers"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
 
 	tracingqueue "github.com/Azure/operatortrace/operatortrace-go/pkg/tracingqueue"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr/funcr"
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
 	"go.opentelemetry.io/otel/propagation"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -196,10 +203,7 @@ func TestEnqueueOwnerCreate(t *testing.T) {
 						SpanID:  differentNameSpanID,
 					},
 					LinkedSpans: [10]tracingtypes.LinkedSpan{
-						{
-							TraceID: baseTraceID,
-							SpanID:  baseSpanID,
-						},
+						linkedParent(baseTraceID, baseSpanID, nodeObjectBase.Name, "Node", "new"),
 					},
 					LinkedSpanCount: 1,
 				},
@@ -265,10 +269,7 @@ func TestEnqueueOwnerCreate(t *testing.T) {
 						SpanID:  mixedOwnerSpanID,
 					},
 					LinkedSpans: [10]tracingtypes.LinkedSpan{
-						{
-							TraceID: baseTraceID,
-							SpanID:  baseSpanID,
-						},
+						linkedParent(baseTraceID, baseSpanID, nodeObjectBase.Name, "Node", "new"),
 					},
 					LinkedSpanCount: 1,
 				},
@@ -328,6 +329,156 @@ func TestEnqueueOwnerCreate(t *testing.T) {
 
 }
 
+// recordingQueue records every request added to it.
+type recordingQueue struct {
+	workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]
+	added []tracingtypes.RequestWithTraceID
+}
+
+func (q *recordingQueue) Add(req tracingtypes.RequestWithTraceID) {
+	q.added = append(q.added, req)
+}
+
+func TestEnqueueOwnerUpdateMergesOwnerRequests(t *testing.T) {
+	t.Parallel()
+
+	ownerRef := metav1.OwnerReference{APIVersion: "1", Kind: "Node", Name: "ParentNode", UID: "abcdef1"}
+	oldNode := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "node1",
+			Annotations:     traceAnnotations(baseTraceID, baseSpanID),
+			OwnerReferences: []metav1.OwnerReference{ownerRef},
+		},
+	}
+	newNode := oldNode.DeepCopy()
+	newNode.Annotations = traceAnnotations(differentNameTraceID, differentNameSpanID)
+	// A second reference to the same owner must not enqueue it twice either
+	newNode.OwnerReferences = append(newNode.OwnerReferences, ownerRef)
+
+	restmap := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "Node", Version: "1"}})
+	restmap.Add(schema.GroupVersionKind{Kind: "Node", Version: "1"}, meta.RESTScopeRoot)
+	k8sClient := fake.NewClientBuilder().WithRESTMapper(restmap).Build()
+	r := EnqueueRequestForOwner(k8sClient.Scheme(), k8sClient.RESTMapper(), &corev1.Node{})
+
+	queue := &recordingQueue{}
+	r.Update(context.TODO(), event.UpdateEvent{ObjectOld: oldNode, ObjectNew: newNode}, queue)
+
+	assert.Len(t, queue.added, 1)
+	req := queue.added[0]
+	assert.Equal(t, "ParentNode", req.Name)
+	assert.Equal(t, "new", req.Parent.EventKind)
+	assert.Equal(t, differentNameTraceID, req.Parent.TraceID)
+	assert.Equal(t, differentNameSpanID, req.Parent.SpanID)
+	assert.Equal(t, 1, req.LinkedSpanCount)
+	assert.Equal(t, linkedParent(baseTraceID, baseSpanID, "node1", "Node", "old"), req.LinkedSpans[0])
+}
+
+// linkedParent returns the linked span of a replaced request parent, with the attributes describing its event.
+func linkedParent(traceID, spanID, name, kind, eventKind string) tracingtypes.LinkedSpan {
+	return tracingtypes.LinkedSpan{TraceID: traceID, SpanID: spanID, LinkedSpanAttributes: attribute.NewSet(
+		tracingtypes.LinkObjectNameAttributeKey.String(name),
+		tracingtypes.LinkObjectKindAttributeKey.String(kind),
+		tracingtypes.LinkEventKindAttributeKey.String(eventKind),
+	)}
+}
+
+func TestEnqueueOwnerDuplicateOwnerReferences(t *testing.T) {
+	t.Parallel()
+
+	ownerRef := metav1.OwnerReference{APIVersion: "1", Kind: "Node", Name: "ParentNode", UID: "abcdef1"}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node1",
+			Annotations: traceAnnotations(baseTraceID, baseSpanID),
+			OwnerReferences: []metav1.OwnerReference{
+				ownerRef,
+				{APIVersion: "1", Kind: "Node", Name: "ParentNode2", UID: "abcdef2"},
+				ownerRef,
+			},
+		},
+	}
+
+	restmap := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "Node", Version: "1"}})
+	restmap.Add(schema.GroupVersionKind{Kind: "Node", Version: "1"}, meta.RESTScopeRoot)
+	k8sClient := fake.NewClientBuilder().WithRESTMapper(restmap).Build()
+	r := EnqueueRequestForOwner(k8sClient.Scheme(), k8sClient.RESTMapper(), &corev1.Node{})
+
+	tests := []struct {
+		name    string
+		trigger func(q *recordingQueue)
+	}{
+		{"create", func(q *recordingQueue) { r.Create(context.TODO(), event.CreateEvent{Object: node}, q) }},
+		{"delete", func(q *recordingQueue) { r.Delete(context.TODO(), event.DeleteEvent{Object: node}, q) }},
+		{"generic", func(q *recordingQueue) { r.Generic(context.TODO(), event.GenericEvent{Object: node}, q) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &recordingQueue{}
+			tt.trigger(queue)
+
+			owners := map[string]int{}
+			for _, req := range queue.added {
+				owners[req.Name]++
+				assert.Equal(t, baseTraceID, req.Parent.TraceID)
+				assert.Equal(t, baseSpanID, req.Parent.SpanID)
+				assert.Zero(t, req.LinkedSpanCount, "the parent must not also be linked")
+			}
+			assert.Equal(t, map[string]int{"ParentNode": 1, "ParentNode2": 1}, owners)
+		})
+	}
+}
+
+// recordingMeter hands out counters that sum the values added to them, by name.
+type recordingMeter struct {
+	noop.Meter
+	counters map[string]*testhelpers.RecordingCounter
+}
+
+func (m *recordingMeter) Int64Counter(name string, _ ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	if m.counters == nil {
+		m.counters = map[string]*testhelpers.RecordingCounter{}
+	}
+	m.counters[name] = &testhelpers.RecordingCounter{}
+	return m.counters[name], nil
+}
+
+func TestEnqueueOwnerReportsDeduplication(t *testing.T) {
+	t.Parallel()
+
+	ownerRef := metav1.OwnerReference{APIVersion: "1", Kind: "Node", Name: "ParentNode", UID: "abcdef1"}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "node1",
+			Annotations: traceAnnotations(baseTraceID, baseSpanID),
+			OwnerReferences: []metav1.OwnerReference{
+				ownerRef,
+				{APIVersion: "1", Kind: "Node", Name: "ParentNode2", UID: "abcdef2"},
+				ownerRef,
+			},
+		},
+	}
+
+	restmap := meta.NewDefaultRESTMapper([]schema.GroupVersion{{Group: "Node", Version: "1"}})
+	restmap.Add(schema.GroupVersionKind{Kind: "Node", Version: "1"}, meta.RESTScopeRoot)
+	k8sClient := fake.NewClientBuilder().WithRESTMapper(restmap).Build()
+
+	var logs strings.Builder
+	logger := funcr.New(func(prefix, args string) { logs.WriteString(args + "\n") }, funcr.Options{Verbosity: 1})
+	meter := &recordingMeter{}
+	r := EnqueueRequestForOwner(k8sClient.Scheme(), k8sClient.RESTMapper(), &corev1.Node{}, WithLogger(logger), WithMeter(meter))
+
+	r.Create(context.TODO(), event.CreateEvent{Object: node}, &recordingQueue{})
+	assert.EqualValues(t, 1, meter.counters[SpanDeduplicatedMetricName].Total())
+	assert.Equal(t, 2, strings.Count(logs.String(), "Enqueueing owner request"))
+	assert.Equal(t, 1, strings.Count(logs.String(), "Merged owner request"))
+
+	// Distinct owners are not deduplicated
+	node.OwnerReferences = node.OwnerReferences[:2]
+	r.Create(context.TODO(), event.CreateEvent{Object: node}, &recordingQueue{})
+	assert.EqualValues(t, 1, meter.counters[SpanDeduplicatedMetricName].Total())
+}
+
 func traceAnnotations(traceID, spanID string) map[string]string {
 	if traceID == "" || spanID == "" {
 		return map[string]string{}
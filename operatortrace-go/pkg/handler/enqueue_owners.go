@@ -0,0 +1,133 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/handler/enqueue_owners.go
+
+package handler
+
+import (
+	"context"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/metric"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+)
+
+var _ EventHandlerWithTrace = &enqueueRequestForOwners[client.Object]{}
+
+// EnqueueRequestForOwners enqueues Requests for the Owners of an object of any of ownerTypes, e.g. for Pods owned
+// by either a ReplicaSet or a StatefulSet. The options apply to every owner type. The requests of all owner types
+// of an event are collected together, so an owner referenced more than once is enqueued once, with the other
+// parents as linked spans.
+func EnqueueRequestForOwners(scheme *runtime.Scheme, mapper meta.RESTMapper, ownerTypes []client.Object, opts ...OwnerOption) EventHandlerWithTrace {
+	return TypedEnqueueRequestForOwners[client.Object](scheme, mapper, ownerTypes, opts...)
+}
+
+// TypedEnqueueRequestForOwners enqueues Requests for the Owners of an object of any of ownerTypes.
+//
+// TypedEnqueueRequestForOwners is experimental and subject to future change.
+func TypedEnqueueRequestForOwners[object client.Object](scheme *runtime.Scheme, mapper meta.RESTMapper, ownerTypes []client.Object, opts ...OwnerOption) handler.TypedEventHandler[object, tracingtypes.RequestWithTraceID] {
+	e := &enqueueRequestForOwners[object]{}
+	for _, ownerType := range ownerTypes {
+		e.owners = append(e.owners, newEnqueueRequestForOwner[object](scheme, mapper, ownerType))
+	}
+	// The options are applied once so that, e.g., WithMeter creates a single counter shared by all owner types
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// enqueueRequestForOwners chains one enqueueRequestForOwner per owner type.
+type enqueueRequestForOwners[object client.Object] struct {
+	owners []*enqueueRequestForOwner[object]
+}
+
+func (e *enqueueRequestForOwners[object]) setIsController(isController bool) {
+	for _, owner := range e.owners {
+		owner.setIsController(isController)
+	}
+}
+
+func (e *enqueueRequestForOwners[object]) setAnnotationConfig(cfg tracecontext.AnnotationExtractionConfig) {
+	for _, owner := range e.owners {
+		owner.setAnnotationConfig(cfg)
+	}
+}
+
+func (e *enqueueRequestForOwners[object]) setAnnotationCodec(codec tracingclient.AnnotationCodec) {
+	for _, owner := range e.owners {
+		owner.setAnnotationCodec(codec)
+	}
+}
+
+func (e *enqueueRequestForOwners[object]) setClusterName(clusterName string) {
+	for _, owner := range e.owners {
+		owner.setClusterName(clusterName)
+	}
+}
+
+func (e *enqueueRequestForOwners[object]) setLogger(l logr.Logger) {
+	for _, owner := range e.owners {
+		owner.setLogger(l)
+	}
+}
+
+func (e *enqueueRequestForOwners[object]) setDeduplicatedCounter(c metric.Int64Counter) {
+	for _, owner := range e.owners {
+		owner.setDeduplicatedCounter(c)
+	}
+}
+
+// getOwnerReconcileRequestForEvent collects the owner requests of every owner type into result.
+func (e *enqueueRequestForOwners[object]) getOwnerReconcileRequestForEvent(obj any, result ownerRequests, eventKind string, deleteStateUnknown bool) {
+	for _, owner := range e.owners {
+		owner.getOwnerReconcileRequestForEvent(obj, result, eventKind, deleteStateUnknown)
+	}
+}
+
+// Create implements EventHandler.
+func (e *enqueueRequestForOwners[object]) Create(ctx context.Context, evt event.TypedCreateEvent[object], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
+	reqs := ownerRequests{}
+	e.getOwnerReconcileRequestForEvent(evt.Object, reqs, "new", false)
+	for _, req := range reqs {
+		q.Add(*req)
+	}
+}
+
+// Update implements EventHandler.
+func (e *enqueueRequestForOwners[object]) Update(ctx context.Context, evt event.TypedUpdateEvent[object], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
+	reqs := ownerRequests{}
+	e.getOwnerReconcileRequestForEvent(evt.ObjectOld, reqs, "old", false)
+	e.getOwnerReconcileRequestForEvent(evt.ObjectNew, reqs, "new", false)
+	changed := changedFields(evt.ObjectOld, evt.ObjectNew)
+	for _, req := range reqs {
+		req.Parent.ChangedFields = changed
+		q.Add(*req)
+	}
+}
+
+// Delete implements EventHandler.
+func (e *enqueueRequestForOwners[object]) Delete(ctx context.Context, evt event.TypedDeleteEvent[object], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
+	reqs := ownerRequests{}
+	e.getOwnerReconcileRequestForEvent(evt.Object, reqs, "new", evt.DeleteStateUnknown)
+	for _, req := range reqs {
+		q.Add(*req)
+	}
+}
+
+// Generic implements EventHandler.
+func (e *enqueueRequestForOwners[object]) Generic(ctx context.Context, evt event.TypedGenericEvent[object], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
+	reqs := ownerRequests{}
+	e.getOwnerReconcileRequestForEvent(evt.Object, reqs, "new", false)
+	for _, req := range reqs {
+		q.Add(*req)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/handler/enqueue_owners_test.go
+
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+// newMultiOwnerHandler returns a handler for Pods owned by ReplicaSets or StatefulSets.
+func newMultiOwnerHandler(opts ...OwnerOption) EventHandlerWithTrace {
+	restmap := meta.NewDefaultRESTMapper(nil)
+	restmap.Add(appsv1.SchemeGroupVersion.WithKind("ReplicaSet"), meta.RESTScopeNamespace)
+	restmap.Add(appsv1.SchemeGroupVersion.WithKind("StatefulSet"), meta.RESTScopeNamespace)
+	k8sClient := fake.NewClientBuilder().WithRESTMapper(restmap).Build()
+	return EnqueueRequestForOwners(k8sClient.Scheme(), k8sClient.RESTMapper(), []client.Object{&appsv1.ReplicaSet{}, &appsv1.StatefulSet{}}, opts...)
+}
+
+// podOwnedBy returns a Pod carrying a trace and owned by the given ReplicaSet and StatefulSet.
+func podOwnedBy(replicaSet, statefulSet string, traceID, spanID string) *corev1.Pod {
+	isController := true
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Namespace:   "default",
+			Annotations: traceAnnotations(traceID, spanID),
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: replicaSet, UID: "rs", Controller: &isController},
+				{APIVersion: "apps/v1", Kind: "StatefulSet", Name: statefulSet, UID: "sts"},
+			},
+		},
+	}
+}
+
+func TestEnqueueOwnersMultipleOwnerTypes(t *testing.T) {
+	t.Parallel()
+
+	pod := podOwnedBy("web-rs", "web-sts", baseTraceID, baseSpanID)
+	r := newMultiOwnerHandler()
+
+	tests := []struct {
+		name    string
+		trigger func(q *recordingQueue)
+	}{
+		{"create", func(q *recordingQueue) { r.Create(context.TODO(), event.CreateEvent{Object: pod}, q) }},
+		{"delete", func(q *recordingQueue) { r.Delete(context.TODO(), event.DeleteEvent{Object: pod}, q) }},
+		{"generic", func(q *recordingQueue) { r.Generic(context.TODO(), event.GenericEvent{Object: pod}, q) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := &recordingQueue{}
+			tt.trigger(queue)
+
+			owners := map[string]int{}
+			for _, req := range queue.added {
+				owners[req.Name]++
+				assert.Equal(t, "default", req.Namespace)
+				assert.Equal(t, baseTraceID, req.Parent.TraceID)
+				assert.Equal(t, baseSpanID, req.Parent.SpanID)
+				assert.Equal(t, "Pod", req.Parent.Kind)
+			}
+			assert.Equal(t, map[string]int{"web-rs": 1, "web-sts": 1}, owners)
+		})
+	}
+}
+
+func TestEnqueueOwnersUpdate(t *testing.T) {
+	t.Parallel()
+
+	oldPod := podOwnedBy("web-rs", "web-sts", baseTraceID, baseSpanID)
+	newPod := podOwnedBy("web-rs", "web-sts", differentNameTraceID, differentNameSpanID)
+	queue := &recordingQueue{}
+	newMultiOwnerHandler().Update(context.TODO(), event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod}, queue)
+
+	require.Len(t, queue.added, 2)
+	for _, req := range queue.added {
+		assert.Equal(t, "new", req.Parent.EventKind)
+		assert.Equal(t, differentNameTraceID, req.Parent.TraceID)
+		assert.Equal(t, 1, req.LinkedSpanCount)
+		assert.Equal(t, linkedParent(baseTraceID, baseSpanID, "pod", "Pod", "old"), req.LinkedSpans[0])
+	}
+}
+
+func TestEnqueueOwnersDeduplicatesAcrossOwnerTypes(t *testing.T) {
+	t.Parallel()
+
+	// Owners of different types with the same name are the same request key
+	pod := podOwnedBy("web", "web", baseTraceID, baseSpanID)
+	meter := &recordingMeter{}
+	queue := &recordingQueue{}
+	newMultiOwnerHandler(WithMeter(meter)).Create(context.TODO(), event.CreateEvent{Object: pod}, queue)
+
+	require.Len(t, queue.added, 1)
+	assert.Equal(t, "web", queue.added[0].Name)
+	assert.Zero(t, queue.added[0].LinkedSpanCount, "the parent must not also be linked")
+	assert.EqualValues(t, 1, meter.counters[SpanDeduplicatedMetricName].Total())
+}
+
+func TestEnqueueOwnersOptionsApplyToAllOwnerTypes(t *testing.T) {
+	t.Parallel()
+
+	pod := podOwnedBy("web-rs", "web-sts", baseTraceID, baseSpanID)
+	queue := &recordingQueue{}
+	newMultiOwnerHandler(OnlyControllerOwner(), WithClusterName("member")).Create(context.TODO(), event.CreateEvent{Object: pod}, queue)
+
+	require.Len(t, queue.added, 1)
+	assert.Equal(t, "web-rs", queue.added[0].Name)
+	assert.Equal(t, "member", queue.added[0].ClusterName)
+}
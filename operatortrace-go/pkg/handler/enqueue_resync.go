@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/handler/enqueue_resync.go
+
+package handler
+
+import (
+	"context"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+var _ EventHandler = &ResyncAwareEnqueueRequestForObject{}
+
+// ResyncAwareEnqueueRequestForObject is EnqueueRequestForObject that marks requests triggered by a resync.
+type ResyncAwareEnqueueRequestForObject = TypedResyncAwareEnqueueRequestForObject[client.Object]
+
+// TypedResyncAwareEnqueueRequestForObject enqueues requests like TypedEnqueueRequestForObject, recording
+// tracingtypes.EventKindResync as the parent event kind of create and update events Resyncs identified as a resync.
+// Resyncs must also be registered as a predicate of the same watch.
+type TypedResyncAwareEnqueueRequestForObject[T client.Object] struct {
+	TypedEnqueueRequestForObject[T]
+
+	// Resyncs detects resync events. When nil, every event keeps its own kind.
+	Resyncs *predicates.TypedResyncPredicate[T]
+}
+
+// Create implements EventHandler.
+func (e *TypedResyncAwareEnqueueRequestForObject[T]) Create(ctx context.Context, evt event.TypedCreateEvent[T], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
+	if e.isResync(evt.Object) {
+		e.enqueueObject(evt.Object, tracingtypes.EventKindResync, "", false, q)
+		return
+	}
+	e.TypedEnqueueRequestForObject.Create(ctx, evt, q)
+}
+
+// Update implements EventHandler.
+func (e *TypedResyncAwareEnqueueRequestForObject[T]) Update(ctx context.Context, evt event.TypedUpdateEvent[T], q workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID]) {
+	if e.isResync(evt.ObjectNew) {
+		e.enqueueObject(evt.ObjectNew, tracingtypes.EventKindResync, "", false, q)
+		return
+	}
+	e.TypedEnqueueRequestForObject.Update(ctx, evt, q)
+}
+
+func (e *TypedResyncAwareEnqueueRequestForObject[T]) isResync(obj T) bool {
+	return e.Resyncs != nil && !isNil(obj) && e.Resyncs.IsResync(obj)
+}
@@ -0,0 +1,271 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/handler/enqueue_test.go
+
+package handler
+
+import (
+	"context"
+	"testing"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
+	tracingqueue "github.com/Azure/operatortrace/operatortrace-go/pkg/tracingqueue"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTracedPod(name string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "default",
+			Annotations: traceAnnotations(baseTraceID, baseSpanID),
+		},
+	}
+}
+
+func TestObjectFromEvent(t *testing.T) {
+	pod := newTracedPod("pod1")
+
+	tests := []struct {
+		name              string
+		input             any
+		expectedName      string
+		expectedNamespace string
+		expectedTombstone bool
+	}{
+		{"plain object", pod, "pod1", "default", false},
+		{"nil object", (*corev1.Pod)(nil), "", "", false},
+		{"tombstone with object", cache.DeletedFinalStateUnknown{Key: "default/pod1", Obj: pod}, "pod1", "default", true},
+		{"tombstone pointer with object", &cache.DeletedFinalStateUnknown{Key: "default/pod1", Obj: pod}, "pod1", "default", true},
+		{"tombstone with key only", cache.DeletedFinalStateUnknown{Key: "default/pod2"}, "pod2", "default", true},
+		{"tombstone with invalid key", cache.DeletedFinalStateUnknown{Key: "a/b/c"}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, tombstone := objectFromEvent(tt.input)
+			assert.Equal(t, tt.expectedTombstone, tombstone)
+			if tt.expectedName == "" {
+				assert.Nil(t, obj)
+				return
+			}
+			require.NotNil(t, obj)
+			assert.Equal(t, tt.expectedName, obj.GetName())
+			assert.Equal(t, tt.expectedNamespace, obj.GetNamespace())
+		})
+	}
+}
+
+func TestEnqueueRequestForObjectNilObject(t *testing.T) {
+	h := &EnqueueRequestForObject{Scheme: scheme.Scheme}
+	queue := tracingqueue.NewTracingQueue()
+
+	assert.NotPanics(t, func() {
+		h.Create(context.TODO(), event.CreateEvent{Object: (*corev1.Pod)(nil)}, queue)
+		h.Update(context.TODO(), event.UpdateEvent{ObjectNew: (*corev1.Pod)(nil)}, queue)
+		h.Delete(context.TODO(), event.DeleteEvent{Object: (*corev1.Pod)(nil)}, queue)
+		h.Generic(context.TODO(), event.GenericEvent{Object: (*corev1.Pod)(nil)}, queue)
+	})
+	assert.Equal(t, 0, queue.Len())
+}
+
+func TestEnqueueRequestForObjectDeleteStateUnknown(t *testing.T) {
+	h := &EnqueueRequestForObject{Scheme: scheme.Scheme}
+
+	t.Run("known final state keeps the trace", func(t *testing.T) {
+		queue := tracingqueue.NewTracingQueue()
+		h.Delete(context.TODO(), event.DeleteEvent{Object: newTracedPod("pod1")}, queue)
+
+		req, _ := queue.Get()
+		assert.Equal(t, "Delete", req.Parent.EventKind)
+		assert.Equal(t, baseTraceID, req.Parent.TraceID)
+		assert.Equal(t, baseSpanID, req.Parent.SpanID)
+	})
+
+	t.Run("unknown final state drops the stale trace", func(t *testing.T) {
+		queue := tracingqueue.NewTracingQueue()
+		h.Delete(context.TODO(), event.DeleteEvent{Object: newTracedPod("pod1"), DeleteStateUnknown: true}, queue)
+
+		req, _ := queue.Get()
+		assert.Equal(t, types.NamespacedName{Name: "pod1", Namespace: "default"}, req.NamespacedName)
+		assert.Equal(t, tracingtypes.RequestParent{Name: "pod1", Kind: "Pod", EventKind: "Delete"}, req.Parent)
+	})
+}
+
+func TestEnqueueRequestsFromMapFuncTombstone(t *testing.T) {
+	tombstone := cache.DeletedFinalStateUnknown{Key: "default/pod1", Obj: newTracedPod("pod1")}
+
+	var mapped []string
+	h := TypedEnqueueRequestsFromMapFunc(func(ctx context.Context, obj any) []tracingtypes.RequestWithTraceID {
+		o, ok := obj.(client.Object)
+		if !ok {
+			return nil
+		}
+		mapped = append(mapped, o.GetName())
+		return []tracingtypes.RequestWithTraceID{{
+			Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "owner", Namespace: o.GetNamespace()}},
+		}}
+	})
+
+	tests := []struct {
+		name    string
+		trigger func(q *tracingqueue.TracingQueue)
+	}{
+		{"create", func(q *tracingqueue.TracingQueue) {
+			h.Create(context.TODO(), event.TypedCreateEvent[any]{Object: tombstone}, q)
+		}},
+		{"delete", func(q *tracingqueue.TracingQueue) {
+			h.Delete(context.TODO(), event.TypedDeleteEvent[any]{Object: tombstone, DeleteStateUnknown: true}, q)
+		}},
+		{"generic", func(q *tracingqueue.TracingQueue) {
+			h.Generic(context.TODO(), event.TypedGenericEvent[any]{Object: tombstone}, q)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapped = nil
+			queue := tracingqueue.NewTracingQueue()
+			assert.NotPanics(t, func() { tt.trigger(queue) })
+			assert.Equal(t, []string{"pod1"}, mapped)
+			assert.Equal(t, 1, queue.Len())
+		})
+	}
+}
+
+func TestResyncAwareEnqueueRequestForObject(t *testing.T) {
+	resyncs := predicates.NewResyncPredicate()
+	h := &ResyncAwareEnqueueRequestForObject{
+		TypedEnqueueRequestForObject: EnqueueRequestForObject{Scheme: scheme.Scheme},
+		Resyncs:                      resyncs,
+	}
+	pod := newTracedPod("pod1")
+	pod.ResourceVersion = "1"
+	updated := pod.DeepCopy()
+	updated.ResourceVersion = "2"
+
+	tests := []struct {
+		name              string
+		deliver           func(queue *tracingqueue.TracingQueue)
+		expectedEventKind string
+	}{
+		{"create", func(queue *tracingqueue.TracingQueue) {
+			evt := event.CreateEvent{Object: pod}
+			resyncs.Create(evt)
+			h.Create(context.TODO(), evt, queue)
+		}, "Create"},
+		{"relisted create", func(queue *tracingqueue.TracingQueue) {
+			evt := event.CreateEvent{Object: pod}
+			resyncs.Create(evt)
+			h.Create(context.TODO(), evt, queue)
+		}, tracingtypes.EventKindResync},
+		{"periodic resync", func(queue *tracingqueue.TracingQueue) {
+			evt := event.UpdateEvent{ObjectOld: pod, ObjectNew: pod}
+			resyncs.Update(evt)
+			h.Update(context.TODO(), evt, queue)
+		}, tracingtypes.EventKindResync},
+		{"update", func(queue *tracingqueue.TracingQueue) {
+			evt := event.UpdateEvent{ObjectOld: pod, ObjectNew: updated}
+			resyncs.Update(evt)
+			h.Update(context.TODO(), evt, queue)
+		}, "Update"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := tracingqueue.NewTracingQueue()
+			tt.deliver(queue)
+
+			require.Equal(t, 1, queue.Len())
+			got, _ := queue.Get()
+			assert.Equal(t, tt.expectedEventKind, got.Parent.EventKind)
+			assert.Equal(t, baseTraceID, got.Parent.TraceID)
+		})
+	}
+}
+
+func TestEnqueueHandlersRecordClusterName(t *testing.T) {
+	pod := newTracedPod("pod1")
+	pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: "node1", UID: "abcdef1"}}
+	restmap := meta.NewDefaultRESTMapper(nil)
+	restmap.Add(corev1.SchemeGroupVersion.WithKind("Node"), meta.RESTScopeRoot)
+
+	tests := []struct {
+		name    string
+		handler EventHandler
+		evt     event.DeleteEvent
+	}{
+		{"object", NewTypedEnqueueRequestForObject[client.Object]().WithScheme(scheme.Scheme).WithClusterName("east").Build(), event.DeleteEvent{Object: pod}},
+		{"object with unknown final state", &EnqueueRequestForObject{Scheme: scheme.Scheme, ClusterName: "east"}, event.DeleteEvent{Object: pod, DeleteStateUnknown: true}},
+		{"owner", EnqueueRequestForOwner(scheme.Scheme, restmap, &corev1.Node{}, WithClusterName("east")), event.DeleteEvent{Object: pod}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := tracingqueue.NewTracingQueue()
+			tt.handler.Delete(context.TODO(), tt.evt, queue)
+			require.Equal(t, 1, queue.Len())
+
+			req, _ := queue.Get()
+			assert.Equal(t, "east", req.ClusterName)
+		})
+	}
+}
+
+func TestEnqueueHandlersVerifyTraceContextWithCodec(t *testing.T) {
+	codec, err := tracingclient.NewHMACAnnotationCodec([]byte("secret"))
+	require.NoError(t, err)
+	restmap := meta.NewDefaultRESTMapper(nil)
+	restmap.Add(corev1.SchemeGroupVersion.WithKind("Node"), meta.RESTScopeRoot)
+
+	handlers := []struct {
+		name    string
+		handler EventHandler
+	}{
+		{"object", NewTypedEnqueueRequestForObject[client.Object]().WithScheme(scheme.Scheme).WithAnnotationCodec(codec).Build()},
+		{"owner", EnqueueRequestForOwner(scheme.Scheme, restmap, &corev1.Node{}, WithAnnotationCodec(codec))},
+	}
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		expectedParent bool
+	}{
+		{"signed trace context is verified", codec.Encode(mustBuildTraceParent(baseTraceID, baseSpanID), ""), true},
+		{"forged trace context is dropped", traceAnnotations(baseTraceID, baseSpanID), false},
+	}
+
+	for _, h := range handlers {
+		for _, tt := range tests {
+			t.Run(h.name+"/"+tt.name, func(t *testing.T) {
+				pod := newTracedPod("pod1")
+				pod.Annotations = tt.annotations
+				pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "v1", Kind: "Node", Name: "node1", UID: "abcdef1"}}
+				queue := tracingqueue.NewTracingQueue()
+				h.handler.Create(context.TODO(), event.CreateEvent{Object: pod}, queue)
+				require.Equal(t, 1, queue.Len())
+
+				req, _ := queue.Get()
+				assert.Equal(t, tt.expectedParent, req.Parent.Verified)
+				if tt.expectedParent {
+					assert.Equal(t, baseTraceID, req.Parent.TraceID)
+					assert.Equal(t, baseSpanID, req.Parent.SpanID)
+				} else {
+					assert.Empty(t, req.Parent.TraceID)
+					assert.Empty(t, req.Parent.SpanID)
+				}
+			})
+		}
+	}
+}
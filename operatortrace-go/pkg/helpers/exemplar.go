@@ -0,0 +1,104 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/helpers/exemplar.go
+
+package helpers
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// TraceIDExemplarLabel is the exemplar label RecordWithExemplar stores the trace ID in.
+	TraceIDExemplarLabel = "trace_id"
+	// SpanIDExemplarLabel is the exemplar label RecordWithExemplar stores the span ID in.
+	SpanIDExemplarLabel = "span_id"
+)
+
+// RecordWithExemplar observes value on observer with an exemplar pointing at the span in ctx, so the observation
+// links to its trace in Prometheus. extraLabels are added to the exemplar and are not modified. When ctx carries no
+// valid span, or observer does not support exemplars, value is observed without one. Exemplar labels are limited
+// to prometheus.ExemplarMaxRunes runes in total; extraLabels are dropped when they do not fit.
+func RecordWithExemplar(ctx context.Context, observer prometheus.Observer, value float64, extraLabels prometheus.Labels) {
+	if observer == nil {
+		return
+	}
+	spanContext := trace.SpanFromContext(ctx).SpanContext()
+	exemplarObserver, ok := observer.(prometheus.ExemplarObserver)
+	if !spanContext.IsValid() || !ok {
+		observer.Observe(value)
+		return
+	}
+
+	labels := prometheus.Labels{
+		TraceIDExemplarLabel: spanContext.TraceID().String(),
+		SpanIDExemplarLabel:  spanContext.SpanID().String(),
+	}
+	if exemplarRunes(labels)+exemplarRunes(extraLabels) <= prometheus.ExemplarMaxRunes {
+		for name, labelValue := range extraLabels {
+			if _, reserved := labels[name]; !reserved {
+				labels[name] = labelValue
+			}
+		}
+	}
+	exemplarObserver.ObserveWithExemplar(value, labels)
+}
+
+// exemplarRunes counts the runes of labels the way Prometheus limits exemplars.
+func exemplarRunes(labels prometheus.Labels) int {
+	runes := 0
+	for name, value := range labels {
+		runes += utf8.RuneCountInString(name) + utf8.RuneCountInString(value)
+	}
+	return runes
+}
+
+// HistogramOption configures a histogram created by NewTracingHistogram.
+type HistogramOption func(*prometheus.HistogramOpts)
+
+// WithHistogramNamespace sets the namespace prefixed to the histogram name.
+func WithHistogramNamespace(namespace string) HistogramOption {
+	return func(opts *prometheus.HistogramOpts) {
+		if namespace == "" {
+			return
+		}
+		opts.Namespace = namespace
+	}
+}
+
+// WithHistogramSubsystem sets the subsystem prefixed to the histogram name, after the namespace.
+func WithHistogramSubsystem(subsystem string) HistogramOption {
+	return func(opts *prometheus.HistogramOpts) {
+		if subsystem == "" {
+			return
+		}
+		opts.Subsystem = subsystem
+	}
+}
+
+// WithHistogramConstLabels sets labels with fixed values on every series of the histogram.
+func WithHistogramConstLabels(labels prometheus.Labels) HistogramOption {
+	return func(opts *prometheus.HistogramOpts) {
+		if len(labels) == 0 {
+			return
+		}
+		opts.ConstLabels = labels
+	}
+}
+
+// NewTracingHistogram returns a histogram to record with RecordWithExemplar. Nil buckets use
+// prometheus.DefBuckets. The histogram is not registered.
+func NewTracingHistogram(name, help string, buckets []float64, opts ...HistogramOption) prometheus.Histogram {
+	histogramOpts := prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&histogramOpts)
+	}
+	return prometheus.NewHistogram(histogramOpts)
+}
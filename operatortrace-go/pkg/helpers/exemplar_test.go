@@ -0,0 +1,133 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/helpers/exemplar_test.go
+
+package helpers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// exemplarLabels returns the labels of the exemplars recorded on h, by name.
+func exemplarLabels(t *testing.T, h prometheus.Histogram) []map[string]string {
+	t.Helper()
+	m := &dto.Metric{}
+	require.NoError(t, h.Write(m))
+	var exemplars []map[string]string
+	for _, bucket := range m.GetHistogram().GetBucket() {
+		if bucket.GetExemplar() == nil {
+			continue
+		}
+		labels := map[string]string{}
+		for _, pair := range bucket.GetExemplar().GetLabel() {
+			labels[pair.GetName()] = pair.GetValue()
+		}
+		exemplars = append(exemplars, labels)
+	}
+	return exemplars
+}
+
+func TestRecordWithExemplar(t *testing.T) {
+	spanContext := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	traced := trace.ContextWithSpanContext(context.Background(), spanContext)
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		extraLabels prometheus.Labels
+		expected    []map[string]string
+	}{
+		{
+			name:        "traced context",
+			ctx:         traced,
+			extraLabels: prometheus.Labels{"controller": "pods"},
+			expected: []map[string]string{{
+				TraceIDExemplarLabel: spanContext.TraceID().String(),
+				SpanIDExemplarLabel:  spanContext.SpanID().String(),
+				"controller":         "pods",
+			}},
+		},
+		{
+			name:        "extra labels cannot replace the trace",
+			ctx:         traced,
+			extraLabels: prometheus.Labels{TraceIDExemplarLabel: "other"},
+			expected: []map[string]string{{
+				TraceIDExemplarLabel: spanContext.TraceID().String(),
+				SpanIDExemplarLabel:  spanContext.SpanID().String(),
+			}},
+		},
+		{
+			name:        "extra labels over the rune limit are dropped",
+			ctx:         traced,
+			extraLabels: prometheus.Labels{"long": strings.Repeat("x", prometheus.ExemplarMaxRunes)},
+			expected: []map[string]string{{
+				TraceIDExemplarLabel: spanContext.TraceID().String(),
+				SpanIDExemplarLabel:  spanContext.SpanID().String(),
+			}},
+		},
+		{
+			name: "context without span",
+			ctx:  context.Background(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewTracingHistogram("reconcile_seconds", "Reconcile duration.", []float64{1})
+			RecordWithExemplar(tt.ctx, h, 0.5, tt.extraLabels)
+
+			assert.Equal(t, 1, testutil.CollectAndCount(h))
+			assert.Equal(t, tt.expected, exemplarLabels(t, h))
+		})
+	}
+}
+
+func TestRecordWithExemplarWithoutExemplarSupport(t *testing.T) {
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "reconcile_seconds", Help: "Reconcile duration."})
+	traced := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1},
+		SpanID:  trace.SpanID{2},
+	}))
+
+	RecordWithExemplar(traced, summary, 0.5, nil)
+	RecordWithExemplar(traced, nil, 0.5, nil)
+
+	m := &dto.Metric{}
+	require.NoError(t, summary.Write(m))
+	assert.Equal(t, uint64(1), m.GetSummary().GetSampleCount())
+}
+
+func TestNewTracingHistogram(t *testing.T) {
+	h := NewTracingHistogram("reconcile_seconds", "Reconcile duration.", nil,
+		WithHistogramNamespace("operatortrace"),
+		WithHistogramSubsystem("controller"),
+		WithHistogramConstLabels(prometheus.Labels{"controller": "pods"}),
+		WithHistogramNamespace(""), // ignored
+		nil,
+	)
+	h.Observe(0.5)
+
+	expected := `
+# HELP operatortrace_controller_reconcile_seconds Reconcile duration.
+# TYPE operatortrace_controller_reconcile_seconds histogram
+operatortrace_controller_reconcile_seconds_count{controller="pods"} 1
+`
+	require.NoError(t, testutil.CollectAndCompare(h, strings.NewReader(expected), "operatortrace_controller_reconcile_seconds_count"))
+
+	m := &dto.Metric{}
+	require.NoError(t, h.Write(m))
+	assert.Len(t, m.GetHistogram().GetBucket(), len(prometheus.DefBuckets))
+}
@@ -0,0 +1,87 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/helpers/manager.go
+
+package helpers
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// DefaultManagerName is used in the manager span name when WithManagerName is not provided.
+const DefaultManagerName = "controller-manager"
+
+type managerTracingConfig struct {
+	name    string
+	ctx     context.Context
+	options ctrl.Options
+}
+
+// ManagerOption configures WithManagerTracingContext.
+type ManagerOption func(*managerTracingConfig)
+
+// WithManagerName sets the name used in the "Manager <name>" span.
+func WithManagerName(name string) ManagerOption {
+	return func(c *managerTracingConfig) {
+		if name == "" {
+			return
+		}
+		c.name = name
+	}
+}
+
+// WithManagerContext ties the manager span to ctx, usually the context passed to the manager's Start
+// (e.g. ctrl.SetupSignalHandler()). The span ends when ctx is cancelled; without it the span is never ended.
+func WithManagerContext(ctx context.Context) ManagerOption {
+	return func(c *managerTracingConfig) {
+		if ctx == nil {
+			return
+		}
+		c.ctx = ctx
+	}
+}
+
+// WithManagerOptions uses options as the base for the returned ctrl.Options instead of the zero value.
+func WithManagerOptions(options ctrl.Options) ManagerOption {
+	return func(c *managerTracingConfig) {
+		c.options = options
+	}
+}
+
+// WithManagerTracingContext returns ctrl.Options whose BaseContext carries a long-lived internal span named
+// "Manager <name>", so background runnables such as caches and leader election run inside a trace.
+// The span is started the first time the manager asks for its base context.
+func WithManagerTracingContext(tracer trace.Tracer, opts ...ManagerOption) ctrl.Options {
+	cfg := managerTracingConfig{
+		name: DefaultManagerName,
+		ctx:  context.Background(),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+
+	var (
+		once    sync.Once
+		baseCtx context.Context
+	)
+	options := cfg.options
+	options.BaseContext = func() context.Context {
+		once.Do(func() {
+			var span trace.Span
+			baseCtx, span = tracer.Start(cfg.ctx, "Manager "+cfg.name, trace.WithSpanKind(trace.SpanKindInternal))
+			go func() {
+				<-cfg.ctx.Done()
+				span.End()
+			}()
+		})
+		return baseCtx
+	}
+	return options
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/helpers/manager_test.go
+
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+// spanRecorder is a runnable that captures the context the manager starts it with.
+type spanRecorder struct {
+	spans chan trace.SpanContext
+}
+
+func (r *spanRecorder) Start(ctx context.Context) error {
+	r.spans <- trace.SpanContextFromContext(ctx)
+	<-ctx.Done()
+	return nil
+}
+
+func TestWithManagerTracingContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options := WithManagerTracingContext(tp.Tracer("operatortrace"),
+		WithManagerName("test"),
+		WithManagerContext(ctx),
+		WithManagerOptions(ctrl.Options{Metrics: metricsserver.Options{BindAddress: "0"}}),
+	)
+	assert.Equal(t, "0", options.Metrics.BindAddress)
+
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "http://127.0.0.1:1"}, options)
+	require.NoError(t, err)
+
+	recorder := &spanRecorder{spans: make(chan trace.SpanContext, 1)}
+	require.NoError(t, mgr.Add(recorder))
+
+	done := make(chan error, 1)
+	go func() { done <- mgr.Start(ctx) }()
+
+	var runnableSpan trace.SpanContext
+	select {
+	case runnableSpan = <-recorder.spans:
+	case <-time.After(10 * time.Second):
+		t.Fatal("runnable was not started")
+	}
+	require.True(t, runnableSpan.IsValid())
+	assert.Empty(t, exporter.GetSpans(), "manager span should stay open while the manager runs")
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("manager did not stop")
+	}
+
+	require.Eventually(t, func() bool { return len(exporter.GetSpans()) == 1 }, 5*time.Second, 10*time.Millisecond)
+	spans := exporter.GetSpans()
+	assert.Equal(t, "Manager test", spans[0].Name)
+	assert.Equal(t, trace.SpanKindInternal, spans[0].SpanKind)
+	assert.Equal(t, runnableSpan.SpanID(), spans[0].SpanContext.SpanID())
+}
+
+var _ manager.Runnable = (*spanRecorder)(nil)
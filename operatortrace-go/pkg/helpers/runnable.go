@@ -0,0 +1,78 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/helpers/runnable.go
+
+package helpers
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// NewTracingRunnable wraps inner so its Start runs inside an internal span named name. The span starts before
+// inner.Start is called and ends when it returns, on shutdown or on error, recording the error if there is one.
+// The wrapper keeps inner's leader election requirement.
+func NewTracingRunnable(tracer trace.Tracer, name string, inner manager.Runnable) manager.Runnable {
+	return &tracingRunnable{tracer: tracer, name: name, inner: inner}
+}
+
+type tracingRunnable struct {
+	tracer trace.Tracer
+	name   string
+	inner  manager.Runnable
+}
+
+var _ manager.LeaderElectionRunnable = (*tracingRunnable)(nil)
+
+// Start implements manager.Runnable.
+func (r *tracingRunnable) Start(ctx context.Context) error {
+	ctx, span := r.tracer.Start(ctx, r.name, trace.WithSpanKind(trace.SpanKindInternal))
+	defer span.End()
+
+	err := r.inner.Start(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Runnables that do not say otherwise need
+// leader election, as in the manager.
+func (r *tracingRunnable) NeedLeaderElection() bool {
+	if ler, ok := r.inner.(manager.LeaderElectionRunnable); ok {
+		return ler.NeedLeaderElection()
+	}
+	return true
+}
+
+// NewTracingManager returns mgr with Start wrapped in a "Start Manager <name>" span covering the manager's whole
+// run. The manager starts its runnables from its base context rather than from Start's, so use
+// WithManagerTracingContext to run them inside a trace. WithManagerName sets the name; other options are ignored.
+func NewTracingManager(mgr ctrl.Manager, tracer trace.Tracer, opts ...ManagerOption) ctrl.Manager {
+	cfg := managerTracingConfig{name: DefaultManagerName}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&cfg)
+	}
+	return &tracingManager{
+		Manager: mgr,
+		start:   NewTracingRunnable(tracer, "Start Manager "+cfg.name, mgr),
+	}
+}
+
+type tracingManager struct {
+	ctrl.Manager
+	start manager.Runnable
+}
+
+// Start implements manager.Manager.
+func (m *tracingManager) Start(ctx context.Context) error {
+	return m.start.Start(ctx)
+}
@@ -0,0 +1,88 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/helpers/runnable_test.go
+
+package helpers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+)
+
+func TestNewTracingRunnable(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	boom := errors.New("boom")
+
+	var started trace.SpanContext
+	runnable := NewTracingRunnable(tp.Tracer("operatortrace"), "Start Cache", manager.RunnableFunc(func(ctx context.Context) error {
+		started = trace.SpanContextFromContext(ctx)
+		return boom
+	}))
+
+	assert.ErrorIs(t, runnable.Start(context.Background()), boom)
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "Start Cache", spans[0].Name)
+	assert.Equal(t, trace.SpanKindInternal, spans[0].SpanKind)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, spans[0].SpanContext.SpanID(), started.SpanID())
+
+	assert.True(t, runnable.(manager.LeaderElectionRunnable).NeedLeaderElection())
+	assert.False(t, NewTracingRunnable(tp.Tracer("operatortrace"), "Start Webhook", &noLeaderElection{}).(manager.LeaderElectionRunnable).NeedLeaderElection())
+}
+
+type noLeaderElection struct{}
+
+func (noLeaderElection) Start(context.Context) error { return nil }
+func (noLeaderElection) NeedLeaderElection() bool    { return false }
+
+func TestNewTracingManager(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "http://127.0.0.1:1"}, ctrl.Options{Metrics: metricsserver.Options{BindAddress: "0"}})
+	require.NoError(t, err)
+	mgr = NewTracingManager(mgr, tp.Tracer("operatortrace"), WithManagerName("test"))
+
+	recorder := &spanRecorder{spans: make(chan trace.SpanContext, 1)}
+	require.NoError(t, mgr.Add(recorder))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- mgr.Start(ctx) }()
+
+	select {
+	case <-recorder.spans:
+	case <-time.After(10 * time.Second):
+		t.Fatal("runnable was not started")
+	}
+	assert.Empty(t, exporter.GetSpans(), "startup span should stay open while the manager runs")
+
+	cancel()
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("manager did not stop")
+	}
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "Start Manager test", spans[0].Name)
+	assert.Equal(t, trace.SpanKindInternal, spans[0].SpanKind)
+}
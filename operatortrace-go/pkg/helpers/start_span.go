@@ -9,14 +9,16 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// spanStarter is implemented by the tracing clients, which start spans honouring their options.
+type spanStarter interface {
+	StartSpanWithOptions(ctx context.Context, operationName string, opts ...trace.SpanStartOption) (context.Context, trace.Span)
+}
+
+// StartSpan starts a span named operationName as a child of the span in ctx. When tracer is a tracing client, such
+// as a TracingClient, the span is started by its StartSpanWithOptions.
 func StartSpan(ctx context.Context, tracer trace.Tracer, operationName string, spanOpts ...trace.SpanStartOption) (context.Context, trace.Span) {
-	span := trace.SpanFromContext(ctx)
-	if span.SpanContext().IsValid() {
-		ctx, span = tracer.Start(ctx, operationName, spanOpts...)
-		return ctx, span
+	if starter, ok := tracer.(spanStarter); ok {
+		return starter.StartSpanWithOptions(ctx, operationName, spanOpts...)
 	}
-
-	// If there is no span in the context, create a new one
-	ctx, span = tracer.Start(ctx, operationName, spanOpts...)
-	return ctx, span
+	return tracer.Start(ctx, operationName, spanOpts...)
 }
@@ -0,0 +1,52 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/helpers/start_span_test.go
+
+package helpers
+
+import (
+	"context"
+	"testing"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestStartSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().Build()
+
+	tests := []struct {
+		name     string
+		tracer   trace.Tracer
+		recorded bool
+	}{
+		{name: "tracer", tracer: tp.Tracer("test"), recorded: true},
+		{name: "tracing client", tracer: tracingclient.NewTracingClient(k8sClient, k8sClient, tp.Tracer("test"), logr.Discard()), recorded: true},
+		// A noop tracing client must not record spans through its embedded tracer either
+		{name: "noop tracing client", tracer: tracingclient.NewTracingClientWithOptions(k8sClient, k8sClient, tp.Tracer("test"), logr.Discard(), nil, tracingclient.WithNoop())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter.Reset()
+			_, span := StartSpan(context.Background(), tt.tracer, "operation", trace.WithSpanKind(trace.SpanKindClient))
+			span.End()
+
+			spans := exporter.GetSpans()
+			if !tt.recorded {
+				assert.Empty(t, spans)
+				return
+			}
+			require.Len(t, spans, 1)
+			assert.Equal(t, trace.SpanKindClient, spans[0].SpanKind)
+		})
+	}
+}
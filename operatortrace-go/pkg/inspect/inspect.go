@@ -0,0 +1,99 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/inspect/inspect.go
+
+// Package inspect reports the trace context stored on an object, for debug commands run against a live cluster.
+package inspect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrNoTraceContext is returned by InspectObject for objects without a usable stored trace context.
+var ErrNoTraceContext = errors.New("object carries no trace context")
+
+// Result is the trace context stored on an object.
+type Result struct {
+	TraceID string                           `json:"traceID"`
+	SpanID  string                           `json:"spanID"`
+	Source  tracingclient.TraceContextSource `json:"source"`
+	// Age is how long ago the trace context was stored, zero when it was stored without a timestamp.
+	Age     time.Duration `json:"-"`
+	Expired bool          `json:"expired"`
+}
+
+// Format selects how WriteResult renders a Result.
+type Format string
+
+const (
+	FormatText Format = "text"
+	FormatJSON Format = "json"
+)
+
+// InspectObject reads the object at key into obj with reader and returns the trace context stored on it, read
+// the same way the tracing client created with opts reads it when starting a trace. The TraceID and SpanID
+// conditions are found with the scheme of reader when it has one, the client-go scheme otherwise. Like the tracing
+// client, it parses the trace context with the global text map propagator, so a command calling it must set one up
+// first, e.g. with otel.SetTextMapPropagator(propagation.TraceContext{}).
+func InspectObject(ctx context.Context, reader client.Reader, key client.ObjectKey, obj client.Object, opts tracingclient.Options) (Result, error) {
+	if err := reader.Get(ctx, key, obj); err != nil {
+		return Result{}, err
+	}
+	stored, ok := tracingclient.LookupStoredTrace(obj, readerScheme(reader), opts)
+	if !ok {
+		return Result{}, fmt.Errorf("%s: %w", key, ErrNoTraceContext)
+	}
+	result := Result{
+		TraceID: stored.TraceID,
+		SpanID:  stored.SpanID,
+		Source:  stored.Source,
+		Expired: stored.Expired,
+	}
+	if !stored.Timestamp.IsZero() {
+		result.Age = time.Since(stored.Timestamp)
+	}
+	return result, nil
+}
+
+// readerScheme returns the scheme of reader, or the client-go scheme if it has none.
+func readerScheme(reader client.Reader) *runtime.Scheme {
+	if withScheme, ok := reader.(interface{ Scheme() *runtime.Scheme }); ok && withScheme.Scheme() != nil {
+		return withScheme.Scheme()
+	}
+	return clientgoscheme.Scheme
+}
+
+// WriteResult writes result to w as text, one "field: value" line per field, or as a JSON object. The age is
+// rounded to the second, and reported as "unknown" in text or omitted from JSON when it was not recorded.
+func WriteResult(w io.Writer, result Result, format Format) error {
+	age := ""
+	if result.Age > 0 {
+		age = result.Age.Round(time.Second).String()
+	}
+	switch format {
+	case FormatText, "":
+		if age == "" {
+			age = "unknown"
+		}
+		_, err := fmt.Fprintf(w, "traceID: %s\nspanID: %s\nsource: %s\nage: %s\nexpired: %t\n",
+			result.TraceID, result.SpanID, result.Source, age, result.Expired)
+		return err
+	case FormatJSON:
+		return json.NewEncoder(w).Encode(struct {
+			Result
+			Age string `json:"age,omitempty"`
+		}{Result: result, Age: age})
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+}
@@ -0,0 +1,176 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/inspect/inspect_test.go
+
+package inspect
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+const (
+	traceID = "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	spanID  = "bbbbbbbbbbbbbbbb"
+)
+
+func TestInspectObject(t *testing.T) {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	traceParent, err := tracecontext.TraceParentFromIDs(traceID, spanID)
+	require.NoError(t, err)
+	traceState := func(storedAt time.Time) string {
+		state, err := tracecontext.SetTraceStateKey("", constants.TraceStateTimestampKey, storedAt.UTC().Format(time.RFC3339Nano))
+		require.NoError(t, err)
+		return state
+	}
+	minuteAgo := time.Now().Add(-time.Minute)
+	dayAgo := time.Now().Add(-24 * time.Hour)
+
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		conditions  []corev1.PodCondition
+		expected    Result
+		expectedAge time.Duration
+		expectErr   error
+	}{
+		{
+			name: "annotation",
+			annotations: map[string]string{
+				constants.DefaultTraceParentAnnotation: traceParent,
+				constants.DefaultTraceStateAnnotation:  traceState(minuteAgo),
+			},
+			expected:    Result{TraceID: traceID, SpanID: spanID, Source: tracingclient.TraceContextSourceAnnotation},
+			expectedAge: time.Minute,
+		},
+		{
+			name: "expired annotation",
+			annotations: map[string]string{
+				constants.DefaultTraceParentAnnotation: traceParent,
+				constants.DefaultTraceStateAnnotation:  traceState(dayAgo),
+			},
+			expected:    Result{TraceID: traceID, SpanID: spanID, Source: tracingclient.TraceContextSourceAnnotation, Expired: true},
+			expectedAge: 24 * time.Hour,
+		},
+		{
+			name:        "annotation without timestamp",
+			annotations: map[string]string{constants.DefaultTraceParentAnnotation: traceParent},
+			expected:    Result{TraceID: traceID, SpanID: spanID, Source: tracingclient.TraceContextSourceAnnotation},
+		},
+		{
+			name: "legacy annotations",
+			annotations: map[string]string{
+				constants.LegacyTraceIDAnnotation:     traceID,
+				constants.LegacySpanIDAnnotation:      spanID,
+				constants.LegacyTraceIDTimeAnnotation: minuteAgo.UTC().Format(time.RFC3339),
+			},
+			expected:    Result{TraceID: traceID, SpanID: spanID, Source: tracingclient.TraceContextSourceLegacy},
+			expectedAge: time.Minute,
+		},
+		{
+			name: "conditions",
+			conditions: []corev1.PodCondition{
+				{Type: "TraceID", Status: corev1.ConditionTrue, Message: traceID, LastTransitionTime: metav1.NewTime(minuteAgo)},
+				{Type: "SpanID", Status: corev1.ConditionTrue, Message: spanID, LastTransitionTime: metav1.NewTime(minuteAgo)},
+			},
+			expected:    Result{TraceID: traceID, SpanID: spanID, Source: tracingclient.TraceContextSourceCondition},
+			expectedAge: time.Minute,
+		},
+		{
+			name:      "no trace context",
+			expectErr: ErrNoTraceContext,
+		},
+		{
+			name:        "invalid traceparent",
+			annotations: map[string]string{constants.DefaultTraceParentAnnotation: "00-invalid"},
+			expectErr:   ErrNoTraceContext,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Annotations: tt.annotations},
+				Status:     corev1.PodStatus{Conditions: tt.conditions},
+			}
+			reader := fake.NewClientBuilder().WithObjects(pod).WithStatusSubresource(pod).Build()
+
+			result, err := InspectObject(context.Background(), reader, client.ObjectKeyFromObject(pod), &corev1.Pod{}, tracingclient.NewOptions())
+			if tt.expectErr != nil {
+				require.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, tt.expectedAge, result.Age, float64(30*time.Second))
+			result.Age = 0
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestInspectObjectNotFound(t *testing.T) {
+	reader := fake.NewClientBuilder().Build()
+	_, err := InspectObject(context.Background(), reader, client.ObjectKey{Namespace: "default", Name: "missing"}, &corev1.Pod{}, tracingclient.NewOptions())
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNoTraceContext)
+}
+
+func TestWriteResult(t *testing.T) {
+	result := Result{TraceID: traceID, SpanID: spanID, Source: tracingclient.TraceContextSourceAnnotation, Age: 90*time.Minute + 400*time.Millisecond}
+
+	tests := []struct {
+		name     string
+		result   Result
+		format   Format
+		expected string
+	}{
+		{
+			name:     "text",
+			result:   result,
+			format:   FormatText,
+			expected: "traceID: " + traceID + "\nspanID: " + spanID + "\nsource: annotation\nage: 1h30m0s\nexpired: false\n",
+		},
+		{
+			name:     "text without age",
+			result:   Result{TraceID: traceID, SpanID: spanID, Source: tracingclient.TraceContextSourceLegacy, Expired: true},
+			format:   FormatText,
+			expected: "traceID: " + traceID + "\nspanID: " + spanID + "\nsource: legacy\nage: unknown\nexpired: true\n",
+		},
+		{
+			name:     "json",
+			result:   result,
+			format:   FormatJSON,
+			expected: `{"traceID":"` + traceID + `","spanID":"` + spanID + `","source":"annotation","expired":false,"age":"1h30m0s"}` + "\n",
+		},
+		{
+			name:     "json without age",
+			result:   Result{TraceID: traceID, SpanID: spanID, Source: tracingclient.TraceContextSourceCondition},
+			format:   FormatJSON,
+			expected: `{"traceID":"` + traceID + `","spanID":"` + spanID + `","source":"condition","expired":false}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, WriteResult(&out, tt.result, tt.format))
+			assert.Equal(t, tt.expected, out.String())
+		})
+	}
+
+	assert.Error(t, WriteResult(&bytes.Buffer{}, result, "yaml"))
+}
@@ -0,0 +1,172 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/janitor/janitor.go
+
+// Package janitor clears trace annotations left behind by reconciles that never ended their trace.
+package janitor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+const (
+	// DefaultInterval is how often a TraceJanitor looks for stale trace annotations unless WithInterval is used.
+	DefaultInterval = 10 * time.Minute
+	// DefaultMargin is how long past the trace expiration a stored trace must be before it is cleared, unless
+	// WithMargin is used.
+	DefaultMargin = 5 * time.Minute
+)
+
+// KindAttributeKey is the attribute of the cleaned objects counter holding the kind of the cleaned object.
+const KindAttributeKey = attribute.Key("operatortrace.janitor.kind")
+
+// Option configures a TraceJanitor.
+type Option func(*TraceJanitor)
+
+// WithInterval sets how often the objects are listed.
+func WithInterval(interval time.Duration) Option {
+	return func(j *TraceJanitor) {
+		if interval <= 0 {
+			return
+		}
+		j.interval = interval
+	}
+}
+
+// WithMargin sets how long past the trace expiration of the tracing client a stored trace must be before it is
+// cleared, so a reconcile still running close to the expiration keeps its trace.
+func WithMargin(margin time.Duration) Option {
+	return func(j *TraceJanitor) {
+		if margin <= 0 {
+			return
+		}
+		j.margin = margin
+	}
+}
+
+// WithCleanedCounter counts the objects whose trace annotations were cleared, by kind.
+func WithCleanedCounter(c metric.Int64Counter) Option {
+	return func(j *TraceJanitor) {
+		if c == nil {
+			return
+		}
+		j.cleaned = c
+	}
+}
+
+// WithLogger sets the logger reporting cleared objects and failed sweeps.
+func WithLogger(l logr.Logger) Option {
+	return func(j *TraceJanitor) {
+		if l.GetSink() == nil {
+			return
+		}
+		j.logger = l
+	}
+}
+
+// TraceJanitor periodically lists objects of the configured kinds and clears the trace annotations of those whose
+// stored trace context is older than the trace expiration plus a margin. Such objects are left behind when EndTrace
+// never ran, e.g. after a crash, and would otherwise be the parent of every later reconcile of the object under
+// ExpirationPolicyExtend, or carry a dead trace forever. It is a manager.Runnable:
+//
+//	if err := mgr.Add(janitor.NewTraceJanitor(tracingClient, gvks)); err != nil {
+//		return err
+//	}
+type TraceJanitor struct {
+	tc       tracingclient.TracingClient
+	gvks     []schema.GroupVersionKind
+	interval time.Duration
+	margin   time.Duration
+	cleaned  metric.Int64Counter
+	logger   logr.Logger
+}
+
+var _ manager.LeaderElectionRunnable = (*TraceJanitor)(nil)
+
+// NewTraceJanitor returns a janitor clearing stale trace annotations from the objects of gvks with tc, which must
+// be created by this module's client package. Objects are listed as metadata only.
+func NewTraceJanitor(tc tracingclient.TracingClient, gvks []schema.GroupVersionKind, opts ...Option) *TraceJanitor {
+	j := &TraceJanitor{
+		tc:       tc,
+		gvks:     append([]schema.GroupVersionKind(nil), gvks...),
+		interval: DefaultInterval,
+		margin:   DefaultMargin,
+		logger:   logr.Discard(),
+	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(j)
+	}
+	return j
+}
+
+// Start implements manager.Runnable. It sweeps once right away, then every interval until ctx is done. A failed
+// sweep is logged and retried at the next interval.
+func (j *TraceJanitor) Start(ctx context.Context) error {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := j.Sweep(ctx); err != nil && ctx.Err() == nil {
+			j.logger.Error(err, "Failed to clear stale trace annotations")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable. Only the leader clears annotations.
+func (j *TraceJanitor) NeedLeaderElection() bool {
+	return true
+}
+
+// Sweep lists the objects of every configured kind once and clears their stale trace annotations. It returns the
+// number of objects cleared and the errors met, after trying every object.
+func (j *TraceJanitor) Sweep(ctx context.Context) (int, error) {
+	// The janitor's own calls are not part of any reconcile, so they are not traced
+	ctx = tracingclient.WithCallOptions(ctx, tracingclient.WithNoop())
+
+	var errs []error
+	cleaned := 0
+	for _, gvk := range j.gvks {
+		list := &metav1.PartialObjectMetadataList{}
+		list.SetGroupVersionKind(gvk.GroupVersion().WithKind(gvk.Kind + "List"))
+		if err := j.tc.List(ctx, list); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for i := range list.Items {
+			obj := &list.Items[i]
+			obj.SetGroupVersionKind(gvk)
+			ok, err := tracingclient.ClearStaleTraceContext(ctx, j.tc, obj, j.margin)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			cleaned++
+			j.logger.Info("Cleared stale trace annotations", "kind", gvk.Kind, "object", tracingclient.RedactedObjectKey(j.tc, client.ObjectKeyFromObject(obj), obj))
+			if j.cleaned != nil {
+				j.cleaned.Add(ctx, 1, metric.WithAttributes(KindAttributeKey.String(gvk.Kind)))
+			}
+		}
+	}
+	return cleaned, errors.Join(errs...)
+}
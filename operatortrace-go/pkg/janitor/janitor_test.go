@@ -0,0 +1,144 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/janitor/janitor_test.go
+
+package janitor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/testhelpers"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace/noop"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// tracedMeta returns object metadata carrying a trace context stored at storedAt.
+func tracedMeta(t *testing.T, name string, storedAt time.Time) metav1.ObjectMeta {
+	t.Helper()
+	traceParent, err := tracecontext.TraceParentFromIDs("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbb")
+	require.NoError(t, err)
+	traceState, err := tracecontext.SetTraceStateKey("", constants.TraceStateTimestampKey, storedAt.UTC().Format(time.RFC3339Nano))
+	require.NoError(t, err)
+	return metav1.ObjectMeta{
+		Name:      name,
+		Namespace: "default",
+		Annotations: map[string]string{
+			constants.DefaultTraceParentAnnotation: traceParent,
+			constants.DefaultTraceStateAnnotation:  traceState,
+			"example.com/other":                    "kept",
+		},
+	}
+}
+
+func TestTraceJanitorSweep(t *testing.T) {
+	stale := time.Now().Add(-constants.DefaultTraceExpiration - time.Hour)
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{ObjectMeta: tracedMeta(t, "stale", stale)},
+		&corev1.ConfigMap{ObjectMeta: tracedMeta(t, "fresh", time.Now())},
+		// Expired, but not by more than the margin yet
+		&corev1.ConfigMap{ObjectMeta: tracedMeta(t, "within-margin", time.Now().Add(-constants.DefaultTraceExpiration-time.Minute))},
+		&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "untraced", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: tracedMeta(t, "stale-pod", stale)},
+	).Build()
+	tc := tracingclient.NewTracingClientWithOptions(k8sClient, k8sClient, noop.NewTracerProvider().Tracer("test"), logr.Discard(), nil)
+	counter := &testhelpers.RecordingCounter{}
+	j := NewTraceJanitor(tc, []schema.GroupVersionKind{
+		corev1.SchemeGroupVersion.WithKind("ConfigMap"),
+		corev1.SchemeGroupVersion.WithKind("Pod"),
+	}, WithCleanedCounter(counter))
+
+	cleaned, err := j.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, cleaned)
+	assert.EqualValues(t, 2, counter.Total())
+
+	tests := []struct {
+		name    string
+		obj     client.Object
+		cleared bool
+	}{
+		{"stale", &corev1.ConfigMap{}, true},
+		{"fresh", &corev1.ConfigMap{}, false},
+		{"within-margin", &corev1.ConfigMap{}, false},
+		{"stale-pod", &corev1.Pod{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, k8sClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: tt.name}, tt.obj))
+			annotations := tt.obj.GetAnnotations()
+			assert.Equal(t, "kept", annotations["example.com/other"])
+			if tt.cleared {
+				assert.NotContains(t, annotations, constants.DefaultTraceParentAnnotation)
+				assert.NotContains(t, annotations, constants.DefaultTraceStateAnnotation)
+			} else {
+				assert.Contains(t, annotations, constants.DefaultTraceParentAnnotation)
+			}
+		})
+	}
+
+	// A second sweep finds nothing left to clear
+	cleaned, err = j.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, cleaned)
+}
+
+func TestTraceJanitorSweepRedactsLoggedObjects(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		&corev1.Secret{ObjectMeta: tracedMeta(t, "db-password", time.Now().Add(-constants.DefaultTraceExpiration-time.Hour))},
+	).Build()
+	secretGVK := corev1.SchemeGroupVersion.WithKind("Secret")
+	tc := tracingclient.NewTracingClientWithOptions(k8sClient, k8sClient, noop.NewTracerProvider().Tracer("test"), logr.Discard(), nil,
+		tracingclient.WithRedactedKinds(secretGVK))
+	var logs strings.Builder
+	logger := funcr.New(func(prefix, args string) { logs.WriteString(args + "\n") }, funcr.Options{})
+	j := NewTraceJanitor(tc, []schema.GroupVersionKind{secretGVK}, WithLogger(logger))
+
+	cleaned, err := j.Sweep(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, cleaned)
+	assert.Contains(t, logs.String(), "Cleared stale trace annotations")
+	assert.NotContains(t, logs.String(), "db-password")
+}
+
+func TestTraceJanitorStart(t *testing.T) {
+	k8sClient := fake.NewClientBuilder().WithObjects(
+		&corev1.ConfigMap{ObjectMeta: tracedMeta(t, "stale", time.Now().Add(-constants.DefaultTraceExpiration-time.Hour))},
+	).Build()
+	tc := tracingclient.NewTracingClientWithOptions(k8sClient, k8sClient, noop.NewTracerProvider().Tracer("test"), logr.Discard(), nil)
+	j := NewTraceJanitor(tc, []schema.GroupVersionKind{corev1.SchemeGroupVersion.WithKind("ConfigMap")}, WithInterval(time.Millisecond))
+	assert.True(t, j.NeedLeaderElection())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- j.Start(ctx) }()
+	require.Eventually(t, func() bool {
+		cm := &corev1.ConfigMap{}
+		require.NoError(t, k8sClient.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "stale"}, cm))
+		_, traced := cm.Annotations[constants.DefaultTraceParentAnnotation]
+		return !traced
+	}, time.Second, time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestJanitorOptionsIgnoreInvalidValues(t *testing.T) {
+	j := NewTraceJanitor(nil, nil, WithInterval(0), WithMargin(-time.Minute), WithCleanedCounter(nil), WithLogger(logr.Logger{}), nil)
+	assert.Equal(t, DefaultInterval, j.interval)
+	assert.Equal(t, DefaultMargin, j.margin)
+	assert.Nil(t, j.cleaned)
+}
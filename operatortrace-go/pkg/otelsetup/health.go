@@ -0,0 +1,90 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/otelsetup/health.go
+
+package otelsetup
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// defaultExportFailureThreshold is how many exports in a row must fail before the exporter is reported unhealthy.
+const defaultExportFailureThreshold = 3
+
+// HealthExporter wraps a span exporter and counts its failed exports, so the health of the tracing pipeline can
+// be checked: the batch span processor drops the spans of a failed export without surfacing the error.
+type HealthExporter struct {
+	sdktrace.SpanExporter
+
+	threshold int
+
+	mu                  sync.Mutex
+	failures            uint64
+	consecutiveFailures int
+	lastErr             error
+}
+
+var _ sdktrace.SpanExporter = (*HealthExporter)(nil)
+
+// NewHealthExporter wraps exporter, reporting it unhealthy once failureThreshold exports in a row failed and
+// healthy again after the next successful export. A failureThreshold below 1 uses a threshold of 3:
+//
+//	exporter := otelsetup.NewHealthExporter(otlpExporter, 0)
+//	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+//	_ = mgr.AddReadyzCheck("tracing", exporter.Checker())
+func NewHealthExporter(exporter sdktrace.SpanExporter, failureThreshold int) *HealthExporter {
+	if failureThreshold < 1 {
+		failureThreshold = defaultExportFailureThreshold
+	}
+	return &HealthExporter{SpanExporter: exporter, threshold: failureThreshold}
+}
+
+// ExportSpans exports spans with the wrapped exporter and records whether the export failed.
+func (e *HealthExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err != nil {
+		e.failures++
+		e.consecutiveFailures++
+		e.lastErr = err
+		return err
+	}
+	e.consecutiveFailures = 0
+	e.lastErr = nil
+	return nil
+}
+
+// Healthy reports whether fewer exports than the failure threshold failed in a row.
+func (e *HealthExporter) Healthy() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.consecutiveFailures < e.threshold
+}
+
+// Failures returns how many exports failed since the exporter was created.
+func (e *HealthExporter) Failures() uint64 {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.failures
+}
+
+// Checker returns a health check failing while the exporter is unhealthy, for mgr.AddHealthzCheck or
+// mgr.AddReadyzCheck.
+func (e *HealthExporter) Checker() healthz.Checker {
+	return func(_ *http.Request) error {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.consecutiveFailures < e.threshold {
+			return nil
+		}
+		return fmt.Errorf("span exporter failed %d exports in a row: %w", e.consecutiveFailures, e.lastErr)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/otelsetup/health_test.go
+
+package otelsetup
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// failingExporter fails its exports while fail is set.
+type failingExporter struct {
+	*tracetest.InMemoryExporter
+	fail bool
+}
+
+func (e *failingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if e.fail {
+		return errors.New("backend unavailable")
+	}
+	return e.InMemoryExporter.ExportSpans(ctx, spans)
+}
+
+func TestHealthExporter(t *testing.T) {
+	inner := &failingExporter{InMemoryExporter: tracetest.NewInMemoryExporter(), fail: true}
+	exporter := NewHealthExporter(inner, 2)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	check := exporter.Checker()
+	export := func() {
+		_, span := tp.Tracer("test").Start(context.Background(), "span")
+		span.End()
+	}
+
+	assert.True(t, exporter.Healthy())
+	require.NoError(t, check(nil))
+
+	export()
+	assert.True(t, exporter.Healthy(), "a single failure stays below the threshold")
+	export()
+	assert.False(t, exporter.Healthy())
+	assert.Equal(t, uint64(2), exporter.Failures())
+	err := check(nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "backend unavailable")
+
+	inner.fail = false
+	export()
+	assert.True(t, exporter.Healthy())
+	require.NoError(t, check(nil))
+	assert.Equal(t, uint64(2), exporter.Failures())
+	assert.Len(t, inner.GetSpans(), 1)
+}
+
+func TestNewHealthExporterDefaultThreshold(t *testing.T) {
+	exporter := NewHealthExporter(&failingExporter{InMemoryExporter: tracetest.NewInMemoryExporter(), fail: true}, 0)
+	for i := 0; i < defaultExportFailureThreshold; i++ {
+		assert.True(t, exporter.Healthy())
+		_ = exporter.ExportSpans(context.Background(), nil)
+	}
+	assert.False(t, exporter.Healthy())
+}
@@ -0,0 +1,44 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/otelsetup/sampler.go
+
+package otelsetup
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// LabelSampledAttributeKey is set on the spans a label sampling rule of the tracing client selected for tracing.
+const LabelSampledAttributeKey = attribute.Key("operatortrace.sampling.label_sampled")
+
+// NewLabelSampler returns a sampler that records and samples the spans the label sampling rules of the tracing
+// client selected, see client.WithLabelSampler, and leaves every other decision to base:
+//
+//	sdktrace.NewTracerProvider(sdktrace.WithSampler(otelsetup.NewLabelSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(0.1)))))
+//
+// Spans the rules drop are never started, so they need no support from the sampler.
+func NewLabelSampler(base sdktrace.Sampler) sdktrace.Sampler {
+	return labelSampler{base: base}
+}
+
+type labelSampler struct {
+	base sdktrace.Sampler
+}
+
+func (s labelSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	for _, attr := range p.Attributes {
+		if attr.Key == LabelSampledAttributeKey && attr.Value.AsBool() {
+			return sdktrace.SamplingResult{
+				Decision:   sdktrace.RecordAndSample,
+				Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+			}
+		}
+	}
+	return s.base.ShouldSample(p)
+}
+
+func (s labelSampler) Description() string {
+	return "OperatorTraceLabelSampler{" + s.base.Description() + "}"
+}
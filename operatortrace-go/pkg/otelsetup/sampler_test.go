@@ -0,0 +1,35 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/otelsetup/sampler_test.go
+
+package otelsetup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestLabelSampler(t *testing.T) {
+	sampler := NewLabelSampler(sdktrace.NeverSample())
+	assert.Equal(t, "OperatorTraceLabelSampler{AlwaysOffSampler}", sampler.Description())
+
+	tests := []struct {
+		name     string
+		attrs    []attribute.KeyValue
+		expected sdktrace.SamplingDecision
+	}{
+		{"label sampled span", []attribute.KeyValue{LabelSampledAttributeKey.Bool(true)}, sdktrace.RecordAndSample},
+		{"other span", []attribute.KeyValue{attribute.String("key", "value")}, sdktrace.Drop},
+		{"attribute set to false", []attribute.KeyValue{LabelSampledAttributeKey.Bool(false)}, sdktrace.Drop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := sampler.ShouldSample(sdktrace.SamplingParameters{Name: "span", Attributes: tt.attrs})
+			assert.Equal(t, tt.expected, result.Decision)
+		})
+	}
+}
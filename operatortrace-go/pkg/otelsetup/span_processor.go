@@ -0,0 +1,155 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/otelsetup/span_processor.go
+
+package otelsetup
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Attribute keys appended to operatortrace spans by the span processor.
+const (
+	ObjectKeyAttributeKey       = attribute.Key("operatortrace.object.key")
+	ControllerNameAttributeKey  = attribute.Key("operatortrace.controller.name")
+	RequeueAttributeKey         = attribute.Key("operatortrace.reconcile.requeue")
+	RequeueAfterAttributeKey    = attribute.Key("operatortrace.reconcile.requeue_after")
+	ReconcileErrorAttributeKey  = attribute.Key("operatortrace.reconcile.error")
+	ReconcileResultAttributeKey = attribute.Key("operatortrace.reconcile.result")
+)
+
+// ReconcileInfo carries reconcile metadata placed in the context by the reconcile adapter.
+// The result is filled in once the user reconciler returns.
+type ReconcileInfo struct {
+	ObjectKey      string
+	ControllerName string
+
+	mu        sync.Mutex
+	hasResult bool
+	result    ctrlreconcile.Result
+	err       error
+}
+
+type reconcileInfoKey struct{}
+
+// ContextWithReconcileInfo stores info in the context so spans started from it can be enriched.
+func ContextWithReconcileInfo(ctx context.Context, info *ReconcileInfo) context.Context {
+	return context.WithValue(ctx, reconcileInfoKey{}, info)
+}
+
+// ReconcileInfoFromContext returns the ReconcileInfo stored in the context, if any.
+func ReconcileInfoFromContext(ctx context.Context) *ReconcileInfo {
+	info, _ := ctx.Value(reconcileInfoKey{}).(*ReconcileInfo)
+	return info
+}
+
+// SetResult records the outcome of the reconcile.
+func (i *ReconcileInfo) SetResult(result ctrlreconcile.Result, err error) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.hasResult = true
+	i.result = result
+	i.err = err
+}
+
+func (i *ReconcileInfo) attributes() []attribute.KeyValue {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	attrs := make([]attribute.KeyValue, 0, 5)
+	if i.ObjectKey != "" {
+		attrs = append(attrs, ObjectKeyAttributeKey.String(i.ObjectKey))
+	}
+	if i.ControllerName != "" {
+		attrs = append(attrs, ControllerNameAttributeKey.String(i.ControllerName))
+	}
+	if !i.hasResult {
+		return attrs
+	}
+	if i.err != nil {
+		attrs = append(attrs, ReconcileResultAttributeKey.String("error"), ReconcileErrorAttributeKey.String(i.err.Error()))
+	} else if i.result.Requeue || i.result.RequeueAfter > 0 {
+		attrs = append(attrs, ReconcileResultAttributeKey.String("requeue"))
+	} else {
+		attrs = append(attrs, ReconcileResultAttributeKey.String("success"))
+	}
+	attrs = append(attrs, RequeueAttributeKey.Bool(i.result.Requeue), RequeueAfterAttributeKey.String(i.result.RequeueAfter.String()))
+	return attrs
+}
+
+// operatorTraceSpanProcessor enriches operatortrace spans with reconcile metadata before handing them to the next processor.
+type operatorTraceSpanProcessor struct {
+	next       sdktrace.SpanProcessor
+	scopeNames map[string]struct{}
+	infos      sync.Map // span ID -> *ReconcileInfo
+}
+
+var _ sdktrace.SpanProcessor = (*operatorTraceSpanProcessor)(nil)
+
+// NewOperatorTraceSpanProcessor returns a SpanProcessor that appends reconcile metadata from the context
+// to spans created by operatortrace and forwards every span to next (typically a batch processor).
+// Spans are identified by their instrumentation scope name; when none are provided, the default
+// operatortrace scope names are used. Spans from other scopes pass through untouched.
+func NewOperatorTraceSpanProcessor(next sdktrace.SpanProcessor, scopeNames ...string) sdktrace.SpanProcessor {
+	if len(scopeNames) == 0 {
+		scopeNames = []string{constants.InstrumentationScopeName, constants.TracerName}
+	}
+	names := make(map[string]struct{}, len(scopeNames))
+	for _, name := range scopeNames {
+		names[name] = struct{}{}
+	}
+	return &operatorTraceSpanProcessor{
+		next:       next,
+		scopeNames: names,
+	}
+}
+
+// OnStart remembers the reconcile metadata for operatortrace spans.
+func (p *operatorTraceSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if _, ok := p.scopeNames[s.InstrumentationScope().Name]; ok {
+		if info := ReconcileInfoFromContext(parent); info != nil {
+			p.infos.Store(s.SpanContext().SpanID(), info)
+		}
+	}
+	p.next.OnStart(parent, s)
+}
+
+// OnEnd appends the remembered reconcile metadata and forwards the span.
+func (p *operatorTraceSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	value, ok := p.infos.LoadAndDelete(s.SpanContext().SpanID())
+	if !ok {
+		p.next.OnEnd(s)
+		return
+	}
+	p.next.OnEnd(enrichedSpan{ReadOnlySpan: s, extra: value.(*ReconcileInfo).attributes()})
+}
+
+// Shutdown shuts down the next processor.
+func (p *operatorTraceSpanProcessor) Shutdown(ctx context.Context) error {
+	return p.next.Shutdown(ctx)
+}
+
+// ForceFlush flushes the next processor.
+func (p *operatorTraceSpanProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// enrichedSpan decorates an ended span with additional attributes.
+type enrichedSpan struct {
+	sdktrace.ReadOnlySpan
+	extra []attribute.KeyValue
+}
+
+// Attributes returns the span attributes followed by the enrichment attributes.
+func (s enrichedSpan) Attributes() []attribute.KeyValue {
+	attrs := s.ReadOnlySpan.Attributes()
+	out := make([]attribute.KeyValue, 0, len(attrs)+len(s.extra))
+	out = append(out, attrs...)
+	return append(out, s.extra...)
+}
@@ -0,0 +1,124 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/otelsetup/span_processor_test.go
+
+package otelsetup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func newTestProvider(t *testing.T, scopeNames ...string) (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	processor := NewOperatorTraceSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter), scopeNames...)
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+	return tp, exporter
+}
+
+func attributeMap(attrs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := make(map[attribute.Key]attribute.Value, len(attrs))
+	for _, kv := range attrs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestOperatorTraceSpanProcessorEnrichesSpans(t *testing.T) {
+	tests := []struct {
+		name     string
+		result   ctrlreconcile.Result
+		err      error
+		expected map[attribute.Key]attribute.Value
+	}{
+		{
+			name: "success",
+			expected: map[attribute.Key]attribute.Value{
+				ObjectKeyAttributeKey:       attribute.StringValue("default/sample"),
+				ControllerNameAttributeKey:  attribute.StringValue("sample-controller"),
+				ReconcileResultAttributeKey: attribute.StringValue("success"),
+				RequeueAttributeKey:         attribute.BoolValue(false),
+				RequeueAfterAttributeKey:    attribute.StringValue("0s"),
+			},
+		},
+		{
+			name:   "requeue",
+			result: ctrlreconcile.Result{RequeueAfter: time.Minute},
+			expected: map[attribute.Key]attribute.Value{
+				ReconcileResultAttributeKey: attribute.StringValue("requeue"),
+				RequeueAfterAttributeKey:    attribute.StringValue("1m0s"),
+			},
+		},
+		{
+			name: "error",
+			err:  errors.New("boom"),
+			expected: map[attribute.Key]attribute.Value{
+				ReconcileResultAttributeKey: attribute.StringValue("error"),
+				ReconcileErrorAttributeKey:  attribute.StringValue("boom"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tp, exporter := newTestProvider(t)
+			info := &ReconcileInfo{ObjectKey: "default/sample", ControllerName: "sample-controller"}
+			ctx := ContextWithReconcileInfo(context.Background(), info)
+
+			_, span := tp.Tracer(constants.InstrumentationScopeName).Start(ctx, "StartTrace")
+			info.SetResult(tt.result, tt.err)
+			span.End()
+
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 1)
+			attrs := attributeMap(spans[0].Attributes)
+			for key, value := range tt.expected {
+				assert.Equal(t, value, attrs[key], "attribute %s", key)
+			}
+		})
+	}
+}
+
+func TestOperatorTraceSpanProcessorSkipsSpans(t *testing.T) {
+	tp, exporter := newTestProvider(t)
+	info := &ReconcileInfo{ObjectKey: "default/sample"}
+	ctx := ContextWithReconcileInfo(context.Background(), info)
+
+	// Spans from other instrumentation scopes are left untouched
+	_, userSpan := tp.Tracer("user").Start(ctx, "user span")
+	userSpan.End()
+
+	// Operatortrace spans without reconcile info are left untouched
+	_, bareSpan := tp.Tracer(constants.InstrumentationScopeName).Start(context.Background(), "bare span")
+	bareSpan.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	for _, span := range spans {
+		assert.NotContains(t, attributeMap(span.Attributes), ObjectKeyAttributeKey)
+	}
+}
+
+func TestOperatorTraceSpanProcessorCustomScope(t *testing.T) {
+	tp, exporter := newTestProvider(t, "custom-scope")
+	ctx := ContextWithReconcileInfo(context.Background(), &ReconcileInfo{ObjectKey: "default/sample"})
+
+	_, span := tp.Tracer("custom-scope").Start(ctx, "span")
+	span.End()
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, attribute.StringValue("default/sample"), attributeMap(spans[0].Attributes)[ObjectKeyAttributeKey])
+}
@@ -0,0 +1,44 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/predicates/changed_fields.go
+
+package predicates
+
+import (
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ChangedFields returns which of spec, status, data, labels, annotations and finalizers differ between
+// oldObj and newObj, in that order. It applies the same rules as HasSignificantUpdate: trace annotations,
+// the provided ignoredAnnotationKeys, TraceID/SpanID conditions and status.observedGeneration are ignored.
+func ChangedFields(oldObj, newObj client.Object, ignoredAnnotationKeys ...string) []string {
+	oldUnstructured := objToUnstructured(oldObj)
+	newUnstructured := objToUnstructured(newObj)
+	replaceEmptyStructsAndSlicesWithNil(oldUnstructured)
+	replaceEmptyStructsAndSlicesWithNil(newUnstructured)
+
+	var fields []string
+	if hasFieldChanged(oldUnstructured, newUnstructured, "spec") {
+		fields = append(fields, "spec")
+	}
+	oldStatus := getFieldExcludingObservedGeneration(oldUnstructured, "status")
+	newStatus := getFieldExcludingObservedGeneration(newUnstructured, "status")
+	if !equality.Semantic.DeepEqual(oldStatus, newStatus) {
+		fields = append(fields, "status")
+	}
+	if hasFieldChanged(oldUnstructured, newUnstructured, "data") {
+		fields = append(fields, "data")
+	}
+	if !equality.Semantic.DeepEqual(oldObj.GetLabels(), newObj.GetLabels()) {
+		fields = append(fields, "labels")
+	}
+	ignored := append(defaultIgnoredAnnotationKeys(), ignoredAnnotationKeys...)
+	if !equalExcept(oldObj.GetAnnotations(), newObj.GetAnnotations(), ignored...) {
+		fields = append(fields, "annotations")
+	}
+	if !equality.Semantic.DeepEqual(oldObj.GetFinalizers(), newObj.GetFinalizers()) {
+		fields = append(fields, "finalizers")
+	}
+	return fields
+}
@@ -0,0 +1,65 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/predicates/changed_fields_test.go
+
+package predicates_test
+
+import (
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestChangedFields(t *testing.T) {
+	basePod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Annotations: map[string]string{"team": "tracing"},
+		},
+		Spec:   corev1.PodSpec{NodeName: "node1"},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+	}
+	baseConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cm"},
+		Data:       map[string]string{"key": "value"},
+	}
+
+	tests := []struct {
+		name     string
+		old      client.Object
+		mutate   func(obj client.Object)
+		expected []string
+	}{
+		{"unchanged", basePod, func(obj client.Object) {}, nil},
+		{"spec only", basePod, func(obj client.Object) { obj.(*corev1.Pod).Spec.NodeName = "node2" }, []string{"spec"}},
+		{"status only", basePod, func(obj client.Object) { obj.(*corev1.Pod).Status.Phase = corev1.PodRunning }, []string{"status"}},
+		{"trace condition ignored", basePod, func(obj client.Object) {
+			pod := obj.(*corev1.Pod)
+			pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{Type: "SpanID", Status: corev1.ConditionTrue})
+		}, nil},
+		{"data", baseConfigMap, func(obj client.Object) { obj.(*corev1.ConfigMap).Data["key"] = "other" }, []string{"data"}},
+		{"labels and finalizers", basePod, func(obj client.Object) {
+			obj.SetLabels(map[string]string{"app": "test"})
+			obj.SetFinalizers([]string{"example.com/cleanup"})
+		}, []string{"labels", "finalizers"}},
+		{"annotations", basePod, func(obj client.Object) {
+			obj.SetAnnotations(map[string]string{"team": "other"})
+		}, []string{"annotations"}},
+		{"trace annotations ignored", basePod, func(obj client.Object) {
+			obj.SetAnnotations(map[string]string{"team": "tracing", constants.DefaultTraceParentAnnotation: "00-aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa-bbbbbbbbbbbbbbbb-01"})
+		}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newObj := tt.old.DeepCopyObject().(client.Object)
+			tt.mutate(newObj)
+			assert.Equal(t, tt.expected, predicates.ChangedFields(tt.old, newObj))
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/predicates/configurable_ignore_annotation_update.go
+
+package predicates
+
+import (
+	"sync"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+type ConfigurableIgnoreAnnotationUpdatePredicate = TypedConfigurableIgnoreAnnotationUpdatePredicate[client.Object]
+
+var _ predicate.Predicate = (*ConfigurableIgnoreAnnotationUpdatePredicate)(nil)
+
+// NewConfigurableIgnoreAnnotationUpdatePredicate creates a ConfigurableIgnoreAnnotationUpdatePredicate ignoring the
+// trace annotation keys and ignoredAnnotationKeys.
+func NewConfigurableIgnoreAnnotationUpdatePredicate(ignoredAnnotationKeys ...string) *ConfigurableIgnoreAnnotationUpdatePredicate {
+	return NewTypedConfigurableIgnoreAnnotationUpdatePredicate[client.Object](ignoredAnnotationKeys...)
+}
+
+// NewTypedConfigurableIgnoreAnnotationUpdatePredicate creates a TypedConfigurableIgnoreAnnotationUpdatePredicate
+// ignoring the trace annotation keys and ignoredAnnotationKeys.
+func NewTypedConfigurableIgnoreAnnotationUpdatePredicate[T client.Object](ignoredAnnotationKeys ...string) *TypedConfigurableIgnoreAnnotationUpdatePredicate[T] {
+	p := &TypedConfigurableIgnoreAnnotationUpdatePredicate[T]{ignoredAnnotationKeys: map[string]struct{}{}}
+	for _, key := range ignoredAnnotationKeys {
+		p.AddIgnoredAnnotationKey(key)
+	}
+	return p
+}
+
+// TypedConfigurableIgnoreAnnotationUpdatePredicate filters updates like TypedIgnoreTraceAnnotationUpdatePredicate,
+// but its ignored annotation keys can be added to after construction, e.g. by plugins registering annotations at
+// init time, while it is in use by a controller.
+type TypedConfigurableIgnoreAnnotationUpdatePredicate[T client.Object] struct {
+	mu                    sync.RWMutex
+	ignoredAnnotationKeys map[string]struct{}
+}
+
+// AddIgnoredAnnotationKey makes the predicate ignore updates of the annotation key. Empty keys are ignored.
+func (p *TypedConfigurableIgnoreAnnotationUpdatePredicate[T]) AddIgnoredAnnotationKey(key string) {
+	if key == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ignoredAnnotationKeys == nil {
+		p.ignoredAnnotationKeys = map[string]struct{}{}
+	}
+	p.ignoredAnnotationKeys[key] = struct{}{}
+}
+
+// IgnoredAnnotationKeys returns the annotation keys added to the predicate, in no particular order.
+func (p *TypedConfigurableIgnoreAnnotationUpdatePredicate[T]) IgnoredAnnotationKeys() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	keys := make([]string, 0, len(p.ignoredAnnotationKeys))
+	for key := range p.ignoredAnnotationKeys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Create implements the create event check for the predicate.
+func (*TypedConfigurableIgnoreAnnotationUpdatePredicate[T]) Create(e event.TypedCreateEvent[T]) bool {
+	return true
+}
+
+// Delete implements the delete event check for the predicate.
+func (*TypedConfigurableIgnoreAnnotationUpdatePredicate[T]) Delete(e event.TypedDeleteEvent[T]) bool {
+	return true
+}
+
+// Generic implements the generic event check for the predicate.
+func (*TypedConfigurableIgnoreAnnotationUpdatePredicate[T]) Generic(e event.TypedGenericEvent[T]) bool {
+	return true
+}
+
+// Update implements the update event check for the predicate.
+func (p *TypedConfigurableIgnoreAnnotationUpdatePredicate[T]) Update(e event.TypedUpdateEvent[T]) bool {
+	if e.ObjectOld.DeepCopyObject() == nil || e.ObjectNew.DeepCopyObject() == nil {
+		return true
+	}
+
+	return hasSignificantUpdate(e.ObjectOld, e.ObjectNew, p.IgnoredAnnotationKeys()...)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/predicates/configurable_ignore_annotation_update_test.go
+
+package predicates_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func podWithAnnotations(annotations map[string]string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Annotations: annotations}}
+}
+
+func TestConfigurableIgnoreAnnotationUpdatePredicate(t *testing.T) {
+	pred := predicates.NewConfigurableIgnoreAnnotationUpdatePredicate("skip-me")
+	pluginUpdate := event.UpdateEvent{
+		ObjectOld: podWithAnnotations(map[string]string{"plugin.example.com/state": "v1"}),
+		ObjectNew: podWithAnnotations(map[string]string{"plugin.example.com/state": "v2"}),
+	}
+
+	assert.True(t, pred.Update(pluginUpdate), "Expected update of a key not yet ignored to be processed")
+
+	pred.AddIgnoredAnnotationKey("plugin.example.com/state")
+	pred.AddIgnoredAnnotationKey("")
+	assert.False(t, pred.Update(pluginUpdate), "Expected update of a key added after construction to be ignored")
+	assert.ElementsMatch(t, []string{"skip-me", "plugin.example.com/state"}, pred.IgnoredAnnotationKeys())
+
+	assert.False(t, pred.Update(event.UpdateEvent{
+		ObjectOld: podWithAnnotations(map[string]string{"skip-me": "v1", constants.DefaultTraceParentAnnotation: buildTraceParent("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbb")}),
+		ObjectNew: podWithAnnotations(map[string]string{"skip-me": "v2", constants.DefaultTraceParentAnnotation: buildTraceParent("cccccccccccccccccccccccccccccccc", "dddddddddddddddd")}),
+	}), "Expected construction and trace annotation keys to stay ignored")
+
+	assert.True(t, pred.Update(event.UpdateEvent{
+		ObjectOld: podWithAnnotations(map[string]string{"other": "v1"}),
+		ObjectNew: podWithAnnotations(map[string]string{"other": "v2"}),
+	}), "Expected update of other annotations to be processed")
+
+	assert.True(t, pred.Create(event.CreateEvent{}))
+	assert.True(t, pred.Delete(event.DeleteEvent{}))
+	assert.True(t, pred.Generic(event.GenericEvent{}))
+}
+
+func TestConfigurableIgnoreAnnotationUpdatePredicateConcurrentUse(t *testing.T) {
+	pred := predicates.NewConfigurableIgnoreAnnotationUpdatePredicate()
+	update := event.UpdateEvent{
+		ObjectOld: podWithAnnotations(map[string]string{"key-0": "v1"}),
+		ObjectNew: podWithAnnotations(map[string]string{"key-0": "v2"}),
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			pred.AddIgnoredAnnotationKey(fmt.Sprintf("key-%d", i))
+		}(i)
+		go func() {
+			defer wg.Done()
+			pred.Update(update)
+		}()
+	}
+	wg.Wait()
+
+	assert.Len(t, pred.IgnoredAnnotationKeys(), 10)
+	assert.False(t, pred.Update(update))
+}
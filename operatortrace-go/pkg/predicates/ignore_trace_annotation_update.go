@@ -6,6 +6,7 @@ package predicates
 
 import (
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -30,6 +31,14 @@ func NewTypedIgnoreAnnotationUpdatePredicate[T client.Object](ignoredAnnotationK
 type TypedIgnoreTraceAnnotationUpdatePredicate[T client.Object] struct {
 	predicate.Funcs
 	ignoredAnnotationKeys []string
+	logger                logr.Logger
+}
+
+// WithLogger returns a copy of the predicate logging, at verbosity 1, which annotations changed in the updates it
+// lets through, to debug what triggered a reconcile.
+func (p TypedIgnoreTraceAnnotationUpdatePredicate[T]) WithLogger(logger logr.Logger) TypedIgnoreTraceAnnotationUpdatePredicate[T] {
+	p.logger = logger
+	return p
 }
 
 // Create implements the create event check for the predicate.
@@ -53,24 +62,28 @@ func (p TypedIgnoreTraceAnnotationUpdatePredicate[T]) Update(e event.TypedUpdate
 		return true
 	}
 
-	oldAnnotations := e.ObjectOld.GetAnnotations()
-	newAnnotations := e.ObjectNew.GetAnnotations()
+	significant := hasSignificantUpdate(e.ObjectOld, e.ObjectNew, p.ignoredAnnotationKeys...)
+	if log := p.logger.V(1); significant && log.Enabled() {
+		ignored := append(defaultIgnoredAnnotationKeys(), p.ignoredAnnotationKeys...)
+		log.Info("Significant update", "object", client.ObjectKeyFromObject(e.ObjectNew).String(),
+			"annotationChanges", AnnotationDiff(e.ObjectOld.GetAnnotations(), e.ObjectNew.GetAnnotations(), ignored...),
+			"changedFields", ChangedFields(e.ObjectOld, e.ObjectNew, p.ignoredAnnotationKeys...))
+	}
+	return significant
+}
 
-	ignoredAnnotations := append(
-		[]string{
-			constants.DefaultTraceParentAnnotation,
-			constants.DefaultTraceStateAnnotation,
-			constants.LegacyTraceIDAnnotation,
-			constants.LegacySpanIDAnnotation,
-			constants.LegacyTraceIDTimeAnnotation,
-		},
-		p.ignoredAnnotationKeys...,
-	)
+// hasSignificantUpdate reports whether anything but the trace annotations, the ignored annotation keys, the
+// resource version and the trace conditions changed between oldObj and newObj.
+func hasSignificantUpdate(oldObj, newObj client.Object, ignoredAnnotationKeys ...string) bool {
+	oldAnnotations := oldObj.GetAnnotations()
+	newAnnotations := newObj.GetAnnotations()
+
+	ignoredAnnotations := append(defaultIgnoredAnnotationKeys(), ignoredAnnotationKeys...)
 
 	// check if metadata except annotations have changed
-	labelsChanged := !equality.Semantic.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels())
-	finalizersChanged := !equality.Semantic.DeepEqual(e.ObjectOld.GetFinalizers(), e.ObjectNew.GetFinalizers())
-	ownerReferenceChanged := !equality.Semantic.DeepEqual(e.ObjectOld.GetOwnerReferences(), e.ObjectNew.GetOwnerReferences())
+	labelsChanged := !equality.Semantic.DeepEqual(oldObj.GetLabels(), newObj.GetLabels())
+	finalizersChanged := !equality.Semantic.DeepEqual(oldObj.GetFinalizers(), newObj.GetFinalizers())
+	ownerReferenceChanged := !equality.Semantic.DeepEqual(oldObj.GetOwnerReferences(), newObj.GetOwnerReferences())
 
 	otherAnnotationsChanged := !equalExcept(
 		oldAnnotations,
@@ -79,7 +92,7 @@ func (p TypedIgnoreTraceAnnotationUpdatePredicate[T]) Update(e event.TypedUpdate
 	)
 
 	// Check if the spec or status fields have changed
-	specOrStatusChanged := hasSpecOrStatusOrDataChanged(e.ObjectOld, e.ObjectNew)
+	specOrStatusChanged := hasSpecOrStatusOrDataChanged(oldObj, newObj, ignoredAnnotations...)
 
 	// if other annotations changed or spec/status changed, we want to process the update
 	if labelsChanged || finalizersChanged || ownerReferenceChanged || otherAnnotationsChanged || specOrStatusChanged {
@@ -101,11 +114,27 @@ func HasSignificantUpdate(oldObj, newObj runtime.Object) bool {
 	return predicate.Update(updateEvent)
 }
 
-// hasSpecOrStatusOrDataChanged checks if the spec, status, or data fields have changed.
-func hasSpecOrStatusOrDataChanged(oldObj, newObj runtime.Object) bool {
+// defaultIgnoredAnnotationKeys returns the trace annotation keys every traced write updates.
+func defaultIgnoredAnnotationKeys() []string {
+	return []string{
+		constants.DefaultTraceParentAnnotation,
+		constants.DefaultTraceStateAnnotation,
+		constants.DefaultOwnerTraceParentAnnotation,
+		constants.LegacyTraceIDAnnotation,
+		constants.LegacySpanIDAnnotation,
+		constants.LegacyTraceIDTimeAnnotation,
+	}
+}
+
+// hasSpecOrStatusOrDataChanged checks if the spec, status, or data fields have changed. The ignored annotation
+// keys of a pod template at spec.template are not compared.
+func hasSpecOrStatusOrDataChanged(oldObj, newObj runtime.Object, ignoredAnnotationKeys ...string) bool {
 	oldUnstructured := objToUnstructured(oldObj)
 	newUnstructured := objToUnstructured(newObj)
 
+	removePodTemplateAnnotations(oldUnstructured, ignoredAnnotationKeys...)
+	removePodTemplateAnnotations(newUnstructured, ignoredAnnotationKeys...)
+
 	// Replace empty structs or slices with nil
 	replaceEmptyStructsAndSlicesWithNil(oldUnstructured)
 	replaceEmptyStructsAndSlicesWithNil(newUnstructured)
@@ -120,6 +149,23 @@ func hasSpecOrStatusOrDataChanged(oldObj, newObj runtime.Object) bool {
 	return specChanged || statusChanged || dataChanged
 }
 
+// removePodTemplateAnnotations removes keys from the annotations of the pod template at spec.template, where
+// workload kinds keep it.
+func removePodTemplateAnnotations(obj map[string]interface{}, keys ...string) {
+	annotations, found, err := unstructured.NestedMap(obj, "spec", "template", "metadata", "annotations")
+	if err != nil || !found {
+		return
+	}
+	for _, key := range keys {
+		delete(annotations, key)
+	}
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj, "spec", "template", "metadata", "annotations")
+		return
+	}
+	_ = unstructured.SetNestedMap(obj, annotations, "spec", "template", "metadata", "annotations")
+}
+
 // getFieldExcludingObservedGeneration retrieves the field and excludes the observedGeneration.
 func getFieldExcludingObservedGeneration(obj map[string]interface{}, field string) interface{} {
 	status, found, err := unstructured.NestedFieldNoCopy(obj, field)
@@ -493,4 +493,32 @@ func TestIgnoreTraceAnnotationUpdatePredicate(t *testing.T) {
 		result := pred.Update(updateEvent)
 		assert.False(t, result, "Expected update to not be processed when Secret data does not changes")
 	})
+
+	t.Run("Deployment pod template trace annotations changed", func(t *testing.T) {
+		deployment := func(traceParent, image string) *appsv1.Deployment {
+			return &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "test-deployment", Namespace: "default"},
+				Spec: appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+							constants.DefaultTraceParentAnnotation: traceParent,
+							"example.com/other":                    "kept",
+						}},
+						Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+					},
+				},
+			}
+		}
+		oldTraceParent := buildTraceParent("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", "bbbbbbbbbbbbbbbb")
+		newTraceParent := buildTraceParent("cccccccccccccccccccccccccccccccc", "dddddddddddddddd")
+
+		assert.False(t, pred.Update(event.UpdateEvent{
+			ObjectOld: deployment(oldTraceParent, "app:v1"),
+			ObjectNew: deployment(newTraceParent, "app:v1"),
+		}), "Expected update to not be processed when only the pod template trace annotations change")
+		assert.True(t, pred.Update(event.UpdateEvent{
+			ObjectOld: deployment(oldTraceParent, "app:v1"),
+			ObjectNew: deployment(newTraceParent, "app:v2"),
+		}), "Expected update to be processed when the pod template spec changes")
+	})
 }
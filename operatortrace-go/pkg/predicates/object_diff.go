@@ -0,0 +1,81 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/predicates/object_diff.go
+
+package predicates
+
+import (
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// AnnotationChange is a key whose value differs between two annotation or label maps. OldValue is "" for added
+// keys and NewValue is "" for removed keys.
+type AnnotationChange struct {
+	Key      string `json:"key"`
+	OldValue string `json:"oldValue"`
+	NewValue string `json:"newValue"`
+}
+
+// ObjectChangeSummary describes what changed between two versions of an object, see ObjectDiff.
+type ObjectChangeSummary struct {
+	AnnotationChanges []AnnotationChange `json:"annotationChanges,omitempty"`
+	LabelChanges      []AnnotationChange `json:"labelChanges,omitempty"`
+	// SpecChanged also reports changes of data, for kinds such as ConfigMaps that keep their content there.
+	SpecChanged   bool `json:"specChanged"`
+	StatusChanged bool `json:"statusChanged"`
+}
+
+// AnnotationDiff returns the keys added, removed or changed between oldAnnotations and newAnnotations, sorted by
+// key. ignoreKeys are left out.
+func AnnotationDiff(oldAnnotations, newAnnotations map[string]string, ignoreKeys ...string) []AnnotationChange {
+	ignored := make(map[string]struct{}, len(ignoreKeys))
+	for _, key := range ignoreKeys {
+		ignored[key] = struct{}{}
+	}
+
+	var changes []AnnotationChange
+	for key, oldValue := range oldAnnotations {
+		if _, isIgnored := ignored[key]; isIgnored {
+			continue
+		}
+		if newValue, exists := newAnnotations[key]; !exists || newValue != oldValue {
+			changes = append(changes, AnnotationChange{Key: key, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	for key, newValue := range newAnnotations {
+		if _, isIgnored := ignored[key]; isIgnored {
+			continue
+		}
+		if _, exists := oldAnnotations[key]; !exists {
+			changes = append(changes, AnnotationChange{Key: key, NewValue: newValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// ObjectDiff summarizes what changed between oldObj and newObj, applying the same rules as HasSignificantUpdate:
+// trace annotations, ignoreAnnotations, TraceID/SpanID conditions and status.observedGeneration are ignored.
+func ObjectDiff(oldObj, newObj client.Object, ignoreAnnotations ...string) ObjectChangeSummary {
+	ignored := append(defaultIgnoredAnnotationKeys(), ignoreAnnotations...)
+
+	oldUnstructured := objToUnstructured(oldObj)
+	newUnstructured := objToUnstructured(newObj)
+	removePodTemplateAnnotations(oldUnstructured, ignored...)
+	removePodTemplateAnnotations(newUnstructured, ignored...)
+	replaceEmptyStructsAndSlicesWithNil(oldUnstructured)
+	replaceEmptyStructsAndSlicesWithNil(newUnstructured)
+
+	oldStatus := getFieldExcludingObservedGeneration(oldUnstructured, "status")
+	newStatus := getFieldExcludingObservedGeneration(newUnstructured, "status")
+
+	return ObjectChangeSummary{
+		AnnotationChanges: AnnotationDiff(oldObj.GetAnnotations(), newObj.GetAnnotations(), ignored...),
+		LabelChanges:      AnnotationDiff(oldObj.GetLabels(), newObj.GetLabels()),
+		SpecChanged:       hasFieldChanged(oldUnstructured, newUnstructured, "spec") || hasFieldChanged(oldUnstructured, newUnstructured, "data"),
+		StatusChanged:     !equality.Semantic.DeepEqual(oldStatus, newStatus),
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/predicates/object_diff_test.go
+
+package predicates_test
+
+import (
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestAnnotationDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      map[string]string
+		new      map[string]string
+		ignore   []string
+		expected []predicates.AnnotationChange
+	}{
+		{name: "unchanged", old: map[string]string{"a": "1"}, new: map[string]string{"a": "1"}},
+		{name: "both nil"},
+		{
+			name: "added, changed and removed",
+			old:  map[string]string{"changed": "1", "removed": "x", "same": "s"},
+			new:  map[string]string{"changed": "2", "added": "y", "same": "s"},
+			expected: []predicates.AnnotationChange{
+				{Key: "added", NewValue: "y"},
+				{Key: "changed", OldValue: "1", NewValue: "2"},
+				{Key: "removed", OldValue: "x"},
+			},
+		},
+		{
+			name:     "ignored keys",
+			old:      map[string]string{"skip-me": "1"},
+			new:      map[string]string{"skip-me": "2", "other": "v"},
+			ignore:   []string{"skip-me"},
+			expected: []predicates.AnnotationChange{{Key: "other", NewValue: "v"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, predicates.AnnotationDiff(tt.old, tt.new, tt.ignore...))
+		})
+	}
+}
+
+func TestObjectDiff(t *testing.T) {
+	basePod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "pod",
+			Labels:      map[string]string{"app": "test"},
+			Annotations: map[string]string{"team": "tracing"},
+		},
+		Spec:   corev1.PodSpec{NodeName: "node1"},
+		Status: corev1.PodStatus{Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}}},
+	}
+	baseConfigMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "cm"}, Data: map[string]string{"key": "value"}}
+
+	tests := []struct {
+		name     string
+		old      client.Object
+		mutate   func(obj client.Object)
+		expected predicates.ObjectChangeSummary
+	}{
+		{"unchanged", basePod, func(obj client.Object) {}, predicates.ObjectChangeSummary{}},
+		{"trace annotations and conditions ignored", basePod, func(obj client.Object) {
+			pod := obj.(*corev1.Pod)
+			pod.Annotations[constants.DefaultTraceParentAnnotation] = "00-aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa-bbbbbbbbbbbbbbbb-01"
+			pod.Status.Conditions = append(pod.Status.Conditions, corev1.PodCondition{Type: "TraceID", Status: corev1.ConditionTrue})
+		}, predicates.ObjectChangeSummary{}},
+		{"metadata", basePod, func(obj client.Object) {
+			obj.SetAnnotations(map[string]string{"team": "other"})
+			obj.SetLabels(nil)
+		}, predicates.ObjectChangeSummary{
+			AnnotationChanges: []predicates.AnnotationChange{{Key: "team", OldValue: "tracing", NewValue: "other"}},
+			LabelChanges:      []predicates.AnnotationChange{{Key: "app", OldValue: "test"}},
+		}},
+		{"spec and status", basePod, func(obj client.Object) {
+			pod := obj.(*corev1.Pod)
+			pod.Spec.NodeName = "node2"
+			pod.Status.Phase = corev1.PodRunning
+		}, predicates.ObjectChangeSummary{SpecChanged: true, StatusChanged: true}},
+		{"data", baseConfigMap, func(obj client.Object) {
+			obj.(*corev1.ConfigMap).Data["key"] = "other"
+		}, predicates.ObjectChangeSummary{SpecChanged: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newObj := tt.old.DeepCopyObject().(client.Object)
+			tt.mutate(newObj)
+			assert.Equal(t, tt.expected, predicates.ObjectDiff(tt.old, newObj))
+		})
+	}
+}
+
+func TestIgnoreTraceAnnotationUpdatePredicateLogsChanges(t *testing.T) {
+	var logged []string
+	logger := funcr.New(func(prefix, args string) { logged = append(logged, args) }, funcr.Options{Verbosity: 1})
+	pred := predicates.NewTypedIgnoreAnnotationUpdatePredicate[client.Object]().WithLogger(logger)
+
+	oldPod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", Annotations: map[string]string{"team": "tracing"}}}
+	newPod := oldPod.DeepCopy()
+	newPod.Annotations[constants.DefaultTraceParentAnnotation] = "00-aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa-bbbbbbbbbbbbbbbb-01"
+	assert.False(t, pred.Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod}))
+	assert.Empty(t, logged)
+
+	newPod.Annotations["team"] = "other"
+	assert.True(t, pred.Update(event.UpdateEvent{ObjectOld: oldPod, ObjectNew: newPod}))
+	if assert.Len(t, logged, 1) {
+		assert.Contains(t, logged[0], `"object"="default/pod"`)
+		assert.Contains(t, logged[0], `"annotationChanges"=[{"key"="team" "oldValue"="tracing" "newValue"="other"}]`)
+		assert.NotContains(t, logged[0], constants.DefaultTraceParentAnnotation)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/predicates/resync.go
+
+package predicates
+
+import (
+	"reflect"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// ResyncPredicate is a TypedResyncPredicate for client.Object.
+type ResyncPredicate = TypedResyncPredicate[client.Object]
+
+// NewResyncPredicate creates a ResyncPredicate.
+func NewResyncPredicate() *ResyncPredicate {
+	return NewTypedResyncPredicate[client.Object]()
+}
+
+// NewTypedResyncPredicate creates a TypedResyncPredicate.
+func NewTypedResyncPredicate[T client.Object]() *TypedResyncPredicate[T] {
+	return &TypedResyncPredicate[T]{seen: make(map[types.NamespacedName]resyncEntry)}
+}
+
+// TypedResyncPredicate lets every event through and remembers which ones an informer replayed without a change:
+// updates whose resource version did not change, and creates for an object version that was already seen, as
+// delivered when the informer relists. Register it on the same watch as the event handler so it sees each event
+// before the handler asks IsResync about it.
+type TypedResyncPredicate[T client.Object] struct {
+	mu   sync.Mutex
+	seen map[types.NamespacedName]resyncEntry
+}
+
+type resyncEntry struct {
+	resourceVersion string
+	resync          bool
+}
+
+var _ predicate.TypedPredicate[client.Object] = &ResyncPredicate{}
+
+// Create records the object's resource version.
+func (p *TypedResyncPredicate[T]) Create(e event.TypedCreateEvent[T]) bool {
+	if isNil(e.Object) {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := client.ObjectKeyFromObject(e.Object)
+	previous, found := p.seen[key]
+	p.seen[key] = resyncEntry{
+		resourceVersion: e.Object.GetResourceVersion(),
+		resync:          found && previous.resourceVersion == e.Object.GetResourceVersion(),
+	}
+	return true
+}
+
+// Update records the new object's resource version.
+func (p *TypedResyncPredicate[T]) Update(e event.TypedUpdateEvent[T]) bool {
+	if isNil(e.ObjectNew) {
+		return true
+	}
+	resync := !isNil(e.ObjectOld) && e.ObjectOld.GetResourceVersion() == e.ObjectNew.GetResourceVersion()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.seen[client.ObjectKeyFromObject(e.ObjectNew)] = resyncEntry{resourceVersion: e.ObjectNew.GetResourceVersion(), resync: resync}
+	return true
+}
+
+// Delete forgets the object.
+func (p *TypedResyncPredicate[T]) Delete(e event.TypedDeleteEvent[T]) bool {
+	if isNil(e.Object) {
+		return true
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.seen, client.ObjectKeyFromObject(e.Object))
+	return true
+}
+
+// Generic lets the event through.
+func (p *TypedResyncPredicate[T]) Generic(e event.TypedGenericEvent[T]) bool {
+	return true
+}
+
+// IsResync reports whether the last create or update event seen for obj, at obj's resource version, was a resync.
+func (p *TypedResyncPredicate[T]) IsResync(obj T) bool {
+	if isNil(obj) {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, found := p.seen[client.ObjectKeyFromObject(obj)]
+	return found && entry.resync && entry.resourceVersion == obj.GetResourceVersion()
+}
+
+// isNil reports whether obj is nil or a typed nil pointer, as a generic T cannot be compared with nil directly.
+func isNil(obj any) bool {
+	if obj == nil {
+		return true
+	}
+	v := reflect.ValueOf(obj)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -0,0 +1,53 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/predicates/resync_test.go
+
+package predicates_test
+
+import (
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/predicates"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestResyncPredicate(t *testing.T) {
+	pod := func(resourceVersion string) client.Object {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default", ResourceVersion: resourceVersion}}
+	}
+
+	p := predicates.NewResyncPredicate()
+
+	// The first create of an object is a real create
+	assert.True(t, p.Create(event.CreateEvent{Object: pod("1")}))
+	assert.False(t, p.IsResync(pod("1")))
+
+	// A relist delivers the same version as a create again
+	assert.True(t, p.Create(event.CreateEvent{Object: pod("1")}))
+	assert.True(t, p.IsResync(pod("1")))
+	assert.False(t, p.IsResync(pod("2")), "a different version is not the resynced one")
+
+	// A periodic resync delivers an update without a new version
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: pod("1"), ObjectNew: pod("1")}))
+	assert.True(t, p.IsResync(pod("1")))
+
+	assert.True(t, p.Update(event.UpdateEvent{ObjectOld: pod("1"), ObjectNew: pod("2")}))
+	assert.False(t, p.IsResync(pod("2")))
+
+	// A recreated object is a real create
+	assert.True(t, p.Delete(event.DeleteEvent{Object: pod("2")}))
+	assert.True(t, p.Create(event.CreateEvent{Object: pod("2")}))
+	assert.False(t, p.IsResync(pod("2")))
+
+	assert.True(t, p.Generic(event.GenericEvent{Object: pod("2")}))
+	assert.NotPanics(t, func() {
+		p.Create(event.CreateEvent{Object: (*corev1.Pod)(nil)})
+		p.Update(event.UpdateEvent{ObjectNew: (*corev1.Pod)(nil)})
+		p.Delete(event.DeleteEvent{Object: (*corev1.Pod)(nil)})
+		assert.False(t, p.IsResync((*corev1.Pod)(nil)))
+	})
+}
@@ -0,0 +1,30 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/reconcile/owner.go
+
+package reconcile
+
+import (
+	"context"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// controllerReferenceHook returns a create hook that makes owner the controller of the objects created during its reconcile.
+// Objects that already have a controller, and objects a namespaced owner cannot own, are left unchanged.
+func controllerReferenceHook(owner ctrlclient.Object, scheme *runtime.Scheme) tracingclient.CreateHook {
+	return func(ctx context.Context, obj ctrlclient.Object) error {
+		if metav1.GetControllerOf(obj) != nil {
+			return nil
+		}
+		// Kubernetes rejects namespaced owners for cluster-scoped objects and objects in other namespaces
+		if ns := owner.GetNamespace(); ns != "" && ns != obj.GetNamespace() {
+			return nil
+		}
+		return controllerutil.SetControllerReference(owner, obj, scheme)
+	}
+}
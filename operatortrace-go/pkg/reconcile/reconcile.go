@@ -6,11 +6,18 @@ package reconcile
 
 import (
 	"context"
+	"fmt"
 	"reflect"
+	"time"
 
 	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/otelsetup"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracingqueue"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/util/workqueue"
 	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -19,11 +26,28 @@ import (
 
 type Reconciler = ctrlreconcile.TypedReconciler[tracingtypes.RequestWithTraceID]
 
+// The adapter must satisfy Reconciler for any type parameter allowed by the ctrlclient.Object constraint.
+var _ Reconciler = (*objectReconcilerAdapter[ctrlclient.Object])(nil)
+
 // ReconcilerBuilder builds a tracing reconciler with configurable options
 type ReconcilerBuilder[T ctrlclient.Object] struct {
-	client          tracingclient.TracingClient
-	objReconciler   ctrlreconcile.ObjectReconciler[T]
-	disableEndTrace bool
+	client                tracingclient.TracingClient
+	objReconciler         ctrlreconcile.ObjectReconciler[T]
+	disableEndTrace       bool
+	endTraceOnSuccessOnly bool
+	controllerName        string
+	queue                 *tracingqueue.TracingQueue
+	safe                  bool
+	ownerScheme           *runtime.Scheme
+	startupWindow         time.Duration
+	suppressedCounter     metric.Int64Counter
+	spanAttributes        []spanAttribute
+}
+
+// spanAttribute is an attribute computed from the reconciled object and recorded on the StartTrace span.
+type spanAttribute struct {
+	key     attribute.Key
+	extract func(ctx context.Context, obj ctrlclient.Object) (attribute.Value, bool)
 }
 
 // NewReconcilerBuilder creates a new builder for a tracing reconciler
@@ -41,17 +65,101 @@ func (b *ReconcilerBuilder[T]) WithDisableEndTrace() *ReconcilerBuilder[T] {
 	return b
 }
 
+// WithEndTraceOnSuccessOnly makes Reconcile call EndTrace only when the reconcile succeeded without requesting a requeue,
+// immediately or with RequeueAfter.
+// Failed or requeued reconciles keep the trace annotations, so the retry continues the same trace instead of starting a new one.
+func (b *ReconcilerBuilder[T]) WithEndTraceOnSuccessOnly() *ReconcilerBuilder[T] {
+	b.endTraceOnSuccessOnly = true
+	return b
+}
+
+// WithOwnerReference makes the reconciled object the controller owner of every object created through the
+// tracing client with the reconcile context, so children are garbage collected with it. scheme resolves the owner's GVK.
+func (b *ReconcilerBuilder[T]) WithOwnerReference(scheme *runtime.Scheme) *ReconcilerBuilder[T] {
+	b.ownerScheme = scheme
+	return b
+}
+
+// WithControllerName names the controller for requests that do not carry a controller name from the queue.
+// The name is recorded on the StartTrace span, the client operation spans of the reconcile, and in the reconcile
+// context for span processors.
+func (b *ReconcilerBuilder[T]) WithControllerName(name string) *ReconcilerBuilder[T] {
+	b.controllerName = name
+	return b
+}
+
+// WithStartupTraceSuppression stops tracing the reconciles started within window of the reconciler's first
+// reconcile, when every watched object is enqueued and would otherwise root a trace of its own. Their spans are
+// non-recording, although a trace already stored on the object or carried by the request is still propagated to
+// the objects they write. The first reconcile traced after the window records StartupTraceSuppressionEndedEvent.
+func (b *ReconcilerBuilder[T]) WithStartupTraceSuppression(window time.Duration) *ReconcilerBuilder[T] {
+	if window <= 0 {
+		return b
+	}
+	b.startupWindow = window
+	return b
+}
+
+// WithSuppressedTraceCounter counts the reconciles not traced because of WithStartupTraceSuppression.
+func (b *ReconcilerBuilder[T]) WithSuppressedTraceCounter(c metric.Int64Counter) *ReconcilerBuilder[T] {
+	if c == nil {
+		return b
+	}
+	b.suppressedCounter = c
+	return b
+}
+
+// WithSpanAttribute records key on the StartTrace span of every reconcile, with the value valueExtractor computes
+// from the object just read, such as its generation or a status condition. No attribute is recorded when
+// valueExtractor returns false. Call it once per attribute.
+func (b *ReconcilerBuilder[T]) WithSpanAttribute(key attribute.Key, valueExtractor func(ctx context.Context, obj ctrlclient.Object) (attribute.Value, bool)) *ReconcilerBuilder[T] {
+	if key == "" || valueExtractor == nil {
+		return b
+	}
+	b.spanAttributes = append(b.spanAttributes, spanAttribute{key: key, extract: valueExtractor})
+	return b
+}
+
+// WithTracingQueue connects the reconciler to the controller's TracingQueue so requeue trace intent
+// set with RequeueKeepingTrace or RequeueDroppingTrace reaches the queue.
+func (b *ReconcilerBuilder[T]) WithTracingQueue(queue *tracingqueue.TracingQueue) *ReconcilerBuilder[T] {
+	b.queue = queue
+	return b
+}
+
 // Build constructs the final TypedReconciler
 func (b *ReconcilerBuilder[T]) Build() ctrlreconcile.TypedReconciler[tracingtypes.RequestWithTraceID] {
+	var startup *startupSuppression
+	if b.startupWindow > 0 {
+		startup = newStartupSuppression(b.startupWindow, b.suppressedCounter)
+	}
 	return &objectReconcilerAdapter[T]{
-		objReconciler:   b.objReconciler,
-		client:          b.client,
-		disableEndTrace: b.disableEndTrace,
+		objReconciler:         b.objReconciler,
+		client:                b.client,
+		disableEndTrace:       b.disableEndTrace,
+		endTraceOnSuccessOnly: b.endTraceOnSuccessOnly,
+		controllerName:        b.controllerName,
+		queue:                 b.queue,
+		safe:                  b.safe,
+		ownerScheme:           b.ownerScheme,
+		startup:               startup,
+		spanAttributes:        append([]spanAttribute(nil), b.spanAttributes...),
 	}
 }
 
 func TracingOptions() controller.TypedOptions[tracingtypes.RequestWithTraceID] {
-	queue := tracingqueue.NewTracingQueue()
+	return TracingOptionsWithQueue(tracingqueue.NewTracingQueue())
+}
+
+// NamedTracingOptions is TracingOptions for the controller called name, usually the name given to the builder's
+// Named. Every request the controller reconciles carries name, which is recorded on the spans of the reconcile.
+func NamedTracingOptions(name string) controller.TypedOptions[tracingtypes.RequestWithTraceID] {
+	return TracingOptionsWithQueue(tracingqueue.NewTracingQueue(tracingqueue.WithControllerName(name)))
+}
+
+// TracingOptionsWithQueue returns controller options that use the given TracingQueue.
+// Pass the same queue to ReconcilerBuilder.WithTracingQueue to honour requeue trace intent.
+func TracingOptionsWithQueue(queue *tracingqueue.TracingQueue) controller.TypedOptions[tracingtypes.RequestWithTraceID] {
 	myQueueFactory := func(name string, rl workqueue.TypedRateLimiter[tracingtypes.RequestWithTraceID]) workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID] {
 		return queue
 	}
@@ -64,39 +172,135 @@ func TracingOptions() controller.TypedOptions[tracingtypes.RequestWithTraceID] {
 // AsTracingReconciler creates a Reconciler based on the given ObjectReconciler.
 // For simple cases with default configuration.
 // For advanced configuration, use NewReconcilerBuilder instead.
+//
+// T must be a pointer to a struct type, such as *corev1.Pod, because a fresh object is allocated for every
+// reconcile. Go generics cannot express that restriction, so an interface type parameter such as
+// ctrlclient.Object compiles but panics on the first Reconcile. Use SafeAsTracingReconciler to get an error instead.
 func AsTracingReconciler[T ctrlclient.Object](client tracingclient.TracingClient, rec ctrlreconcile.ObjectReconciler[T]) ctrlreconcile.TypedReconciler[tracingtypes.RequestWithTraceID] {
 	return NewReconcilerBuilder(client, rec).Build()
 }
 
+// SafeAsTracingReconciler is like AsTracingReconciler but recovers when T cannot be instantiated and
+// returns the failure as an error from Reconcile instead of panicking.
+func SafeAsTracingReconciler[T ctrlclient.Object](client tracingclient.TracingClient, rec ctrlreconcile.ObjectReconciler[T]) ctrlreconcile.TypedReconciler[tracingtypes.RequestWithTraceID] {
+	b := NewReconcilerBuilder(client, rec)
+	b.safe = true
+	return b.Build()
+}
+
 // objectReconcilerAdapter is the object for creating a reconcile request as a converted object.
 type objectReconcilerAdapter[T ctrlclient.Object] struct {
-	objReconciler   ctrlreconcile.ObjectReconciler[T]
-	client          tracingclient.TracingClient
-	disableEndTrace bool // If true, the EndTrace call is NOT made at the end of Reconcile. (default is false - EndTrace is called)
+	objReconciler         ctrlreconcile.ObjectReconciler[T]
+	client                tracingclient.TracingClient
+	disableEndTrace       bool // If true, the EndTrace call is NOT made at the end of Reconcile. (default is false - EndTrace is called)
+	endTraceOnSuccessOnly bool // If true, EndTrace is only called when Reconcile returns no error and no requeue.
+	controllerName        string
+	queue                 *tracingqueue.TracingQueue
+	safe                  bool                // If true, a failure to instantiate T is returned as an error instead of panicking.
+	ownerScheme           *runtime.Scheme     // If set, objects created during Reconcile get the reconciled object as controller owner.
+	startup               *startupSuppression // If set, reconciles within the startup window are not traced.
+	spanAttributes        []spanAttribute     // Recorded on the StartTrace span from the object read.
+}
+
+// newObject allocates a new T. T must be a pointer to a struct type, otherwise reflect panics.
+func newObject[T ctrlclient.Object]() T {
+	return reflect.New(reflect.TypeOf(*new(T)).Elem()).Interface().(T)
+}
+
+// newObjectSafely allocates a new T, converting an instantiation panic into an error.
+func newObjectSafely[T ctrlclient.Object]() (o T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("cannot instantiate reconcile object of type %s, it must be a pointer to a struct: %v", reflect.TypeOf((*T)(nil)).Elem(), r)
+		}
+	}()
+	return newObject[T](), nil
 }
 
 // Reconcile implements Reconciler.
 func (a *objectReconcilerAdapter[T]) Reconcile(ctx context.Context, req tracingtypes.RequestWithTraceID) (ctrlreconcile.Result, error) {
-	o := reflect.New(reflect.TypeOf(*new(T)).Elem()).Interface().(T)
+	var o T
+	if a.safe {
+		var err error
+		if o, err = newObjectSafely[T](); err != nil {
+			return ctrlreconcile.Result{}, err
+		}
+	} else {
+		o = newObject[T]()
+	}
+
+	if req.ControllerName == "" {
+		req.ControllerName = a.controllerName
+	}
+	info := &otelsetup.ReconcileInfo{
+		ObjectKey:      tracingclient.RedactedObjectKey(a.client, req.NamespacedName, o),
+		ControllerName: req.ControllerName,
+	}
+	ctx = otelsetup.ContextWithReconcileInfo(ctx, info)
+	requeueTrace := new(tracingtypes.ResultWithTraceOption)
+	ctx = context.WithValue(ctx, requeueTraceKey{}, requeueTrace)
+	suppress, suppressed, startupEnded := a.startup.begin(ctx)
+	if suppress {
+		ctx = tracingclient.WithCallOptions(ctx, tracingclient.WithSuppressedSpans())
+	}
 
 	ctx, span, err := a.client.StartTrace(ctx, &req, o)
 	defer span.End()
+	if startupEnded {
+		span.AddEvent(StartupTraceSuppressionEndedEvent, trace.WithAttributes(SuppressedReconcilesAttributeKey.Int64(suppressed)))
+	}
 	if err != nil {
 		span.RecordError(err)
+		info.SetResult(ctrlreconcile.Result{}, ctrlclient.IgnoreNotFound(err))
 		return ctrlreconcile.Result{}, ctrlclient.IgnoreNotFound(err)
 	}
 
+	a.setSpanAttributes(ctx, span, o)
+
+	if a.ownerScheme != nil {
+		ctx = tracingclient.WithCallOptions(ctx, tracingclient.WithCreateHook(controllerReferenceHook(o, a.ownerScheme)))
+	}
+
 	result, err := a.objReconciler.Reconcile(ctx, o)
+	info.SetResult(result, err)
+	if a.queue != nil && (result.Requeue || result.RequeueAfter > 0) && *requeueTrace != tracingtypes.RequeueTraceDefault {
+		a.queue.SetRequeueTrace(tracingqueue.Key(req), *requeueTrace)
+	}
 
 	if err != nil {
 		// Record the error in the span
 		span.RecordError(err)
 	}
 
-	if !a.disableEndTrace {
+	if a.shouldEndTrace(result, err) {
 		// errors from EndTrace are recorded in the span
 		a.client.EndTrace(ctx, o)
 	}
 
 	return result, err
 }
+
+// setSpanAttributes records the attributes registered with WithSpanAttribute for obj on span.
+func (a *objectReconcilerAdapter[T]) setSpanAttributes(ctx context.Context, span trace.Span, obj ctrlclient.Object) {
+	if len(a.spanAttributes) == 0 {
+		return
+	}
+	attrs := make([]attribute.KeyValue, 0, len(a.spanAttributes))
+	for _, attr := range a.spanAttributes {
+		if value, ok := attr.extract(ctx, obj); ok {
+			attrs = append(attrs, attribute.KeyValue{Key: attr.key, Value: value})
+		}
+	}
+	span.SetAttributes(attrs...)
+}
+
+// shouldEndTrace reports whether the trace annotations should be cleared after a reconcile with the given outcome.
+func (a *objectReconcilerAdapter[T]) shouldEndTrace(result ctrlreconcile.Result, err error) bool {
+	if a.disableEndTrace {
+		return false
+	}
+	if a.endTraceOnSuccessOnly {
+		return err == nil && !result.Requeue && result.RequeueAfter == 0
+	}
+	return true
+}
@@ -8,17 +8,24 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
+	"time"
 
 	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
 	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/testhelpers"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracingqueue"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
 	"github.com/go-logr/logr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -350,6 +357,184 @@ func TestObjectReconcilerAdapter_Reconcile_DisableEndTrace(t *testing.T) {
 	assert.Equal(t, buildTraceParent("test-trace-id", "test-span-id"), updatedPod.Annotations[constants.DefaultTraceParentAnnotation])
 }
 
+func TestObjectReconcilerAdapter_Reconcile_EndTraceOnSuccessOnly(t *testing.T) {
+	tests := []struct {
+		name            string
+		result          ctrlreconcile.Result
+		err             error
+		keepsAnnotation bool
+	}{
+		{"success clears the trace", ctrlreconcile.Result{}, nil, false},
+		{"error keeps the trace", ctrlreconcile.Result{}, errors.New("reconcile failed"), true},
+		{"requeue keeps the trace", ctrlreconcile.Result{Requeue: true}, nil, true},
+		{"requeue after keeps the trace", ctrlreconcile.Result{RequeueAfter: time.Minute}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceParent := buildTraceParent("4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7")
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "test-pod",
+					Namespace: "default",
+					Annotations: map[string]string{
+						constants.DefaultTraceParentAnnotation: traceParent,
+					},
+				},
+			}
+
+			client, _ := setupTestClient(pod)
+			mockRec := &mockObjectReconciler{reconcileResult: tt.result, reconcileError: tt.err}
+			reconciler := NewReconcilerBuilder(client, mockRec).
+				WithEndTraceOnSuccessOnly().
+				Build()
+
+			req := tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "test-pod", Namespace: "default"}},
+			}
+			ctx := context.Background()
+			_, err := reconciler.Reconcile(ctx, req)
+			assert.Equal(t, tt.err, err)
+			assert.True(t, mockRec.reconcileCalled)
+
+			var updatedPod corev1.Pod
+			require.NoError(t, client.Get(ctx, req.NamespacedName, &updatedPod))
+			if tt.keepsAnnotation {
+				assert.Equal(t, traceParent, updatedPod.Annotations[constants.DefaultTraceParentAnnotation])
+			} else {
+				assert.NotContains(t, updatedPod.Annotations, constants.DefaultTraceParentAnnotation)
+			}
+		})
+	}
+}
+
+// childCreatingReconciler creates the given children through the tracing client with the reconcile context.
+type childCreatingReconciler struct {
+	client   tracingclient.TracingClient
+	children []ctrlclient.Object
+}
+
+func (r *childCreatingReconciler) Reconcile(ctx context.Context, obj *corev1.Pod) (ctrlreconcile.Result, error) {
+	for _, child := range r.children {
+		if err := r.client.Create(ctx, child); err != nil {
+			return ctrlreconcile.Result{}, err
+		}
+	}
+	return ctrlreconcile.Result{}, nil
+}
+
+func TestObjectReconcilerAdapter_Reconcile_WithOwnerReference(t *testing.T) {
+	owner := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "owner-pod", Namespace: "default", UID: "owner-uid"}}
+	otherController := metav1.OwnerReference{APIVersion: "v1", Kind: "Pod", Name: "other", UID: "other-uid", Controller: ptrTo(true)}
+
+	client, scheme := setupTestClient(owner)
+	rec := &childCreatingReconciler{client: client, children: []ctrlclient.Object{
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "child-pod", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other-namespace-pod", Namespace: "other"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "controlled-pod", Namespace: "default", OwnerReferences: []metav1.OwnerReference{otherController}}},
+	}}
+	reconciler := NewReconcilerBuilder[*corev1.Pod](client, rec).
+		WithOwnerReference(scheme).
+		Build()
+
+	ctx := context.Background()
+	_, err := reconciler.Reconcile(ctx, tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "owner-pod", Namespace: "default"}},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		key      types.NamespacedName
+		expected []metav1.OwnerReference
+	}{
+		{"child gets the reconciled object as controller", types.NamespacedName{Name: "child-pod", Namespace: "default"}, []metav1.OwnerReference{{
+			APIVersion: "v1", Kind: "Pod", Name: "owner-pod", UID: "owner-uid", Controller: ptrTo(true), BlockOwnerDeletion: ptrTo(true),
+		}}},
+		{"child in another namespace is left alone", types.NamespacedName{Name: "other-namespace-pod", Namespace: "other"}, nil},
+		{"child with a controller is left alone", types.NamespacedName{Name: "controlled-pod", Namespace: "default"}, []metav1.OwnerReference{otherController}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var child corev1.Pod
+			require.NoError(t, client.Get(ctx, tt.key, &child))
+			assert.Equal(t, tt.expected, child.OwnerReferences)
+		})
+	}
+
+	// Creates outside the reconcile context are not affected
+	outside := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "outside-pod", Namespace: "default"}}
+	require.NoError(t, client.Create(ctx, outside))
+	assert.Empty(t, outside.OwnerReferences)
+}
+
+func TestControllerNameOnReconcileSpans(t *testing.T) {
+	tests := []struct {
+		name         string
+		queueName    string
+		builderName  string
+		expectedName string
+	}{
+		{"named tracing options", "pod-controller", "", "pod-controller"},
+		{"builder name", "", "builder-controller", "builder-controller"},
+		{"queue name wins over the builder", "pod-controller", "builder-controller", "pod-controller"},
+		{"no name", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := tracetest.NewInMemoryExporter()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+			k8sClient := fake.NewClientBuilder().WithStatusSubresource(&corev1.Pod{}).WithObjects(
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "owner-pod", Namespace: "default"}},
+			).Build()
+			client := tracingclient.NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace-test"), logr.Discard(), nil)
+			rec := &childCreatingReconciler{client: client, children: []ctrlclient.Object{
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "child-pod", Namespace: "default"}},
+			}}
+			reconciler := NewReconcilerBuilder[*corev1.Pod](client, rec).WithControllerName(tt.builderName).Build()
+
+			opts := TracingOptions()
+			if tt.queueName != "" {
+				opts = NamedTracingOptions(tt.queueName)
+			}
+			queue := opts.NewQueue("ignored", nil)
+			defer queue.ShutDown()
+			queue.Add(tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "owner-pod", Namespace: "default"}},
+			})
+			req, shutdown := queue.Get()
+			require.False(t, shutdown)
+			assert.Equal(t, tt.queueName, req.ControllerName)
+
+			_, err := reconciler.Reconcile(context.Background(), req)
+			require.NoError(t, err)
+			queue.Done(req)
+
+			// StartTrace, the nested Create and EndTrace all name the controller
+			named := 0
+			spans := exporter.GetSpans()
+			require.Len(t, spans, 3)
+			for _, span := range spans {
+				attrs := attribute.NewSet(span.Attributes...)
+				if value, ok := attrs.Value(tracingclient.ControllerNameAttributeKey); ok {
+					assert.Equal(t, tt.expectedName, value.AsString(), span.Name)
+					named++
+				}
+			}
+			if tt.expectedName == "" {
+				assert.Zero(t, named)
+			} else {
+				assert.Equal(t, len(spans), named)
+			}
+		})
+	}
+}
+
+func ptrTo[T any](v T) *T {
+	return &v
+}
+
 func TestObjectReconcilerAdapter_Reconcile_WithLinkedSpans(t *testing.T) {
 	pod := &corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
@@ -485,3 +670,249 @@ func TestReconcilerBuilder_FluentAPI(t *testing.T) {
 	require.True(t, ok)
 	assert.True(t, adapter.disableEndTrace)
 }
+
+// requeueObjectReconciler requeues using the trace-aware helpers.
+type requeueObjectReconciler struct {
+	keepTrace bool
+}
+
+func (r *requeueObjectReconciler) Reconcile(ctx context.Context, obj *corev1.Pod) (ctrlreconcile.Result, error) {
+	if r.keepTrace {
+		return RequeueKeepingTrace(ctx, time.Millisecond), nil
+	}
+	return RequeueDroppingTrace(ctx, time.Millisecond), nil
+}
+
+func TestObjectReconcilerAdapter_RequeueTrace(t *testing.T) {
+	parent := tracingtypes.RequestParent{
+		TraceID:   "0af7651916cd43dd8448eb211c80319c",
+		SpanID:    "b7ad6b7169203331",
+		Name:      "parent-object",
+		Kind:      "Deployment",
+		EventKind: "Update",
+	}
+
+	tests := []struct {
+		name           string
+		keepTrace      bool
+		expectedParent tracingtypes.RequestParent
+	}{
+		{name: "keep trace", keepTrace: true, expectedParent: parent},
+		{name: "drop trace", keepTrace: false, expectedParent: tracingtypes.RequestParent{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+			client, _ := setupTestClient(pod)
+			queue := tracingqueue.NewTracingQueue()
+			defer queue.ShutDown()
+
+			reconciler := NewReconcilerBuilder(client, &requeueObjectReconciler{keepTrace: tt.keepTrace}).
+				WithTracingQueue(queue).
+				Build()
+
+			queue.Add(tracingtypes.RequestWithTraceID{
+				Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "test-pod", Namespace: "default"}},
+				Parent:  parent,
+			})
+
+			// Mirror the controller's handling of a RequeueAfter result
+			req, shutdown := queue.Get()
+			require.False(t, shutdown)
+			result, err := reconciler.Reconcile(context.Background(), req)
+			require.NoError(t, err)
+			require.Equal(t, time.Millisecond, result.RequeueAfter)
+			queue.Forget(req)
+			queue.AddAfter(req, result.RequeueAfter)
+			queue.Done(req)
+
+			requeued, shutdown := queue.Get()
+			require.False(t, shutdown)
+			assert.Equal(t, tt.expectedParent, requeued.Parent)
+			assert.Zero(t, requeued.LinkedSpanCount)
+			queue.Done(requeued)
+		})
+	}
+}
+
+func TestRequeueHelpersWithoutAdapterContext(t *testing.T) {
+	assert.Equal(t, ctrlreconcile.Result{RequeueAfter: time.Second}, RequeueKeepingTrace(context.Background(), time.Second))
+	assert.Equal(t, ctrlreconcile.Result{Requeue: true}, RequeueDroppingTrace(context.Background(), 0))
+}
+
+// interfaceObjectReconciler is instantiated with an interface type parameter, which cannot be allocated.
+type interfaceObjectReconciler[T ctrlclient.Object] struct {
+	reconcileCalled bool
+}
+
+func (r *interfaceObjectReconciler[T]) Reconcile(ctx context.Context, obj T) (ctrlreconcile.Result, error) {
+	r.reconcileCalled = true
+	return ctrlreconcile.Result{}, nil
+}
+
+// namedObject is an interface type that still satisfies the ctrlclient.Object constraint.
+type namedObject interface {
+	ctrlclient.Object
+	GetName() string
+}
+
+func TestSafeAsTracingReconciler_MalformedTypeParameter(t *testing.T) {
+	req := tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "test-pod", Namespace: "default"}},
+	}
+
+	t.Run("client.Object interface", func(t *testing.T) {
+		client, _ := setupTestClient()
+		rec := &interfaceObjectReconciler[ctrlclient.Object]{}
+
+		assert.Panics(t, func() {
+			_, _ = AsTracingReconciler[ctrlclient.Object](client, rec).Reconcile(context.Background(), req)
+		})
+
+		var err error
+		assert.NotPanics(t, func() {
+			_, err = SafeAsTracingReconciler[ctrlclient.Object](client, rec).Reconcile(context.Background(), req)
+		})
+		assert.ErrorContains(t, err, "must be a pointer to a struct")
+		assert.False(t, rec.reconcileCalled)
+	})
+
+	t.Run("custom interface", func(t *testing.T) {
+		client, _ := setupTestClient()
+		rec := &interfaceObjectReconciler[namedObject]{}
+
+		result, err := SafeAsTracingReconciler[namedObject](client, rec).Reconcile(context.Background(), req)
+		assert.ErrorContains(t, err, "namedObject")
+		assert.Equal(t, ctrlreconcile.Result{}, result)
+		assert.False(t, rec.reconcileCalled)
+	})
+}
+
+func TestSafeAsTracingReconciler_ValidTypeParameter(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "test-pod", Namespace: "default"}}
+	client, _ := setupTestClient(pod)
+	mockRec := &mockObjectReconciler{}
+
+	req := tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "test-pod", Namespace: "default"}},
+	}
+	_, err := SafeAsTracingReconciler(client, mockRec).Reconcile(context.Background(), req)
+	assert.NoError(t, err)
+	assert.True(t, mockRec.reconcileCalled)
+}
+
+func TestStartupTraceSuppression(t *testing.T) {
+	const storedTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().WithStatusSubresource(&corev1.Pod{}).WithObjects(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "new-pod", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "traced-pod", Namespace: "default", Annotations: map[string]string{
+			constants.DefaultTraceParentAnnotation: buildTraceParent(storedTraceID, "00f067aa0ba902b7"),
+		}}},
+	).Build()
+	client := tracingclient.NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace-test"), logr.Discard(), nil)
+	rec := &childCreatingReconciler{client: client}
+	counter := &testhelpers.RecordingCounter{}
+	reconciler := NewReconcilerBuilder[*corev1.Pod](client, rec).
+		WithStartupTraceSuppression(time.Minute).
+		WithSuppressedTraceCounter(counter).
+		Build()
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	reconciler.(*objectReconcilerAdapter[*corev1.Pod]).startup.now = func() time.Time { return now }
+
+	reconcile := func(name, child string) *corev1.Pod {
+		t.Helper()
+		created := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: child, Namespace: "default"}}
+		rec.children = []ctrlclient.Object{created}
+		_, err := reconciler.Reconcile(context.Background(), tracingtypes.RequestWithTraceID{
+			Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: name, Namespace: "default"}},
+		})
+		require.NoError(t, err)
+		return created
+	}
+
+	// Within the window nothing is recorded and no new trace starts, but a stored trace still propagates
+	untraced := reconcile("new-pod", "untraced-child")
+	assert.Empty(t, untraced.Annotations[constants.DefaultTraceParentAnnotation])
+	now = now.Add(59 * time.Second)
+	propagated := reconcile("traced-pod", "propagated-child")
+	assert.Contains(t, propagated.Annotations[constants.DefaultTraceParentAnnotation], storedTraceID)
+	assert.Empty(t, exporter.GetSpans())
+	assert.Equal(t, int64(2), counter.Total())
+
+	// Once the window has passed reconciles are traced again, and the first one reports the suppressed count
+	now = now.Add(time.Second)
+	traced := reconcile("new-pod", "traced-child")
+	assert.NotEmpty(t, traced.Annotations[constants.DefaultTraceParentAnnotation])
+	assert.Equal(t, int64(2), counter.Total())
+
+	var startTrace *tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		if strings.HasPrefix(span.Name, "StartTrace") {
+			startTrace = &span
+		}
+	}
+	require.NotNil(t, startTrace)
+	require.Len(t, startTrace.Events, 1)
+	assert.Equal(t, StartupTraceSuppressionEndedEvent, startTrace.Events[0].Name)
+	attrs := attribute.NewSet(startTrace.Events[0].Attributes...)
+	suppressed, ok := attrs.Value(SuppressedReconcilesAttributeKey)
+	require.True(t, ok)
+	assert.Equal(t, int64(2), suppressed.AsInt64())
+
+	exporter.Reset()
+	reconcile("new-pod", "later-child")
+	for _, span := range exporter.GetSpans() {
+		assert.Empty(t, span.Events, span.Name)
+	}
+}
+
+func TestReconcilerBuilder_WithSpanAttribute(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	k8sClient := fake.NewClientBuilder().WithObjects(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default", Generation: 3},
+		Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+	}).Build()
+	client := tracingclient.NewTracingClient(k8sClient, k8sClient, tp.Tracer("operatortrace-test"), logr.Discard(), nil)
+
+	var seen []string
+	reconciler := NewReconcilerBuilder[*corev1.Pod](client, &mockObjectReconciler{}).
+		WithSpanAttribute("pod.generation", func(ctx context.Context, obj ctrlclient.Object) (attribute.Value, bool) {
+			seen = append(seen, obj.GetName())
+			return attribute.Int64Value(obj.GetGeneration()), true
+		}).
+		WithSpanAttribute("pod.phase", func(ctx context.Context, obj ctrlclient.Object) (attribute.Value, bool) {
+			return attribute.StringValue(string(obj.(*corev1.Pod).Status.Phase)), true
+		}).
+		WithSpanAttribute("pod.skipped", func(ctx context.Context, obj ctrlclient.Object) (attribute.Value, bool) {
+			return attribute.Value{}, false
+		}).
+		Build()
+
+	_, err := reconciler.Reconcile(context.Background(), tracingtypes.RequestWithTraceID{
+		Request: ctrlreconcile.Request{NamespacedName: types.NamespacedName{Name: "sample", Namespace: "default"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sample"}, seen, "extractors get the object read by StartTrace")
+
+	var startTrace *tracetest.SpanStub
+	for _, span := range exporter.GetSpans() {
+		if strings.HasPrefix(span.Name, "StartTrace") {
+			startTrace = &span
+		}
+	}
+	require.NotNil(t, startTrace)
+	attrs := attribute.NewSet(startTrace.Attributes...)
+	generation, ok := attrs.Value("pod.generation")
+	require.True(t, ok)
+	assert.Equal(t, int64(3), generation.AsInt64())
+	phase, ok := attrs.Value("pod.phase")
+	require.True(t, ok)
+	assert.Equal(t, "Running", phase.AsString())
+	_, ok = attrs.Value("pod.skipped")
+	assert.False(t, ok)
+}
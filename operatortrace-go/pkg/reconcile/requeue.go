@@ -0,0 +1,37 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/reconcile/requeue.go
+
+package reconcile
+
+import (
+	"context"
+	"time"
+
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+type requeueTraceKey struct{}
+
+// RequeueKeepingTrace returns a result that requeues after the given duration and asks the tracing
+// queue to carry the current trace into the requeued reconcile. ctx must be the reconcile context.
+func RequeueKeepingTrace(ctx context.Context, after time.Duration) ctrlreconcile.Result {
+	return requeueWithTrace(ctx, after, tracingtypes.RequeueTraceKeep)
+}
+
+// RequeueDroppingTrace returns a result that requeues after the given duration and asks the tracing
+// queue to start a fresh trace for the requeued reconcile. ctx must be the reconcile context.
+func RequeueDroppingTrace(ctx context.Context, after time.Duration) ctrlreconcile.Result {
+	return requeueWithTrace(ctx, after, tracingtypes.RequeueTraceDrop)
+}
+
+func requeueWithTrace(ctx context.Context, after time.Duration, option tracingtypes.ResultWithTraceOption) ctrlreconcile.Result {
+	if requeueTrace, ok := ctx.Value(requeueTraceKey{}).(*tracingtypes.ResultWithTraceOption); ok {
+		*requeueTrace = option
+	}
+	if after <= 0 {
+		return ctrlreconcile.Result{Requeue: true}
+	}
+	return ctrlreconcile.Result{RequeueAfter: after}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/reconcile/startup.go
+
+package reconcile
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// StartupTraceSuppressionEndedEvent is recorded on the span of the first reconcile traced after the startup
+// suppression window, with the number of reconciles that were not traced.
+const StartupTraceSuppressionEndedEvent = "startup trace suppression ended"
+
+// SuppressedReconcilesAttributeKey is the attribute of StartupTraceSuppressionEndedEvent holding the number of
+// reconciles suppressed during the window.
+const SuppressedReconcilesAttributeKey = attribute.Key("operatortrace.startup_suppression.suppressed")
+
+// startupSuppression tracks the startup window of a reconciler, which starts with its first reconcile.
+type startupSuppression struct {
+	window  time.Duration
+	counter metric.Int64Counter
+	now     func() time.Time
+
+	mu         sync.Mutex
+	start      time.Time
+	suppressed int64
+	ended      bool
+}
+
+func newStartupSuppression(window time.Duration, counter metric.Int64Counter) *startupSuppression {
+	return &startupSuppression{window: window, counter: counter, now: time.Now}
+}
+
+// begin is called when a reconcile starts. It reports whether the reconcile falls within the window, and for the
+// first reconcile after it, how many reconciles were suppressed.
+func (s *startupSuppression) begin(ctx context.Context) (suppress bool, endedAfter int64, ended bool) {
+	if s == nil {
+		return false, 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return false, 0, false
+	}
+
+	now := s.now()
+	if s.start.IsZero() {
+		s.start = now
+	}
+	if now.Sub(s.start) < s.window {
+		s.suppressed++
+		if s.counter != nil {
+			s.counter.Add(ctx, 1)
+		}
+		return true, 0, false
+	}
+	s.ended = true
+	return false, s.suppressed, true
+}
@@ -0,0 +1,64 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/testhelpers/envtest_example_test.go
+
+package testhelpers_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	tracingreconcile "github.com/Azure/operatortrace/operatortrace-go/pkg/reconcile"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/testhelpers"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// configMapReconciler marks every ConfigMap it reconciles.
+type configMapReconciler struct {
+	client tracingclient.TracingClient
+}
+
+func (r *configMapReconciler) Reconcile(ctx context.Context, cm *corev1.ConfigMap) (ctrlreconcile.Result, error) {
+	cm.Data = map[string]string{"reconciled": "true"}
+	return ctrlreconcile.Result{}, r.client.Update(ctx, cm)
+}
+
+// TestConfigMapReconcilerTrace shows how an operator asserts on the spans of its reconciles against envtest. It
+// runs when KUBEBUILDER_ASSETS points at the envtest binaries, e.g. as set by setup-envtest.
+func TestConfigMapReconcilerTrace(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	testEnv := &envtest.Environment{}
+	cfg, err := testEnv.Start()
+	require.NoError(t, err)
+	defer func() { require.NoError(t, testEnv.Stop()) }()
+
+	tracingClient, spans, err := testhelpers.NewEnvtestTracingClient(cfg, clientgoscheme.Scheme)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}
+	require.NoError(t, tracingClient.Create(ctx, cm))
+	spans.Reset()
+
+	reconciler := tracingreconcile.AsTracingReconciler(tracingClient, &configMapReconciler{client: tracingClient})
+	request := tracingclient.ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "app", Namespace: "default"})
+	_, err = reconciler.Reconcile(ctx, request)
+	require.NoError(t, err)
+
+	spans.AssertSpanForObject(t, "ConfigMap", "app")
+	spans.AssertSpanChain(t, "StartTrace ConfigMap app", "Prepare Update ConfigMap app", "Update ConfigMap app")
+}
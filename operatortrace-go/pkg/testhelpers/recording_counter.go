@@ -0,0 +1,32 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/testhelpers/recording_counter.go
+
+package testhelpers
+
+import (
+	"context"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/embedded"
+)
+
+// RecordingCounter is a metric.Int64Counter that sums the values added to it, for asserting on the counters of the
+// operatortrace options that take one.
+type RecordingCounter struct {
+	embedded.Int64Counter
+	total atomic.Int64
+}
+
+var _ metric.Int64Counter = (*RecordingCounter)(nil)
+
+// Add implements metric.Int64Counter.
+func (c *RecordingCounter) Add(_ context.Context, incr int64, _ ...metric.AddOption) {
+	c.total.Add(incr)
+}
+
+// Total returns the sum of the values added so far.
+func (c *RecordingCounter) Total() int64 {
+	return c.total.Load()
+}
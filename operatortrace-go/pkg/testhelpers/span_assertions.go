@@ -0,0 +1,143 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/testhelpers/span_assertions.go
+
+// Package testhelpers builds tracing clients for operator integration tests that record their spans in memory,
+// with assertions on the trace chain of a reconcile.
+package testhelpers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	"github.com/go-logr/logr"
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// NewEnvtestTracingClient returns a TracingClient talking to the API server at cfg, e.g. the one started by
+// envtest, whose spans are recorded for the returned SpanAssertions. Like in the operator, set the TraceContext
+// propagator with otel.SetTextMapPropagator for the traces stored on objects to be continued.
+func NewEnvtestTracingClient(cfg *rest.Config, scheme *runtime.Scheme, opts ...tracingclient.Option) (tracingclient.TracingClient, *SpanAssertions, error) {
+	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating client: %w", err)
+	}
+	tracingClient, spans := newTracingClient(k8sClient, scheme, opts...)
+	return tracingClient, spans, nil
+}
+
+// newTracingClient wraps c in a TracingClient recording its spans.
+func newTracingClient(c client.Client, scheme *runtime.Scheme, opts ...tracingclient.Option) (tracingclient.TracingClient, *SpanAssertions) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	tracingClient := tracingclient.NewTracingClientWithOptions(c, c, tp.Tracer("operatortrace"), logr.Discard(), scheme, opts...)
+	return tracingClient, &SpanAssertions{exporter: exporter}
+}
+
+// SpanAssertions asserts on the spans ended by a TracingClient of NewEnvtestTracingClient. Spans are recorded when
+// they end, so end the spans of a reconcile before asserting on them.
+type SpanAssertions struct {
+	exporter *tracetest.InMemoryExporter
+}
+
+// Spans returns the spans ended so far, in the order they ended.
+func (a *SpanAssertions) Spans() tracetest.SpanStubs {
+	return a.exporter.GetSpans()
+}
+
+// Reset forgets the spans ended so far, e.g. between the steps of a test.
+func (a *SpanAssertions) Reset() {
+	a.exporter.Reset()
+}
+
+// AssertSpanChain asserts that spans named names were recorded, each one the child of the one before it, such as
+// a StartTrace span and the Update span of the reconcile.
+func (a *SpanAssertions) AssertSpanChain(t testing.TB, names ...string) bool {
+	t.Helper()
+	if len(names) == 0 {
+		return true
+	}
+	spans := a.Spans()
+	for _, first := range spans {
+		if first.Name == names[0] && chainFrom(spans, first, names[1:]) {
+			return true
+		}
+	}
+	return assert.Fail(t, fmt.Sprintf("no span chain %s", strings.Join(names, " -> ")), "recorded spans:\n%s", describeSpans(spans))
+}
+
+// chainFrom reports whether the spans named names, in order, descend from parent.
+func chainFrom(spans tracetest.SpanStubs, parent tracetest.SpanStub, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, child := range spans {
+		if child.Name == names[0] && child.Parent.SpanID() == parent.SpanContext.SpanID() && child.Parent.TraceID() == parent.SpanContext.TraceID() &&
+			chainFrom(spans, child, names[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+// AssertNoRootSpan asserts that every recorded span has a parent, e.g. when every reconcile of the test continues a
+// trace stored on the object or carried by the request.
+func (a *SpanAssertions) AssertNoRootSpan(t testing.TB) bool {
+	t.Helper()
+	var roots []string
+	for _, span := range a.Spans() {
+		if !span.Parent.IsValid() {
+			roots = append(roots, span.Name)
+		}
+	}
+	return assert.Empty(t, roots, "root spans were recorded")
+}
+
+// AssertSpanForObject asserts that a span was recorded for the object of kind named name. It matches the default
+// span names, such as "Update ConfigMap app" or "StartTrace ConfigMap/app Triggered By Changed Object Pod/web", so
+// spans renamed by a span name registry or redacted are not found.
+func (a *SpanAssertions) AssertSpanForObject(t testing.TB, kind, name string) bool {
+	t.Helper()
+	spans := a.Spans()
+	for _, span := range spans {
+		if spanNamesObject(span.Name, kind, name) {
+			return true
+		}
+	}
+	return assert.Fail(t, fmt.Sprintf("no span for %s %s", kind, name), "recorded spans:\n%s", describeSpans(spans))
+}
+
+// spanNamesObject reports whether spanName names the object of kind named name as "<kind> <name>" or
+// "<kind>/<name>". Only the object named first counts, so the trigger of a StartTrace span does not.
+func spanNamesObject(spanName, kind, name string) bool {
+	fields := strings.Fields(spanName)
+	for i, field := range fields {
+		if field == kind+"/"+name || (field == kind && i+1 < len(fields) && fields[i+1] == name) {
+			return true
+		}
+		if strings.Contains(field, "/") && i > 0 {
+			return false
+		}
+	}
+	return false
+}
+
+// describeSpans lists spans with their IDs and parents for failure messages.
+func describeSpans(spans tracetest.SpanStubs) string {
+	var b strings.Builder
+	for _, span := range spans {
+		parent := "root"
+		if span.Parent.IsValid() {
+			parent = span.Parent.SpanID().String()
+		}
+		fmt.Fprintf(&b, "  %s (span %s, parent %s, %s)\n", span.Name, span.SpanContext.SpanID(), parent, span.SpanKind)
+	}
+	return b.String()
+}
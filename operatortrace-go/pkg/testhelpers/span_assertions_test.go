@@ -0,0 +1,118 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/testhelpers/span_assertions_test.go
+
+package testhelpers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	tracingclient "github.com/Azure/operatortrace/operatortrace-go/pkg/client"
+	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// recordingT records the failures of assertions expected to fail instead of failing the test.
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+// reconcileConfigMap traces a reconcile of the ConfigMap app that updates it, and returns the recorded spans.
+func reconcileConfigMap(t *testing.T, request tracingtypes.RequestWithTraceID) *SpanAssertions {
+	t.Helper()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	k8sClient := fake.NewClientBuilder().WithObjects(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "default"}}).Build()
+	tracingClient, spans := newTracingClient(k8sClient, clientgoscheme.Scheme)
+
+	cm := &corev1.ConfigMap{}
+	ctx, span, err := tracingClient.StartTrace(context.Background(), &request, cm)
+	require.NoError(t, err)
+	cm.Data = map[string]string{"k": "v"}
+	require.NoError(t, tracingClient.Update(ctx, cm))
+	span.End()
+	return spans
+}
+
+func TestSpanAssertions(t *testing.T) {
+	spans := reconcileConfigMap(t, tracingclient.ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "app", Namespace: "default"}))
+	require.Len(t, spans.Spans(), 3)
+
+	assert.True(t, spans.AssertSpanChain(t, "StartTrace ConfigMap app", "Prepare Update ConfigMap app", "Update ConfigMap app"))
+	assert.True(t, spans.AssertSpanChain(t))
+	assert.True(t, spans.AssertSpanForObject(t, "ConfigMap", "app"))
+
+	failing := &recordingT{TB: t}
+	assert.False(t, spans.AssertSpanChain(failing, "Update ConfigMap app", "StartTrace ConfigMap app"))
+	assert.False(t, spans.AssertSpanChain(failing, "StartTrace ConfigMap app", "Get ConfigMap app"))
+	assert.False(t, spans.AssertSpanForObject(failing, "ConfigMap", "other"))
+	assert.False(t, spans.AssertNoRootSpan(failing), "StartTrace started a new trace")
+	assert.Len(t, failing.failures, 4)
+	assert.Contains(t, failing.failures[0], "StartTrace ConfigMap app")
+
+	spans.Reset()
+	assert.Empty(t, spans.Spans())
+}
+
+func TestSpanAssertionsNoRootSpan(t *testing.T) {
+	request := tracingclient.ClientObjectToRequestWithTraceID(&client.ObjectKey{Name: "app", Namespace: "default"})
+	request.Parent = tracingtypes.RequestParent{
+		TraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanID:  "00f067aa0ba902b7",
+		Kind:    "Pod",
+		Name:    "web",
+	}
+	spans := reconcileConfigMap(t, request)
+
+	assert.True(t, spans.AssertNoRootSpan(t))
+	assert.True(t, spans.AssertSpanForObject(t, "ConfigMap", "app"))
+	assert.False(t, spans.AssertSpanForObject(&recordingT{TB: t}, "Pod", "web"), "the trigger of a StartTrace span is not its object")
+}
+
+func TestSpanNamesObject(t *testing.T) {
+	tests := []struct {
+		spanName string
+		expected bool
+	}{
+		{"Update ConfigMap app", true},
+		{"StartTrace ConfigMap app", true},
+		{"StartTrace ConfigMap/app Triggered By Changed Object Pod/web", true},
+		{"Update ConfigMap application", false},
+		{"Update Secret app", false},
+		{"StartTrace Pod/web Triggered By Changed Object ConfigMap/app", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spanName, func(t *testing.T) {
+			assert.Equal(t, tt.expected, spanNamesObject(tt.spanName, "ConfigMap", "app"))
+		})
+	}
+}
+
+func TestNewEnvtestTracingClient(t *testing.T) {
+	_, _, err := NewEnvtestTracingClient(nil, clientgoscheme.Scheme)
+	assert.Error(t, err)
+
+	// The API server is only contacted on the first request
+	tracingClient, spans, err := NewEnvtestTracingClient(&rest.Config{Host: "https://127.0.0.1:6443"}, clientgoscheme.Scheme)
+	require.NoError(t, err)
+	assert.NotNil(t, tracingClient)
+	assert.Empty(t, spans.Spans())
+}
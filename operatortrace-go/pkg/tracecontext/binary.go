@@ -0,0 +1,96 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/tracecontext/binary.go
+
+package tracecontext
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// binaryVersion is the leading version byte of the compact binary span context format.
+	binaryVersion byte = 0
+	// BinarySpanContextLength is the size of an encoded span context: version + trace ID + span ID.
+	BinarySpanContextLength = 1 + 16 + 8
+)
+
+// EncodeBinary encodes the span context into the compact 25-byte format: 1-byte version, 16-byte trace ID, 8-byte span ID.
+func EncodeBinary(sc trace.SpanContext) ([]byte, error) {
+	if !sc.IsValid() {
+		return nil, fmt.Errorf("invalid span context")
+	}
+	traceID := sc.TraceID()
+	spanID := sc.SpanID()
+
+	b := make([]byte, 0, BinarySpanContextLength)
+	b = append(b, binaryVersion)
+	b = append(b, traceID[:]...)
+	b = append(b, spanID[:]...)
+	return b, nil
+}
+
+// DecodeBinary decodes a span context produced by EncodeBinary. The result is marked remote and sampled.
+func DecodeBinary(b []byte) (trace.SpanContext, error) {
+	if len(b) != BinarySpanContextLength {
+		return trace.SpanContext{}, fmt.Errorf("invalid binary span context length %d", len(b))
+	}
+	if b[0] != binaryVersion {
+		return trace.SpanContext{}, fmt.Errorf("unsupported binary span context version %d", b[0])
+	}
+
+	var traceID trace.TraceID
+	var spanID trace.SpanID
+	copy(traceID[:], b[1:17])
+	copy(spanID[:], b[17:])
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	if !sc.IsValid() {
+		return trace.SpanContext{}, fmt.Errorf("invalid trace context")
+	}
+	return sc, nil
+}
+
+// EncodeTraceParentBinary converts a W3C traceparent string into its base64-URL encoded binary form.
+func EncodeTraceParentBinary(traceParent string) (string, error) {
+	sc, err := SpanContextFromTraceData(traceParent, "")
+	if err != nil {
+		return "", err
+	}
+	b, err := EncodeBinary(sc)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// decodeTraceParentBinary converts a base64-URL encoded binary span context back into a W3C traceparent string.
+func decodeTraceParentBinary(value string) (string, bool) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", false
+	}
+	sc, err := DecodeBinary(b)
+	if err != nil {
+		return "", false
+	}
+	return TraceParentFromSpanContext(sc), true
+}
+
+// normalizeTraceParent returns a W3C traceparent for values stored in either the W3C or the binary format.
+func normalizeTraceParent(value string) string {
+	if len(value) == base64.RawURLEncoding.EncodedLen(BinarySpanContextLength) {
+		if traceParent, ok := decodeTraceParentBinary(value); ok {
+			return traceParent
+		}
+	}
+	return value
+}
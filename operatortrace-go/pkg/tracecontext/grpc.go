@@ -0,0 +1,64 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/tracecontext/grpc.go
+
+package tracecontext
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+// metadataCarrier adapts gRPC metadata to the OTEL TextMapCarrier interface.
+type metadataCarrier metadata.MD
+
+var _ propagation.TextMapCarrier = metadataCarrier{}
+
+// Get returns the first value stored for key.
+func (c metadataCarrier) Get(key string) string {
+	values := metadata.MD(c).Get(key)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Set stores value for key, replacing any existing values.
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+// Keys lists the keys stored in the carrier.
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// ExtractFromGRPCMetadata reads trace context from incoming gRPC metadata using the globally configured propagator.
+// The returned traceparent/tracestate strings are in W3C format so they can be persisted like annotation values.
+func ExtractFromGRPCMetadata(md metadata.MD) (traceParent, traceState string, ok bool) {
+	if len(md) == 0 {
+		return "", "", false
+	}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), metadataCarrier(md))
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return "", "", false
+	}
+	return TraceParentFromSpanContext(spanContext), spanContext.TraceState().String(), true
+}
+
+// InjectIntoGRPCMetadata writes the span context from ctx into new outgoing gRPC metadata
+// using the globally configured propagator.
+func InjectIntoGRPCMetadata(ctx context.Context) metadata.MD {
+	md := metadata.MD{}
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+	return md
+}
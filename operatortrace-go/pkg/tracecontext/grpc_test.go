@@ -0,0 +1,88 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/tracecontext/grpc_test.go
+
+package tracecontext
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	testTraceIDHex = "0af7651916cd43dd8448eb211c80319c"
+	testSpanIDHex  = "b7ad6b7169203331"
+)
+
+func init() {
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+func TestExtractFromGRPCMetadata(t *testing.T) {
+	traceParent := "00-" + testTraceIDHex + "-" + testSpanIDHex + "-01"
+
+	tests := []struct {
+		name               string
+		md                 metadata.MD
+		expectedOK         bool
+		expectedTraceState string
+	}{
+		{"traceparent only", metadata.Pairs("traceparent", traceParent), true, ""},
+		{"traceparent and tracestate", metadata.Pairs("traceparent", traceParent, "tracestate", "vendor=value"), true, "vendor=value"},
+		{"extra metadata keys", metadata.MD{"traceparent": {traceParent}, "other": {"x"}}, true, ""},
+		{"invalid traceparent", metadata.Pairs("traceparent", "not-a-traceparent"), false, ""},
+		{"empty metadata", metadata.MD{}, false, ""},
+		{"nil metadata", nil, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotParent, gotState, ok := ExtractFromGRPCMetadata(tt.md)
+			assert.Equal(t, tt.expectedOK, ok)
+			if !tt.expectedOK {
+				return
+			}
+			assert.Equal(t, traceParent, gotParent)
+			assert.Equal(t, tt.expectedTraceState, gotState)
+		})
+	}
+}
+
+func TestInjectIntoGRPCMetadataRoundTrip(t *testing.T) {
+	traceID, err := trace.TraceIDFromHex(testTraceIDHex)
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex(testSpanIDHex)
+	require.NoError(t, err)
+	traceState, err := trace.ParseTraceState("vendor=value")
+	require.NoError(t, err)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		TraceState: traceState,
+	})
+	md := InjectIntoGRPCMetadata(trace.ContextWithSpanContext(context.Background(), sc))
+	assert.Equal(t, []string{"00-" + testTraceIDHex + "-" + testSpanIDHex + "-01"}, md.Get("traceparent"))
+
+	traceParent, gotState, ok := ExtractFromGRPCMetadata(md)
+	require.True(t, ok)
+	assert.Equal(t, "vendor=value", gotState)
+
+	roundTripped, err := SpanContextFromTraceData(traceParent, gotState)
+	require.NoError(t, err)
+	assert.Equal(t, sc.TraceID(), roundTripped.TraceID())
+	assert.Equal(t, sc.SpanID(), roundTripped.SpanID())
+}
+
+func TestInjectIntoGRPCMetadataWithoutSpan(t *testing.T) {
+	md := InjectIntoGRPCMetadata(context.Background())
+	assert.Empty(t, md.Get("traceparent"))
+}
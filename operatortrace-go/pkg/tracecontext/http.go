@@ -0,0 +1,36 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/tracecontext/http.go
+
+package tracecontext
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ExtractFromHTTPHeaders reads trace context from incoming HTTP headers using the globally configured propagator.
+// The returned traceparent/tracestate strings are in W3C format so they can be persisted like annotation values.
+func ExtractFromHTTPHeaders(h http.Header) (traceParent, traceState string, ok bool) {
+	if len(h) == 0 {
+		return "", "", false
+	}
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.HeaderCarrier(h))
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return "", "", false
+	}
+	return TraceParentFromSpanContext(spanContext), spanContext.TraceState().String(), true
+}
+
+// InjectIntoHTTPHeaders writes the span context from ctx into h using the globally configured propagator.
+func InjectIntoHTTPHeaders(ctx context.Context, h http.Header) {
+	if h == nil {
+		return
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(h))
+}
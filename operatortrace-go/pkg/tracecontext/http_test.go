@@ -0,0 +1,79 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/tracecontext/http_test.go
+
+package tracecontext
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestExtractFromHTTPHeaders(t *testing.T) {
+	traceParent := "00-" + testTraceIDHex + "-" + testSpanIDHex + "-01"
+
+	tests := []struct {
+		name               string
+		header             http.Header
+		expectedOK         bool
+		expectedTraceState string
+	}{
+		{"traceparent only", http.Header{"Traceparent": {traceParent}}, true, ""},
+		{"traceparent and tracestate", http.Header{"Traceparent": {traceParent}, "Tracestate": {"vendor=value"}}, true, "vendor=value"},
+		{"extra headers", http.Header{"Traceparent": {traceParent}, "Content-Type": {"application/json"}}, true, ""},
+		{"invalid traceparent", http.Header{"Traceparent": {"not-a-traceparent"}}, false, ""},
+		{"empty headers", http.Header{}, false, ""},
+		{"nil headers", nil, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotParent, gotState, ok := ExtractFromHTTPHeaders(tt.header)
+			assert.Equal(t, tt.expectedOK, ok)
+			if !tt.expectedOK {
+				return
+			}
+			assert.Equal(t, traceParent, gotParent)
+			assert.Equal(t, tt.expectedTraceState, gotState)
+		})
+	}
+}
+
+func TestInjectIntoHTTPHeadersRoundTrip(t *testing.T) {
+	traceID, err := trace.TraceIDFromHex(testTraceIDHex)
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex(testSpanIDHex)
+	require.NoError(t, err)
+	traceState, err := trace.ParseTraceState("vendor=value")
+	require.NoError(t, err)
+
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		TraceState: traceState,
+	})
+	header := http.Header{}
+	InjectIntoHTTPHeaders(trace.ContextWithSpanContext(context.Background(), sc), header)
+	assert.Equal(t, "00-"+testTraceIDHex+"-"+testSpanIDHex+"-01", header.Get("traceparent"))
+
+	traceParent, gotState, ok := ExtractFromHTTPHeaders(header)
+	require.True(t, ok)
+	assert.Equal(t, "vendor=value", gotState)
+
+	roundTripped, err := SpanContextFromTraceData(traceParent, gotState)
+	require.NoError(t, err)
+	assert.Equal(t, sc.TraceID(), roundTripped.TraceID())
+	assert.Equal(t, sc.SpanID(), roundTripped.SpanID())
+}
+
+func TestInjectIntoHTTPHeadersWithoutSpan(t *testing.T) {
+	header := http.Header{}
+	InjectIntoHTTPHeaders(context.Background(), header)
+	assert.Empty(t, header.Get("traceparent"))
+}
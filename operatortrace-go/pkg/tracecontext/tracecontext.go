@@ -6,7 +6,9 @@ package tracecontext
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -14,6 +16,12 @@ import (
 	"go.opentelemetry.io/otel/trace"
 )
 
+// maxTraceStateMembers is the most list members a W3C tracestate may hold.
+const maxTraceStateMembers = 32
+
+// ErrTraceStateFull is returned by SetTraceStateKey when adding a key would exceed the tracestate member limit.
+var ErrTraceStateFull = errors.New("tracestate already holds the maximum number of members")
+
 // AnnotationExtractionConfig describes how to read trace context data from annotations.
 type AnnotationExtractionConfig struct {
 	TraceParentKey         string
@@ -22,6 +30,10 @@ type AnnotationExtractionConfig struct {
 	LegacySpanIDKey        string
 	LegacyTimestampKey     string
 	TraceStateTimestampKey string
+
+	// FallbackTraceParentKeys are tried in order when TraceParentKey holds no traceparent.
+	// Trace state is only read together with TraceParentKey.
+	FallbackTraceParentKeys []string
 }
 
 // AnnotationTraceContext captures the reconstructed trace context from annotations.
@@ -29,6 +41,9 @@ type AnnotationTraceContext struct {
 	TraceParent string
 	TraceState  string
 	Timestamp   time.Time
+
+	// Legacy is set when the trace context was rebuilt from the legacy trace ID and span ID annotations.
+	Legacy bool
 }
 
 // TraceParentFromIDs constructs a traceparent header string from trace/span IDs.
@@ -47,6 +62,30 @@ func TraceParentFromIDs(traceIDHex, spanIDHex string) (string, error) {
 	return fmt.Sprintf("00-%s-%s-01", traceID.String(), spanID.String()), nil
 }
 
+// BuildTraceParentFromSpanContext formats sc as a W3C traceparent string, returning an error when sc is not valid.
+// Unlike TraceParentFromIDs the trace flags of sc are kept, so an unsampled span stays unsampled.
+func BuildTraceParentFromSpanContext(sc trace.SpanContext) (string, error) {
+	if !sc.IsValid() {
+		return "", fmt.Errorf("invalid span context")
+	}
+	return TraceParentFromSpanContext(sc), nil
+}
+
+// SpanContextToTraceData returns the traceparent of sc and its tracestate with the timestamp under timestampKey
+// set to now, ready to be stored and later read back with SpanContextFromTraceData.
+// When only the tracestate cannot be built, traceParent is still returned alongside the error.
+func SpanContextToTraceData(sc trace.SpanContext, timestampKey string, now time.Time) (traceParent, traceState string, err error) {
+	traceParent, err = BuildTraceParentFromSpanContext(sc)
+	if err != nil {
+		return "", "", err
+	}
+	traceState, err = BuildTraceStateString(sc, timestampKey, now)
+	if err != nil {
+		return traceParent, "", err
+	}
+	return traceParent, traceState, nil
+}
+
 // SpanContextFromTraceData reconstructs a span context from traceparent/tracestate strings.
 func SpanContextFromTraceData(traceParent, traceState string) (trace.SpanContext, error) {
 	if traceParent == "" {
@@ -86,17 +125,75 @@ func ExtractTimestampFromTraceState(raw, key string) (time.Time, bool) {
 }
 
 // BuildTraceStateString inserts or updates the timestamp value inside tracestate.
+// Other entries, including vendor keys written by other components, are preserved.
 func BuildTraceStateString(sc trace.SpanContext, timestampKey string, now time.Time) (string, error) {
-	traceState := sc.TraceState()
-	if timestampKey != "" {
-		traceState = traceState.Delete(timestampKey)
-		var err error
-		traceState, err = traceState.Insert(timestampKey, now.UTC().Format(time.RFC3339Nano))
+	traceState := sc.TraceState().String()
+	if timestampKey == "" {
+		return traceState, nil
+	}
+	return SetTraceStateKey(traceState, timestampKey, now.UTC().Format(time.RFC3339Nano))
+}
+
+// SetTraceStateKey inserts or updates key in the raw tracestate without discarding other keys.
+// An existing key is updated in place so the order of the other vendors' entries never changes; a new key is
+// added to the front. Rather than evicting another vendor's entry, adding a key to a full tracestate fails with
+// ErrTraceStateFull.
+func SetTraceStateKey(raw, key, value string) (string, error) {
+	traceState, err := trace.ParseTraceState(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid tracestate %q: %w", raw, err)
+	}
+	if _, err := (trace.TraceState{}).Insert(key, value); err != nil {
+		return "", err
+	}
+	if traceState.Get(key) == "" {
+		if traceState.Len() >= maxTraceStateMembers {
+			return "", fmt.Errorf("%w: cannot add %q", ErrTraceStateFull, key)
+		}
+		traceState, err = traceState.Insert(key, value)
+		if err != nil {
+			return "", err
+		}
+		return traceState.String(), nil
+	}
+
+	members := make([]string, 0, traceState.Len())
+	traceState.Walk(func(k, v string) bool {
+		if k == key {
+			v = value
+		}
+		members = append(members, k+"="+v)
+		return true
+	})
+	return strings.Join(members, ","), nil
+}
+
+// MergeTraceStates combines two tracestate strings into one without duplicating keys.
+// Entries from a take precedence on key collision and are listed first, followed by the remaining entries of b.
+func MergeTraceStates(a, b string) (string, error) {
+	stateA, err := trace.ParseTraceState(a)
+	if err != nil {
+		return "", fmt.Errorf("invalid tracestate %q: %w", a, err)
+	}
+	merged, err := trace.ParseTraceState(b)
+	if err != nil {
+		return "", fmt.Errorf("invalid tracestate %q: %w", b, err)
+	}
+
+	type member struct{ key, value string }
+	var members []member
+	stateA.Walk(func(key, value string) bool {
+		members = append(members, member{key: key, value: value})
+		return true
+	})
+	// Insert prepends, so walk a backwards to keep its original order.
+	for i := len(members) - 1; i >= 0; i-- {
+		merged, err = merged.Delete(members[i].key).Insert(members[i].key, members[i].value)
 		if err != nil {
 			return "", err
 		}
 	}
-	return traceState.String(), nil
+	return merged.String(), nil
 }
 
 // ExtractTraceContextFromAnnotations attempts to read trace context information using the provided config.
@@ -107,7 +204,7 @@ func ExtractTraceContextFromAnnotations(annotations map[string]string, cfg Annot
 	}
 
 	if cfg.TraceParentKey != "" {
-		if traceParent := annotations[cfg.TraceParentKey]; traceParent != "" {
+		if traceParent := normalizeTraceParent(annotations[cfg.TraceParentKey]); traceParent != "" {
 			traceState := ""
 			if cfg.TraceStateKey != "" {
 				traceState = annotations[cfg.TraceStateKey]
@@ -121,6 +218,11 @@ func ExtractTraceContextFromAnnotations(annotations map[string]string, cfg Annot
 			return AnnotationTraceContext{TraceParent: traceParent, TraceState: traceState, Timestamp: timestamp}, true
 		}
 	}
+	for _, key := range cfg.FallbackTraceParentKeys {
+		if traceParent := normalizeTraceParent(annotations[key]); traceParent != "" {
+			return AnnotationTraceContext{TraceParent: traceParent}, true
+		}
+	}
 
 	if cfg.LegacyTraceIDKey == "" || cfg.LegacySpanIDKey == "" {
 		return AnnotationTraceContext{}, false
@@ -142,5 +244,78 @@ func ExtractTraceContextFromAnnotations(annotations map[string]string, cfg Annot
 			}
 		}
 	}
-	return AnnotationTraceContext{TraceParent: traceParent, Timestamp: timestamp}, true
+	return AnnotationTraceContext{TraceParent: traceParent, Timestamp: timestamp, Legacy: true}, true
+}
+
+// InjectIntoMapData writes the span context as a W3C traceparent string into data under key.
+// This allows trace context to travel inside ConfigMap/Secret data where annotations are not copied.
+func InjectIntoMapData(data map[string]string, key string, sc trace.SpanContext) error {
+	if data == nil {
+		return fmt.Errorf("data map is nil")
+	}
+	if key == "" {
+		return fmt.Errorf("missing data key")
+	}
+	if !sc.IsValid() {
+		return fmt.Errorf("invalid span context")
+	}
+	data[key] = TraceParentFromSpanContext(sc)
+	return nil
+}
+
+// ExtractFromMapData reconstructs the span context stored in data under key by InjectIntoMapData.
+func ExtractFromMapData(data map[string]string, key string) (trace.SpanContext, error) {
+	if key == "" {
+		return trace.SpanContext{}, fmt.Errorf("missing data key")
+	}
+	traceParent, ok := data[key]
+	if !ok || traceParent == "" {
+		return trace.SpanContext{}, fmt.Errorf("data key %s not found", key)
+	}
+	return SpanContextFromTraceData(traceParent, "")
+}
+
+// TraceParentFromSpanContext formats the span context as a W3C traceparent string.
+func TraceParentFromSpanContext(sc trace.SpanContext) string {
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID().String(), sc.SpanID().String(), sc.TraceFlags().String())
+}
+
+// ValidateTraceParent returns an error describing why value is not a traceparent the tracing client can continue.
+// Both the W3C format and the binary format written with WithBinaryAnnotationEncoding are accepted.
+func ValidateTraceParent(value string) error {
+	traceParent := normalizeTraceParent(value)
+	parts := strings.Split(traceParent, "-")
+	if len(parts) < 4 {
+		return fmt.Errorf("traceparent %q must have the form version-traceid-spanid-flags", value)
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isLowerHex(version, 2) || version == "ff" {
+		return fmt.Errorf("traceparent %q has invalid version %q", value, version)
+	}
+	if version == "00" && len(parts) != 4 {
+		return fmt.Errorf("traceparent %q has unexpected fields after the trace flags", value)
+	}
+	if !isLowerHex(traceID, 32) || strings.Trim(traceID, "0") == "" {
+		return fmt.Errorf("traceparent %q has invalid trace ID %q, want 32 lowercase hex characters, not all zero", value, traceID)
+	}
+	if !isLowerHex(spanID, 16) || strings.Trim(spanID, "0") == "" {
+		return fmt.Errorf("traceparent %q has invalid span ID %q, want 16 lowercase hex characters, not all zero", value, spanID)
+	}
+	if !isLowerHex(flags, 2) {
+		return fmt.Errorf("traceparent %q has invalid trace flags %q", value, flags)
+	}
+	return nil
+}
+
+// isLowerHex reports whether s is n lowercase hexadecimal characters.
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,339 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/tracecontext/tracecontext_test.go
+
+package tracecontext
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestSpanContext(t *testing.T) trace.SpanContext {
+	t.Helper()
+	traceID, err := trace.TraceIDFromHex(testTraceIDHex)
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex(testSpanIDHex)
+	require.NoError(t, err)
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+}
+
+func TestMapDataRoundTrip(t *testing.T) {
+	sc := newTestSpanContext(t)
+	data := map[string]string{"config.yaml": "key: value"}
+
+	require.NoError(t, InjectIntoMapData(data, "traceparent", sc))
+	assert.Equal(t, "key: value", data["config.yaml"])
+
+	extracted, err := ExtractFromMapData(data, "traceparent")
+	require.NoError(t, err)
+	assert.Equal(t, sc.TraceID(), extracted.TraceID())
+	assert.Equal(t, sc.SpanID(), extracted.SpanID())
+	assert.True(t, extracted.IsRemote())
+}
+
+func TestInjectIntoMapDataErrors(t *testing.T) {
+	sc := newTestSpanContext(t)
+
+	tests := []struct {
+		name string
+		data map[string]string
+		key  string
+		sc   trace.SpanContext
+	}{
+		{"nil data", nil, "traceparent", sc},
+		{"empty key", map[string]string{}, "", sc},
+		{"invalid span context", map[string]string{}, "traceparent", trace.SpanContext{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Error(t, InjectIntoMapData(tt.data, tt.key, tt.sc))
+		})
+	}
+}
+
+func TestExtractFromMapDataErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]string
+		key  string
+	}{
+		{"missing key", map[string]string{"other": "value"}, "traceparent"},
+		{"empty key", map[string]string{"traceparent": "value"}, ""},
+		{"malformed value", map[string]string{"traceparent": "not-a-traceparent"}, "traceparent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExtractFromMapData(tt.data, tt.key)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	sc := newTestSpanContext(t)
+
+	b, err := EncodeBinary(sc)
+	require.NoError(t, err)
+	assert.Len(t, b, BinarySpanContextLength)
+
+	decoded, err := DecodeBinary(b)
+	require.NoError(t, err)
+	assert.Equal(t, sc.TraceID(), decoded.TraceID())
+	assert.Equal(t, sc.SpanID(), decoded.SpanID())
+	assert.True(t, decoded.IsRemote())
+}
+
+func TestBinaryErrors(t *testing.T) {
+	_, err := EncodeBinary(trace.SpanContext{})
+	assert.Error(t, err)
+
+	valid, err := EncodeBinary(newTestSpanContext(t))
+	require.NoError(t, err)
+	badVersion := append([]byte{}, valid...)
+	badVersion[0] = 1
+
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{"empty", nil},
+		{"too short", valid[:10]},
+		{"unsupported version", badVersion},
+		{"zero ids", make([]byte, BinarySpanContextLength)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeBinary(tt.input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestExtractTraceContextFromAnnotationsDetectsEncoding(t *testing.T) {
+	traceParent := "00-" + testTraceIDHex + "-" + testSpanIDHex + "-01"
+	encoded, err := EncodeTraceParentBinary(traceParent)
+	require.NoError(t, err)
+	assert.Less(t, len(encoded), len(traceParent))
+
+	cfg := AnnotationExtractionConfig{TraceParentKey: "traceparent"}
+	for name, value := range map[string]string{"w3c": traceParent, "binary": encoded} {
+		t.Run(name, func(t *testing.T) {
+			result, ok := ExtractTraceContextFromAnnotations(map[string]string{"traceparent": value}, cfg)
+			require.True(t, ok)
+			assert.Equal(t, traceParent, result.TraceParent)
+		})
+	}
+}
+
+func TestMergeTraceStates(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        string
+		b        string
+		expected string
+		wantErr  bool
+	}{
+		{"both empty", "", "", "", false},
+		{"empty a", "", "vendor=1", "vendor=1", false},
+		{"empty b", "operatortrace_ts=x", "", "operatortrace_ts=x", false},
+		{"disjoint keys", "operatortrace_ts=x", "vendor=1", "operatortrace_ts=x,vendor=1", false},
+		{"collision prefers a", "vendor=a,other=2", "vendor=b,third=3", "vendor=a,other=2,third=3", false},
+		{"identical", "vendor=1", "vendor=1", "vendor=1", false},
+		{"invalid a", "not a tracestate", "vendor=1", "", true},
+		{"invalid b", "vendor=1", "=broken", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, err := MergeTraceStates(tt.a, tt.b)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, merged)
+		})
+	}
+}
+
+func TestSetTraceStateKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		key      string
+		value    string
+		expected string
+		wantErr  bool
+	}{
+		{"empty state", "", "operatortrace_ts", "x", "operatortrace_ts=x", false},
+		{"insert keeps vendor keys", "vendor=1", "operatortrace_ts", "x", "operatortrace_ts=x,vendor=1", false},
+		{"update keeps key in place", "vendor=1,operatortrace_ts=old,other=2", "operatortrace_ts", "new", "vendor=1,operatortrace_ts=new,other=2", false},
+		{"invalid value", "vendor=1", "operatortrace_ts", "a,b=c", "", true},
+		{"invalid state", "not a tracestate", "operatortrace_ts", "x", "", true},
+		{"invalid key", "vendor=1", "Invalid Key", "x", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updated, err := SetTraceStateKey(tt.raw, tt.key, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, updated)
+		})
+	}
+}
+
+func TestBuildTraceStateStringPreservesVendorKeys(t *testing.T) {
+	traceState, err := trace.ParseTraceState("vendor=1,operatortrace_ts=old")
+	require.NoError(t, err)
+	sc := newTestSpanContext(t).WithTraceState(traceState)
+	now := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	built, err := BuildTraceStateString(sc, "operatortrace_ts", now)
+	require.NoError(t, err)
+	assert.Equal(t, "vendor=1,operatortrace_ts=2024-01-02T03:04:05Z", built)
+
+	ts, ok := ExtractTimestampFromTraceState(built, "operatortrace_ts")
+	require.True(t, ok)
+	assert.True(t, now.Equal(ts))
+}
+
+func TestBuildTraceStateStringNearFullTraceState(t *testing.T) {
+	vendors := make([]string, maxTraceStateMembers-1)
+	for i := range vendors {
+		vendors[i] = fmt.Sprintf("vendor%d=%d", i, i)
+	}
+	raw := strings.Join(vendors, ",")
+	now := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		traceState, err := trace.ParseTraceState(raw)
+		require.NoError(t, err)
+		raw, err = BuildTraceStateString(newTestSpanContext(t).WithTraceState(traceState), "operatortrace_ts", now.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, "operatortrace_ts=2024-01-02T03:06:05Z,"+strings.Join(vendors, ","), raw)
+	traceState, err := trace.ParseTraceState(raw)
+	require.NoError(t, err)
+	assert.Equal(t, maxTraceStateMembers, traceState.Len())
+
+	_, err = SetTraceStateKey(raw, "another", "x")
+	assert.ErrorIs(t, err, ErrTraceStateFull)
+}
+
+func TestBuildTraceParentFromSpanContext(t *testing.T) {
+	sampled := newTestSpanContext(t)
+	unsampled := sampled.WithTraceFlags(0)
+
+	tests := []struct {
+		name     string
+		sc       trace.SpanContext
+		expected string
+		wantErr  bool
+	}{
+		{"sampled", sampled, "00-" + testTraceIDHex + "-" + testSpanIDHex + "-01", false},
+		{"unsampled", unsampled, "00-" + testTraceIDHex + "-" + testSpanIDHex + "-00", false},
+		{"invalid", trace.SpanContext{}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceParent, err := BuildTraceParentFromSpanContext(tt.sc)
+			if tt.wantErr {
+				assert.Error(t, err)
+				assert.Empty(t, traceParent)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, traceParent)
+
+			roundTrip, err := SpanContextFromTraceData(traceParent, "")
+			require.NoError(t, err)
+			assert.Equal(t, tt.sc.TraceID(), roundTrip.TraceID())
+			assert.Equal(t, tt.sc.SpanID(), roundTrip.SpanID())
+			assert.Equal(t, tt.sc.TraceFlags(), roundTrip.TraceFlags())
+		})
+	}
+}
+
+func TestSpanContextToTraceDataRoundTrip(t *testing.T) {
+	traceState, err := trace.ParseTraceState("vendor=1")
+	require.NoError(t, err)
+	sc := newTestSpanContext(t).WithTraceState(traceState)
+	now := time.Date(2024, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	traceParent, rawTraceState, err := SpanContextToTraceData(sc, "operatortrace_ts", now)
+	require.NoError(t, err)
+	assert.Equal(t, "operatortrace_ts=2024-01-02T03:04:05Z,vendor=1", rawTraceState)
+
+	roundTrip, err := SpanContextFromTraceData(traceParent, rawTraceState)
+	require.NoError(t, err)
+	assert.Equal(t, sc.TraceID(), roundTrip.TraceID())
+	assert.Equal(t, sc.SpanID(), roundTrip.SpanID())
+	assert.Equal(t, "1", roundTrip.TraceState().Get("vendor"))
+	ts, ok := ExtractTimestampFromTraceState(roundTrip.TraceState().String(), "operatortrace_ts")
+	require.True(t, ok)
+	assert.True(t, now.Equal(ts))
+
+	_, _, err = SpanContextToTraceData(trace.SpanContext{}, "operatortrace_ts", now)
+	assert.Error(t, err)
+
+	// An invalid timestamp key still yields the traceparent
+	traceParent, rawTraceState, err = SpanContextToTraceData(sc, "Invalid Key", now)
+	assert.Error(t, err)
+	assert.Equal(t, "00-"+testTraceIDHex+"-"+testSpanIDHex+"-01", traceParent)
+	assert.Empty(t, rawTraceState)
+}
+
+func TestValidateTraceParent(t *testing.T) {
+	valid := "00-" + testTraceIDHex + "-" + testSpanIDHex + "-01"
+	binary, err := EncodeTraceParentBinary(valid)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"binary", binary, false},
+		{"future version with extra fields", "01-" + testTraceIDHex + "-" + testSpanIDHex + "-01-extra", false},
+		{"empty", "", true},
+		{"too few fields", "00-" + testTraceIDHex + "-01", true},
+		{"forbidden version", "ff-" + testTraceIDHex + "-" + testSpanIDHex + "-01", true},
+		{"extra fields for version 00", valid + "-extra", true},
+		{"uppercase trace ID", "00-" + strings.ToUpper(testTraceIDHex) + "-" + testSpanIDHex + "-01", true},
+		{"zero trace ID", "00-" + strings.Repeat("0", 32) + "-" + testSpanIDHex + "-01", true},
+		{"short span ID", "00-" + testTraceIDHex + "-" + testSpanIDHex[:8] + "-01", true},
+		{"zero span ID", "00-" + testTraceIDHex + "-" + strings.Repeat("0", 16) + "-01", true},
+		{"invalid flags", "00-" + testTraceIDHex + "-" + testSpanIDHex + "-zz", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTraceParent(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/tracingqueue/rate_limiter.go
+
+package tracingqueue
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// swappableRateLimiter forwards to a rate limiter that can be replaced while the queue is running.
+type swappableRateLimiter struct {
+	mu sync.RWMutex
+	rl workqueue.TypedRateLimiter[types.NamespacedName]
+}
+
+var _ workqueue.TypedRateLimiter[types.NamespacedName] = (*swappableRateLimiter)(nil)
+
+func (s *swappableRateLimiter) When(key types.NamespacedName) time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rl.When(key)
+}
+
+func (s *swappableRateLimiter) Forget(key types.NamespacedName) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	s.rl.Forget(key)
+}
+
+func (s *swappableRateLimiter) NumRequeues(key types.NamespacedName) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.rl.NumRequeues(key)
+}
+
+func (s *swappableRateLimiter) set(rl workqueue.TypedRateLimiter[types.NamespacedName]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rl = rl
+}
+
+// SetRateLimiter replaces the rate limiter used by AddRateLimited. Items waiting on the previous limiter's backoff
+// are forgotten and rate limited again with rl, so they become ready no later than rl's backoff allows.
+func (tq *TracingQueue) SetRateLimiter(rl workqueue.TypedRateLimiter[types.NamespacedName]) {
+	if rl == nil {
+		return
+	}
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+
+	for key := range tq.rateLimited {
+		tq.queue.Forget(key)
+	}
+	tq.rateLimiter.set(rl)
+	for key := range tq.rateLimited {
+		tq.queue.AddRateLimited(key)
+	}
+}
@@ -5,9 +5,13 @@
 package tracingqueue
 
 import (
+	"context"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/metric"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/workqueue"
 
@@ -21,86 +25,250 @@ type TracingQueue struct {
 	mu          sync.Mutex
 	m           map[types.NamespacedName]*tracingtypes.RequestWithTraceID
 	softDeleted map[types.NamespacedName]*tracingtypes.RequestWithTraceID
+
+	// rateLimiter is the limiter behind queue; SetRateLimiter swaps its implementation.
+	rateLimiter *swappableRateLimiter
+	// rateLimited holds the keys added with AddRateLimited that have not been handed out by Get yet.
+	rateLimited map[types.NamespacedName]struct{}
+
+	// processing holds the keys handed out by Get that have not been marked Done yet.
+	processing map[types.NamespacedName]struct{}
+	// doneOnce holds the keys marked Done since they were last handed out by Get, to detect duplicate Done calls.
+	doneOnce           map[types.NamespacedName]struct{}
+	duplicateDoneCount metric.Int64Counter
+
+	logger logr.Logger
+
+	// enqueuedAt records when a key was first added since it was last handed out by Get.
+	enqueuedAt   map[types.NamespacedName]time.Time
+	ageHistogram metric.Float64Histogram
+	ageCount     int64
+	ageTotal     time.Duration
+	ageMax       time.Duration
+	now          func() time.Time
+
+	// requeueTrace holds the trace intent for the next AddAfter/AddRateLimited of a key.
+	requeueTrace map[types.NamespacedName]tracingtypes.ResultWithTraceOption
+
+	// controllerName is recorded on every request handed out by Get.
+	controllerName string
+
+	// requeues counts the AddRateLimited calls for a key since it was last forgotten.
+	requeues map[types.NamespacedName]int
+	// maxRetries and deadLetter are set by WithDeadLetterQueue; deadLetter is nil when it is not configured.
+	maxRetries int
+	deadLetter func(req tracingtypes.RequestWithTraceID)
+}
+
+// QueueOption configures a TracingQueue during construction.
+type QueueOption func(*TracingQueue)
+
+// WithAgeHistogram records the time, in seconds, each item spent in the queue between Add and Get.
+func WithAgeHistogram(h metric.Float64Histogram) QueueOption {
+	return func(tq *TracingQueue) {
+		if h == nil {
+			return
+		}
+		tq.ageHistogram = h
+	}
+}
+
+// WithLogger sets the logger used to report misuse of the queue, such as duplicate Done calls.
+func WithLogger(l logr.Logger) QueueOption {
+	return func(tq *TracingQueue) {
+		if l.GetSink() == nil {
+			return
+		}
+		tq.logger = l
+	}
+}
+
+// WithDuplicateDoneCounter counts the Done calls made for items that were already marked Done.
+func WithDuplicateDoneCounter(c metric.Int64Counter) QueueOption {
+	return func(tq *TracingQueue) {
+		if c == nil {
+			return
+		}
+		tq.duplicateDoneCount = c
+	}
+}
+
+// WithControllerName records name as the ControllerName of every request handed out by Get, so the reconcile knows
+// which controller it runs in.
+func WithControllerName(name string) QueueOption {
+	return func(tq *TracingQueue) {
+		if name == "" {
+			return
+		}
+		tq.controllerName = name
+	}
+}
+
+// WithDeadLetterQueue stops retrying a request rate limited more than maxRetries times since it was last forgotten:
+// the request is forgotten instead of requeued and handed to handler, which may e.g. alert, log or record it for an
+// operator to inspect. handler is called without holding the queue lock. A later Add of the key queues it again.
+func WithDeadLetterQueue(maxRetries int, handler func(req tracingtypes.RequestWithTraceID)) QueueOption {
+	return func(tq *TracingQueue) {
+		if maxRetries <= 0 || handler == nil {
+			return
+		}
+		tq.maxRetries = maxRetries
+		tq.deadLetter = handler
+	}
 }
 
 // NewTracingQueue creates a new TracingQueue instance using generics and the recommended rate limiter.
-func NewTracingQueue() *TracingQueue {
-	return &TracingQueue{
-		queue: workqueue.NewTypedRateLimitingQueue(
-			workqueue.DefaultTypedControllerRateLimiter[types.NamespacedName](),
-		),
-		m:           make(map[types.NamespacedName]*tracingtypes.RequestWithTraceID),
-		softDeleted: make(map[types.NamespacedName]*tracingtypes.RequestWithTraceID),
+func NewTracingQueue(opts ...QueueOption) *TracingQueue {
+	rateLimiter := &swappableRateLimiter{rl: workqueue.DefaultTypedControllerRateLimiter[types.NamespacedName]()}
+	tq := &TracingQueue{
+		queue:        workqueue.NewTypedRateLimitingQueue[types.NamespacedName](rateLimiter),
+		rateLimiter:  rateLimiter,
+		rateLimited:  make(map[types.NamespacedName]struct{}),
+		m:            make(map[types.NamespacedName]*tracingtypes.RequestWithTraceID),
+		softDeleted:  make(map[types.NamespacedName]*tracingtypes.RequestWithTraceID),
+		processing:   make(map[types.NamespacedName]struct{}),
+		doneOnce:     make(map[types.NamespacedName]struct{}),
+		enqueuedAt:   make(map[types.NamespacedName]time.Time),
+		requeueTrace: make(map[types.NamespacedName]tracingtypes.ResultWithTraceOption),
+		requeues:     make(map[types.NamespacedName]int),
+		now:          time.Now,
+		logger:       logr.Discard(),
 	}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(tq)
+	}
+	return tq
 }
 
 var _ workqueue.TypedRateLimitingInterface[tracingtypes.RequestWithTraceID] = (*TracingQueue)(nil)
 
 // Add adds or merges a tracing request into the queue, deduping by key.
 func (tq *TracingQueue) Add(req tracingtypes.RequestWithTraceID) {
+	key := Key(req)
 	tq.mu.Lock()
 	defer tq.mu.Unlock()
 
-	if _, found := tq.m[req.NamespacedName]; found {
-		existing := tq.m[req.NamespacedName]
-		mergeRequest(existing, req)
-		// Mark dirty in underlying queue so it requeues after Done()
-		tq.queue.Add(req.NamespacedName)
-	} else {
-		tval := req // Copy, to avoid retaining the caller's pointer.
-		tq.m[req.NamespacedName] = &tval
-		tq.queue.Add(req.NamespacedName)
+	if _, found := tq.enqueuedAt[key]; !found {
+		tq.enqueuedAt[key] = tq.now()
 	}
+
+	tq.store(key, req)
+	// Marks the key dirty in the underlying queue when it is in flight, so it requeues after Done()
+	tq.queue.Add(key)
 }
 
 // AddAfter adds or merges a tracing request into the queue, deduping by key, with a delay.
 func (tq *TracingQueue) AddAfter(req tracingtypes.RequestWithTraceID, duration time.Duration) {
+	key := Key(req)
 	tq.mu.Lock()
 	defer tq.mu.Unlock()
 
-	if existing, found := tq.m[req.NamespacedName]; found {
-		// Merge new metadata (including a newer parent) but keep existing links/parent unless changed.
-		mergeRequest(existing, req)
-	} else {
-		// First enqueue for this key: start clean to avoid linking to older spans.
-		tval := req
-		req.LinkedSpanCount = 0
-		req.LinkedSpans = [10]tracingtypes.LinkedSpan{}
-		req.Parent = tracingtypes.RequestParent{}
-		tq.m[req.NamespacedName] = &tval
-	}
+	req = tq.applyRequeueTrace(req)
+	tq.store(key, req)
 
 	// Always schedule the delayed enqueue, even if the key is already present, to match workqueue semantics.
-	tq.queue.AddAfter(req.NamespacedName, duration)
+	tq.queue.AddAfter(key, duration)
 }
 
 // AddRateLimited adds or merges a tracing request into the queue, deduping by key, with rate limiting.
+// With WithDeadLetterQueue, a request rate limited too many times is handed to the dead letter handler instead.
 func (tq *TracingQueue) AddRateLimited(req tracingtypes.RequestWithTraceID) {
+	if dead, ok := tq.addRateLimited(req); ok {
+		tq.deadLetter(dead)
+	}
+}
+
+// addRateLimited queues req with rate limiting, unless it exceeded the retries of the dead letter queue, in which
+// case it returns the request to hand to the dead letter handler.
+func (tq *TracingQueue) addRateLimited(req tracingtypes.RequestWithTraceID) (tracingtypes.RequestWithTraceID, bool) {
+	key := Key(req)
 	tq.mu.Lock()
 	defer tq.mu.Unlock()
 
 	// This is usually called after an error so keeping it linked to the previous span.
-	if _, found := tq.m[req.NamespacedName]; found {
-		existing := tq.m[req.NamespacedName]
-		mergeRequest(existing, req)
-		// Mark dirty in underlying queue so it requeues after Done()
-		tq.queue.AddRateLimited(req.NamespacedName)
-	} else {
-		tval := req
-		tq.m[req.NamespacedName] = &tval
-		tq.queue.AddRateLimited(req.NamespacedName)
+	req = tq.applyRequeueTrace(req)
+	tq.requeues[key]++
+	if tq.deadLetter != nil && tq.requeues[key] > tq.maxRetries {
+		dead := req
+		if existing, found := tq.m[key]; found {
+			dead = *existing
+			dead.Merge(req)
+		}
+		tq.forget(key)
+		return tq.withControllerName(dead), true
+	}
+
+	tq.rateLimited[key] = struct{}{}
+	tq.store(key, req)
+	tq.queue.AddRateLimited(key)
+	return tracingtypes.RequestWithTraceID{}, false
+}
+
+// store records req as the queued request for key, merging it into the request already queued for the key.
+// Add, AddAfter and AddRateLimited all store through it, so the result does not depend on which of them queued
+// each request. The caller must hold tq.mu.
+func (tq *TracingQueue) store(key types.NamespacedName, req tracingtypes.RequestWithTraceID) {
+	if existing, found := tq.m[key]; found {
+		existing.Merge(req)
+		return
+	}
+	tval := req // Copy, to avoid retaining the caller's pointer.
+	tval.LinkedSpans = [10]tracingtypes.LinkedSpan{}
+	tval.LinkedSpanCount = 0
+	// Merging req into itself without links keeps its links, except one to its own parent
+	tval.Merge(req)
+	tq.m[key] = &tval
+}
+
+// SetRequeueTrace records whether the next delayed or rate limited requeue of key keeps its trace.
+// key is the request's Key.
+// The intent is consumed by the next AddAfter or AddRateLimited call for the key.
+func (tq *TracingQueue) SetRequeueTrace(key types.NamespacedName, option tracingtypes.ResultWithTraceOption) {
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+
+	if option == tracingtypes.RequeueTraceDefault {
+		delete(tq.requeueTrace, key)
+		return
 	}
+	tq.requeueTrace[key] = option
 }
 
-// Forget removes a tracing request from the queue, if it exists.
+// applyRequeueTrace consumes the recorded requeue intent for req. The caller must hold tq.mu.
+func (tq *TracingQueue) applyRequeueTrace(req tracingtypes.RequestWithTraceID) tracingtypes.RequestWithTraceID {
+	key := Key(req)
+	option, found := tq.requeueTrace[key]
+	if !found {
+		return req
+	}
+	delete(tq.requeueTrace, key)
+
+	if option == tracingtypes.RequeueTraceDrop {
+		req.Parent = tracingtypes.RequestParent{}
+		req.LinkedSpans = [10]tracingtypes.LinkedSpan{}
+		req.LinkedSpanCount = 0
+	}
+	return req
+}
+
+// Forget removes a tracing request from the queue, if it exists, and resets its requeue count.
 func (tq *TracingQueue) Forget(req tracingtypes.RequestWithTraceID) {
+	key := Key(req)
 	tq.mu.Lock()
 	defer tq.mu.Unlock()
+	tq.forget(key)
+}
 
-	if val, found := tq.m[req.NamespacedName]; found {
-		tq.softDeleted[req.NamespacedName] = val
-		delete(tq.m, req.NamespacedName)
-		tq.queue.Forget(req.NamespacedName)
+// forget implements Forget. The caller must hold tq.mu.
+func (tq *TracingQueue) forget(key types.NamespacedName) {
+	delete(tq.requeues, key)
+	if val, found := tq.m[key]; found {
+		tq.softDeleted[key] = val
+		delete(tq.m, key)
+		tq.queue.Forget(key)
 	}
 }
 
@@ -111,9 +279,12 @@ func (tq *TracingQueue) Len() int {
 	return len(tq.m)
 }
 
-// NumRequeues returns the number of requeues for a given request.
+// NumRequeues returns the number of times req was rate limited since it was last forgotten. The queue counts them
+// itself, so the count does not depend on the rate limiter, which may not track requeues or may be swapped.
 func (tq *TracingQueue) NumRequeues(req tracingtypes.RequestWithTraceID) int {
-	return tq.queue.NumRequeues(req.NamespacedName)
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+	return tq.requeues[Key(req)]
 }
 
 // ShutDownWithDrain stops accepting new work and drains the queue.
@@ -128,6 +299,24 @@ func (tq *TracingQueue) ShutDownWithDrain() {
 	for key := range tq.softDeleted {
 		delete(tq.softDeleted, key)
 	}
+	for key := range tq.processing {
+		delete(tq.processing, key)
+	}
+	for key := range tq.doneOnce {
+		delete(tq.doneOnce, key)
+	}
+	for key := range tq.rateLimited {
+		delete(tq.rateLimited, key)
+	}
+	for key := range tq.enqueuedAt {
+		delete(tq.enqueuedAt, key)
+	}
+	for key := range tq.requeueTrace {
+		delete(tq.requeueTrace, key)
+	}
+	for key := range tq.requeues {
+		delete(tq.requeues, key)
+	}
 }
 
 // Get returns and removes the next queued TracingRequest (merged value).
@@ -140,37 +329,117 @@ func (tq *TracingQueue) Get() (req tracingtypes.RequestWithTraceID, shutdown boo
 
 	tq.mu.Lock()
 	defer tq.mu.Unlock()
+	tq.recordAge(key)
+	tq.processing[key] = struct{}{}
+	delete(tq.doneOnce, key)
+	delete(tq.rateLimited, key)
 	valPtr, found := tq.m[key]
 	if found && valPtr != nil {
-		return *valPtr, false
+		return tq.withControllerName(*valPtr), false
 	}
 	// Check softDeleted map
 	softPtr, softFound := tq.softDeleted[key]
 	if softFound && softPtr != nil {
-		return *softPtr, false
+		return tq.withControllerName(*softPtr), false
 	}
 	// Key not found in either map
-	return tracingtypes.RequestWithTraceID{
-		Request: ctrlreconcile.Request{
-			NamespacedName: key,
-		},
-	}, false
+	return tq.withControllerName(requestForKey(key)), false
+}
+
+// withControllerName sets the queue's controller name on req, unless the request already names its controller.
+func (tq *TracingQueue) withControllerName(req tracingtypes.RequestWithTraceID) tracingtypes.RequestWithTraceID {
+	if req.ControllerName == "" {
+		req.ControllerName = tq.controllerName
+	}
+	return req
+}
+
+// IsObjectInFlight reports whether key has been handed out by Get and is still being reconciled, i.e. Done
+// has not been called for it yet. The result is only a snapshot: the reconcile may finish, or a new one
+// may start, before the caller acts on it, so use it to skip redundant work rather than for correctness.
+// key is the request's Key, which is its NamespacedName unless the request carries a ClusterName.
+func (tq *TracingQueue) IsObjectInFlight(key types.NamespacedName) bool {
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+	_, found := tq.processing[key]
+	return found
+}
+
+// IsObjectPending reports whether a request for key is queued, including one that is in flight and has not
+// been marked Done yet. Like IsObjectInFlight, the state may have changed by the time the caller reads it.
+func (tq *TracingQueue) IsObjectPending(key types.NamespacedName) bool {
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+	_, found := tq.m[key]
+	return found
+}
+
+// AverageQueueAge returns the mean time items spent in the queue between Add and Get.
+func (tq *TracingQueue) AverageQueueAge() time.Duration {
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+	if tq.ageCount == 0 {
+		return 0
+	}
+	return tq.ageTotal / time.Duration(tq.ageCount)
+}
+
+// MaxQueueAge returns the longest time an item spent in the queue between Add and Get.
+func (tq *TracingQueue) MaxQueueAge() time.Duration {
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+	return tq.ageMax
+}
+
+// recordAge observes how long key waited since it was added. The caller must hold tq.mu.
+func (tq *TracingQueue) recordAge(key types.NamespacedName) {
+	enqueuedAt, found := tq.enqueuedAt[key]
+	if !found {
+		return
+	}
+	delete(tq.enqueuedAt, key)
+
+	age := tq.now().Sub(enqueuedAt)
+	tq.ageCount++
+	tq.ageTotal += age
+	if age > tq.ageMax {
+		tq.ageMax = age
+	}
+	if tq.ageHistogram != nil {
+		tq.ageHistogram.Record(context.Background(), age.Seconds())
+	}
 }
 
 // Done notifies the underlying queue that you're done with this key (for rate limiting).
+// A second Done for the same item is logged and otherwise ignored, so it cannot release a later Get of the key.
 func (tq *TracingQueue) Done(req tracingtypes.RequestWithTraceID) {
+	key := Key(req)
 	tq.mu.Lock()
 	defer tq.mu.Unlock()
-	tq.queue.Done(req.NamespacedName)
-	if val, found := tq.m[req.NamespacedName]; found {
-		tq.softDeleted[req.NamespacedName] = val
-		delete(tq.m, req.NamespacedName)
+	if _, found := tq.doneOnce[key]; found {
+		tq.logger.Error(nil, "Done called more than once for the same item", "key", key.String())
+		if tq.duplicateDoneCount != nil {
+			tq.duplicateDoneCount.Add(context.Background(), 1)
+		}
+		return
+	}
+	tq.doneOnce[key] = struct{}{}
+	tq.queue.Done(key)
+	delete(tq.processing, key)
+	if val, found := tq.m[key]; found {
+		tq.softDeleted[key] = val
+		delete(tq.m, key)
 	}
 }
 
 // ShutDown stops accepting new work and shuts down the queue.
 func (tq *TracingQueue) ShutDown() {
 	tq.queue.ShutDown()
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+	for key := range tq.doneOnce {
+		delete(tq.doneOnce, key)
+	}
 }
 
 // ShuttingDown reports if the queue is shutting down.
@@ -178,45 +447,19 @@ func (tq *TracingQueue) ShuttingDown() bool {
 	return tq.queue.ShuttingDown()
 }
 
-func appendLinkedSpan(req *tracingtypes.RequestWithTraceID, span tracingtypes.LinkedSpan) {
-	// Don't add empty linked spans
-	if len(span.TraceID) == 0 && len(span.SpanID) == 0 {
-		return
-	}
-
-	for i := 0; i < req.LinkedSpanCount; i++ {
-		if req.LinkedSpans[i] == span {
-			return // Already present, skip duplicate
-		}
-	}
-	if req.LinkedSpanCount < len(req.LinkedSpans) {
-		req.LinkedSpans[req.LinkedSpanCount] = span
-		req.LinkedSpanCount++
-	}
-}
-
-func mergeRequest(existing *tracingtypes.RequestWithTraceID, incoming tracingtypes.RequestWithTraceID) {
-	// Only try to promote the incoming parent if it has a valid trace context
-	if len(incoming.Parent.TraceID) > 0 && len(incoming.Parent.SpanID) > 0 {
-		incomingDiffers := existing.Parent.TraceID != incoming.Parent.TraceID ||
-			existing.Parent.SpanID != incoming.Parent.SpanID ||
-			existing.Parent.Name != incoming.Parent.Name ||
-			existing.Parent.Kind != incoming.Parent.Kind ||
-			existing.Parent.EventKind != incoming.Parent.EventKind
-		if incomingDiffers {
-			// Preserve the previous parent as a linked span before overwriting it
-			if len(existing.Parent.TraceID) > 0 || len(existing.Parent.SpanID) > 0 {
-				appendLinkedSpan(existing, tracingtypes.LinkedSpan{
-					TraceID: existing.Parent.TraceID,
-					SpanID:  existing.Parent.SpanID,
-				})
-			}
-			existing.Parent = incoming.Parent
-		}
-	}
+// Key returns the key the queue dedupes req by. It is req's NamespacedName, with the namespace prefixed by
+// req.ClusterName when the request belongs to a named cluster, so the same object in two clusters is queued,
+// rate limited and reported separately. Pass it to SetRequeueTrace, IsObjectInFlight and IsObjectPending.
+func Key(req tracingtypes.RequestWithTraceID) types.NamespacedName {
+	return req.Key()
+}
 
-	// Merge any linked spans that came with the incoming request (e.g., retries)
-	for i := 0; i < incoming.LinkedSpanCount; i++ {
-		appendLinkedSpan(existing, incoming.LinkedSpans[i])
+// requestForKey rebuilds a bare request from a key returned by Key.
+// Namespaces cannot contain a slash, so everything before the last one is the cluster name.
+func requestForKey(key types.NamespacedName) tracingtypes.RequestWithTraceID {
+	req := tracingtypes.RequestWithTraceID{Request: ctrlreconcile.Request{NamespacedName: key}}
+	if i := strings.LastIndex(key.Namespace, "/"); i >= 0 {
+		req.ClusterName, req.Namespace = key.Namespace[:i], key.Namespace[i+1:]
 	}
+	return req
 }
@@ -1,50 +1,26 @@
 package tracingqueue
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-logr/logr/funcr"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/embedded"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
 	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/testhelpers"
 	tracingtypes "github.com/Azure/operatortrace/operatortrace-go/pkg/types"
 )
 
-func TestAppendLinkedSpan(t *testing.T) {
-	req := &tracingtypes.RequestWithTraceID{
-		LinkedSpans:     [10]tracingtypes.LinkedSpan{},
-		LinkedSpanCount: 0,
-	}
-
-	span1 := tracingtypes.LinkedSpan{TraceID: "1", SpanID: "a"}
-	span2 := tracingtypes.LinkedSpan{TraceID: "2", SpanID: "b"}
-	span3 := tracingtypes.LinkedSpan{TraceID: "3", SpanID: "c"}
-	spanEmpty := tracingtypes.LinkedSpan{}
-
-	// Start: add two spans
-	appendLinkedSpan(req, span1)
-	appendLinkedSpan(req, span2)
-
-	require.Equal(t, 2, req.LinkedSpanCount)
-	require.ElementsMatch(t, []tracingtypes.LinkedSpan{span1, span2}, req.LinkedSpans[:req.LinkedSpanCount])
-
-	// Add third, expect three
-	appendLinkedSpan(req, span3)
-
-	require.Equal(t, 3, req.LinkedSpanCount)
-	require.ElementsMatch(t, []tracingtypes.LinkedSpan{span1, span2, span3}, req.LinkedSpans[:req.LinkedSpanCount])
-
-	// Try to add a duplicate
-	appendLinkedSpan(req, span1)
-	require.Equal(t, 3, req.LinkedSpanCount)
-	require.ElementsMatch(t, []tracingtypes.LinkedSpan{span1, span2, span3}, req.LinkedSpans[:req.LinkedSpanCount])
-
-	// Try to add an empty linked span
-	appendLinkedSpan(req, spanEmpty)
-	require.Equal(t, 3, req.LinkedSpanCount)
-	require.ElementsMatch(t, []tracingtypes.LinkedSpan{span1, span2, span3}, req.LinkedSpans[:req.LinkedSpanCount])
-}
-
 func TestTracingQueuePrefersLatestParentForDuplicateKey(t *testing.T) {
 	queue := NewTracingQueue()
 	key := types.NamespacedName{Namespace: "default", Name: "sample1"}
@@ -59,10 +35,43 @@ func TestTracingQueuePrefersLatestParentForDuplicateKey(t *testing.T) {
 	require.Equal(t, "trace-new", got.Parent.TraceID)
 	require.Equal(t, "span-new", got.Parent.SpanID)
 	require.Equal(t, 1, got.LinkedSpanCount)
-	require.Equal(t, tracingtypes.LinkedSpan{TraceID: "trace-old", SpanID: "span-old"}, got.LinkedSpans[0])
+	require.Equal(t, linkedParent("trace-old", "span-old", "sample1", "Sample", "Update"), got.LinkedSpans[0])
 	queue.Done(got)
 }
 
+func TestTracingQueueMergesChangedFields(t *testing.T) {
+	key := types.NamespacedName{Namespace: "default", Name: "sample1"}
+	parent := tracingtypes.RequestParent{TraceID: "trace", SpanID: "span", Name: "sample1", Kind: "Sample", EventKind: "Update"}
+
+	tests := []struct {
+		name     string
+		first    string
+		second   string
+		expected string
+	}{
+		{"same fields", "spec", "spec", "spec"},
+		{"union of fields", "status", "spec", "spec,status"},
+		{"unknown wins", "status", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := NewTracingQueue()
+			first, second := parent, parent
+			first.ChangedFields = tt.first
+			second.ChangedFields = tt.second
+			queue.Add(newRequest(key, first))
+			queue.Add(newRequest(key, second))
+
+			got, shutdown := queue.Get()
+			require.False(t, shutdown)
+			require.Equal(t, tt.expected, got.Parent.ChangedFields)
+			require.Equal(t, 0, got.LinkedSpanCount)
+			queue.Done(got)
+		})
+	}
+}
+
 func TestTracingQueueUsesLatestParentAfterDoneAndReAdd(t *testing.T) {
 	queue := NewTracingQueue()
 	key := types.NamespacedName{Namespace: "default", Name: "sample1"}
@@ -83,9 +92,373 @@ func TestTracingQueueUsesLatestParentAfterDoneAndReAdd(t *testing.T) {
 	queue.Done(got)
 }
 
+func TestTracingQueueKeepsMostRecentLinkedSpansAcrossRateLimitedStorm(t *testing.T) {
+	queue := NewTracingQueue()
+	key := types.NamespacedName{Namespace: "default", Name: "sample1"}
+
+	const retries = 25
+	for i := 0; i < retries; i++ {
+		queue.AddRateLimited(newRequest(key, tracingtypes.RequestParent{TraceID: fmt.Sprintf("trace-%d", i), SpanID: fmt.Sprintf("span-%d", i), Name: "sample1", Kind: "Sample", EventKind: "Update"}))
+		// Retries repeatedly re-link the same earlier failure.
+		queue.AddRateLimited(tracingtypes.RequestWithTraceID{
+			Request:         ctrlreconcile.Request{NamespacedName: key},
+			LinkedSpans:     [10]tracingtypes.LinkedSpan{{TraceID: "trace-0", SpanID: "span-0"}},
+			LinkedSpanCount: 1,
+		})
+	}
+
+	got, shutdown := queue.Get()
+	require.False(t, shutdown)
+	require.Equal(t, fmt.Sprintf("trace-%d", retries-1), got.Parent.TraceID)
+	require.Equal(t, len(got.LinkedSpans), got.LinkedSpanCount)
+
+	// The newest previous parents are kept in order, followed by the span that keeps being re-linked.
+	expected := make([]tracingtypes.LinkedSpan, 0, len(got.LinkedSpans))
+	for i := retries - len(got.LinkedSpans); i < retries-1; i++ {
+		expected = append(expected, linkedParent(fmt.Sprintf("trace-%d", i), fmt.Sprintf("span-%d", i), "sample1", "Sample", "Update"))
+	}
+	// Re-linking it without attributes keeps the attributes it got as a parent
+	expected = append(expected, linkedParent("trace-0", "span-0", "sample1", "Sample", "Update"))
+	require.Equal(t, expected, got.LinkedSpans[:])
+	queue.Done(got)
+}
+
+func TestTracingQueueMergesAcrossAddMethods(t *testing.T) {
+	key := types.NamespacedName{Namespace: "default", Name: "sample1"}
+	parent := func(id string) tracingtypes.RequestParent {
+		return tracingtypes.RequestParent{TraceID: "trace-" + id, SpanID: "span-" + id, Name: "sample1", Kind: "Sample", EventKind: "Update"}
+	}
+	link := func(id string) tracingtypes.LinkedSpan {
+		return tracingtypes.LinkedSpan{TraceID: "trace-" + id, SpanID: "span-" + id}
+	}
+	parentLink := func(id string) tracingtypes.LinkedSpan {
+		return linkedParent("trace-"+id, "span-"+id, "sample1", "Sample", "Update")
+	}
+	withLinks := func(req tracingtypes.RequestWithTraceID, links ...tracingtypes.LinkedSpan) tracingtypes.RequestWithTraceID {
+		for _, l := range links {
+			req.AppendLinkedSpan(l)
+		}
+		return req
+	}
+
+	type add func(q *TracingQueue, req tracingtypes.RequestWithTraceID)
+	var (
+		plain       add = func(q *TracingQueue, req tracingtypes.RequestWithTraceID) { q.Add(req) }
+		rateLimited add = func(q *TracingQueue, req tracingtypes.RequestWithTraceID) { q.AddRateLimited(req) }
+		after       add = func(q *TracingQueue, req tracingtypes.RequestWithTraceID) { q.AddAfter(req, time.Millisecond) }
+	)
+
+	tests := []struct {
+		name          string
+		adds          []add
+		reqs          []tracingtypes.RequestWithTraceID
+		expectedTrace string
+		expectedLinks []tracingtypes.LinkedSpan
+	}{
+		{
+			name:          "each method links the parent it replaces",
+			adds:          []add{plain, rateLimited, after},
+			reqs:          []tracingtypes.RequestWithTraceID{newRequest(key, parent("a")), newRequest(key, parent("b")), newRequest(key, parent("c"))},
+			expectedTrace: "trace-c",
+			expectedLinks: []tracingtypes.LinkedSpan{parentLink("a"), parentLink("b")},
+		},
+		{
+			name:          "a parent coming back is not linked as well",
+			adds:          []add{after, plain, rateLimited},
+			reqs:          []tracingtypes.RequestWithTraceID{newRequest(key, parent("a")), newRequest(key, parent("b")), newRequest(key, parent("a"))},
+			expectedTrace: "trace-a",
+			expectedLinks: []tracingtypes.LinkedSpan{parentLink("b")},
+		},
+		{
+			name: "incoming links are deduplicated against links and parent",
+			adds: []add{rateLimited, after, plain},
+			reqs: []tracingtypes.RequestWithTraceID{
+				withLinks(newRequest(key, parent("a")), link("a"), link("x")),
+				withLinks(newRequest(key, tracingtypes.RequestParent{}), link("x"), link("a")),
+				withLinks(newRequest(key, parent("b")), link("b"), link("x")),
+			},
+			expectedTrace: "trace-b",
+			expectedLinks: []tracingtypes.LinkedSpan{parentLink("a"), link("x")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := NewTracingQueue()
+			defer queue.ShutDown()
+			for i, req := range tt.reqs {
+				tt.adds[i](queue, req)
+			}
+			require.Equal(t, 1, queue.Len())
+
+			got, shutdown := queue.Get()
+			require.False(t, shutdown)
+			require.Equal(t, tt.expectedTrace, got.Parent.TraceID)
+			require.Equal(t, tt.expectedLinks, got.LinkedSpans[:got.LinkedSpanCount])
+			queue.Done(got)
+		})
+	}
+}
+
 func newRequest(key types.NamespacedName, parent tracingtypes.RequestParent) tracingtypes.RequestWithTraceID {
 	return tracingtypes.RequestWithTraceID{
 		Request: ctrlreconcile.Request{NamespacedName: key},
 		Parent:  parent,
 	}
 }
+
+// linkedParent returns the linked span of a replaced request parent, with the attributes describing its event.
+func linkedParent(traceID, spanID, name, kind, eventKind string) tracingtypes.LinkedSpan {
+	return tracingtypes.LinkedSpan{TraceID: traceID, SpanID: spanID, LinkedSpanAttributes: attribute.NewSet(
+		tracingtypes.LinkObjectNameAttributeKey.String(name),
+		tracingtypes.LinkObjectKindAttributeKey.String(kind),
+		tracingtypes.LinkEventKindAttributeKey.String(eventKind),
+	)}
+}
+
+// recordingHistogram captures the values recorded on a Float64Histogram.
+type recordingHistogram struct {
+	embedded.Float64Histogram
+	values []float64
+}
+
+func (h *recordingHistogram) Record(_ context.Context, value float64, _ ...metric.RecordOption) {
+	h.values = append(h.values, value)
+}
+
+func TestTracingQueueAge(t *testing.T) {
+	histogram := &recordingHistogram{}
+	queue := NewTracingQueue(WithAgeHistogram(histogram))
+	now := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	queue.now = func() time.Time { return now }
+
+	key1 := types.NamespacedName{Namespace: "default", Name: "sample1"}
+	key2 := types.NamespacedName{Namespace: "default", Name: "sample2"}
+
+	require.Zero(t, queue.AverageQueueAge())
+	require.Zero(t, queue.MaxQueueAge())
+
+	queue.Add(newRequest(key1, tracingtypes.RequestParent{}))
+	now = now.Add(2 * time.Second)
+	// Merging into a queued item must not reset its age
+	queue.Add(newRequest(key1, tracingtypes.RequestParent{}))
+	queue.Add(newRequest(key2, tracingtypes.RequestParent{}))
+	now = now.Add(2 * time.Second)
+
+	got, shutdown := queue.Get()
+	require.False(t, shutdown)
+	require.Equal(t, key1, got.NamespacedName)
+	queue.Done(got)
+
+	now = now.Add(4 * time.Second)
+	got, shutdown = queue.Get()
+	require.False(t, shutdown)
+	require.Equal(t, key2, got.NamespacedName)
+	queue.Done(got)
+
+	require.Equal(t, []float64{4, 6}, histogram.values)
+	require.Equal(t, 5*time.Second, queue.AverageQueueAge())
+	require.Equal(t, 6*time.Second, queue.MaxQueueAge())
+
+	// A re-added key starts aging from the new Add
+	queue.Add(newRequest(key1, tracingtypes.RequestParent{}))
+	now = now.Add(time.Second)
+	got, _ = queue.Get()
+	queue.Done(got)
+	require.Equal(t, []float64{4, 6, 1}, histogram.values)
+	require.Equal(t, 6*time.Second, queue.MaxQueueAge())
+}
+
+func TestTracingQueueDetectsDuplicateDone(t *testing.T) {
+	var logs strings.Builder
+	logger := funcr.New(func(prefix, args string) { logs.WriteString(args + "\n") }, funcr.Options{})
+	counter := &testhelpers.RecordingCounter{}
+	queue := NewTracingQueue(WithLogger(logger), WithDuplicateDoneCounter(counter))
+	key := types.NamespacedName{Namespace: "default", Name: "sample1"}
+
+	queue.Add(newRequest(key, tracingtypes.RequestParent{}))
+	got, shutdown := queue.Get()
+	require.False(t, shutdown)
+	queue.Done(got)
+	require.Zero(t, counter.Total())
+	require.Empty(t, logs.String())
+
+	// Re-adding the key while the duplicate Done arrives must not hand it out twice
+	queue.Add(newRequest(key, tracingtypes.RequestParent{}))
+	queue.Done(got)
+	require.Equal(t, int64(1), counter.Total())
+	require.Contains(t, logs.String(), "Done called more than once")
+	require.Contains(t, logs.String(), key.String())
+
+	// A new Get starts a new round, so its Done is not a duplicate
+	got, shutdown = queue.Get()
+	require.False(t, shutdown)
+	require.True(t, queue.IsObjectInFlight(key))
+	queue.Done(got)
+	require.False(t, queue.IsObjectInFlight(key))
+	require.Equal(t, int64(1), counter.Total())
+
+	queue.ShutDown()
+	queue.Done(got)
+	require.Equal(t, int64(1), counter.Total())
+}
+
+func TestTracingQueueInFlightAndPending(t *testing.T) {
+	queue := NewTracingQueue()
+	key := types.NamespacedName{Namespace: "default", Name: "sample1"}
+
+	require.False(t, queue.IsObjectPending(key))
+	require.False(t, queue.IsObjectInFlight(key))
+
+	queue.Add(newRequest(key, tracingtypes.RequestParent{}))
+	require.True(t, queue.IsObjectPending(key))
+	require.False(t, queue.IsObjectInFlight(key))
+
+	got, shutdown := queue.Get()
+	require.False(t, shutdown)
+	require.True(t, queue.IsObjectPending(key))
+	require.True(t, queue.IsObjectInFlight(key))
+
+	queue.Done(got)
+	require.False(t, queue.IsObjectPending(key))
+	require.False(t, queue.IsObjectInFlight(key))
+}
+
+// TestTracingQueueInFlightConcurrentAccess is meant to be run with -race.
+func TestTracingQueueInFlightConcurrentAccess(t *testing.T) {
+	queue := NewTracingQueue()
+	key := types.NamespacedName{Namespace: "default", Name: "sample1"}
+	const iterations = 200
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			queue.Add(newRequest(key, tracingtypes.RequestParent{}))
+			got, shutdown := queue.Get()
+			if shutdown {
+				return
+			}
+			queue.Done(got)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < iterations; i++ {
+			queue.IsObjectInFlight(key)
+			queue.IsObjectPending(key)
+		}
+	}()
+	wg.Wait()
+
+	require.False(t, queue.IsObjectInFlight(key))
+}
+
+func TestTracingQueueSetRateLimiterRequeuesWaitingItems(t *testing.T) {
+	tq := NewTracingQueue()
+	defer tq.ShutDown()
+	key := types.NamespacedName{Namespace: "default", Name: "obj"}
+
+	tq.SetRateLimiter(workqueue.NewTypedItemExponentialFailureRateLimiter[types.NamespacedName](time.Hour, time.Hour))
+	tq.AddRateLimited(newRequest(key, tracingtypes.RequestParent{}))
+	require.Equal(t, 0, tq.queue.Len(), "item should be waiting on the slow backoff")
+
+	tq.SetRateLimiter(nil) // ignored
+	tq.SetRateLimiter(workqueue.NewTypedItemExponentialFailureRateLimiter[types.NamespacedName](time.Millisecond, time.Millisecond))
+	require.Equal(t, 1, tq.NumRequeues(newRequest(key, tracingtypes.RequestParent{})), "the new limiter should track the requeued item")
+
+	got := make(chan tracingtypes.RequestWithTraceID, 1)
+	go func() {
+		req, _ := tq.Get()
+		got <- req
+	}()
+	select {
+	case req := <-got:
+		require.Equal(t, key, req.NamespacedName)
+	case <-time.After(5 * time.Second):
+		t.Fatal("item was not requeued with the new rate limiter's backoff")
+	}
+	tq.Done(newRequest(key, tracingtypes.RequestParent{}))
+
+	// Items handed out by Get are no longer waiting, so another swap does not requeue them
+	tq.SetRateLimiter(workqueue.NewTypedItemExponentialFailureRateLimiter[types.NamespacedName](time.Millisecond, time.Millisecond))
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(t, 0, tq.queue.Len())
+}
+
+func TestTracingQueueKeepsClustersApart(t *testing.T) {
+	queue := NewTracingQueue()
+	key := types.NamespacedName{Namespace: "default", Name: "sample1"}
+	east := newRequest(key, tracingtypes.RequestParent{TraceID: "1", SpanID: "a"})
+	east.ClusterName = "east"
+	west := newRequest(key, tracingtypes.RequestParent{TraceID: "2", SpanID: "b"})
+	west.ClusterName = "west"
+	local := newRequest(key, tracingtypes.RequestParent{TraceID: "3", SpanID: "c"})
+
+	queue.Add(east)
+	queue.Add(west)
+	queue.Add(local)
+	require.Equal(t, 3, queue.Len())
+	require.True(t, queue.IsObjectPending(Key(east)))
+	require.True(t, queue.IsObjectPending(key))
+
+	var got []tracingtypes.RequestWithTraceID
+	for i := 0; i < 3; i++ {
+		req, shutdown := queue.Get()
+		require.False(t, shutdown)
+		got = append(got, req)
+	}
+	require.ElementsMatch(t, []tracingtypes.RequestWithTraceID{east, west, local}, got)
+
+	queue.Done(east)
+	require.False(t, queue.IsObjectPending(Key(east)))
+	require.True(t, queue.IsObjectInFlight(Key(west)))
+	require.True(t, queue.IsObjectInFlight(key))
+
+	for _, req := range []tracingtypes.RequestWithTraceID{east, west, local} {
+		bare := newRequest(req.NamespacedName, tracingtypes.RequestParent{})
+		bare.ClusterName = req.ClusterName
+		require.Equal(t, bare, requestForKey(Key(req)))
+	}
+}
+
+func TestTracingQueueDeadLetterQueue(t *testing.T) {
+	const maxRetries = 3
+	var dead []tracingtypes.RequestWithTraceID
+	tq := NewTracingQueue(WithDeadLetterQueue(maxRetries, func(req tracingtypes.RequestWithTraceID) {
+		dead = append(dead, req)
+	}))
+	defer tq.ShutDown()
+	tq.SetRateLimiter(workqueue.NewTypedItemExponentialFailureRateLimiter[types.NamespacedName](time.Millisecond, time.Millisecond))
+	key := types.NamespacedName{Namespace: "default", Name: "obj"}
+	parent := tracingtypes.RequestParent{TraceID: "1", SpanID: "a", Kind: "Pod", Name: "pod"}
+
+	tq.Add(newRequest(key, parent))
+	for i := 1; i <= maxRetries; i++ {
+		req, shutdown := tq.Get()
+		require.False(t, shutdown)
+		tq.AddRateLimited(req)
+		tq.Done(req)
+		require.Equal(t, i, tq.NumRequeues(req))
+		require.Empty(t, dead, "the request must be retried %d times", maxRetries)
+	}
+
+	// The next failure hands the request to the handler instead of retrying it
+	req, shutdown := tq.Get()
+	require.False(t, shutdown)
+	tq.AddRateLimited(req)
+	tq.Done(req)
+	require.Len(t, dead, 1)
+	require.Equal(t, key, dead[0].NamespacedName)
+	require.Equal(t, parent, dead[0].Parent)
+	require.Zero(t, tq.NumRequeues(req))
+	require.False(t, tq.IsObjectPending(key))
+	require.Zero(t, tq.Len())
+}
+
+func TestWithDeadLetterQueueIgnoresInvalidValues(t *testing.T) {
+	handler := func(tracingtypes.RequestWithTraceID) {}
+	require.Nil(t, NewTracingQueue(WithDeadLetterQueue(0, handler)).deadLetter)
+	require.Nil(t, NewTracingQueue(WithDeadLetterQueue(3, nil)).deadLetter)
+}
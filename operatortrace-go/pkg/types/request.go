@@ -5,26 +5,215 @@
 package types
 
 import (
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	k8stypes "k8s.io/apimachinery/pkg/types"
 	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // RequestWithTraceID is the normal reconcile request object with tracing information added to it.
 type RequestWithTraceID struct {
 	ctrlreconcile.Request
+	// ClusterName names the cluster the object lives in for controllers watching several clusters.
+	// Empty means the controller's own cluster.
+	ClusterName string
+	// ControllerName names the controller reconciling the request, set by the TracingQueue of a named controller.
+	ControllerName  string
 	Parent          RequestParent
 	LinkedSpans     [10]LinkedSpan
 	LinkedSpanCount int
 }
 
+// Key returns the key requests for the same object share. RequestWithTraceID itself is comparable, but two
+// requests for one object with different traces are different values, so maps and queues that dedupe requests
+// must key them by Key and merge the duplicates with Merge. It is the NamespacedName, with the namespace prefixed
+// by ClusterName when the request belongs to a named cluster.
+func (r RequestWithTraceID) Key() k8stypes.NamespacedName {
+	if r.ClusterName == "" {
+		return r.NamespacedName
+	}
+	return k8stypes.NamespacedName{Namespace: r.ClusterName + "/" + r.Namespace, Name: r.Name}
+}
+
 type RequestParent struct {
 	TraceID   string
 	SpanID    string
 	Name      string
 	Kind      string
 	EventKind string
+	// ChangedFields lists the fields (e.g. spec, status, labels) changed by the update that produced the request,
+	// sorted and comma separated.
+	// It is a string rather than a slice so requests stay comparable as work queue keys. Empty means unknown.
+	ChangedFields string
+	// Verified reports that TraceID and SpanID were read through the AnnotationCodec of the tracing client, so
+	// the client may store them with that codec. Unverified parents are only linked when a codec is configured.
+	Verified bool
+}
+
+// EventKindResync is the RequestParent.EventKind of requests triggered by an informer resync rather than by a change
+// to the object.
+const EventKindResync = "Resync"
+
+// ChangedFieldList returns the fields recorded in ChangedFields.
+func (p RequestParent) ChangedFieldList() []string {
+	if p.ChangedFields == "" {
+		return nil
+	}
+	return strings.Split(p.ChangedFields, ",")
+}
+
+// HasChangedField reports whether field is recorded in ChangedFields.
+func (p RequestParent) HasChangedField(field string) bool {
+	for _, changed := range p.ChangedFieldList() {
+		if changed == field {
+			return true
+		}
+	}
+	return false
+}
+
+// JoinChangedFields builds a ChangedFields value from fields, dropping empty and duplicate entries.
+func JoinChangedFields(fields ...string) string {
+	seen := make(map[string]struct{}, len(fields))
+	unique := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if _, ok := seen[field]; ok || field == "" {
+			continue
+		}
+		seen[field] = struct{}{}
+		unique = append(unique, field)
+	}
+	sort.Strings(unique)
+	return strings.Join(unique, ",")
 }
 
+// Attributes of the span links of linked spans that were once the parent of a request, describing the event that
+// enqueued the request.
+const (
+	LinkObjectNameAttributeKey = attribute.Key("k8s.object.name")
+	LinkObjectKindAttributeKey = attribute.Key("k8s.object.kind")
+	LinkEventKindAttributeKey  = attribute.Key("event.kind")
+)
+
 type LinkedSpan struct {
 	TraceID string
 	SpanID  string
+	// LinkedSpanAttributes are set on the span link to the linked span. It is a set rather than a slice so requests
+	// stay comparable as work queue keys.
+	LinkedSpanAttributes attribute.Set
+}
+
+// sameSpan reports whether s and other identify the same span, whatever their attributes.
+func (s LinkedSpan) sameSpan(other LinkedSpan) bool {
+	return s.TraceID == other.TraceID && s.SpanID == other.SpanID
+}
+
+// ResultWithTraceOption tells the tracing queue whether a requeued request keeps the trace of the reconcile that requeued it.
+type ResultWithTraceOption string
+
+const (
+	// RequeueTraceDefault leaves the requeued request to the queue's default merge behaviour.
+	RequeueTraceDefault ResultWithTraceOption = ""
+	// RequeueTraceKeep carries the current parent and linked spans into the requeued request.
+	RequeueTraceKeep ResultWithTraceOption = "keep"
+	// RequeueTraceDrop requeues the request without any trace so the next reconcile starts a fresh trace.
+	RequeueTraceDrop ResultWithTraceOption = "drop"
+)
+
+// AppendLinkedSpan records span as the most recent linked span, skipping empty entries.
+// LinkedSpans is kept ordered from oldest to newest and holds each (TraceID, SpanID) pair once: appending a span
+// that is already recorded moves it to the end, keeping its attributes unless span has its own, and once
+// LinkedSpans is full the oldest span is evicted.
+func (r *RequestWithTraceID) AppendLinkedSpan(span LinkedSpan) {
+	if len(span.TraceID) == 0 && len(span.SpanID) == 0 {
+		return
+	}
+
+	// drop is the slot that gives way to span: its previous position, the oldest span, or a free slot.
+	drop := r.LinkedSpanCount
+	for i := 0; i < r.LinkedSpanCount; i++ {
+		if r.LinkedSpans[i].sameSpan(span) {
+			drop = i
+			if span.LinkedSpanAttributes.Len() == 0 {
+				span.LinkedSpanAttributes = r.LinkedSpans[i].LinkedSpanAttributes
+			}
+			break
+		}
+	}
+	if drop == len(r.LinkedSpans) {
+		// Full and not yet recorded: evict the oldest span.
+		drop = 0
+	} else if drop == r.LinkedSpanCount {
+		r.LinkedSpanCount++
+	}
+	copy(r.LinkedSpans[drop:r.LinkedSpanCount-1], r.LinkedSpans[drop+1:r.LinkedSpanCount])
+	r.LinkedSpans[r.LinkedSpanCount-1] = span
+}
+
+// Merge merges incoming, a request with the same Key, into r the way the tracing queue merges duplicate requests:
+// the newest parent with a trace context wins, the parent it replaces becomes a linked span, and no span is
+// recorded both as the parent and as a link.
+func (r *RequestWithTraceID) Merge(incoming RequestWithTraceID) {
+	// Only try to promote the incoming parent if it has a valid trace context
+	if len(incoming.Parent.TraceID) > 0 && len(incoming.Parent.SpanID) > 0 {
+		if r.Parent.TraceID != incoming.Parent.TraceID ||
+			r.Parent.SpanID != incoming.Parent.SpanID ||
+			r.Parent.Name != incoming.Parent.Name ||
+			r.Parent.Kind != incoming.Parent.Kind ||
+			r.Parent.EventKind != incoming.Parent.EventKind {
+			// Preserve the previous parent as a linked span before overwriting it
+			r.AppendLinkedSpan(r.Parent.span())
+			r.Parent = incoming.Parent
+			r.removeLinkedSpan(r.Parent.span())
+		} else if r.Parent.ChangedFields != incoming.Parent.ChangedFields {
+			// Coalesced updates from the same trace changed the union of both field sets; unknown stays unknown
+			if r.Parent.ChangedFields != "" && incoming.Parent.ChangedFields != "" {
+				r.Parent.ChangedFields = JoinChangedFields(append(r.Parent.ChangedFieldList(), incoming.Parent.ChangedFieldList()...)...)
+			} else {
+				r.Parent.ChangedFields = ""
+			}
+		}
+	}
+
+	// Link the spans that came with incoming (e.g., retries), except the parent
+	parent := r.Parent.span()
+	for i := 0; i < incoming.LinkedSpanCount; i++ {
+		if !incoming.LinkedSpans[i].sameSpan(parent) {
+			r.AppendLinkedSpan(incoming.LinkedSpans[i])
+		}
+	}
+}
+
+// removeLinkedSpan removes span from the linked spans of r, keeping the order of the others.
+func (r *RequestWithTraceID) removeLinkedSpan(span LinkedSpan) {
+	for i := 0; i < r.LinkedSpanCount; i++ {
+		if !r.LinkedSpans[i].sameSpan(span) {
+			continue
+		}
+		copy(r.LinkedSpans[i:r.LinkedSpanCount-1], r.LinkedSpans[i+1:r.LinkedSpanCount])
+		r.LinkedSpanCount--
+		r.LinkedSpans[r.LinkedSpanCount] = LinkedSpan{}
+		return
+	}
+}
+
+// span returns p as a linked span, with link attributes describing the object and event of p.
+func (p RequestParent) span() LinkedSpan {
+	var attrs []attribute.KeyValue
+	if p.Name != "" {
+		attrs = append(attrs, LinkObjectNameAttributeKey.String(p.Name))
+	}
+	if p.Kind != "" {
+		attrs = append(attrs, LinkObjectKindAttributeKey.String(p.Kind))
+	}
+	if p.EventKind != "" {
+		attrs = append(attrs, LinkEventKindAttributeKey.String(p.EventKind))
+	}
+	span := LinkedSpan{TraceID: p.TraceID, SpanID: p.SpanID}
+	if len(attrs) > 0 {
+		span.LinkedSpanAttributes = attribute.NewSet(attrs...)
+	}
+	return span
 }
@@ -0,0 +1,174 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/types/request_test.go
+
+package types
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	ctrlreconcile "sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestAppendLinkedSpan(t *testing.T) {
+	req := &RequestWithTraceID{
+		LinkedSpans:     [10]LinkedSpan{},
+		LinkedSpanCount: 0,
+	}
+
+	span1 := LinkedSpan{TraceID: "1", SpanID: "a"}
+	span2 := LinkedSpan{TraceID: "2", SpanID: "b"}
+	span3 := LinkedSpan{TraceID: "3", SpanID: "c"}
+	spanEmpty := LinkedSpan{}
+
+	// Start: add two spans
+	req.AppendLinkedSpan(span1)
+	req.AppendLinkedSpan(span2)
+
+	require.Equal(t, 2, req.LinkedSpanCount)
+	require.Equal(t, []LinkedSpan{span1, span2}, req.LinkedSpans[:req.LinkedSpanCount])
+
+	// Add third, expect three
+	req.AppendLinkedSpan(span3)
+
+	require.Equal(t, 3, req.LinkedSpanCount)
+	require.Equal(t, []LinkedSpan{span1, span2, span3}, req.LinkedSpans[:req.LinkedSpanCount])
+
+	// Add a duplicate, expect it to become the most recent span
+	req.AppendLinkedSpan(span1)
+	require.Equal(t, 3, req.LinkedSpanCount)
+	require.Equal(t, []LinkedSpan{span2, span3, span1}, req.LinkedSpans[:req.LinkedSpanCount])
+
+	// Try to add an empty linked span
+	req.AppendLinkedSpan(spanEmpty)
+	require.Equal(t, 3, req.LinkedSpanCount)
+	require.Equal(t, []LinkedSpan{span2, span3, span1}, req.LinkedSpans[:req.LinkedSpanCount])
+}
+
+func TestAppendLinkedSpanEvictsOldestWhenFull(t *testing.T) {
+	req := &RequestWithTraceID{}
+	spans := make([]LinkedSpan, 0, len(req.LinkedSpans)+2)
+	for i := 0; i < cap(spans); i++ {
+		spans = append(spans, LinkedSpan{TraceID: fmt.Sprintf("trace-%d", i), SpanID: fmt.Sprintf("span-%d", i)})
+	}
+
+	for _, span := range spans {
+		req.AppendLinkedSpan(span)
+	}
+	require.Equal(t, len(req.LinkedSpans), req.LinkedSpanCount)
+	require.Equal(t, spans[2:], req.LinkedSpans[:])
+
+	// Refreshing the oldest span protects it from the next eviction
+	req.AppendLinkedSpan(spans[2])
+	req.AppendLinkedSpan(LinkedSpan{TraceID: "trace-new", SpanID: "span-new"})
+	require.Equal(t, len(req.LinkedSpans), req.LinkedSpanCount)
+	require.Equal(t, spans[2], req.LinkedSpans[len(req.LinkedSpans)-2])
+	require.Equal(t, LinkedSpan{TraceID: "trace-new", SpanID: "span-new"}, req.LinkedSpans[len(req.LinkedSpans)-1])
+	require.NotContains(t, req.LinkedSpans[:], spans[3])
+}
+
+func TestRequestKey(t *testing.T) {
+	req := RequestWithTraceID{Request: ctrlreconcile.Request{NamespacedName: k8stypes.NamespacedName{Namespace: "default", Name: "pod"}}}
+	require.Equal(t, k8stypes.NamespacedName{Namespace: "default", Name: "pod"}, req.Key())
+
+	// The trace does not change the key, the cluster does
+	traced := req
+	traced.Parent = RequestParent{TraceID: "1", SpanID: "a"}
+	require.Equal(t, req.Key(), traced.Key())
+	traced.ClusterName = "east"
+	require.Equal(t, k8stypes.NamespacedName{Namespace: "east/default", Name: "pod"}, traced.Key())
+}
+
+func TestMerge(t *testing.T) {
+	first := RequestParent{TraceID: "1", SpanID: "a", Kind: "Pod", Name: "first", EventKind: "new"}
+	second := RequestParent{TraceID: "2", SpanID: "b", Kind: "Pod", Name: "second", EventKind: "new"}
+	retry := LinkedSpan{TraceID: "3", SpanID: "c"}
+
+	tests := []struct {
+		name           string
+		existing       RequestWithTraceID
+		incoming       RequestWithTraceID
+		expectedParent RequestParent
+		expectedLinks  []LinkedSpan
+	}{
+		{
+			name:           "newest parent wins and the previous one is linked",
+			existing:       RequestWithTraceID{Parent: first},
+			incoming:       RequestWithTraceID{Parent: second},
+			expectedParent: second,
+			expectedLinks:  []LinkedSpan{first.span()},
+		},
+		{
+			name:           "incoming parent without a trace is ignored",
+			existing:       RequestWithTraceID{Parent: first},
+			incoming:       RequestWithTraceID{Parent: RequestParent{Kind: "Pod", Name: "untraced"}},
+			expectedParent: first,
+		},
+		{
+			name:           "promoted parent is no longer linked",
+			existing:       RequestWithTraceID{Parent: first, LinkedSpans: [10]LinkedSpan{second.span()}, LinkedSpanCount: 1},
+			incoming:       RequestWithTraceID{Parent: second},
+			expectedParent: second,
+			expectedLinks:  []LinkedSpan{first.span()},
+		},
+		{
+			name:           "incoming links are kept except the parent",
+			existing:       RequestWithTraceID{Parent: first},
+			incoming:       RequestWithTraceID{Parent: first, LinkedSpans: [10]LinkedSpan{first.span(), retry}, LinkedSpanCount: 2},
+			expectedParent: first,
+			expectedLinks:  []LinkedSpan{retry},
+		},
+		{
+			name: "changed fields of the same parent are joined",
+			existing: RequestWithTraceID{Parent: RequestParent{
+				TraceID: "1", SpanID: "a", Kind: "Pod", Name: "first", EventKind: "new", ChangedFields: "spec",
+			}},
+			incoming: RequestWithTraceID{Parent: RequestParent{
+				TraceID: "1", SpanID: "a", Kind: "Pod", Name: "first", EventKind: "new", ChangedFields: "labels",
+			}},
+			expectedParent: RequestParent{TraceID: "1", SpanID: "a", Kind: "Pod", Name: "first", EventKind: "new", ChangedFields: "labels,spec"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := tt.existing
+			merged.Merge(tt.incoming)
+			require.Equal(t, tt.expectedParent, merged.Parent)
+			require.Equal(t, len(tt.expectedLinks), merged.LinkedSpanCount)
+			for i, link := range tt.expectedLinks {
+				require.Equal(t, link, merged.LinkedSpans[i])
+			}
+		})
+	}
+}
+
+func TestLinkedSpanAttributes(t *testing.T) {
+	parent := RequestParent{TraceID: "1", SpanID: "a", Kind: "Pod", Name: "first", EventKind: "new"}
+	attrs := attribute.NewSet(
+		LinkObjectNameAttributeKey.String("first"),
+		LinkObjectKindAttributeKey.String("Pod"),
+		LinkEventKindAttributeKey.String("new"),
+	)
+	require.Equal(t, LinkedSpan{TraceID: "1", SpanID: "a", LinkedSpanAttributes: attrs}, parent.span())
+	require.Equal(t, LinkedSpan{TraceID: "1", SpanID: "a"}, RequestParent{TraceID: "1", SpanID: "a"}.span())
+
+	req := RequestWithTraceID{}
+	req.AppendLinkedSpan(parent.span())
+	req.AppendLinkedSpan(LinkedSpan{TraceID: "2", SpanID: "b"})
+
+	// Re-linking the span without attributes keeps the ones it was recorded with
+	req.AppendLinkedSpan(LinkedSpan{TraceID: "1", SpanID: "a"})
+	require.Equal(t, 2, req.LinkedSpanCount)
+	require.Equal(t, attrs, req.LinkedSpans[1].LinkedSpanAttributes)
+
+	// Attributes of their own replace them
+	other := attribute.NewSet(LinkEventKindAttributeKey.String("old"))
+	req.AppendLinkedSpan(LinkedSpan{TraceID: "1", SpanID: "a", LinkedSpanAttributes: other})
+	require.Equal(t, 2, req.LinkedSpanCount)
+	require.Equal(t, other, req.LinkedSpans[1].LinkedSpanAttributes)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/webhook/webhook.go
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/tracecontext"
+	admissionv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// Option configures the handler returned by NewTracingValidatingWebhook.
+type Option func(*options)
+
+type options struct {
+	traceParentAnnotation string
+	validateOnUpdate      bool
+}
+
+// WithValidateOnUpdate controls whether updates are validated as well as creates. Updates are validated by default.
+func WithValidateOnUpdate(validate bool) Option {
+	return func(o *options) {
+		o.validateOnUpdate = validate
+	}
+}
+
+// WithTraceParentAnnotation validates the given annotation key instead of the default traceparent annotation, for
+// tracing clients configured with another annotation prefix or key.
+func WithTraceParentAnnotation(key string) Option {
+	return func(o *options) {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return
+		}
+		o.traceParentAnnotation = key
+	}
+}
+
+// tracingValidatingWebhook rejects objects whose traceparent annotation is malformed.
+type tracingValidatingWebhook struct {
+	options
+}
+
+// NewTracingValidatingWebhook returns an admission handler that rejects the creation or update of any object whose
+// traceparent annotation is set but malformed, which would otherwise be silently ignored when the trace context is
+// read back. Objects without the annotation are allowed. Register it with a webhook.Admission for the resources to
+// guard.
+func NewTracingValidatingWebhook(opts ...Option) admission.Handler {
+	w := &tracingValidatingWebhook{options: options{
+		traceParentAnnotation: constants.DefaultTraceParentAnnotation,
+		validateOnUpdate:      true,
+	}}
+	for _, opt := range opts {
+		if opt == nil {
+			continue
+		}
+		opt(&w.options)
+	}
+	return w
+}
+
+// Handle implements admission.Handler.
+func (w *tracingValidatingWebhook) Handle(_ context.Context, req admission.Request) admission.Response {
+	switch req.Operation {
+	case admissionv1.Create:
+	case admissionv1.Update:
+		if !w.validateOnUpdate {
+			return admission.Allowed("")
+		}
+	default:
+		return admission.Allowed("")
+	}
+
+	obj := &metav1.PartialObjectMetadata{}
+	if err := json.Unmarshal(req.Object.Raw, obj); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	traceParent, ok := obj.GetAnnotations()[w.traceParentAnnotation]
+	if !ok {
+		return admission.Allowed("")
+	}
+	if err := tracecontext.ValidateTraceParent(traceParent); err != nil {
+		return admission.Denied(fmt.Sprintf("annotation %s is malformed: %v", w.traceParentAnnotation, err))
+	}
+	return admission.Allowed("")
+}
@@ -0,0 +1,79 @@
+// Copyright (c) Microsoft Corporation.
+// Licensed under the MIT License.
+// pkg/webhook/webhook_test.go
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/Azure/operatortrace/operatortrace-go/pkg/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const validTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func admissionRequest(t *testing.T, operation admissionv1.Operation, annotations map[string]string) admission.Request {
+	t.Helper()
+	raw, err := json.Marshal(&corev1.ConfigMap{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Name: "sample", Namespace: "default", Annotations: annotations},
+	})
+	require.NoError(t, err)
+	return admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: operation,
+		Object:    runtime.RawExtension{Raw: raw},
+	}}
+}
+
+func TestTracingValidatingWebhook(t *testing.T) {
+	tests := []struct {
+		name        string
+		operation   admissionv1.Operation
+		annotations map[string]string
+		opts        []Option
+		allowed     bool
+	}{
+		{"valid traceparent on create", admissionv1.Create, map[string]string{constants.DefaultTraceParentAnnotation: validTraceParent}, nil, true},
+		{"invalid traceparent on create", admissionv1.Create, map[string]string{constants.DefaultTraceParentAnnotation: "not-a-traceparent"}, nil, false},
+		{"absent traceparent on create", admissionv1.Create, map[string]string{"team": "a"}, nil, true},
+		{"empty traceparent on create", admissionv1.Create, map[string]string{constants.DefaultTraceParentAnnotation: ""}, nil, false},
+		{"valid traceparent on update", admissionv1.Update, map[string]string{constants.DefaultTraceParentAnnotation: validTraceParent}, nil, true},
+		{"invalid traceparent on update", admissionv1.Update, map[string]string{constants.DefaultTraceParentAnnotation: "00-zz-zz-01"}, nil, false},
+		{"absent traceparent on update", admissionv1.Update, nil, nil, true},
+		{"update validation disabled", admissionv1.Update, map[string]string{constants.DefaultTraceParentAnnotation: "00-zz-zz-01"}, []Option{WithValidateOnUpdate(false)}, true},
+		{"create still validated with update validation disabled", admissionv1.Create, map[string]string{constants.DefaultTraceParentAnnotation: "00-zz-zz-01"}, []Option{WithValidateOnUpdate(false)}, false},
+		{"delete is not validated", admissionv1.Delete, map[string]string{constants.DefaultTraceParentAnnotation: "00-zz-zz-01"}, nil, true},
+		{"custom annotation key", admissionv1.Create, map[string]string{"example.com/traceparent": "00-zz-zz-01"}, []Option{WithTraceParentAnnotation("example.com/traceparent")}, false},
+		{"default key ignored with a custom key", admissionv1.Create, map[string]string{constants.DefaultTraceParentAnnotation: "00-zz-zz-01"}, []Option{WithTraceParentAnnotation("example.com/traceparent")}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := NewTracingValidatingWebhook(tt.opts...).Handle(context.Background(), admissionRequest(t, tt.operation, tt.annotations))
+			assert.Equal(t, tt.allowed, resp.Allowed)
+			if !tt.allowed {
+				assert.Equal(t, int32(http.StatusForbidden), resp.Result.Code)
+				assert.Contains(t, resp.Result.Message, "is malformed")
+			}
+		})
+	}
+}
+
+func TestTracingValidatingWebhookUndecodableObject(t *testing.T) {
+	resp := NewTracingValidatingWebhook().Handle(context.Background(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+		Object:    runtime.RawExtension{Raw: []byte("{")},
+	}})
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, int32(http.StatusBadRequest), resp.Result.Code)
+}